@@ -0,0 +1,340 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"runtime"
+	"sync"
+)
+
+// AggregatedRangeProof proves that every one of K value commitments hides a value in [0, Np^(Nd/K)) with a
+// single arithmetic circuit proof. V contains the digit reciprocal commitment of every value, in the order of
+// the value commitments.
+type AggregatedRangeProof struct {
+	*ArithmeticCircuitProof
+	V []*bn256.G1
+}
+
+// aggregatedDigits returns the digit count of a single value when public is shared by K values.
+func aggregatedDigits(public *ReciprocalPublic, K int) (int, error) {
+	if K < 1 {
+		return 0, errors.New("at least one value is required")
+	}
+
+	if public.Nd%K != 0 {
+		return 0, fmt.Errorf("Nd=%d is not divisible by the number of values %d", public.Nd, K)
+	}
+
+	// The multiplicities are placed into the free witness and digit slots of the circuit.
+	if nd, No := public.Nd/K, len(public.digitSet()); No > nd+1+public.Nd {
+		return 0, fmt.Errorf("%d digit multiplicities do not fit into %d values of %d digits", No, K, nd)
+	}
+
+	return public.Nd / K, nil
+}
+
+// valuePublic returns the parameters of a single value of an aggregated proof: the commitments of all values
+// share G, the blinding generator and the witness generators, only the digit generators differ.
+func valuePublic(public *ReciprocalPublic, nd int) *ReciprocalPublic {
+	return &ReciprocalPublic{
+		G:    public.G,
+		GVec: public.GVec[:nd],
+		HVec: public.HVec[:reciprocalHVecLen(nd)],
+		Nd:   nd,
+		Np:   public.Np,
+
+		DigitSet: public.DigitSet,
+	}
+}
+
+// aggregatedRangeCircuit builds the reciprocal range argument over K values of nd = Nd/K digits each for
+// challenge e. The digits of value k occupy wires k*nd...(k+1)*nd-1 and the multiplicities are shared, so the
+// single pole sum covers the digits of all values. Only the first reciprocalHVecLen(nd) entries of HVec are used for
+// the commitments, the rest of HVec pads the WNLA vectors.
+func aggregatedRangeCircuit(public *ReciprocalPublic, K int, e *big.Int) *ArithmeticCircuitPublic {
+	nd := public.Nd / K
+	set := public.digitSet()
+
+	Nm := public.Nd
+	No := len(set)
+
+	Nv := nd + 1
+	Nl := Nv * K
+	Nw := Nm + Nm + No
+
+	am := oneVector(Nm)
+	Wm := zeroMatrix(Nm, Nw)
+
+	for i := 0; i < Nm; i++ {
+		Wm[i][i+Nm] = minus(e)
+	}
+
+	poles := make([]*big.Int, No)
+	for j := range poles {
+		poles[j] = minus(inv(add(e, bint(set[j]))))
+	}
+
+	al := zeroVector(Nl)
+	Wl := zeroMatrix(Nl, Nw)
+
+	base := bint(public.Np)
+
+	for k := 0; k < K; k++ {
+		row := k * Nv
+
+		// v
+		for i := 0; i < nd; i++ {
+			Wl[row][k*nd+i] = minus(pow(base, i))
+		}
+
+		// r
+		for i := 0; i < nd; i++ {
+			for j := 0; j < Nm; j++ {
+				if j != k*nd+i {
+					Wl[row+1+i][j+Nm] = bint(1)
+				}
+			}
+
+			for j := 0; j < No; j++ {
+				Wl[row+1+i][j+2*Nm] = poles[j]
+			}
+		}
+	}
+
+	return &ArithmeticCircuitPublic{
+		Nm:   Nm,
+		Nl:   Nl,
+		Nv:   Nv,
+		Nw:   Nw,
+		No:   No,
+		K:    K,
+		G:    public.G,
+		GVec: public.GVec,
+		HVec: public.HVec[:reciprocalHVecLen(nd)],
+		Wm:   Wm,
+		Wl:   Wl,
+		Am:   am,
+		Al:   al,
+		Fl:   true,
+		Fm:   false,
+		F: func(typ PartitionType, index int) *int {
+			// The multiplicities fill the free witness slots first and continue in the n part.
+			switch {
+			case typ == PartitionLL && index < min(No, Nv):
+				return &index
+			case typ == PartitionNO && index < No-Nv:
+				o := index + Nv
+				return &o
+			}
+
+			return nil
+		},
+		GVec_: public.GVec_,
+		HVec_: append(append([]*bn256.G1{}, public.HVec[reciprocalHVecLen(nd):]...), public.HVec_...),
+	}
+}
+
+// parallelFor calls fn(i) for every i < n on up to workers goroutines and returns the error of the lowest failing
+// index. Every call must only write to its own index, so the results do not depend on scheduling.
+func parallelFor(n, workers int, fn func(i int) error) error {
+	errs := make([]error, n)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(max(workers, 1), n); w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < n; i += max(workers, 1) {
+				errs[i] = fn(i)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ProveRangeAggregated proves that every values[k] lies in [0, Np^(Nd/K)) for the commitments
+// CommitValue(values[k], blindings[k]), where K = len(values), with one proof. The public parameters are shared by
+// all values, so they must be created for the total digit count, e.g. NewReciprocalPublic(domain, K*Nd, Np).
+//
+// The digit decomposition and the commitments of the values are independent and computed in parallel. The
+// transcript only absorbs their results, in the order of values, so the proof does not depend on scheduling.
+// Use empty FiatShamirEngine for call.
+func ProveRangeAggregated(public *ReciprocalPublic, fs FiatShamirEngine, values, blindings []*big.Int) (*AggregatedRangeProof, error) {
+	if err := checkFreshTranscript(fs); err != nil {
+		return nil, err
+	}
+
+	return proveRangeAggregated(public, fs, values, blindings, runtime.GOMAXPROCS(0))
+}
+
+func proveRangeAggregated(public *ReciprocalPublic, fs FiatShamirEngine, values, blindings []*big.Int, workers int) (*AggregatedRangeProof, error) {
+	if err := public.Validate(); err != nil {
+		return nil, err
+	}
+
+	if fs == nil {
+		return nil, errors.New("Fiat-Shamir engine cannot be nil")
+	}
+
+	if len(values) != len(blindings) {
+		return nil, fmt.Errorf("got %d values and %d blindings", len(values), len(blindings))
+	}
+
+	K := len(values)
+	nd, err := aggregatedDigits(public, K)
+	if err != nil {
+		return nil, err
+	}
+
+	vp := valuePublic(public, nd)
+
+	privates := make([]*ReciprocalPrivate, K)
+	vComs := make([]*bn256.G1, K)
+
+	err = parallelFor(K, workers, func(k int) error {
+		if values[k] == nil || blindings[k] == nil {
+			return fmt.Errorf("value and blinding %d cannot be nil", k)
+		}
+
+		if err := checkBlinding(scalarField{bn256.Order}, blindings[k]); err != nil {
+			return fmt.Errorf("value %d: %w", k, err)
+		}
+
+		private, err := vp.newPrivate(values[k], blindings[k])
+		if err != nil {
+			return fmt.Errorf("value %d: %w", k, err)
+		}
+
+		privates[k] = private
+		vComs[k] = vp.CommitValue(values[k], blindings[k])
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := addPointVector(fs, vComs); err != nil {
+		return nil, err
+	}
+
+	e := fs.GetChallenge()
+	if err := checkPoleChallenge(scalarField{bn256.Order}, e, public.digitSet()); err != nil {
+		return nil, err
+	}
+
+	circuit := aggregatedRangeCircuit(public, K, e)
+
+	prv := &ArithmeticCircuitPrivate{
+		V:  make([][]*big.Int, K),
+		Sv: make([]*big.Int, K),
+		Wo: zeroVector(len(public.digitSet())),
+	}
+
+	rs := make([][]*big.Int, K)
+	rComs := make([]*bn256.G1, K)
+	V := make([]*bn256.G1, K)
+
+	err = parallelFor(K, workers, func(k int) error {
+		r := make([]*big.Int, nd)
+		for j := range r {
+			r[j] = inv(add(privates[k].Digits[j], e))
+		}
+
+		rBlind := NewRandScalar()
+		rComs[k] = vp.CommitPoles(r, rBlind)
+
+		prv.V[k] = append([]*big.Int{privates[k].X}, r...)
+		prv.Sv[k] = add(privates[k].S, rBlind)
+		V[k] = circuit.CommitCircuit(prv.V[k], prv.Sv[k])
+		rs[k] = r
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for k := range privates {
+		prv.Wl = append(prv.Wl, privates[k].Digits...)
+		prv.Wr = append(prv.Wr, rs[k]...)
+		prv.Wo = vectorAdd(prv.Wo, privates[k].M)
+	}
+
+	circuitProof, err := ProveCircuit(circuit, V, fs, prv)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AggregatedRangeProof{
+		ArithmeticCircuitProof: circuitProof,
+		V:                      rComs,
+	}, nil
+}
+
+// VerifyRangeAggregated verifies a proof produced by ProveRangeAggregated for the value commitments V. If err is
+// nil then proof is valid. Use empty FiatShamirEngine for call.
+func VerifyRangeAggregated(public *ReciprocalPublic, V []*bn256.G1, fs FiatShamirEngine, proof *AggregatedRangeProof) error {
+	if err := checkFreshTranscript(fs); err != nil {
+		return err
+	}
+
+	if err := public.Validate(); err != nil {
+		return err
+	}
+
+	if fs == nil {
+		return errors.New("Fiat-Shamir engine cannot be nil")
+	}
+
+	K := len(V)
+	if _, err := aggregatedDigits(public, K); err != nil {
+		return err
+	}
+
+	for k := range V {
+		if V[k] == nil {
+			return fmt.Errorf("value commitment %d cannot be nil", k)
+		}
+	}
+
+	if proof == nil || proof.ArithmeticCircuitProof == nil || len(proof.V) != K {
+		return errors.New("aggregated range proof is incomplete")
+	}
+
+	for k := range proof.V {
+		if proof.V[k] == nil {
+			return errors.New("aggregated range proof is incomplete")
+		}
+	}
+
+	if err := addPointVector(fs, V); err != nil {
+		return err
+	}
+
+	e := fs.GetChallenge()
+	if err := checkPoleChallenge(scalarField{bn256.Order}, e, public.digitSet()); err != nil {
+		return err
+	}
+
+	circuit := aggregatedRangeCircuit(public, K, e)
+
+	coms := make([]*bn256.G1, K)
+	for k := range coms {
+		coms[k] = new(bn256.G1).Add(V[k], proof.V[k])
+	}
+
+	return VerifyCircuit(circuit, coms, fs, proof.ArithmeticCircuitProof)
+}
@@ -0,0 +1,95 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"runtime"
+	"testing"
+)
+
+func TestRangeAggregated(t *testing.T) {
+	// Four values of four hex digits each.
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	values := []*big.Int{bint(0), bint(1), bint(0xffff), bint(0x1234)}
+	blindings := []*big.Int{NewRandScalar(), NewRandScalar(), NewRandScalar(), NewRandScalar()}
+
+	coms := make([]*bn256.G1, len(values))
+	for k := range coms {
+		coms[k] = public.CommitValue(values[k], blindings[k])
+	}
+
+	for _, workers := range []int{1, 3, runtime.GOMAXPROCS(0)} {
+		proof, err := proveRangeAggregated(public, NewKeccakFS(), values, blindings, workers)
+		if err != nil {
+			t.Fatalf("ProveRangeAggregated with %d workers failed: %v", workers, err)
+		}
+
+		if err := VerifyRangeAggregated(public, coms, NewKeccakFS(), proof); err != nil {
+			t.Fatalf("VerifyRangeAggregated with %d workers failed: %v", workers, err)
+		}
+	}
+
+	proof, err := ProveRangeAggregated(public, NewKeccakFS(), values, blindings)
+	if err != nil {
+		t.Fatalf("ProveRangeAggregated failed: %v", err)
+	}
+
+	swapped := []*bn256.G1{coms[1], coms[0], coms[2], coms[3]}
+	if err := VerifyRangeAggregated(public, swapped, NewKeccakFS(), proof); err == nil {
+		t.Error("Proof accepted for reordered commitments")
+	}
+
+	if err := VerifyRangeAggregated(public, coms[:2], NewKeccakFS(), proof); err == nil {
+		t.Error("Proof accepted for a subset of the commitments")
+	}
+
+	// Each value only has Nd/K digits.
+	tooLarge := []*big.Int{bint(0), bint(0x10000), bint(0), bint(0)}
+	if _, err := ProveRangeAggregated(public, NewKeccakFS(), tooLarge, blindings); err == nil {
+		t.Error("Proved a value outside of the per-value range")
+	}
+
+	if _, err := ProveRangeAggregated(public, NewKeccakFS(), values[:3], blindings[:3]); err == nil {
+		t.Error("Expected an error when Nd is not divisible by the number of values")
+	}
+}
+
+// BenchmarkProveRangeAggregated compares sequential and parallel preprocessing when aggregating 64 values of four
+// hex digits.
+func BenchmarkProveRangeAggregated(b *testing.B) {
+	const K, nd = 64, 4
+
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, K*nd, 16)
+	if err != nil {
+		b.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	values := make([]*big.Int, K)
+	blindings := make([]*big.Int, K)
+	for k := range values {
+		values[k] = bint(k * 1000)
+		blindings[k] = NewRandScalar()
+	}
+
+	for _, bench := range []struct {
+		name    string
+		workers int
+	}{{"sequential", 1}, {"parallel", runtime.GOMAXPROCS(0)}} {
+		workers := bench.workers
+		b.Run(bench.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := proveRangeAggregated(public, NewKeccakFS(), values, blindings, workers); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
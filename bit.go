@@ -0,0 +1,119 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+)
+
+// checkBitStatement validates the parameters of a bit proof. The value is decomposed into bits, so the parameters
+// must use base 2, e.g. NewReciprocalPublic(domain, 64, 2) for 64-bit values.
+func checkBitStatement(public *ReciprocalPublic, bitIndex, expected int) error {
+	if err := public.Validate(); err != nil {
+		return err
+	}
+
+	if public.Np != 2 {
+		return fmt.Errorf("bit proofs require base Np=2, got Np=%d", public.Np)
+	}
+
+	if bitIndex < 0 || bitIndex >= public.Nd {
+		return fmt.Errorf("bit index %d out of range [0, %d)", bitIndex, public.Nd)
+	}
+
+	if expected != 0 && expected != 1 {
+		return fmt.Errorf("expected bit must be 0 or 1, got %d", expected)
+	}
+
+	return nil
+}
+
+// bitChallenge absorbs the bit statement, so a proof is bound to the position and bit it was made for.
+func bitChallenge(fs FiatShamirEngine, bitIndex, expected int) error {
+	if fs == nil {
+		return errors.New("Fiat-Shamir engine cannot be nil")
+	}
+	if err := fs.AddNumber(big.NewInt(int64(bitIndex))); err != nil {
+		return err
+	}
+	return fs.AddNumber(big.NewInt(int64(expected)))
+}
+
+// bitCircuit extends the binary range circuit by the linear constraint digits[bitIndex] - expected = 0.
+func bitCircuit(public *ReciprocalPublic, e *big.Int, bitIndex, expected int) *GroupArithmeticCircuitPublic {
+	circuit := rangeCircuit(public.group(), e, negBasePowers(scalarField{bn256.Order}, public.Np, public.Nd))
+
+	row := zeroVector(circuit.Nw)
+	row[bitIndex] = bint(1)
+
+	circuit.Wl = append(circuit.Wl, row)
+	circuit.Al = append(circuit.Al, minus(bint(expected)))
+	circuit.Nl++
+
+	return circuit
+}
+
+// ProveBit proves that bit bitIndex of the value committed in CommitValue(value, blinding) equals expected, without
+// revealing the other bits. The proof also shows that the value lies in [0, 2^Nd), because the statement is made
+// over the full binary decomposition. Use empty FiatShamirEngine for call.
+func ProveBit(public *ReciprocalPublic, fs FiatShamirEngine, value, blinding *big.Int, bitIndex int, expected int) (*ReciprocalProof, error) {
+	if err := checkFreshTranscript(fs); err != nil {
+		return nil, err
+	}
+
+	if err := checkBitStatement(public, bitIndex, expected); err != nil {
+		return nil, err
+	}
+
+	if value == nil || blinding == nil {
+		return nil, errors.New("value and blinding cannot be nil")
+	}
+
+	private, err := public.newPrivate(value, blinding)
+	if err != nil {
+		return nil, err
+	}
+
+	if private.Digits[bitIndex].Cmp(bint(expected)) != 0 {
+		return nil, fmt.Errorf("bit %d of the value is not %d", bitIndex, expected)
+	}
+
+	if err := bitChallenge(fs, bitIndex, expected); err != nil {
+		return nil, err
+	}
+
+	return proveReciprocal(public, fs, private, rangeDomainTag(public, fs), func(e *big.Int) *GroupArithmeticCircuitPublic {
+		return bitCircuit(public, e, bitIndex, expected)
+	})
+}
+
+// VerifyBit verifies a proof produced by ProveBit that bit bitIndex of the value committed in com equals expected.
+// If err is nil then proof is valid. Use empty FiatShamirEngine for call. Of the options only WithMSM applies; the
+// RangeOptions are rejected.
+func VerifyBit(public *ReciprocalPublic, fs FiatShamirEngine, com *bn256.G1, bitIndex int, expected int, proof *ReciprocalProof, opts ...VerifyOption) error {
+	if err := checkFreshTranscript(fs); err != nil {
+		return err
+	}
+
+	if err := checkBitStatement(public, bitIndex, expected); err != nil {
+		return err
+	}
+
+	msm, err := statementMSM(opts)
+	if err != nil {
+		return err
+	}
+
+	if err := bitChallenge(fs, bitIndex, expected); err != nil {
+		return err
+	}
+
+	return verifyReciprocal(public, com, fs, proof, rangeDomainTag(public, fs), func(e *big.Int) *GroupArithmeticCircuitPublic {
+		return bitCircuit(public, e, bitIndex, expected)
+	}, msm)
+}
@@ -0,0 +1,97 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestBit(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 2)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	value, blinding := big.NewInt(0b1010_0000_0000_0101), NewRandScalar()
+	com := public.CommitValue(value, blinding)
+
+	for _, tc := range []struct{ index, bit int }{{0, 1}, {1, 0}, {2, 1}, {13, 1}, {15, 1}, {14, 0}} {
+		proof, err := ProveBit(public, NewKeccakFS(), value, blinding, tc.index, tc.bit)
+		if err != nil {
+			t.Fatalf("ProveBit(%d, %d) failed: %v", tc.index, tc.bit, err)
+		}
+
+		if err := VerifyBit(public, NewKeccakFS(), com, tc.index, tc.bit, proof); err != nil {
+			t.Fatalf("VerifyBit(%d, %d) failed: %v", tc.index, tc.bit, err)
+		}
+
+		if err := VerifyBit(public, NewKeccakFS(), com, tc.index, tc.bit, proof, WithMSM(vectorPointScalarMul)); err != nil {
+			t.Errorf("VerifyBit(%d, %d) failed with WithMSM: %v", tc.index, tc.bit, err)
+		}
+
+		if err := VerifyBit(public, NewKeccakFS(), com, tc.index, 1-tc.bit, proof); err == nil {
+			t.Errorf("Proof for bit %d = %d accepted for the opposite bit", tc.index, tc.bit)
+		}
+
+		if err := VerifyBit(public, NewKeccakFS(), public.CommitValue(bint(0), blinding), tc.index, tc.bit, proof); err == nil {
+			t.Errorf("Proof for bit %d accepted for another commitment", tc.index)
+		}
+	}
+
+	if _, err := ProveBit(public, NewKeccakFS(), value, blinding, 1, 1); err == nil {
+		t.Error("Proved a clear bit to be set")
+	}
+
+	// A forged witness with the wrong bit does not satisfy the extra constraint.
+	private, err := public.newPrivate(value, blinding)
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	fs := bitTranscript(t, 1, 1)
+	forged, err := proveReciprocal(public, fs, private, rangeDomainTag(public, fs), func(e *big.Int) *GroupArithmeticCircuitPublic {
+		return bitCircuit(public, e, 1, 1)
+	})
+	if err != nil {
+		t.Fatalf("proveReciprocal failed: %v", err)
+	}
+
+	if err := VerifyBit(public, NewKeccakFS(), com, 1, 1, forged); err == nil {
+		t.Error("Forged proof for a clear bit accepted")
+	}
+
+	for _, tc := range []struct {
+		name       string
+		index, bit int
+		value      *big.Int
+	}{
+		{"index out of range", 16, 0, value},
+		{"negative index", -1, 0, value},
+		{"not a bit", 0, 2, value},
+		{"value out of range", 0, 0, big.NewInt(1 << 16)},
+	} {
+		if _, err := ProveBit(public, NewKeccakFS(), tc.value, blinding, tc.index, tc.bit); err == nil {
+			t.Errorf("Expected an error for %s", tc.name)
+		}
+	}
+
+	hex, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	if _, err := ProveBit(hex, NewKeccakFS(), value, blinding, 0, 1); err == nil {
+		t.Error("Expected an error for a non-binary base")
+	}
+}
+
+func bitTranscript(t *testing.T, bitIndex, expected int) FiatShamirEngine {
+	fs := NewKeccakFS()
+	if err := bitChallenge(fs, bitIndex, expected); err != nil {
+		t.Fatalf("bitChallenge failed: %v", err)
+	}
+	return fs
+}
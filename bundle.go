@@ -0,0 +1,199 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"sync"
+)
+
+// BundleVersion is the version of the ProofBundle encoding written by Marshal.
+const BundleVersion = 1
+
+// ErrUnsupportedBundleVersion is returned for a bundle of a version this package does not understand.
+var ErrUnsupportedBundleVersion = errors.New("unsupported proof bundle version")
+
+// ProofBundle pairs a range proof with the value commitment it was produced for, so the two travel together.
+// The metadata describes the range: values lie in [0, 2^BitLength) and are decomposed into digits of base Base.
+// BitLength is zero for bases that are not a power of 2, where the range is not a whole number of bits.
+//
+// Encoding (see Marshal), integers as 4-byte big-endian:
+//
+//	Version || BitLength || Base || Commitment || Range proof (see WriteRangeProof)
+type ProofBundle struct {
+	Version    int
+	BitLength  int
+	Base       int
+	Commitment *bn256.G1
+	Proof      *ReciprocalProof
+}
+
+// rangeBits returns the bit length of the range [0, Np^Nd) or zero if Np is not a power of 2.
+func rangeBits(public *ReciprocalPublic) int {
+	bits := 0
+	for p := 1; p < public.Np; p *= 2 {
+		bits++
+	}
+
+	if 1<<bits != public.Np {
+		return 0
+	}
+
+	return bits * public.Nd
+}
+
+// ProveRangeBundle works like ProveRange and also returns the value commitment CommitValue(private.X, private.S)
+// the proof is bound to, together with the metadata of the range. Use empty FiatShamirEngine for call.
+func ProveRangeBundle(public *ReciprocalPublic, fs FiatShamirEngine, private *ReciprocalPrivate) (*ProofBundle, error) {
+	return NewProver(public).proveBundle(fs, private)
+}
+
+// proveBundle works like ProveRangeBundle with the parameters of the Prover.
+func (p *Prover) proveBundle(fs FiatShamirEngine, private *ReciprocalPrivate) (*ProofBundle, error) {
+	proof, err := p.Prove(fs, private)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProofBundle{
+		Version:    BundleVersion,
+		BitLength:  rangeBits(p.public),
+		Base:       p.public.Np,
+		Commitment: p.public.CommitValue(private.X, private.S),
+		Proof:      proof,
+	}, nil
+}
+
+// VerifyBundle checks that the bundle was made for the range of the public parameters and verifies its proof for
+// the bundled commitment with VerifyRange. If err is nil then proof is valid. Use empty FiatShamirEngine for call.
+func VerifyBundle(public *ReciprocalPublic, fs FiatShamirEngine, bundle *ProofBundle) error {
+	if err := public.Validate(); err != nil {
+		return err
+	}
+
+	if bundle == nil {
+		return errors.New("proof bundle cannot be nil")
+	}
+
+	if bundle.Version != BundleVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedBundleVersion, bundle.Version)
+	}
+
+	if bundle.Base != public.Np || bundle.BitLength != rangeBits(public) {
+		return fmt.Errorf("%w: bundle has BitLength=%d, Base=%d, parameters have BitLength=%d, Base=%d",
+			ErrBaseMismatch, bundle.BitLength, bundle.Base, rangeBits(public), public.Np)
+	}
+
+	return VerifyRange(public, bundle.Commitment, fs, bundle.Proof)
+}
+
+// public64 derives the parameters of Prove64 once; the derivation is deterministic.
+var public64 = sync.OnceValues(func() (*ReciprocalPublic, error) {
+	return NewReciprocalPublic(DOMAIN_RANGE, 16, Base16)
+})
+
+// Prove64 proves that value lies in [0, 2^64) for the commitment value*G + blinding*H with the standard
+// parameters: 16 hex digits, derived for DOMAIN_RANGE. It returns the bundle with the commitment and the proof,
+// and the parameters to pass to Verify64. The parameters are shared by all calls and must not be modified.
+// Use ProveRange directly for other ranges, bases or transcripts.
+func Prove64(value uint64, blinding *big.Int) (*ProofBundle, *ReciprocalPublic, error) {
+	public, err := public64()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if blinding == nil {
+		return nil, nil, errors.New("blinding cannot be nil")
+	}
+
+	private, err := public.newPrivate(new(big.Int).SetUint64(value), blinding)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bundle, err := ProveRangeBundle(public, NewKeccakFS(), private)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return bundle, public, nil
+}
+
+// Verify64 verifies a bundle produced by Prove64. A nil public uses the standard parameters of Prove64. If err is
+// nil then the bundled commitment hides a value in [0, 2^64).
+func Verify64(public *ReciprocalPublic, bundle *ProofBundle) error {
+	if public == nil {
+		var err error
+		if public, err = public64(); err != nil {
+			return err
+		}
+	}
+
+	return VerifyBundle(public, NewKeccakFS(), bundle)
+}
+
+// Marshal encodes the bundle.
+func (b *ProofBundle) Marshal() ([]byte, error) {
+	if b == nil {
+		return nil, errors.New("proof bundle cannot be nil")
+	}
+
+	var buf bytes.Buffer
+	for _, n := range []int{b.Version, b.BitLength, b.Base} {
+		if n < 0 {
+			return nil, errors.New("bundle metadata cannot be negative")
+		}
+		if err := writeLength(&buf, n); err != nil {
+			return nil, fmt.Errorf("failed to write metadata: %w", err)
+		}
+	}
+
+	if err := writePoint(&buf, b.Commitment); err != nil {
+		return nil, fmt.Errorf("failed to write commitment: %w", err)
+	}
+
+	if err := WriteRangeProof(&buf, b.Proof); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes a bundle encoded with Marshal. Bundles of another version and trailing data are rejected.
+func (b *ProofBundle) Unmarshal(data []byte) error {
+	r := bytes.NewReader(data)
+
+	var res ProofBundle
+	for _, n := range []*int{&res.Version, &res.BitLength, &res.Base} {
+		var err error
+		if *n, err = readLength(r); err != nil {
+			return fmt.Errorf("failed to read metadata: %w", err)
+		}
+	}
+
+	if res.Version != BundleVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedBundleVersion, res.Version)
+	}
+
+	var err error
+	if res.Commitment, err = readPoint(r); err != nil {
+		return fmt.Errorf("failed to read commitment: %w", err)
+	}
+
+	if res.Proof, err = ReadRangeProof(r); err != nil {
+		return err
+	}
+
+	if r.Len() != 0 {
+		return fmt.Errorf("%d trailing bytes after proof bundle", r.Len())
+	}
+
+	*b = res
+	return nil
+}
@@ -0,0 +1,153 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProveRangeBundle(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	private, err := public.newPrivate(bint(0xcafe), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	bundle, err := ProveRangeBundle(public, NewKeccakFS(), private)
+	if err != nil {
+		t.Fatalf("ProveRangeBundle failed: %v", err)
+	}
+
+	if bundle.Version != BundleVersion || bundle.BitLength != 64 || bundle.Base != 16 {
+		t.Errorf("Unexpected metadata: Version=%d, BitLength=%d, Base=%d", bundle.Version, bundle.BitLength, bundle.Base)
+	}
+
+	if !VerifyOpening(public, bundle.Commitment, private.X, private.S) {
+		t.Error("Bundle commitment does not open to the proven value")
+	}
+
+	if err := VerifyRange(public, bundle.Commitment, NewKeccakFS(), bundle.Proof); err != nil {
+		t.Fatalf("VerifyRange failed for the bundled commitment: %v", err)
+	}
+
+	private.S = bint(0)
+	if _, err := ProveRangeBundle(public, NewKeccakFS(), private); err == nil {
+		t.Error("Expected an error for an invalid witness")
+	}
+}
+
+func TestProofBundleMarshal(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	private, err := public.newPrivate(bint(0xcafe), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	bundle, err := ProveRangeBundle(public, NewKeccakFS(), private)
+	if err != nil {
+		t.Fatalf("ProveRangeBundle failed: %v", err)
+	}
+
+	data, err := bundle.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	decoded := new(ProofBundle)
+	if err := decoded.Unmarshal(data); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if err := VerifyBundle(public, NewKeccakFS(), decoded); err != nil {
+		t.Fatalf("VerifyBundle failed: %v", err)
+	}
+
+	if err := decoded.Unmarshal(append(data, 0x00)); err == nil {
+		t.Error("Trailing data accepted")
+	}
+
+	if err := decoded.Unmarshal(data[:len(data)-1]); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Expected ErrTruncated, got %v", err)
+	}
+
+	future := append([]byte{0x00, 0x00, 0x00, 0x02}, data[4:]...)
+	if err := decoded.Unmarshal(future); !errors.Is(err, ErrUnsupportedBundleVersion) {
+		t.Errorf("Expected ErrUnsupportedBundleVersion, got %v", err)
+	}
+
+	other, err := NewReciprocalPublic(DOMAIN_RANGE, 8, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	if err := VerifyBundle(other, NewKeccakFS(), bundle); !errors.Is(err, ErrBaseMismatch) {
+		t.Errorf("Expected ErrBaseMismatch for other parameters, got %v", err)
+	}
+
+	wrongVersion := *bundle
+	wrongVersion.Version = 0
+	if err := VerifyBundle(public, NewKeccakFS(), &wrongVersion); !errors.Is(err, ErrUnsupportedBundleVersion) {
+		t.Errorf("Expected ErrUnsupportedBundleVersion, got %v", err)
+	}
+
+	swapped := *bundle
+	swapped.Commitment = public.CommitValue(bint(0xcaff), private.S)
+	if err := VerifyBundle(public, NewKeccakFS(), &swapped); err == nil {
+		t.Error("Bundle with another commitment accepted")
+	}
+}
+
+func TestProve64(t *testing.T) {
+	bundle, public, err := Prove64(0xab4f0540ab4f0540, NewRandScalar())
+	if err != nil {
+		t.Fatalf("Prove64 failed: %v", err)
+	}
+
+	if public.Nd != 16 || public.Np != 16 || bundle.BitLength != 64 {
+		t.Errorf("Unexpected range: Nd=%d, Np=%d, BitLength=%d", public.Nd, public.Np, bundle.BitLength)
+	}
+
+	if err := Verify64(public, bundle); err != nil {
+		t.Fatalf("Verify64 failed: %v", err)
+	}
+
+	// The bundle survives the encoding and verifies with the standard parameters.
+	data, err := bundle.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded ProofBundle
+	if err := decoded.Unmarshal(data); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if err := Verify64(nil, &decoded); err != nil {
+		t.Fatalf("Verify64 with the standard parameters failed: %v", err)
+	}
+
+	other, _, err := Prove64(1, NewRandScalar())
+	if err != nil {
+		t.Fatalf("Prove64 failed: %v", err)
+	}
+
+	decoded.Commitment = other.Commitment
+	if err := Verify64(nil, &decoded); err == nil {
+		t.Error("Proof accepted for another commitment")
+	}
+
+	if _, _, err := Prove64(0, nil); err == nil {
+		t.Error("Expected an error for a nil blinding")
+	}
+}
@@ -0,0 +1,494 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+)
+
+// CBOR (RFC 8949) encoding of the proof structures.
+//
+// Only a small subset of CBOR is produced and accepted: unsigned integers (major type 0), byte strings (major
+// type 2), text strings (major type 3), arrays (major type 4) and maps (major type 5), all with definite lengths.
+// Points are encoded as 64-byte byte strings holding bn256.G1.Marshal() output, and no other encoding of a point is
+// accepted; scalars as 32-byte big-endian byte strings. Maps are keyed by short text strings and written in the
+// fixed order listed below, so the encoding is deterministic.
+//
+//	WeightNormLinearArgumentProof = {"r": [* point], "x": [* point], "l": [* scalar], "n": [* scalar]}
+//	ArithmeticCircuitProof        = {"cl": point, "cr": point, "co": point, "cs": point, "wnla": WeightNormLinearArgumentProof}
+//	ReciprocalProof               = {"nd": uint, "np": uint, ? "dom": uint, "v": point, "circuit": ArithmeticCircuitProof}
+//
+// The "dom" entry holds the domain tag of a range proof and is only written if the tag is known.
+
+const (
+	cborMajorUint  = 0
+	cborMajorBytes = 2
+	cborMajorText  = 3
+	cborMajorArray = 4
+	cborMajorMap   = 5
+)
+
+const (
+	pointSize  = 64
+	scalarSize = 32
+)
+
+var errCBORTruncated = fmt.Errorf("cbor: unexpected end of data: %w", ErrTruncated)
+
+type cborEncoder struct {
+	buf []byte
+}
+
+func (e *cborEncoder) head(major byte, n uint64) {
+	m := major << 5
+	switch {
+	case n < 24:
+		e.buf = append(e.buf, m|byte(n))
+	case n <= 0xff:
+		e.buf = append(e.buf, m|24, byte(n))
+	case n <= 0xffff:
+		e.buf = append(e.buf, m|25)
+		e.buf = binary.BigEndian.AppendUint16(e.buf, uint16(n))
+	case n <= 0xffffffff:
+		e.buf = append(e.buf, m|26)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, uint32(n))
+	default:
+		e.buf = append(e.buf, m|27)
+		e.buf = binary.BigEndian.AppendUint64(e.buf, n)
+	}
+}
+
+func (e *cborEncoder) bytes(b []byte) {
+	e.head(cborMajorBytes, uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+func (e *cborEncoder) text(s string) {
+	e.head(cborMajorText, uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+func (e *cborEncoder) point(p *bn256.G1) error {
+	if p == nil {
+		return errors.New("cbor: point cannot be nil")
+	}
+	e.bytes(p.Marshal())
+	return nil
+}
+
+func (e *cborEncoder) points(ps []*bn256.G1) error {
+	e.head(cborMajorArray, uint64(len(ps)))
+	for i, p := range ps {
+		if err := e.point(p); err != nil {
+			return fmt.Errorf("cbor: point %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (e *cborEncoder) scalars(ss []*big.Int) error {
+	e.head(cborMajorArray, uint64(len(ss)))
+	for i, s := range ss {
+		if s == nil {
+			return fmt.Errorf("cbor: scalar %d cannot be nil", i)
+		}
+		e.bytes(scalarTo32Byte(s))
+	}
+	return nil
+}
+
+type cborDecoder struct {
+	buf []byte
+}
+
+func (d *cborDecoder) head() (byte, uint64, error) {
+	if len(d.buf) < 1 {
+		return 0, 0, errCBORTruncated
+	}
+
+	major, info := d.buf[0]>>5, d.buf[0]&0x1f
+	d.buf = d.buf[1:]
+
+	var size int
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, 0, fmt.Errorf("cbor: unsupported additional info %d", info)
+	}
+
+	if len(d.buf) < size {
+		return 0, 0, errCBORTruncated
+	}
+
+	var n uint64
+	for _, b := range d.buf[:size] {
+		n = n<<8 | uint64(b)
+	}
+	d.buf = d.buf[size:]
+	return major, n, nil
+}
+
+func (d *cborDecoder) expect(major byte) (uint64, error) {
+	m, n, err := d.head()
+	if err != nil {
+		return 0, err
+	}
+	if m != major {
+		return 0, fmt.Errorf("cbor: expected major type %d, got %d", major, m)
+	}
+	return n, nil
+}
+
+func (d *cborDecoder) uint() (int, error) {
+	n, err := d.expect(cborMajorUint)
+	if err != nil {
+		return 0, err
+	}
+	if n > 0xffffffff {
+		return 0, fmt.Errorf("cbor: integer %d out of range", n)
+	}
+	return int(n), nil
+}
+
+func (d *cborDecoder) bytes() ([]byte, error) {
+	n, err := d.expect(cborMajorBytes)
+	if err != nil {
+		return nil, err
+	}
+	if n > uint64(len(d.buf)) {
+		return nil, errCBORTruncated
+	}
+	res := d.buf[:n]
+	d.buf = d.buf[n:]
+	return res, nil
+}
+
+func (d *cborDecoder) key(want string) error {
+	n, err := d.expect(cborMajorText)
+	if err != nil {
+		return err
+	}
+	if n > uint64(len(d.buf)) {
+		return errCBORTruncated
+	}
+	if got := string(d.buf[:n]); got != want {
+		return fmt.Errorf("cbor: expected key %q, got %q", want, got)
+	}
+	d.buf = d.buf[n:]
+	return nil
+}
+
+func (d *cborDecoder) mapOf(size uint64) error {
+	n, err := d.expect(cborMajorMap)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("cbor: expected map of %d entries, got %d", size, n)
+	}
+	return nil
+}
+
+// arrayOf reads an array header. Every element takes at least elemSize bytes, which bounds the declared length by
+// the remaining input.
+func (d *cborDecoder) arrayOf(elemSize int) (int, error) {
+	n, err := d.expect(cborMajorArray)
+	if err != nil {
+		return 0, err
+	}
+	if n > maxProofVectorLength {
+		return 0, fmt.Errorf("cbor: %w: %d elements", ErrTooLarge, n)
+	}
+	if n > uint64(len(d.buf)/elemSize) {
+		return 0, errCBORTruncated
+	}
+	return int(n), nil
+}
+
+func (d *cborDecoder) point() (*bn256.G1, error) {
+	b, err := d.bytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != pointSize {
+		return nil, fmt.Errorf("cbor: invalid point length %d", len(b))
+	}
+	p, err := unmarshalPoint(b)
+	if err != nil {
+		return nil, fmt.Errorf("cbor: invalid point: %w", err)
+	}
+	return p, nil
+}
+
+func (d *cborDecoder) points() ([]*bn256.G1, error) {
+	n, err := d.arrayOf(1 + pointSize)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*bn256.G1, n)
+	for i := range res {
+		if res[i], err = d.point(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func (d *cborDecoder) scalars() ([]*big.Int, error) {
+	n, err := d.arrayOf(1 + scalarSize)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*big.Int, n)
+	for i := range res {
+		b, err := d.bytes()
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != scalarSize {
+			return nil, fmt.Errorf("cbor: invalid scalar length %d", len(b))
+		}
+		res[i] = new(big.Int).SetBytes(b)
+	}
+	return res, nil
+}
+
+func (d *cborDecoder) done() error {
+	if len(d.buf) != 0 {
+		return fmt.Errorf("cbor: %d trailing bytes", len(d.buf))
+	}
+	return nil
+}
+
+func (p *WeightNormLinearArgumentProof) encodeCBOR(e *cborEncoder) error {
+	e.head(cborMajorMap, 4)
+	e.text("r")
+	if err := e.points(p.R); err != nil {
+		return err
+	}
+	e.text("x")
+	if err := e.points(p.X); err != nil {
+		return err
+	}
+	e.text("l")
+	if err := e.scalars(p.L); err != nil {
+		return err
+	}
+	e.text("n")
+	return e.scalars(p.N)
+}
+
+func (p *WeightNormLinearArgumentProof) decodeCBOR(d *cborDecoder) (err error) {
+	if err = d.mapOf(4); err != nil {
+		return err
+	}
+	if err = d.key("r"); err != nil {
+		return err
+	}
+	if p.R, err = d.points(); err != nil {
+		return err
+	}
+	if err = d.key("x"); err != nil {
+		return err
+	}
+	if p.X, err = d.points(); err != nil {
+		return err
+	}
+	if err = d.key("l"); err != nil {
+		return err
+	}
+	if p.L, err = d.scalars(); err != nil {
+		return err
+	}
+	if err = d.key("n"); err != nil {
+		return err
+	}
+	p.N, err = d.scalars()
+	return err
+}
+
+func (p *ArithmeticCircuitProof) encodeCBOR(e *cborEncoder) error {
+	if p.WNLA == nil {
+		return errors.New("cbor: WNLA proof cannot be nil")
+	}
+
+	e.head(cborMajorMap, 5)
+	for _, kv := range []struct {
+		k string
+		v *bn256.G1
+	}{{"cl", p.CL}, {"cr", p.CR}, {"co", p.CO}, {"cs", p.CS}} {
+		e.text(kv.k)
+		if err := e.point(kv.v); err != nil {
+			return fmt.Errorf("cbor: %s: %w", kv.k, err)
+		}
+	}
+	e.text("wnla")
+	return p.WNLA.encodeCBOR(e)
+}
+
+func (p *ArithmeticCircuitProof) decodeCBOR(d *cborDecoder) (err error) {
+	if err = d.mapOf(5); err != nil {
+		return err
+	}
+	for _, kv := range []struct {
+		k string
+		v **bn256.G1
+	}{{"cl", &p.CL}, {"cr", &p.CR}, {"co", &p.CO}, {"cs", &p.CS}} {
+		if err = d.key(kv.k); err != nil {
+			return err
+		}
+		if *kv.v, err = d.point(); err != nil {
+			return err
+		}
+	}
+	if err = d.key("wnla"); err != nil {
+		return err
+	}
+	p.WNLA = new(WeightNormLinearArgumentProof)
+	return p.WNLA.decodeCBOR(d)
+}
+
+func (p *ReciprocalProof) encodeCBOR(e *cborEncoder) error {
+	if p.ArithmeticCircuitProof == nil {
+		return errors.New("cbor: circuit proof cannot be nil")
+	}
+
+	if p.Nd < 0 || p.Np < 0 {
+		return errors.New("cbor: proof header cannot be negative")
+	}
+
+	if p.Domain == 0 {
+		e.head(cborMajorMap, 4)
+	} else {
+		e.head(cborMajorMap, 5)
+	}
+	e.text("nd")
+	e.head(cborMajorUint, uint64(p.Nd))
+	e.text("np")
+	e.head(cborMajorUint, uint64(p.Np))
+	if p.Domain != 0 {
+		e.text("dom")
+		e.head(cborMajorUint, uint64(p.Domain))
+	}
+	e.text("v")
+	if err := e.point(p.V); err != nil {
+		return fmt.Errorf("cbor: v: %w", err)
+	}
+	e.text("circuit")
+	return p.ArithmeticCircuitProof.encodeCBOR(e)
+}
+
+func (p *ReciprocalProof) decodeCBOR(d *cborDecoder) (err error) {
+	size, err := d.expect(cborMajorMap)
+	if err != nil {
+		return err
+	}
+	if size != 4 && size != 5 {
+		return fmt.Errorf("cbor: expected map of 4 or 5 entries, got %d", size)
+	}
+	if err = d.key("nd"); err != nil {
+		return err
+	}
+	if p.Nd, err = d.uint(); err != nil {
+		return err
+	}
+	if err = d.key("np"); err != nil {
+		return err
+	}
+	if p.Np, err = d.uint(); err != nil {
+		return err
+	}
+	if size == 5 {
+		if err = d.key("dom"); err != nil {
+			return err
+		}
+		dom, err := d.uint()
+		if err != nil {
+			return err
+		}
+		if dom == 0 {
+			return errors.New("cbor: domain tag cannot be zero")
+		}
+		p.Domain = uint32(dom)
+	}
+	if err = d.key("v"); err != nil {
+		return err
+	}
+	if p.V, err = d.point(); err != nil {
+		return err
+	}
+	if err = d.key("circuit"); err != nil {
+		return err
+	}
+	p.ArithmeticCircuitProof = new(ArithmeticCircuitProof)
+	return p.ArithmeticCircuitProof.decodeCBOR(d)
+}
+
+// MarshalCBOR encodes the WNLA proof as CBOR (see the schema at the top of cbor.go).
+func (p *WeightNormLinearArgumentProof) MarshalCBOR() ([]byte, error) {
+	e := new(cborEncoder)
+	if err := p.encodeCBOR(e); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// UnmarshalCBOR decodes the WNLA proof from the CBOR produced by MarshalCBOR.
+func (p *WeightNormLinearArgumentProof) UnmarshalCBOR(data []byte) error {
+	d := &cborDecoder{buf: data}
+	if err := p.decodeCBOR(d); err != nil {
+		return err
+	}
+	return d.done()
+}
+
+// MarshalCBOR encodes the arithmetic circuit proof as CBOR (see the schema at the top of cbor.go).
+func (p *ArithmeticCircuitProof) MarshalCBOR() ([]byte, error) {
+	e := new(cborEncoder)
+	if err := p.encodeCBOR(e); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// UnmarshalCBOR decodes the arithmetic circuit proof from the CBOR produced by MarshalCBOR.
+func (p *ArithmeticCircuitProof) UnmarshalCBOR(data []byte) error {
+	d := &cborDecoder{buf: data}
+	if err := p.decodeCBOR(d); err != nil {
+		return err
+	}
+	return d.done()
+}
+
+// MarshalCBOR encodes the range proof as CBOR (see the schema at the top of cbor.go).
+func (p *ReciprocalProof) MarshalCBOR() ([]byte, error) {
+	e := new(cborEncoder)
+	if err := p.encodeCBOR(e); err != nil {
+		return nil, err
+	}
+	return e.buf, nil
+}
+
+// UnmarshalCBOR decodes the range proof from the CBOR produced by MarshalCBOR.
+func (p *ReciprocalProof) UnmarshalCBOR(data []byte) error {
+	d := &cborDecoder{buf: data}
+	if err := p.decodeCBOR(d); err != nil {
+		return err
+	}
+	return d.done()
+}
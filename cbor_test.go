@@ -0,0 +1,131 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestReciprocalProofCBOR(t *testing.T) {
+	x := uint64(0xab4f0540ab4f0540)
+	digits := UInt64Hex(x)
+
+	Nd := 16
+	Np := 16
+
+	wnlaPublic := NewWeightNormLinearPublic(32, 16)
+
+	public := &ReciprocalPublic{
+		G:     wnlaPublic.G,
+		GVec:  wnlaPublic.GVec[:Nd],
+		HVec:  wnlaPublic.HVec[:Nd+1+9],
+		Nd:    Nd,
+		Np:    Np,
+		GVec_: wnlaPublic.GVec[Nd:],
+		HVec_: wnlaPublic.HVec[Nd+1+9:],
+	}
+
+	private := &ReciprocalPrivate{
+		X:      new(big.Int).SetUint64(x),
+		M:      HexMapping(digits),
+		Digits: digits,
+		S:      NewRandScalar(),
+	}
+
+	VCom := public.CommitValue(private.X, private.S)
+	proof, err := ProveRange(public, NewKeccakFS(), private)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	data, err := proof.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("MarshalCBOR failed: %v", err)
+	}
+
+	decoded := new(ReciprocalProof)
+	if err := decoded.UnmarshalCBOR(data); err != nil {
+		t.Fatalf("UnmarshalCBOR failed: %v", err)
+	}
+
+	if decoded.Nd != Nd || decoded.Np != Np {
+		t.Errorf("Header changed across a round trip: Nd=%d, Np=%d", decoded.Nd, decoded.Np)
+	}
+
+	again, err := decoded.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("MarshalCBOR of decoded proof failed: %v", err)
+	}
+
+	if !bytes.Equal(data, again) {
+		t.Error("CBOR encoding is not stable across a round trip")
+	}
+
+	if err := VerifyRange(public, VCom, NewKeccakFS(), decoded); err != nil {
+		t.Fatalf("Decoded proof failed verification: %v", err)
+	}
+
+	if err := new(ReciprocalProof).UnmarshalCBOR(data[:len(data)-1]); err == nil {
+		t.Error("Should reject truncated CBOR")
+	}
+
+	if err := new(ReciprocalProof).UnmarshalCBOR(append(data, 0x00)); err == nil {
+		t.Error("Should reject trailing bytes")
+	}
+}
+
+func TestWNLAProofCBOROversizedArray(t *testing.T) {
+	// {"r": array declaring 2^64-1 points}
+	data := []byte{0xa4, 0x61, 'r', 0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+	if err := new(WeightNormLinearArgumentProof).UnmarshalCBOR(data); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge, got %v", err)
+	}
+
+	// {"r": array declaring 16 points, followed by one}
+	data = append([]byte{0xa4, 0x61, 'r', 0x90, 0x58, pointSize}, make([]byte, pointSize)...)
+
+	if err := new(WeightNormLinearArgumentProof).UnmarshalCBOR(data); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Expected ErrTruncated, got %v", err)
+	}
+}
+
+func TestWNLAProofCBOR(t *testing.T) {
+	public := NewWeightNormLinearPublic(4, 2)
+
+	l := []*big.Int{bint(1), bint(2), bint(3), bint(4)}
+	n := []*big.Int{bint(5), bint(6)}
+
+	commitment, err := public.CommitWNLAChecked(l, n)
+	if err != nil {
+		t.Fatalf("CommitWNLAChecked failed: %v", err)
+	}
+	proof, err := ProveWNLA(public, commitment, NewKeccakFS(), l, n)
+	if err != nil {
+		t.Fatalf("ProveWNLA failed: %v", err)
+	}
+
+	data, err := proof.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("MarshalCBOR failed: %v", err)
+	}
+
+	// Map header with 4 entries followed by the "r" key.
+	if data[0] != 0xa4 || data[1] != 0x61 || data[2] != 'r' {
+		t.Errorf("Unexpected CBOR prefix: %x", data[:3])
+	}
+
+	decoded := new(WeightNormLinearArgumentProof)
+	if err := decoded.UnmarshalCBOR(data); err != nil {
+		t.Fatalf("UnmarshalCBOR failed: %v", err)
+	}
+
+	if err := VerifyWNLA(public, decoded, commitment, NewKeccakFS()); err != nil {
+		t.Fatalf("Decoded proof failed verification: %v", err)
+	}
+}
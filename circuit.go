@@ -5,177 +5,518 @@
 package bulletproofs
 
 import (
+	"errors"
+	"fmt"
 	"github.com/cloudflare/bn256"
 	"math/big"
 )
 
+// circuitBlindingSlots is the number of blinding coefficients that precede the Nv witness entries in the HVec part
+// of the circuit commitments (the ro, rl and rr vectors of commitOL and commitR), so HVec has Nv+circuitBlindingSlots
+// generators. HVec[0] also blinds the v witness commitments of CommitCircuit.
+const circuitBlindingSlots = 9
+
+// GroupArithmeticCircuitPublic contains the public values of the BP++ arithmetic circuit over an arbitrary Group.
+// ArithmeticCircuitPublic is the same structure fixed to the BN256 backend. The coefficients of Wm, Wl, Am and Al
+// are elements of the scalar field of the group.
+type GroupArithmeticCircuitPublic struct {
+	Group              Group
+	Nm, Nl, Nv, Nw, No int // Nw = Nm + Nm + No (for L, R, O parts), Nl = Nv * K
+	K                  int // Count of witness vectors v.
+	G                  Point
+	GVec               []Point // Nm
+	HVec               []Point // Nv+circuitBlindingSlots
+
+	Wm [][]*big.Int // Nm * Nw
+	Wl [][]*big.Int // Nl * Nw
+
+	Am []*big.Int // Nm
+	Al []*big.Int // Nl
+
+	Fl bool
+	Fm bool
+
+	F PartitionF
+
+	// Vectors of points that will be used in WNLA protocol
+	GVec_ []Point // 2^n - Nm
+	HVec_ []Point // 2^n - (Nv+circuitBlindingSlots)
+
+	// wnlaGVec and wnlaHVec cache GVec||GVec_ and HVec||HVec_ for circuits built from a rangeTemplate.
+	wnlaGVec, wnlaHVec []Point
+}
+
+// GroupArithmeticCircuitProof is the arithmetic circuit proof over an arbitrary Group.
+type GroupArithmeticCircuitProof struct {
+	CL, CR, CO, CS Point
+	WNLA           *GroupWNLAProof
+}
+
+func (p *GroupArithmeticCircuitPublic) field() scalarField {
+	return scalarField{p.Group.Order()}
+}
+
+// wnlaGenerators returns the generator vectors of the final WNLA proof, GVec||GVec_ and HVec||HVec_. The cached
+// vectors of a template are shared and must not be modified.
+func (p *GroupArithmeticCircuitPublic) wnlaGenerators() (GVec, HVec []Point) {
+	if p.wnlaGVec != nil && p.wnlaHVec != nil {
+		return p.wnlaGVec, p.wnlaHVec
+	}
+	return append(append([]Point{}, p.GVec...), p.GVec_...), append(append([]Point{}, p.HVec...), p.HVec_...)
+}
+
+func (p *ArithmeticCircuitPublic) group() *GroupArithmeticCircuitPublic {
+	return &GroupArithmeticCircuitPublic{
+		Group: BN256,
+		Nm:    p.Nm,
+		Nl:    p.Nl,
+		Nv:    p.Nv,
+		Nw:    p.Nw,
+		No:    p.No,
+		K:     p.K,
+		G:     toPoint(p.G),
+		GVec:  toPoints(p.GVec),
+		HVec:  toPoints(p.HVec),
+		Wm:    p.Wm,
+		Wl:    p.Wl,
+		Am:    p.Am,
+		Al:    p.Al,
+		Fl:    p.Fl,
+		Fm:    p.Fm,
+		F:     p.F,
+		GVec_: toPoints(p.GVec_),
+		HVec_: toPoints(p.HVec_),
+	}
+}
+
+func (p *ArithmeticCircuitProof) group() *GroupArithmeticCircuitProof {
+	if p == nil {
+		return nil
+	}
+
+	res := &GroupArithmeticCircuitProof{
+		CL: toPoint(p.CL),
+		CR: toPoint(p.CR),
+		CO: toPoint(p.CO),
+		CS: toPoint(p.CS),
+	}
+	if p.WNLA != nil {
+		res.WNLA = p.WNLA.group()
+	}
+	return res
+}
+
+func (p *GroupArithmeticCircuitProof) bn256() *ArithmeticCircuitProof {
+	return &ArithmeticCircuitProof{
+		CL:   toG1(p.CL),
+		CR:   toG1(p.CR),
+		CO:   toG1(p.CO),
+		CS:   toG1(p.CS),
+		WNLA: p.WNLA.bn256(),
+	}
+}
+
+// Validate checks that the circuit dimensions match its matrices and generators and that no generator or
+// coefficient is missing, so a partially constructed circuit is reported instead of panicking deep in the proof.
+func (p *ArithmeticCircuitPublic) Validate() error {
+	if p == nil {
+		return errors.New("circuit public parameters cannot be nil")
+	}
+
+	return p.group().Validate()
+}
+
+// Validate checks that the circuit dimensions match its matrices and generators and that no generator or
+// coefficient is missing, so a partially constructed circuit is reported instead of panicking deep in the proof.
+func (p *GroupArithmeticCircuitPublic) Validate() error {
+	if p == nil {
+		return errors.New("circuit public parameters cannot be nil")
+	}
+
+	if p.Group == nil {
+		return errors.New("group cannot be nil")
+	}
+
+	if p.Nw != 2*p.Nm+p.No {
+		return fmt.Errorf("Nw=%d does not match 2*Nm+No=%d", p.Nw, 2*p.Nm+p.No)
+	}
+
+	if p.G == nil {
+		return errors.New("generator G cannot be nil")
+	}
+
+	if err := checkBasePoint(p.Group, p.G); err != nil {
+		return err
+	}
+
+	if p.F == nil {
+		return errors.New("partition function F cannot be nil")
+	}
+
+	if len(p.GVec) != p.Nm {
+		return fmt.Errorf("len(GVec)=%d does not match Nm=%d", len(p.GVec), p.Nm)
+	}
+
+	if len(p.HVec) != p.Nv+circuitBlindingSlots {
+		return fmt.Errorf("len(HVec)=%d does not match Nv+%d=%d", len(p.HVec), circuitBlindingSlots, p.Nv+circuitBlindingSlots)
+	}
+
+	for _, vec := range []struct {
+		name   string
+		points []Point
+	}{{"GVec", p.GVec}, {"HVec", p.HVec}, {"GVec_", p.GVec_}, {"HVec_", p.HVec_}} {
+		for i, g := range vec.points {
+			if g == nil {
+				return fmt.Errorf("%s[%d] cannot be nil", vec.name, i)
+			}
+		}
+	}
+
+	for _, vec := range []struct {
+		name    string
+		scalars []*big.Int
+		n       int
+	}{{"Am", p.Am, p.Nm}, {"Al", p.Al, p.Nl}} {
+		if len(vec.scalars) != vec.n {
+			return fmt.Errorf("len(%s)=%d, expected %d", vec.name, len(vec.scalars), vec.n)
+		}
+		for i, s := range vec.scalars {
+			if s == nil {
+				return fmt.Errorf("%s[%d] cannot be nil", vec.name, i)
+			}
+		}
+	}
+
+	for _, m := range []struct {
+		name string
+		rows [][]*big.Int
+		n    int
+	}{{"Wm", p.Wm, p.Nm}, {"Wl", p.Wl, p.Nl}} {
+		if len(m.rows) != m.n {
+			return fmt.Errorf("%s has %d rows, expected %d", m.name, len(m.rows), m.n)
+		}
+		for i, row := range m.rows {
+			if len(row) != p.Nw {
+				return fmt.Errorf("%s[%d] has %d columns, expected Nw=%d", m.name, i, len(row), p.Nw)
+			}
+			for j, s := range row {
+				if s == nil {
+					return fmt.Errorf("%s[%d][%d] cannot be nil", m.name, i, j)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkCircuitInputs rejects missing inputs shared by the circuit prover and verifier.
+func checkCircuitInputs(public *GroupArithmeticCircuitPublic, V []Point, fs FiatShamirEngine) error {
+	if err := public.Validate(); err != nil {
+		return err
+	}
+
+	if fs == nil {
+		return errors.New("Fiat-Shamir engine cannot be nil")
+	}
+
+	if len(V) != public.K {
+		return fmt.Errorf("expected %d commitments, got %d", public.K, len(V))
+	}
+
+	for i := range V {
+		if V[i] == nil {
+			return fmt.Errorf("commitment %d cannot be nil", i)
+		}
+	}
+
+	return nil
+}
+
 // CommitCircuit creates a commitment for v vector and blinding s.
-// Com = v[0]*G + s*H[0] + <v[1:], H[9:]>
+// Com = v[0]*G + s*H[0] + <v[1:], H[circuitBlindingSlots:]>
 func (p *ArithmeticCircuitPublic) CommitCircuit(v []*big.Int, s *big.Int) *bn256.G1 {
-	res := new(bn256.G1).ScalarMult(p.G, v[0])
-	res.Add(res, new(bn256.G1).ScalarMult(p.HVec[0], s))
-	res.Add(res, vectorPointScalarMul(p.HVec[9:], v[1:]))
-	return res
+	return toG1(p.group().Commit(v, s))
+}
+
+// Commit creates a commitment for v vector and blinding s, see ArithmeticCircuitPublic.CommitCircuit.
+// Com = v[0]*G + s*H[0] + <v[1:], H[circuitBlindingSlots:]>
+func (p *GroupArithmeticCircuitPublic) Commit(v []*big.Int, s *big.Int) Point {
+	g := p.Group
+	res := g.Add(g.ScalarMult(p.G, v[0]), g.ScalarMult(p.HVec[0], s))
+	return g.Add(res, groupVectorPointScalarMul(g, p.HVec[circuitBlindingSlots:], v[1:]))
 }
 
 // VerifyCircuit verifies BP++ arithmetic circuit zero-knowledge proof using WNLA protocol. If err is nil then proof is valid.
-// Use empty FiatShamirEngine for call.
+// Use empty FiatShamirEngine for call. See VerifyGroupCircuit.
 func VerifyCircuit(public *ArithmeticCircuitPublic, V []*bn256.G1, fs FiatShamirEngine, proof *ArithmeticCircuitProof) error {
-	fs.AddPoint(proof.CL)
-	fs.AddPoint(proof.CR)
-	fs.AddPoint(proof.CO)
+	if public == nil {
+		return errors.New("circuit public parameters cannot be nil")
+	}
 
-	for i := range V {
-		fs.AddPoint(V[i])
+	return verifyCircuit(public.group(), toPoints(V), fs, proof.group(), nil)
+}
+
+// VerifyGroupCircuit verifies the arithmetic circuit proof over the group of public. If err is nil then proof is
+// valid. Use empty FiatShamirEngine for call.
+func VerifyGroupCircuit(public *GroupArithmeticCircuitPublic, V []Point, fs FiatShamirEngine, proof *GroupArithmeticCircuitProof) error {
+	return verifyCircuit(public, V, fs, proof, nil)
+}
+
+// groupMSM returns sum(scalars[i]*points[i]) computed by msm over BN256 (see WithMSM) and with the group operations
+// otherwise.
+func groupMSM(g Group, msm MSMFunc, points []Point, scalars []*big.Int) (Point, error) {
+	if _, ok := g.(bn256Group); ok {
+		res, err := msm.mul(toG1s(points), scalars)
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+
+	return groupVectorPointScalarMul(g, points, scalars), nil
+}
+
+// verifyCircuit works like VerifyGroupCircuit and computes the multi-scalar multiplications over the generator
+// vectors with msm over BN256, see WithMSM. A nil msm uses the built-in one.
+func verifyCircuit(public *GroupArithmeticCircuitPublic, V []Point, fs FiatShamirEngine, proof *GroupArithmeticCircuitProof, msm MSMFunc) error {
+	if err := checkCircuitInputs(public, V, fs); err != nil {
+		return err
+	}
+
+	if proof == nil || proof.CL == nil || proof.CR == nil || proof.CO == nil || proof.CS == nil {
+		return errors.New("circuit proof is incomplete")
+	}
+
+	g, f := public.Group, public.field()
+
+	// Reject malformed scalars and round counts before doing any curve work
+	if err := proof.WNLA.checkScalars(f); err != nil {
+		return err
+	}
+
+	if err := proof.WNLA.checkRounds(len(public.HVec)+len(public.HVec_), len(public.GVec)+len(public.GVec_)); err != nil {
+		return err
+	}
+
+	for _, p := range append([]Point{proof.CL, proof.CR, proof.CO}, V...) {
+		if err := addGroupPoint(fs, p); err != nil {
+			return err
+		}
 	}
 
 	// Generates challenges using Fiat-Shamir heuristic
-	ro := fs.GetChallenge()
-	lambda := fs.GetChallenge()
-	beta := fs.GetChallenge()
-	delta := fs.GetChallenge()
+	ro := f.challenge(fs)
+	lambda := f.challenge(fs)
+	beta := f.challenge(fs)
+	delta := f.challenge(fs)
+
+	if err := f.checkChallenges(ro, lambda, beta, delta); err != nil {
+		return err
+	}
 
 	MlnL, MmnL, MlnR, MmnR := calculateMRL(public)
 	MlnO, MmnO, MllL, MmlL, MllR, MmlR, MllO, MmlO := calculateMO(public)
 
-	mu := mul(ro, ro)
+	mu := f.mul(ro, ro)
 
 	lcomb := func(i int) *big.Int {
-		return add(
-			mul(bbool(public.Fl), pow(lambda, public.Nv*i)),
-			mul(bbool(public.Fm), pow(mu, public.Nv*i+1)),
+		return f.add(
+			f.mul(bbool(public.Fl), f.pow(lambda, public.Nv*i)),
+			f.mul(bbool(public.Fm), f.pow(mu, public.Nv*i+1)),
 		)
 	}
 
 	// Calculate linear combination of V
-	V_ := func() *bn256.G1 {
-		var V_ = new(bn256.G1).ScalarBaseMult(bint(0)) // set infinite
+	V_ := func() Point {
+		V_ := g.Identity()
 
 		for i := 0; i < public.K; i++ {
-			V_ = V_.Add(V_, new(bn256.G1).ScalarMult(
-				V[i],
-				lcomb(i),
-			))
+			V_ = g.Add(V_, g.ScalarMult(V[i], lcomb(i)))
 		}
 
-		return V_.ScalarMult(V_, bint(2))
+		return g.ScalarMult(V_, bint(2))
 	}()
 
 	// Calculate lambda vector (nl == nv * k)
-	lambdaVec := vectorAdd(
-		vectorTensorMul(vectorMulOnScalar(e(lambda, public.Nv), mu), e(pow(mu, public.Nv), public.K)),
-		vectorTensorMul(e(mu, public.Nv), e(pow(lambda, public.Nv), public.K)),
+	lambdaVec := f.vectorAdd(
+		f.vectorTensorMul(f.vectorMulOnScalar(f.e(lambda, public.Nv), mu), f.e(f.pow(mu, public.Nv), public.K)),
+		f.vectorTensorMul(f.e(mu, public.Nv), f.e(f.pow(lambda, public.Nv), public.K)),
 	)
 
-	lambdaVec = vectorMulOnScalar(lambdaVec, bbool(public.Fl && public.Fm))
-	lambdaVec = vectorSub(e(lambda, public.Nl), lambdaVec) //Nl
+	lambdaVec = f.vectorMulOnScalar(lambdaVec, bbool(public.Fl && public.Fm))
+	lambdaVec = f.vectorSub(f.e(lambda, public.Nl), lambdaVec) //Nl
 
 	// Calculate mu vector
-	muVec := vectorMulOnScalar(e(mu, public.Nm), mu) // Nm
+	muVec := f.vectorMulOnScalar(f.e(mu, public.Nm), mu) // Nm
 
 	// Calculate coefficients clX, X = {L,R,O}
-	muDiagInv := diagInv(mu, public.Nm) // Nm*Nm
+	muDiagInv := f.diagInv(mu, public.Nm) // Nm*Nm
 
-	cnL := vectorMulOnMatrix(vectorSub(vectorMulOnMatrix(lambdaVec, MlnL), vectorMulOnMatrix(muVec, MmnL)), muDiagInv) // Nm
-	cnR := vectorMulOnMatrix(vectorSub(vectorMulOnMatrix(lambdaVec, MlnR), vectorMulOnMatrix(muVec, MmnR)), muDiagInv) // Nm
-	cnO := vectorMulOnMatrix(vectorSub(vectorMulOnMatrix(lambdaVec, MlnO), vectorMulOnMatrix(muVec, MmnO)), muDiagInv) // Nm
+	cnL := f.vectorMulOnMatrix(f.vectorSub(f.vectorMulOnMatrix(lambdaVec, MlnL), f.vectorMulOnMatrix(muVec, MmnL)), muDiagInv) // Nm
+	cnR := f.vectorMulOnMatrix(f.vectorSub(f.vectorMulOnMatrix(lambdaVec, MlnR), f.vectorMulOnMatrix(muVec, MmnR)), muDiagInv) // Nm
+	cnO := f.vectorMulOnMatrix(f.vectorSub(f.vectorMulOnMatrix(lambdaVec, MlnO), f.vectorMulOnMatrix(muVec, MmnO)), muDiagInv) // Nm
 
-	clL := vectorSub(vectorMulOnMatrix(lambdaVec, MllL), vectorMulOnMatrix(muVec, MmlL)) // Nv
-	clR := vectorSub(vectorMulOnMatrix(lambdaVec, MllR), vectorMulOnMatrix(muVec, MmlR)) // Nv
-	clO := vectorSub(vectorMulOnMatrix(lambdaVec, MllO), vectorMulOnMatrix(muVec, MmlO)) // Nv
+	clL := f.vectorSub(f.vectorMulOnMatrix(lambdaVec, MllL), f.vectorMulOnMatrix(muVec, MmlL)) // Nv
+	clR := f.vectorSub(f.vectorMulOnMatrix(lambdaVec, MllR), f.vectorMulOnMatrix(muVec, MmlR)) // Nv
+	clO := f.vectorSub(f.vectorMulOnMatrix(lambdaVec, MllO), f.vectorMulOnMatrix(muVec, MmlO)) // Nv
 
-	fs.AddPoint(proof.CS)
+	if err := addGroupPoint(fs, proof.CS); err != nil {
+		return err
+	}
 
 	// Select random t using Fiat-Shamir heuristic
-	t := fs.GetChallenge()
-	tinv := inv(t)
-	t2 := mul(t, t)
-	t3 := mul(t2, t)
+	t := f.challenge(fs)
+	if err := f.checkChallenges(t); err != nil {
+		return err
+	}
+	tinv := f.inv(t)
+	t2 := f.mul(t, t)
+	t3 := f.mul(t2, t)
 
-	pnT := vectorMulOnScalar(cnO, mul(inv(delta), t3))
-	pnT = vectorSub(pnT, vectorMulOnScalar(cnL, t2))
-	pnT = vectorAdd(pnT, vectorMulOnScalar(cnR, t))
+	pnT := f.vectorMulOnScalar(cnO, f.mul(f.inv(delta), t3))
+	pnT = f.vectorSub(pnT, f.vectorMulOnScalar(cnL, t2))
+	pnT = f.vectorAdd(pnT, f.vectorMulOnScalar(cnR, t))
 
-	psT := weightVectorMul(pnT, pnT, mu)
-	psT = add(psT, mul(bint(2), mul(vectorMul(lambdaVec, public.Al), t3)))
-	psT = sub(psT, mul(bint(2), mul(vectorMul(muVec, public.Am), t3)))
+	psT := f.weightVectorMul(pnT, pnT, mu)
+	psT = f.add(psT, f.mul(bint(2), f.mul(f.vectorMul(lambdaVec, public.Al), t3)))
+	psT = f.sub(psT, f.mul(bint(2), f.mul(f.vectorMul(muVec, public.Am), t3)))
+
+	PnT, err := groupMSM(g, msm, public.GVec, pnT)
+	if err != nil {
+		return err
+	}
 
-	PT := new(bn256.G1).ScalarMult(public.G, psT)
-	PT.Add(PT, vectorPointScalarMul(public.GVec, pnT))
+	PT := g.Add(g.ScalarMult(public.G, psT), PnT)
 
 	cr_T := []*big.Int{
 		bint(1),
-		mul(beta, tinv),
-		mul(beta, t),
-		mul(beta, t2),
-		mul(beta, t3),
-		mul(beta, mul(t, t3)),
-		mul(beta, mul(t2, t3)),
-		mul(beta, mul(t3, t3)),
-		mul(beta, mul(mul(t3, t), t3)),
+		f.mul(beta, tinv),
+		f.mul(beta, t),
+		f.mul(beta, t2),
+		f.mul(beta, t3),
+		f.mul(beta, f.mul(t, t3)),
+		f.mul(beta, f.mul(t2, t3)),
+		f.mul(beta, f.mul(t3, t3)),
+		f.mul(beta, f.mul(f.mul(t3, t), t3)),
 	} // 9
 
-	cl0 := vectorSub(
-		vectorMulOnScalar(e(lambda, public.Nv)[1:], bbool(public.Fl)),
-		vectorMulOnScalar(vectorMulOnScalar(e(mu, public.Nv)[1:], mu), bbool(public.Fm)),
+	cl0 := f.vectorSub(
+		f.vectorMulOnScalar(f.e(lambda, public.Nv)[1:], bbool(public.Fl)),
+		f.vectorMulOnScalar(f.vectorMulOnScalar(f.e(mu, public.Nv)[1:], mu), bbool(public.Fm)),
 	)
 
-	cl_T := vectorMulOnScalar(clO, mul(t3, inv(delta)))
-	cl_T = vectorSub(cl_T, vectorMulOnScalar(clL, t2))
-	cl_T = vectorAdd(cl_T, vectorMulOnScalar(clR, t))
-	cl_T = vectorMulOnScalar(cl_T, bint(2))
-	cl_T = vectorSub(cl_T, cl0)
+	cl_T := f.vectorMulOnScalar(clO, f.mul(t3, f.inv(delta)))
+	cl_T = f.vectorSub(cl_T, f.vectorMulOnScalar(clL, t2))
+	cl_T = f.vectorAdd(cl_T, f.vectorMulOnScalar(clR, t))
+	cl_T = f.vectorMulOnScalar(cl_T, bint(2))
+	cl_T = f.vectorSub(cl_T, cl0)
 
 	cT := append(cr_T, cl_T...)
 
-	CT := new(bn256.G1).Add(PT, new(bn256.G1).ScalarMult(proof.CS, tinv))
-	CT.Add(CT, new(bn256.G1).ScalarMult(proof.CO, minus(delta)))
-	CT.Add(CT, new(bn256.G1).ScalarMult(proof.CL, t))
-	CT.Add(CT, new(bn256.G1).ScalarMult(proof.CR, minus(t2)))
-	CT.Add(CT, new(bn256.G1).ScalarMult(V_, t3))
-
-	return VerifyWNLA(
-		&WeightNormLinearPublic{
-			G:    public.G,
-			GVec: append(public.GVec, public.GVec_...),
-			HVec: append(public.HVec, public.HVec_...),
-			C:    cT,
-			Ro:   ro,
-			Mu:   mu,
+	// Extend weights with zeros up to the WNLA generator count
+	for len(cT) < len(public.HVec)+len(public.HVec_) {
+		cT = append(cT, bint(0))
+	}
+
+	CT := g.Add(PT, g.ScalarMult(proof.CS, tinv))
+	CT = g.Add(CT, g.ScalarMult(proof.CO, f.neg(delta)))
+	CT = g.Add(CT, g.ScalarMult(proof.CL, t))
+	CT = g.Add(CT, g.ScalarMult(proof.CR, f.neg(t2)))
+	CT = g.Add(CT, g.ScalarMult(V_, t3))
+
+	GVec, HVec := public.wnlaGenerators()
+
+	return verifyGroupWNLA(
+		&GroupWNLAPublic{
+			Group: g,
+			G:     public.G,
+			GVec:  GVec,
+			HVec:  HVec,
+			C:     cT,
+			Ro:    ro,
+			Mu:    mu,
 		},
 		proof.WNLA,
 		CT,
 		fs,
+		msm,
 	)
 }
 
 // ProveCircuit generates zero knowledge proof that witness satisfies BP++ arithmetic circuit.
-// Use empty FiatShamirEngine for call.
-func ProveCircuit(public *ArithmeticCircuitPublic, V []*bn256.G1, fs FiatShamirEngine, private *ArithmeticCircuitPrivate) *ArithmeticCircuitProof {
-	ro, rl, no, nl, lo, ll, Co, Cl := commitOL(public, private.Wo, private.Wl)
+// Use empty FiatShamirEngine for call. The options are passed on to the final WNLA proof, see WithLowMemory.
+// See ProveGroupCircuit.
+func ProveCircuit(public *ArithmeticCircuitPublic, V []*bn256.G1, fs FiatShamirEngine, private *ArithmeticCircuitPrivate, opts ...ProveOption) (*ArithmeticCircuitProof, error) {
+	if public == nil {
+		return nil, errors.New("circuit public parameters cannot be nil")
+	}
 
-	rr, nr, lr, Cr := commitR(public, private.Wo, private.Wr)
+	proof, err := proveCircuit(public.group(), toPoints(V), fs, private, nil, opts)
+	if err != nil {
+		return nil, err
+	}
 
-	fs.AddPoint(Cl)
-	fs.AddPoint(Cr)
-	fs.AddPoint(Co)
+	return proof.bn256(), nil
+}
 
-	for i := range V {
-		fs.AddPoint(V[i])
+// ProveGroupCircuit generates zero knowledge proof that witness satisfies the arithmetic circuit over the group of
+// public. Use empty FiatShamirEngine for call. The options are passed on to the final WNLA proof, see WithLowMemory.
+func ProveGroupCircuit(public *GroupArithmeticCircuitPublic, V []Point, fs FiatShamirEngine, private *ArithmeticCircuitPrivate, opts ...ProveOption) (*GroupArithmeticCircuitProof, error) {
+	return proveCircuit(public, V, fs, private, nil, opts)
+}
+
+// olCommitment holds the output of commitOL, so the commitments to the wl and wo wires can be made ahead of the
+// proof.
+type olCommitment struct {
+	ro, rl, no, nl, lo, ll []*big.Int
+	Co, Cl                 Point
+}
+
+func newOLCommitment(public *GroupArithmeticCircuitPublic, wo, wl []*big.Int) *olCommitment {
+	c := &olCommitment{}
+	c.ro, c.rl, c.no, c.nl, c.lo, c.ll, c.Co, c.Cl = commitOL(public, wo, wl)
+	return c
+}
+
+// proveCircuit works like ProveGroupCircuit. If ol is not nil, it is used as the commitment to the wl and wo wires
+// instead of committing them again; the caller is responsible for it matching the witness.
+func proveCircuit(public *GroupArithmeticCircuitPublic, V []Point, fs FiatShamirEngine, private *ArithmeticCircuitPrivate, ol *olCommitment, opts []ProveOption) (*GroupArithmeticCircuitProof, error) {
+	if err := checkCircuitInputs(public, V, fs); err != nil {
+		return nil, err
+	}
+
+	if private == nil {
+		return nil, errors.New("circuit private values cannot be nil")
+	}
+
+	if ol == nil {
+		ol = newOLCommitment(public, private.Wo, private.Wl)
+	}
+
+	rr, nr, lr, Cr := commitR(public, private.Wo, private.Wr)
+
+	for _, p := range append([]Point{ol.Cl, Cr, ol.Co}, V...) {
+		if err := addGroupPoint(fs, p); err != nil {
+			return nil, err
+		}
 	}
 
 	return innerArithmeticCircuitProve(public, fs, private,
-		[][]*big.Int{rl, rr, ro},
-		[][]*big.Int{nl, nr, no},
-		[][]*big.Int{ll, lr, lo},
-		[]*bn256.G1{Cl, Cr, Co},
+		[][]*big.Int{ol.rl, rr, ol.ro},
+		[][]*big.Int{ol.nl, nr, ol.no},
+		[][]*big.Int{ol.ll, lr, ol.lo},
+		[]Point{ol.Cl, Cr, ol.Co},
+		opts,
 	)
 }
 
-func commitOL(public *ArithmeticCircuitPublic, wo, wl []*big.Int) (ro []*big.Int, rl []*big.Int, no []*big.Int, nl []*big.Int, lo []*big.Int, ll []*big.Int, Co *bn256.G1, Cl *bn256.G1) {
+func commitOL(public *GroupArithmeticCircuitPublic, wo, wl []*big.Int) (ro []*big.Int, rl []*big.Int, no []*big.Int, nl []*big.Int, lo []*big.Int, ll []*big.Int, Co Point, Cl Point) {
+	g, f := public.Group, public.field()
+
 	// contains random values, except several positions
-	ro = []*big.Int{NewRandScalar(), NewRandScalar(), NewRandScalar(), NewRandScalar(), bint(0), NewRandScalar(), NewRandScalar(), NewRandScalar(), bint(0)} // 9
-	rl = []*big.Int{NewRandScalar(), NewRandScalar(), NewRandScalar(), bint(0), NewRandScalar(), NewRandScalar(), NewRandScalar(), bint(0), bint(0)}         // 9
+	ro = []*big.Int{f.randScalar(), f.randScalar(), f.randScalar(), f.randScalar(), bint(0), f.randScalar(), f.randScalar(), f.randScalar(), bint(0)} // 9
+	rl = []*big.Int{f.randScalar(), f.randScalar(), f.randScalar(), bint(0), f.randScalar(), f.randScalar(), f.randScalar(), bint(0), bint(0)}        // 9
 
 	nl = wl // Nm
 
@@ -206,18 +547,17 @@ func commitOL(public *ArithmeticCircuitPublic, wo, wl []*big.Int) (ro []*big.Int
 		}
 	}
 
-	Co = vectorPointScalarMul(public.HVec, append(ro, lo...))
-	Co.Add(Co, vectorPointScalarMul(public.GVec, no))
-
-	Cl = vectorPointScalarMul(public.HVec, append(rl, ll...))
-	Cl.Add(Cl, vectorPointScalarMul(public.GVec, nl))
+	Co = g.Add(groupVectorPointScalarMul(g, public.HVec, append(ro, lo...)), groupVectorPointScalarMul(g, public.GVec, no))
+	Cl = g.Add(groupVectorPointScalarMul(g, public.HVec, append(rl, ll...)), groupVectorPointScalarMul(g, public.GVec, nl))
 
 	return
 }
 
-func commitR(public *ArithmeticCircuitPublic, wo, wr []*big.Int) (rr []*big.Int, nr []*big.Int, lr []*big.Int, Cr *bn256.G1) {
+func commitR(public *GroupArithmeticCircuitPublic, wo, wr []*big.Int) (rr []*big.Int, nr []*big.Int, lr []*big.Int, Cr Point) {
+	g, f := public.Group, public.field()
+
 	// contains random values, except several positions
-	rr = []*big.Int{NewRandScalar(), NewRandScalar(), bint(0), NewRandScalar(), NewRandScalar(), NewRandScalar(), bint(0), bint(0), bint(0)} // 9
+	rr = []*big.Int{f.randScalar(), f.randScalar(), bint(0), f.randScalar(), f.randScalar(), f.randScalar(), bint(0), bint(0), bint(0)} // 9
 
 	nr = wr // Nm
 
@@ -231,12 +571,13 @@ func commitR(public *ArithmeticCircuitPublic, wo, wr []*big.Int) (rr []*big.Int,
 		}
 	}
 
-	Cr = vectorPointScalarMul(public.HVec, append(rr, lr...))
-	Cr.Add(Cr, vectorPointScalarMul(public.GVec, nr))
+	Cr = g.Add(groupVectorPointScalarMul(g, public.HVec, append(rr, lr...)), groupVectorPointScalarMul(g, public.GVec, nr))
 	return
 }
 
-func innerArithmeticCircuitProve(public *ArithmeticCircuitPublic, fs FiatShamirEngine, private *ArithmeticCircuitPrivate, r, n, l [][]*big.Int, C []*bn256.G1) *ArithmeticCircuitProof {
+func innerArithmeticCircuitProve(public *GroupArithmeticCircuitPublic, fs FiatShamirEngine, private *ArithmeticCircuitPrivate, r, n, l [][]*big.Int, C []Point, opts []ProveOption) (*GroupArithmeticCircuitProof, error) {
+	g, f := public.Group, public.field()
+
 	rl := r[0] // 8
 	rr := r[1] // 8
 	ro := r[2] // 8
@@ -253,61 +594,65 @@ func innerArithmeticCircuitProve(public *ArithmeticCircuitPublic, fs FiatShamirE
 	Cr := C[1]
 	Co := C[2]
 
-	proof := &ArithmeticCircuitProof{
+	proof := &GroupArithmeticCircuitProof{
 		CL: Cl,
 		CR: Cr,
 		CO: Co,
 	}
 
 	// Generates challenges using Fiat-Shamir heuristic
-	rho := fs.GetChallenge()
-	lambda := fs.GetChallenge()
-	beta := fs.GetChallenge()
-	delta := fs.GetChallenge()
+	rho := f.challenge(fs)
+	lambda := f.challenge(fs)
+	beta := f.challenge(fs)
+	delta := f.challenge(fs)
+
+	if err := f.checkChallenges(rho, lambda, beta, delta); err != nil {
+		return nil, err
+	}
 
 	MlnL, MmnL, MlnR, MmnR := calculateMRL(public)
 	MlnO, MmnO, MllL, MmlL, MllR, MmlR, MllO, MmlO := calculateMO(public)
 
-	mu := mul(rho, rho)
+	mu := f.mul(rho, rho)
 
 	// Calculate lambda vector (nl == nv * k)
-	lambdaVec := vectorAdd(
-		vectorTensorMul(vectorMulOnScalar(e(lambda, public.Nv), mu), e(pow(mu, public.Nv), public.K)),
-		vectorTensorMul(e(mu, public.Nv), e(pow(lambda, public.Nv), public.K)),
+	lambdaVec := f.vectorAdd(
+		f.vectorTensorMul(f.vectorMulOnScalar(f.e(lambda, public.Nv), mu), f.e(f.pow(mu, public.Nv), public.K)),
+		f.vectorTensorMul(f.e(mu, public.Nv), f.e(f.pow(lambda, public.Nv), public.K)),
 	)
 
-	lambdaVec = vectorMulOnScalar(lambdaVec, bbool(public.Fl && public.Fm))
-	lambdaVec = vectorSub(e(lambda, public.Nl), lambdaVec) //Nl
+	lambdaVec = f.vectorMulOnScalar(lambdaVec, bbool(public.Fl && public.Fm))
+	lambdaVec = f.vectorSub(f.e(lambda, public.Nl), lambdaVec) //Nl
 
 	// Calculate mu vector
-	muVec := vectorMulOnScalar(e(mu, public.Nm), mu) // Nm
+	muVec := f.vectorMulOnScalar(f.e(mu, public.Nm), mu) // Nm
 
 	// Calculate coefficients clX, X = {L,R,O}
-	muDiagInv := diagInv(mu, public.Nm) // Nm*Nm
+	muDiagInv := f.diagInv(mu, public.Nm) // Nm*Nm
 
-	cnL := vectorMulOnMatrix(vectorSub(vectorMulOnMatrix(lambdaVec, MlnL), vectorMulOnMatrix(muVec, MmnL)), muDiagInv) // Nm
-	cnR := vectorMulOnMatrix(vectorSub(vectorMulOnMatrix(lambdaVec, MlnR), vectorMulOnMatrix(muVec, MmnR)), muDiagInv) // Nm
-	cnO := vectorMulOnMatrix(vectorSub(vectorMulOnMatrix(lambdaVec, MlnO), vectorMulOnMatrix(muVec, MmnO)), muDiagInv) // Nm
+	cnL := f.vectorMulOnMatrix(f.vectorSub(f.vectorMulOnMatrix(lambdaVec, MlnL), f.vectorMulOnMatrix(muVec, MmnL)), muDiagInv) // Nm
+	cnR := f.vectorMulOnMatrix(f.vectorSub(f.vectorMulOnMatrix(lambdaVec, MlnR), f.vectorMulOnMatrix(muVec, MmnR)), muDiagInv) // Nm
+	cnO := f.vectorMulOnMatrix(f.vectorSub(f.vectorMulOnMatrix(lambdaVec, MlnO), f.vectorMulOnMatrix(muVec, MmnO)), muDiagInv) // Nm
 
-	clL := vectorSub(vectorMulOnMatrix(lambdaVec, MllL), vectorMulOnMatrix(muVec, MmlL)) // Nv
-	clR := vectorSub(vectorMulOnMatrix(lambdaVec, MllR), vectorMulOnMatrix(muVec, MmlR)) // Nv
-	clO := vectorSub(vectorMulOnMatrix(lambdaVec, MllO), vectorMulOnMatrix(muVec, MmlO)) // Nv
+	clL := f.vectorSub(f.vectorMulOnMatrix(lambdaVec, MllL), f.vectorMulOnMatrix(muVec, MmlL)) // Nv
+	clR := f.vectorSub(f.vectorMulOnMatrix(lambdaVec, MllR), f.vectorMulOnMatrix(muVec, MmlR)) // Nv
+	clO := f.vectorSub(f.vectorMulOnMatrix(lambdaVec, MllO), f.vectorMulOnMatrix(muVec, MmlO)) // Nv
 
 	// Prover computes
 	ls := make([]*big.Int, public.Nv) // Nv
 	for i := range ls {
-		ls[i] = NewRandScalar()
+		ls[i] = f.randScalar()
 	}
 
 	ns := make([]*big.Int, public.Nm) // Nm
 	for i := range ns {
-		ns[i] = NewRandScalar()
+		ns[i] = f.randScalar()
 	}
 
 	lcomb := func(i int) *big.Int {
-		return add(
-			mul(bbool(public.Fl), pow(lambda, public.Nv*i)),
-			mul(bbool(public.Fm), pow(mu, public.Nv*i+1)),
+		return f.add(
+			f.mul(bbool(public.Fl), f.pow(lambda, public.Nv*i)),
+			f.mul(bbool(public.Fm), f.pow(mu, public.Nv*i+1)),
 		)
 	}
 
@@ -316,13 +661,13 @@ func innerArithmeticCircuitProve(public *ArithmeticCircuitPublic, fs FiatShamirE
 		v_ := bint(0)
 
 		for i := 0; i < public.K; i++ {
-			v_ = add(v_, mul(
+			v_ = f.add(v_, f.mul(
 				private.V[i][0],
 				lcomb(i),
 			))
 		}
 
-		return mul(v_, bint(2))
+		return f.mul(v_, bint(2))
 	}()
 
 	rv := zeroVector(9) // 9
@@ -330,13 +675,13 @@ func innerArithmeticCircuitProve(public *ArithmeticCircuitPublic, fs FiatShamirE
 		rv1 := bint(0)
 
 		for i := 0; i < public.K; i++ {
-			rv1 = add(rv1, mul(
+			rv1 = f.add(rv1, f.mul(
 				private.Sv[i],
 				lcomb(i),
 			))
 		}
 
-		return mul(rv1, bint(2))
+		return f.mul(rv1, bint(2))
 	}()
 
 	// Calc linear combination of v[][1:]
@@ -344,181 +689,192 @@ func innerArithmeticCircuitProve(public *ArithmeticCircuitPublic, fs FiatShamirE
 		var v_1 = zeroVector(1)
 
 		for i := 0; i < public.K; i++ {
-			v_1 = vectorAdd(v_1, vectorMulOnScalar(
+			v_1 = f.vectorAdd(v_1, f.vectorMulOnScalar(
 				private.V[i][1:],
 				lcomb(i),
 			))
 		}
 
-		return vectorMulOnScalar(v_1, bint(2))
+		return f.vectorMulOnScalar(v_1, bint(2))
 	}()
 
-	cl0 := vectorSub(
-		vectorMulOnScalar(e(lambda, public.Nv)[1:], bbool(public.Fl)),
-		vectorMulOnScalar(vectorMulOnScalar(e(mu, public.Nv)[1:], mu), bbool(public.Fm)),
+	cl0 := f.vectorSub(
+		f.vectorMulOnScalar(f.e(lambda, public.Nv)[1:], bbool(public.Fl)),
+		f.vectorMulOnScalar(f.vectorMulOnScalar(f.e(mu, public.Nv)[1:], mu), bbool(public.Fm)),
 	)
 
 	// Define f'(t):
 	f_ := make(map[int]*big.Int)
 
-	f_[-2] = sub(f_[-2], weightVectorMul(ns, ns, mu))
+	f_[-2] = f.sub(f_[-2], f.weightVectorMul(ns, ns, mu))
 
-	f_[-1] = add(f_[-1], vectorMul(cl0, ls))
-	f_[-1] = add(f_[-1], mul(mul(bint(2), delta), weightVectorMul(ns, no, mu)))
+	f_[-1] = f.add(f_[-1], f.vectorMul(cl0, ls))
+	f_[-1] = f.add(f_[-1], f.mul(f.mul(bint(2), delta), f.weightVectorMul(ns, no, mu)))
 
-	f_[0] = sub(f_[0], mul(bint(2), vectorMul(clR, ls)))
-	f_[0] = sub(f_[0], mul(delta, vectorMul(cl0, lo)))
-	f_[0] = sub(f_[0], mul(weightVectorMul(ns, vectorAdd(nl, cnR), mu), bint(2)))
-	f_[0] = sub(f_[0], mul(mul(delta, delta), weightVectorMul(no, no, mu)))
+	f_[0] = f.sub(f_[0], f.mul(bint(2), f.vectorMul(clR, ls)))
+	f_[0] = f.sub(f_[0], f.mul(delta, f.vectorMul(cl0, lo)))
+	f_[0] = f.sub(f_[0], f.mul(f.weightVectorMul(ns, f.vectorAdd(nl, cnR), mu), bint(2)))
+	f_[0] = f.sub(f_[0], f.mul(f.mul(delta, delta), f.weightVectorMul(no, no, mu)))
 
-	f_[1] = add(f_[1], mul(bint(2), vectorMul(clL, ls)))
-	f_[1] = add(f_[1], mul(bint(2), mul(delta, vectorMul(clR, lo))))
-	f_[1] = add(f_[1], vectorMul(cl0, ll))
-	f_[1] = add(f_[1], mul(weightVectorMul(ns, vectorAdd(nr, cnL), mu), bint(2)))
-	f_[1] = add(f_[1], mul(weightVectorMul(no, vectorAdd(nl, cnR), mu), mul(bint(2), delta)))
+	f_[1] = f.add(f_[1], f.mul(bint(2), f.vectorMul(clL, ls)))
+	f_[1] = f.add(f_[1], f.mul(bint(2), f.mul(delta, f.vectorMul(clR, lo))))
+	f_[1] = f.add(f_[1], f.vectorMul(cl0, ll))
+	f_[1] = f.add(f_[1], f.mul(f.weightVectorMul(ns, f.vectorAdd(nr, cnL), mu), bint(2)))
+	f_[1] = f.add(f_[1], f.mul(f.weightVectorMul(no, f.vectorAdd(nl, cnR), mu), f.mul(bint(2), delta)))
 
-	f_[2] = add(f_[2], weightVectorMul(cnR, cnR, mu))
-	f_[2] = sub(f_[2], mul(bint(2), mul(inv(delta), vectorMul(clO, ls))))
-	f_[2] = sub(f_[2], mul(bint(2), mul(delta, vectorMul(clL, lo))))
-	f_[2] = sub(f_[2], mul(bint(2), vectorMul(clR, ll)))
-	f_[2] = sub(f_[2], vectorMul(cl0, lr))
-	f_[2] = sub(f_[2], mul(mul(bint(2), inv(delta)), weightVectorMul(ns, cnO, mu)))
-	f_[2] = sub(f_[2], mul(mul(bint(2), delta), weightVectorMul(no, vectorAdd(nr, cnL), mu)))
-	f_[2] = sub(f_[2], weightVectorMul(vectorAdd(nl, cnR), vectorAdd(nl, cnR), mu))
+	f_[2] = f.add(f_[2], f.weightVectorMul(cnR, cnR, mu))
+	f_[2] = f.sub(f_[2], f.mul(bint(2), f.mul(f.inv(delta), f.vectorMul(clO, ls))))
+	f_[2] = f.sub(f_[2], f.mul(bint(2), f.mul(delta, f.vectorMul(clL, lo))))
+	f_[2] = f.sub(f_[2], f.mul(bint(2), f.vectorMul(clR, ll)))
+	f_[2] = f.sub(f_[2], f.vectorMul(cl0, lr))
+	f_[2] = f.sub(f_[2], f.mul(f.mul(bint(2), f.inv(delta)), f.weightVectorMul(ns, cnO, mu)))
+	f_[2] = f.sub(f_[2], f.mul(f.mul(bint(2), delta), f.weightVectorMul(no, f.vectorAdd(nr, cnL), mu)))
+	f_[2] = f.sub(f_[2], f.weightVectorMul(f.vectorAdd(nl, cnR), f.vectorAdd(nl, cnR), mu))
 
 	// f_3[3] should be zero
 
-	f_[4] = add(f_[4], mul(mul(bint(2), inv(delta)), weightVectorMul(cnO, cnR, mu)))
-	f_[4] = add(f_[4], weightVectorMul(cnL, cnL, mu))
-	f_[4] = sub(f_[4], mul(mul(bint(2), inv(delta)), vectorMul(clO, ll)))
-	f_[4] = sub(f_[4], mul(bint(2), vectorMul(clL, lr)))
-	f_[4] = sub(f_[4], mul(bint(2), vectorMul(clR, v_1)))
-	f_[4] = sub(f_[4], mul(mul(bint(2), inv(delta)), weightVectorMul(vectorAdd(nl, cnR), cnO, mu)))
-	f_[4] = sub(f_[4], weightVectorMul(vectorAdd(nr, cnL), vectorAdd(nr, cnL), mu))
-
-	f_[5] = sub(f_[5], mul(mul(bint(2), inv(delta)), weightVectorMul(cnO, cnL, mu)))
-	f_[5] = add(f_[5], mul(mul(bint(2), inv(delta)), vectorMul(clO, lr)))
-	f_[5] = add(f_[5], mul(bint(2), vectorMul(clL, v_1)))
-	f_[5] = add(f_[5], mul(mul(bint(2), inv(delta)), weightVectorMul(vectorAdd(nr, cnL), cnO, mu)))
-
-	f_[6] = sub(f_[6], mul(mul(bint(2), inv(delta)), vectorMul(clO, v_1)))
-
-	f_[3] = add(f_[3], mul(bint(2), sub(vectorMul(lambdaVec, public.Al), vectorMul(muVec, public.Am))))
-	f_[3] = sub(f_[3], mul(bint(2), weightVectorMul(cnL, cnR, mu))) // 2+1
-	f_[3] = add(f_[3], v_)
-	f_[3] = add(f_[3], mul(bint(2), vectorMul(clO, lo)))
-	f_[3] = add(f_[3], mul(bint(2), vectorMul(clL, ll)))
-	f_[3] = add(f_[3], mul(bint(2), vectorMul(clR, lr)))
-	f_[3] = add(f_[3], vectorMul(cl0, v_1))
-	f_[3] = add(f_[3], mul(weightVectorMul(no, cnO, mu), bint(2)))
-	f_[3] = add(f_[3], mul(weightVectorMul(vectorAdd(nl, cnR), vectorAdd(nr, cnL), mu), bint(2)))
-
-	ch_beta_inv := inv(beta)
+	f_[4] = f.add(f_[4], f.mul(f.mul(bint(2), f.inv(delta)), f.weightVectorMul(cnO, cnR, mu)))
+	f_[4] = f.add(f_[4], f.weightVectorMul(cnL, cnL, mu))
+	f_[4] = f.sub(f_[4], f.mul(f.mul(bint(2), f.inv(delta)), f.vectorMul(clO, ll)))
+	f_[4] = f.sub(f_[4], f.mul(bint(2), f.vectorMul(clL, lr)))
+	f_[4] = f.sub(f_[4], f.mul(bint(2), f.vectorMul(clR, v_1)))
+	f_[4] = f.sub(f_[4], f.mul(f.mul(bint(2), f.inv(delta)), f.weightVectorMul(f.vectorAdd(nl, cnR), cnO, mu)))
+	f_[4] = f.sub(f_[4], f.weightVectorMul(f.vectorAdd(nr, cnL), f.vectorAdd(nr, cnL), mu))
+
+	f_[5] = f.sub(f_[5], f.mul(f.mul(bint(2), f.inv(delta)), f.weightVectorMul(cnO, cnL, mu)))
+	f_[5] = f.add(f_[5], f.mul(f.mul(bint(2), f.inv(delta)), f.vectorMul(clO, lr)))
+	f_[5] = f.add(f_[5], f.mul(bint(2), f.vectorMul(clL, v_1)))
+	f_[5] = f.add(f_[5], f.mul(f.mul(bint(2), f.inv(delta)), f.weightVectorMul(f.vectorAdd(nr, cnL), cnO, mu)))
+
+	f_[6] = f.sub(f_[6], f.mul(f.mul(bint(2), f.inv(delta)), f.vectorMul(clO, v_1)))
+
+	f_[3] = f.add(f_[3], f.mul(bint(2), f.sub(f.vectorMul(lambdaVec, public.Al), f.vectorMul(muVec, public.Am))))
+	f_[3] = f.sub(f_[3], f.mul(bint(2), f.weightVectorMul(cnL, cnR, mu))) // 2+1
+	f_[3] = f.add(f_[3], v_)
+	f_[3] = f.add(f_[3], f.mul(bint(2), f.vectorMul(clO, lo)))
+	f_[3] = f.add(f_[3], f.mul(bint(2), f.vectorMul(clL, ll)))
+	f_[3] = f.add(f_[3], f.mul(bint(2), f.vectorMul(clR, lr)))
+	f_[3] = f.add(f_[3], f.vectorMul(cl0, v_1))
+	f_[3] = f.add(f_[3], f.mul(f.weightVectorMul(no, cnO, mu), bint(2)))
+	f_[3] = f.add(f_[3], f.mul(f.weightVectorMul(f.vectorAdd(nl, cnR), f.vectorAdd(nr, cnL), mu), bint(2)))
+
+	ch_beta_inv := f.inv(beta)
 
 	rs := []*big.Int{
-		add(f_[-1], mul(beta, mul(delta, ro[1]))),
-		mul(f_[-2], ch_beta_inv),
-		sub(mul(add(f_[0], mul(delta, ro[0])), ch_beta_inv), rl[1]),
-		add(mul(sub(f_[1], rl[0]), ch_beta_inv), add(rr[1], mul(delta, ro[2]))),
-		add(mul(add(f_[2], rr[0]), ch_beta_inv), sub(mul(delta, ro[3]), rl[2])),
-		minus(mul(rv[0], ch_beta_inv)),
-		add(mul(f_[4], ch_beta_inv), add(mul(delta, ro[5]), sub(rr[3], rl[4]))),
-		add(mul(f_[5], ch_beta_inv), sub(add(rr[4], mul(delta, ro[6])), rl[5])),
-		add(mul(f_[6], ch_beta_inv), add(sub(mul(delta, ro[7]), rl[6]), rr[5])),
+		f.add(f_[-1], f.mul(beta, f.mul(delta, ro[1]))),
+		f.mul(f_[-2], ch_beta_inv),
+		f.sub(f.mul(f.add(f_[0], f.mul(delta, ro[0])), ch_beta_inv), rl[1]),
+		f.add(f.mul(f.sub(f_[1], rl[0]), ch_beta_inv), f.add(rr[1], f.mul(delta, ro[2]))),
+		f.add(f.mul(f.add(f_[2], rr[0]), ch_beta_inv), f.sub(f.mul(delta, ro[3]), rl[2])),
+		f.neg(f.mul(rv[0], ch_beta_inv)),
+		f.add(f.mul(f_[4], ch_beta_inv), f.add(f.mul(delta, ro[5]), f.sub(rr[3], rl[4]))),
+		f.add(f.mul(f_[5], ch_beta_inv), f.sub(f.add(rr[4], f.mul(delta, ro[6])), rl[5])),
+		f.add(f.mul(f_[6], ch_beta_inv), f.add(f.sub(f.mul(delta, ro[7]), rl[6]), rr[5])),
 	} // 9
 
-	Cs := vectorPointScalarMul(public.HVec, append(rs, ls...))
-	Cs.Add(Cs, vectorPointScalarMul(public.GVec, ns))
+	Cs := g.Add(groupVectorPointScalarMul(g, public.HVec, append(rs, ls...)), groupVectorPointScalarMul(g, public.GVec, ns))
 
 	proof.CS = Cs
 
-	fs.AddPoint(Cs)
+	if err := addGroupPoint(fs, Cs); err != nil {
+		return nil, err
+	}
 
 	// Select random t using Fiat-Shamir heuristic
-	t := fs.GetChallenge()
-	tinv := inv(t)
-	t2 := mul(t, t)
-	t3 := mul(t2, t)
-
-	lT := vectorMulOnScalar(append(rs, ls...), tinv)
-	lT = vectorSub(lT, vectorMulOnScalar(append(ro, lo...), delta))
-	lT = vectorAdd(lT, vectorMulOnScalar(append(rl, ll...), t))
-	lT = vectorSub(lT, vectorMulOnScalar(append(rr, lr...), t2))
-	lT = vectorAdd(lT, vectorMulOnScalar(append(rv, v_1...), t3))
+	t := f.challenge(fs)
+	if err := f.checkChallenges(t); err != nil {
+		return nil, err
+	}
+	tinv := f.inv(t)
+	t2 := f.mul(t, t)
+	t3 := f.mul(t2, t)
 
-	pnT := vectorMulOnScalar(cnO, mul(inv(delta), t3))
-	pnT = vectorSub(pnT, vectorMulOnScalar(cnL, t2))
-	pnT = vectorAdd(pnT, vectorMulOnScalar(cnR, t))
+	lT := f.vectorMulOnScalar(append(rs, ls...), tinv)
+	lT = f.vectorSub(lT, f.vectorMulOnScalar(append(ro, lo...), delta))
+	lT = f.vectorAdd(lT, f.vectorMulOnScalar(append(rl, ll...), t))
+	lT = f.vectorSub(lT, f.vectorMulOnScalar(append(rr, lr...), t2))
+	lT = f.vectorAdd(lT, f.vectorMulOnScalar(append(rv, v_1...), t3))
 
-	psT := weightVectorMul(pnT, pnT, mu)
-	psT = add(psT, mul(bint(2), mul(vectorMul(lambdaVec, public.Al), t3)))
-	psT = sub(psT, mul(bint(2), mul(vectorMul(muVec, public.Am), t3)))
+	pnT := f.vectorMulOnScalar(cnO, f.mul(f.inv(delta), t3))
+	pnT = f.vectorSub(pnT, f.vectorMulOnScalar(cnL, t2))
+	pnT = f.vectorAdd(pnT, f.vectorMulOnScalar(cnR, t))
 
-	n_T := vectorMulOnScalar(ns, tinv)
-	n_T = vectorSub(n_T, vectorMulOnScalar(no, delta))
-	n_T = vectorAdd(n_T, vectorMulOnScalar(nl, t))
-	n_T = vectorSub(n_T, vectorMulOnScalar(nr, t2))
+	psT := f.weightVectorMul(pnT, pnT, mu)
+	psT = f.add(psT, f.mul(bint(2), f.mul(f.vectorMul(lambdaVec, public.Al), t3)))
+	psT = f.sub(psT, f.mul(bint(2), f.mul(f.vectorMul(muVec, public.Am), t3)))
 
-	nT := vectorAdd(pnT, n_T)
+	n_T := f.vectorMulOnScalar(ns, tinv)
+	n_T = f.vectorSub(n_T, f.vectorMulOnScalar(no, delta))
+	n_T = f.vectorAdd(n_T, f.vectorMulOnScalar(nl, t))
+	n_T = f.vectorSub(n_T, f.vectorMulOnScalar(nr, t2))
 
-	PT := new(bn256.G1).ScalarMult(public.G, psT)
-	PT.Add(PT, vectorPointScalarMul(public.GVec, pnT))
+	nT := f.vectorAdd(pnT, n_T)
 
 	cr_T := []*big.Int{
 		bint(1),
-		mul(beta, tinv),
-		mul(beta, t),
-		mul(beta, t2),
-		mul(beta, t3),
-		mul(beta, mul(t, t3)),
-		mul(beta, mul(t2, t3)),
-		mul(beta, mul(t3, t3)),
-		mul(beta, mul(mul(t3, t), t3)),
+		f.mul(beta, tinv),
+		f.mul(beta, t),
+		f.mul(beta, t2),
+		f.mul(beta, t3),
+		f.mul(beta, f.mul(t, t3)),
+		f.mul(beta, f.mul(t2, t3)),
+		f.mul(beta, f.mul(t3, t3)),
+		f.mul(beta, f.mul(f.mul(t3, t), t3)),
 	} // 9
 
-	cl_T := vectorMulOnScalar(clO, mul(t3, inv(delta)))
-	cl_T = vectorSub(cl_T, vectorMulOnScalar(clL, t2))
-	cl_T = vectorAdd(cl_T, vectorMulOnScalar(clR, t))
-	cl_T = vectorMulOnScalar(cl_T, bint(2))
-	cl_T = vectorSub(cl_T, cl0)
+	cl_T := f.vectorMulOnScalar(clO, f.mul(t3, f.inv(delta)))
+	cl_T = f.vectorSub(cl_T, f.vectorMulOnScalar(clL, t2))
+	cl_T = f.vectorAdd(cl_T, f.vectorMulOnScalar(clR, t))
+	cl_T = f.vectorMulOnScalar(cl_T, bint(2))
+	cl_T = f.vectorSub(cl_T, cl0)
 
 	cT := append(cr_T, cl_T...)
 
-	vT := add(psT, mul(v_, t3))
+	vT := f.add(psT, f.mul(v_, t3))
 
-	CT := new(bn256.G1).ScalarMult(public.G, vT)
-	CT.Add(CT, vectorPointScalarMul(public.HVec, lT))
-	CT.Add(CT, vectorPointScalarMul(public.GVec, nT))
+	CT := g.ScalarMult(public.G, vT)
+	CT = g.Add(CT, groupVectorPointScalarMul(g, public.HVec, lT))
+	CT = g.Add(CT, groupVectorPointScalarMul(g, public.GVec, nT))
 
 	// Extend vectors with zeros up to 2^i
 
 	for len(lT) < len(public.HVec)+len(public.HVec_) {
 		lT = append(lT, bint(0))
+	}
+
+	for len(cT) < len(public.HVec)+len(public.HVec_) {
 		cT = append(cT, bint(0))
 	}
 
-	for len(nT) < len(public.GVec_)+len(public.GVec_) {
+	for len(nT) < len(public.GVec)+len(public.GVec_) {
 		nT = append(nT, bint(0))
 	}
 
-	proof.WNLA = ProveWNLA(
-		&WeightNormLinearPublic{
-			G:    public.G,
-			GVec: append(public.GVec, public.GVec_...),
-			HVec: append(public.HVec, public.HVec_...),
-			C:    cT,
-			Ro:   rho,
-			Mu:   mu,
+	wnla, err := ProveGroupWNLA(
+		&GroupWNLAPublic{
+			Group: g,
+			G:     public.G,
+			GVec:  append(append([]Point{}, public.GVec...), public.GVec_...),
+			HVec:  append(append([]Point{}, public.HVec...), public.HVec_...),
+			C:     cT,
+			Ro:    rho,
+			Mu:    mu,
 		},
 		CT,
 		fs,
 		lT,
 		nT,
+		opts...,
 	)
-	return proof
+	if err != nil {
+		return nil, err
+	}
+
+	proof.WNLA = wnla
+	return proof, nil
 }
 
-func calculateMRL(public *ArithmeticCircuitPublic) (MlnL, MmnL, MlnR, MmnR [][]*big.Int) {
+func calculateMRL(public *GroupArithmeticCircuitPublic) (MlnL, MmnL, MlnR, MmnR [][]*big.Int) {
 	for i := 0; i < public.Nl; i++ { // Nl * Nm
 		MlnL = append(MlnL, public.Wl[i][:public.Nm])
 	}
@@ -538,7 +894,7 @@ func calculateMRL(public *ArithmeticCircuitPublic) (MlnL, MmnL, MlnR, MmnR [][]*
 	return
 }
 
-func calculateMO(public *ArithmeticCircuitPublic) (MlnO, MmnO, MllL, MmlL, MllR, MmlR, MllO, MmlO [][]*big.Int) {
+func calculateMO(public *GroupArithmeticCircuitPublic) (MlnO, MmnO, MllL, MmlL, MllR, MmlR, MllO, MmlO [][]*big.Int) {
 	var WlO [][]*big.Int // Nl*No
 	for i := 0; i < public.Nl; i++ {
 		WlO = append(WlO, public.Wl[i][public.Nm*2:])
@@ -9,6 +9,7 @@ import (
 	"github.com/cloudflare/bn256"
 	"github.com/davecgh/go-spew/spew"
 	"math/big"
+	"strings"
 	"testing"
 )
 
@@ -51,57 +52,64 @@ func TestArithmeticCircuit(t *testing.T) {
 	fmt.Println("Circuit check:", vectorMul(Wm[0], w), "=", vectorMul(wl, wr))
 	fmt.Println("Circuit check:", vectorAdd(vectorAdd([]*big.Int{vectorMul(Wl[0], w), vectorMul(Wl[1], w)}, wv), Al), "= 0")
 
-	wnla := NewWeightNormLinearPublic(16, 1)
-
-	public := &ArithmeticCircuitPublic{
-		Nm: Nm,
-		Nl: Nl,
-		Nv: Nv,
-		Nw: Nw,
-		No: No,
-		K:  K,
-
-		G:    wnla.G,
-		GVec: wnla.GVec[:Nm],
-		HVec: wnla.HVec[:9+Nv],
-
-		Wm: Wm,
-		Wl: Wl,
-		Am: Am,
-		Al: Al,
-		Fl: true,
-		Fm: false,
-
-		F: func(typ PartitionType, index int) *int {
-			if typ == PartitionLL { // map all to ll
-				return &index
-			}
-
-			return nil
-		},
-
-		GVec_: wnla.GVec[Nm:],
-		HVec_: wnla.HVec[9+Nv:],
-	}
-
-	private := &ArithmeticCircuitPrivate{
-		V:  [][]*big.Int{wv},
-		Sv: []*big.Int{NewRandScalar()},
-		Wl: wl,
-		Wr: wr,
-		Wo: wo,
-	}
-
-	V := make([]*bn256.G1, public.K)
-	for i := range V {
-		V[i] = public.CommitCircuit(private.V[i], private.Sv[i])
-	}
-
-	proof := ProveCircuit(public, V, NewKeccakFS(), private)
-	spew.Dump(proof)
-
-	if err := VerifyCircuit(public, V, NewKeccakFS(), proof); err != nil {
-		panic(err)
+	// The WNLA pads the circuit vectors with GVec_ and HVec_ up to its generator counts. GVec_ is empty for one
+	// GVec entry and longer than GVec for four.
+	for _, gLen := range []int{1, 4} {
+		wnla := NewWeightNormLinearPublic(16, gLen)
+
+		public := &ArithmeticCircuitPublic{
+			Nm: Nm,
+			Nl: Nl,
+			Nv: Nv,
+			Nw: Nw,
+			No: No,
+			K:  K,
+
+			G:    wnla.G,
+			GVec: wnla.GVec[:Nm],
+			HVec: wnla.HVec[:9+Nv],
+
+			Wm: Wm,
+			Wl: Wl,
+			Am: Am,
+			Al: Al,
+			Fl: true,
+			Fm: false,
+
+			F: func(typ PartitionType, index int) *int {
+				if typ == PartitionLL { // map all to ll
+					return &index
+				}
+
+				return nil
+			},
+
+			GVec_: wnla.GVec[Nm:],
+			HVec_: wnla.HVec[9+Nv:],
+		}
+
+		private := &ArithmeticCircuitPrivate{
+			V:  [][]*big.Int{wv},
+			Sv: []*big.Int{NewRandScalar()},
+			Wl: wl,
+			Wr: wr,
+			Wo: wo,
+		}
+
+		V := make([]*bn256.G1, public.K)
+		for i := range V {
+			V[i] = public.CommitCircuit(private.V[i], private.Sv[i])
+		}
+
+		proof, err := ProveCircuit(public, V, NewKeccakFS(), private)
+		if err != nil {
+			t.Fatalf("ProveCircuit failed: %v", err)
+		}
+		spew.Dump(proof)
+
+		if err := VerifyCircuit(public, V, NewKeccakFS(), proof); err != nil {
+			t.Fatalf("GVec length %d: VerifyCircuit failed: %v", gLen, err)
+		}
 	}
 }
 
@@ -225,7 +233,10 @@ func TestArithmeticCircuitBinaryRangeProof(t *testing.T) {
 		V[i] = public.CommitCircuit(private.V[i], private.Sv[i])
 	}
 
-	proof := ProveCircuit(public, V, NewKeccakFS(), private)
+	proof, err := ProveCircuit(public, V, NewKeccakFS(), private)
+	if err != nil {
+		t.Fatalf("ProveCircuit failed: %v", err)
+	}
 	spew.Dump(proof)
 
 	if err := VerifyCircuit(public, V, NewKeccakFS(), proof); err != nil {
@@ -236,3 +247,45 @@ func TestArithmeticCircuitBinaryRangeProof(t *testing.T) {
 func frac(a, b int) *big.Int {
 	return mul(bint(a), inv(bint(b)))
 }
+
+func TestArithmeticCircuitValidate(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	if err := rangeCircuit(public.group(), bint(7), negBasePowers(scalarField{bn256.Order}, public.Np, public.Nd)).Validate(); err != nil {
+		t.Fatalf("Range circuit rejected: %v", err)
+	}
+
+	cases := map[string]struct {
+		modify   func(c *GroupArithmeticCircuitPublic)
+		expected string
+	}{
+		"nil generator in GVec": {func(c *GroupArithmeticCircuitPublic) { c.GVec[7] = nil }, "GVec[7] cannot be nil"},
+		"nil generator in HVec": {func(c *GroupArithmeticCircuitPublic) { c.HVec[12] = nil }, "HVec[12] cannot be nil"},
+		"short HVec":            {func(c *GroupArithmeticCircuitPublic) { c.HVec = c.HVec[:20] }, "len(HVec)=20"},
+		"nil coefficient":       {func(c *GroupArithmeticCircuitPublic) { c.Wl[3][5] = nil }, "Wl[3][5] cannot be nil"},
+		"short row":             {func(c *GroupArithmeticCircuitPublic) { c.Wm[2] = c.Wm[2][:10] }, "Wm[2] has 10 columns"},
+		"missing Al":            {func(c *GroupArithmeticCircuitPublic) { c.Al = nil }, "len(Al)=0"},
+		"wrong Nw":              {func(c *GroupArithmeticCircuitPublic) { c.Nw++ }, "Nw=49"},
+		"nil F":                 {func(c *GroupArithmeticCircuitPublic) { c.F = nil }, "partition function F"},
+	}
+
+	for name, tc := range cases {
+		circuit := rangeCircuit(public.group(), bint(7), negBasePowers(scalarField{bn256.Order}, public.Np, public.Nd))
+		circuit.GVec = append([]Point{}, circuit.GVec...)
+		circuit.HVec = append([]Point{}, circuit.HVec...)
+		tc.modify(circuit)
+
+		err := circuit.Validate()
+		if err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%s: expected error containing %q, got %v", name, tc.expected, err)
+		}
+
+		// The provers and verifiers report the error instead of panicking.
+		if err := VerifyGroupCircuit(circuit, []Point{public.G}, NewKeccakFS(), &GroupArithmeticCircuitProof{}); err == nil {
+			t.Errorf("%s: VerifyGroupCircuit accepted an invalid circuit", name)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"encoding/hex"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"strings"
+)
+
+// Human-readable rendering of proofs for tests and debugging.
+// Proofs are public data and contain no secret material, so everything printed here is safe to log.
+
+const debugHexLen = 8
+
+func shortPoint(p *bn256.G1) string {
+	if p == nil {
+		return "<nil>"
+	}
+	return hex.EncodeToString(p.Marshal())[:debugHexLen] + "…"
+}
+
+func shortScalar(s *big.Int) string {
+	if s == nil {
+		return "<nil>"
+	}
+	return hex.EncodeToString(scalarTo32Byte(s))[:debugHexLen] + "…"
+}
+
+func writeDebugPoints(b *strings.Builder, indent, name string, ps []*bn256.G1) {
+	fmt.Fprintf(b, "%s%s (%d):\n", indent, name, len(ps))
+	for i, p := range ps {
+		fmt.Fprintf(b, "%s  [%d] %s\n", indent, i, shortPoint(p))
+	}
+}
+
+func writeDebugScalars(b *strings.Builder, indent, name string, ss []*big.Int) {
+	fmt.Fprintf(b, "%s%s (%d):\n", indent, name, len(ss))
+	for i, s := range ss {
+		fmt.Fprintf(b, "%s  [%d] %s\n", indent, i, shortScalar(s))
+	}
+}
+
+func (p *WeightNormLinearArgumentProof) writeDebug(b *strings.Builder, indent string) {
+	fmt.Fprintf(b, "%srounds: %d\n", indent, len(p.X))
+	writeDebugPoints(b, indent, "R", p.R)
+	writeDebugPoints(b, indent, "X", p.X)
+	writeDebugScalars(b, indent, "L", p.L)
+	writeDebugScalars(b, indent, "N", p.N)
+}
+
+func (p *ArithmeticCircuitProof) writeDebug(b *strings.Builder, indent string) {
+	fmt.Fprintf(b, "%sCL: %s\n", indent, shortPoint(p.CL))
+	fmt.Fprintf(b, "%sCR: %s\n", indent, shortPoint(p.CR))
+	fmt.Fprintf(b, "%sCO: %s\n", indent, shortPoint(p.CO))
+	fmt.Fprintf(b, "%sCS: %s\n", indent, shortPoint(p.CS))
+	if p.WNLA == nil {
+		fmt.Fprintf(b, "%sWNLA: <nil>\n", indent)
+		return
+	}
+	fmt.Fprintf(b, "%sWNLA:\n", indent)
+	p.WNLA.writeDebug(b, indent+"  ")
+}
+
+// String returns a one-line summary of the WNLA proof.
+func (p *WeightNormLinearArgumentProof) String() string {
+	return fmt.Sprintf("WNLAProof{rounds: %d, L: %d, N: %d}", len(p.X), len(p.L), len(p.N))
+}
+
+// DebugString renders every field of the WNLA proof with truncated hex values, one per line.
+func (p *WeightNormLinearArgumentProof) DebugString() string {
+	b := new(strings.Builder)
+	b.WriteString("WNLAProof:\n")
+	p.writeDebug(b, "  ")
+	return b.String()
+}
+
+// String returns a one-line summary of the arithmetic circuit proof.
+func (p *ArithmeticCircuitProof) String() string {
+	rounds := 0
+	if p.WNLA != nil {
+		rounds = len(p.WNLA.X)
+	}
+	return fmt.Sprintf("CircuitProof{CL: %s, CR: %s, CO: %s, CS: %s, rounds: %d}",
+		shortPoint(p.CL), shortPoint(p.CR), shortPoint(p.CO), shortPoint(p.CS), rounds)
+}
+
+// DebugString renders every field of the arithmetic circuit proof with truncated hex values, one per line.
+func (p *ArithmeticCircuitProof) DebugString() string {
+	b := new(strings.Builder)
+	b.WriteString("CircuitProof:\n")
+	p.writeDebug(b, "  ")
+	return b.String()
+}
+
+// String returns a one-line summary of the range proof.
+func (p *ReciprocalProof) String() string {
+	if p.ArithmeticCircuitProof == nil {
+		return fmt.Sprintf("RangeProof{V: %s, circuit: <nil>}", shortPoint(p.V))
+	}
+	return fmt.Sprintf("RangeProof{V: %s, %s}", shortPoint(p.V), p.ArithmeticCircuitProof.String())
+}
+
+// DebugString renders every field of the range proof with truncated hex values, one per line.
+func (p *ReciprocalProof) DebugString() string {
+	b := new(strings.Builder)
+	b.WriteString("RangeProof:\n")
+	fmt.Fprintf(b, "  V: %s\n", shortPoint(p.V))
+	if p.ArithmeticCircuitProof == nil {
+		b.WriteString("  circuit: <nil>\n")
+		return b.String()
+	}
+	p.ArithmeticCircuitProof.writeDebug(b, "  ")
+	return b.String()
+}
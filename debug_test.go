@@ -0,0 +1,67 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func TestProofDebugString(t *testing.T) {
+	public := NewWeightNormLinearPublic(16, 8)
+
+	l := make([]*big.Int, 16)
+	for i := range l {
+		l[i] = bint(i + 1)
+	}
+
+	n := make([]*big.Int, 8)
+	for i := range n {
+		n[i] = bint(i + 100)
+	}
+
+	commitment, err := public.CommitWNLAChecked(l, n)
+	if err != nil {
+		t.Fatalf("CommitWNLAChecked failed: %v", err)
+	}
+
+	proof, err := ProveWNLA(public, commitment, NewKeccakFS(), l, n)
+	if err != nil {
+		t.Fatalf("ProveWNLA failed: %v", err)
+	}
+
+	summary := proof.String()
+	if !strings.Contains(summary, fmt.Sprintf("rounds: %d", len(proof.X))) {
+		t.Errorf("Summary does not report the round count: %s", summary)
+	}
+
+	debug := proof.DebugString()
+	for _, label := range []string{"R (", "X (", "L (", "N ("} {
+		if !strings.Contains(debug, label) {
+			t.Errorf("Debug output is missing %q:\n%s", label, debug)
+		}
+	}
+
+	rp := &ReciprocalProof{
+		ArithmeticCircuitProof: &ArithmeticCircuitProof{
+			CL:   NewRandPoint(),
+			CR:   NewRandPoint(),
+			CO:   NewRandPoint(),
+			CS:   NewRandPoint(),
+			WNLA: proof,
+		},
+		V: NewRandPoint(),
+	}
+
+	if !strings.HasPrefix(rp.String(), "RangeProof{V: ") {
+		t.Errorf("Unexpected range proof summary: %s", rp.String())
+	}
+
+	if !strings.Contains(rp.DebugString(), "    rounds: ") {
+		t.Errorf("Range proof debug output is missing nested WNLA rounds:\n%s", rp.DebugString())
+	}
+}
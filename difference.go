@@ -0,0 +1,115 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"github.com/cloudflare/bn256"
+	"math/big"
+)
+
+// differenceCommitment returns comX - comY, which by the homomorphic property is the commitment
+// CommitValue(x-y, sx-sy).
+func differenceCommitment(comX, comY *bn256.G1) *bn256.G1 {
+	return new(bn256.G1).Add(comX, new(bn256.G1).Neg(comY))
+}
+
+// ProveGreaterEqual proves that x >= y for the value commitments comX = CommitValue(x, sx) and
+// comY = CommitValue(y, sy) without revealing x or y. It runs a range proof for x-y over the commitment comX - comY,
+// so x-y must also fit into [0, Np^Nd). If x < y no proof is produced and an error is returned.
+// Use empty FiatShamirEngine for call.
+func ProveGreaterEqual(public *ReciprocalPublic, fs FiatShamirEngine, comX, comY *bn256.G1, x, y, sx, sy *big.Int) (*ReciprocalProof, error) {
+	if err := public.Validate(); err != nil {
+		return nil, err
+	}
+
+	if comX == nil || comY == nil {
+		return nil, errors.New("commitments cannot be nil")
+	}
+
+	if x == nil || y == nil || sx == nil || sy == nil {
+		return nil, errors.New("openings cannot be nil")
+	}
+
+	if !VerifyOpening(public, comX, x, sx) || !VerifyOpening(public, comY, y, sy) {
+		return nil, errors.New("openings do not match the commitments")
+	}
+
+	d := new(big.Int).Sub(x, y)
+	if d.Sign() < 0 {
+		return nil, errors.New("x is less than y")
+	}
+
+	private, err := public.newPrivate(d, sub(sx, sy))
+	if err != nil {
+		return nil, err
+	}
+
+	return ProveRange(public, fs, private)
+}
+
+// VerifyGreaterEqual verifies a proof produced by ProveGreaterEqual that the value committed in comX is greater
+// than or equal to the value committed in comY. If err is nil then proof is valid.
+//
+// The proof only shows that x-y modulo the group order lies in [0, Np^Nd). Values wrap around the order: for
+// comX = CommitValue(0, sx) and comY = CommitValue(bn256.Order-5, sy) the difference is 5, and a proof verifies
+// although x < y. Callers must check separately, e.g. with VerifyRange, that comX and comY commit to values in
+// [0, Np^Nd); then x-y cannot wrap and the proof implies x >= y.
+// Use empty FiatShamirEngine for call.
+func VerifyGreaterEqual(public *ReciprocalPublic, fs FiatShamirEngine, comX, comY *bn256.G1, proof *ReciprocalProof) error {
+	if comX == nil || comY == nil {
+		return errors.New("commitments cannot be nil")
+	}
+
+	return VerifyRange(public, differenceCommitment(comX, comY), fs, proof)
+}
+
+// BoundedDifferenceProof proves |x - y| <= k for two value commitments and a public bound k. It is the
+// LinearRangeProof of -k <= x - y <= k: Lower is a range proof for x-y+k and Upper a range proof for y-x+k.
+type BoundedDifferenceProof = LinearRangeProof
+
+// ProveBoundedDifference proves that |x - y| <= k for the value commitments comX = CommitValue(x, sx) and
+// comY = CommitValue(y, sy) and a public bound k >= 0, without revealing x or y. It is ProveLinearRange for
+// -k <= x - y <= k over the commitment comX - comY, so x-y+k and y-x+k must also fit into [0, Np^Nd). If
+// |x - y| > k no proof is produced and an error wrapping ErrValueOutOfRange is returned.
+// Use empty FiatShamirEngine for call.
+func ProveBoundedDifference(public *ReciprocalPublic, fs FiatShamirEngine, comX, comY *bn256.G1, x, y, sx, sy, k *big.Int) (*BoundedDifferenceProof, error) {
+	if err := public.Validate(); err != nil {
+		return nil, err
+	}
+
+	if comX == nil || comY == nil {
+		return nil, errors.New("commitments cannot be nil")
+	}
+
+	if x == nil || y == nil || sx == nil || sy == nil {
+		return nil, errors.New("openings cannot be nil")
+	}
+
+	if k == nil || k.Sign() < 0 {
+		return nil, errors.New("bound must be non-negative")
+	}
+
+	if !VerifyOpening(public, comX, x, sx) || !VerifyOpening(public, comY, y, sy) {
+		return nil, errors.New("openings do not match the commitments")
+	}
+
+	return ProveLinearRange(public, fs, sub(x, y), sub(sx, sy), bint(1), bint(0), new(big.Int).Neg(k), k)
+}
+
+// VerifyBoundedDifference verifies a proof produced by ProveBoundedDifference that the values committed in comX and
+// comY differ by at most k. If err is nil then proof is valid.
+// Use empty FiatShamirEngine for call.
+func VerifyBoundedDifference(public *ReciprocalPublic, fs FiatShamirEngine, comX, comY *bn256.G1, k *big.Int, proof *BoundedDifferenceProof) error {
+	if comX == nil || comY == nil {
+		return errors.New("commitments cannot be nil")
+	}
+
+	if k == nil || k.Sign() < 0 {
+		return errors.New("bound must be non-negative")
+	}
+
+	return VerifyLinearRange(public, fs, differenceCommitment(comX, comY), bint(1), bint(0), new(big.Int).Neg(k), k, proof)
+}
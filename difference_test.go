@@ -0,0 +1,135 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"testing"
+)
+
+func TestGreaterEqual(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	prove := func(x, y uint64) (*ReciprocalProof, *bn256.G1, *bn256.G1, error) {
+		X, Y := new(big.Int).SetUint64(x), new(big.Int).SetUint64(y)
+		sx, sy := NewRandScalar(), NewRandScalar()
+		comX, comY := public.CommitValue(X, sx), public.CommitValue(Y, sy)
+		proof, err := ProveGreaterEqual(public, NewKeccakFS(), comX, comY, X, Y, sx, sy)
+		return proof, comX, comY, err
+	}
+
+	for _, tc := range [][2]uint64{{1000, 1}, {42, 42}, {0xffffffffffffffff, 0}} {
+		proof, comX, comY, err := prove(tc[0], tc[1])
+		if err != nil {
+			t.Fatalf("%d >= %d: ProveGreaterEqual failed: %v", tc[0], tc[1], err)
+		}
+
+		if err := VerifyGreaterEqual(public, NewKeccakFS(), comX, comY, proof); err != nil {
+			t.Errorf("%d >= %d: valid proof rejected: %v", tc[0], tc[1], err)
+		}
+
+		if tc[0] != tc[1] {
+			if err := VerifyGreaterEqual(public, NewKeccakFS(), comY, comX, proof); err == nil {
+				t.Errorf("%d >= %d: proof accepted for swapped commitments", tc[0], tc[1])
+			}
+		}
+	}
+
+	if _, _, _, err := prove(1, 2); err == nil {
+		t.Error("Should not produce a proof for x < y")
+	}
+}
+
+// TestGreaterEqualWraparound shows why VerifyGreaterEqual needs range proofs of both commitments: 0 - (order-5) is
+// 5 modulo the group order, so a proof for the difference verifies, but comY has no range proof.
+func TestGreaterEqualWraparound(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	x, y := bint(0), new(big.Int).Sub(bn256.Order, bint(5))
+	sx, sy := NewRandScalar(), NewRandScalar()
+	comX, comY := public.CommitValue(x, sx), public.CommitValue(y, sy)
+
+	if _, err := ProveGreaterEqual(public, NewKeccakFS(), comX, comY, x, y, sx, sy); err == nil {
+		t.Error("ProveGreaterEqual should not prove 0 >= order-5")
+	}
+
+	private, err := public.newPrivate(sub(x, y), sub(sx, sy))
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	proof, err := ProveRange(public, NewKeccakFS(), private)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	if err := VerifyGreaterEqual(public, NewKeccakFS(), comX, comY, proof); err != nil {
+		t.Fatalf("The wrapped difference should verify: %v", err)
+	}
+
+	// The separate range proof of comY is what rejects the statement.
+	if _, err := public.newPrivate(y, sy); !errors.Is(err, ErrValueOutOfRange) {
+		t.Errorf("Expected ErrValueOutOfRange for a range proof of order-5, got %v", err)
+	}
+}
+
+func TestBoundedDifference(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	k := bint(10)
+
+	prove := func(x, y int) (*BoundedDifferenceProof, *bn256.G1, *bn256.G1, error) {
+		X, Y := bint(x), bint(y)
+		sx, sy := NewRandScalar(), NewRandScalar()
+		comX, comY := public.CommitValue(X, sx), public.CommitValue(Y, sy)
+		proof, err := ProveBoundedDifference(public, NewKeccakFS(), comX, comY, X, Y, sx, sy, k)
+		return proof, comX, comY, err
+	}
+
+	for _, tc := range [][2]int{{100, 95}, {95, 100}, {100, 110}, {110, 100}, {7, 7}} {
+		proof, comX, comY, err := prove(tc[0], tc[1])
+		if err != nil {
+			t.Fatalf("|%d - %d| <= %d: ProveBoundedDifference failed: %v", tc[0], tc[1], k, err)
+		}
+
+		if err := VerifyBoundedDifference(public, NewKeccakFS(), comX, comY, k, proof); err != nil {
+			t.Errorf("|%d - %d| <= %d: valid proof rejected: %v", tc[0], tc[1], k, err)
+		}
+
+		if err := VerifyBoundedDifference(public, NewKeccakFS(), comX, comY, bint(3), proof); err == nil {
+			t.Errorf("|%d - %d|: proof accepted for another bound", tc[0], tc[1])
+		}
+	}
+
+	for _, tc := range [][2]int{{100, 89}, {89, 100}} {
+		if _, _, _, err := prove(tc[0], tc[1]); !errors.Is(err, ErrValueOutOfRange) {
+			t.Errorf("Should not produce a proof for |%d - %d| > %d, got %v", tc[0], tc[1], k, err)
+		}
+	}
+
+	proof, comX, comY, err := prove(1, 2)
+	if err != nil {
+		t.Fatalf("ProveBoundedDifference failed: %v", err)
+	}
+
+	if err := VerifyBoundedDifference(public, NewKeccakFS(), comX, comY, k, &BoundedDifferenceProof{Lower: proof.Lower}); err == nil {
+		t.Error("Expected an error for an incomplete proof")
+	}
+
+	if err := VerifyBoundedDifference(public, NewKeccakFS(), comX, comY, bint(-1), proof); err == nil {
+		t.Error("Expected an error for a negative bound")
+	}
+}
@@ -0,0 +1,185 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"sync"
+)
+
+// ErrDigitCommitment is returned when a DigitCommitment passed with WithDigitCommitment does not fit the proof.
+var ErrDigitCommitment = errors.New("digit commitment does not match the proof")
+
+// DigitCommitment is the commitment to the digits and digit multiplicities of a range proof, made ahead of the
+// proof with CommitDigits. It holds the CL and CO commitments of the range circuit together with their openings:
+// CL commits to the digits and the multiplicities, CO to blinding values only. Neither depends on a challenge, so a
+// multi-phase protocol can publish them early and pass the commitment to ProveRange with WithDigitCommitment, which
+// then skips committing the digits again. The proof contains the same CL and CO.
+//
+// A DigitCommitment can be used for a single proof: the blinding values of its opening are combined with the
+// challenges of the proof, and two proofs sharing them reveal the digits. Once a proof attempt has used it, every
+// further attempt fails with ErrDigitCommitment.
+type DigitCommitment struct {
+	CL, CO *bn256.G1
+
+	nd       int
+	np       int
+	digitSet []int
+	gvec     []*bn256.G1
+	hvec     []*bn256.G1
+	digits   []*big.Int
+	m        []*big.Int
+	ol       *olCommitment
+
+	mu   sync.Mutex
+	used bool
+}
+
+// CommitDigits commits to the digits and multiplicities of private for a later ProveRange with
+// WithDigitCommitment. The options select the parameter view exactly as for ProveRange, so pass the same
+// WithDigitCount and WithValueGenerators options to both.
+func (p *ReciprocalPublic) CommitDigits(private *ReciprocalPrivate, opts ...ProveOption) (*DigitCommitment, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	public, err := p.rangeView(newProveConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := private.validate(public); err != nil {
+		return nil, err
+	}
+
+	set := public.digitSet()
+
+	// commitOL only needs the shape of the range circuit and its partition, not the challenge dependent matrices.
+	shape := &GroupArithmeticCircuitPublic{
+		Group: BN256,
+		Nm:    public.Nd,
+		Nv:    public.Nd + 1,
+		GVec:  toPoints(public.GVec),
+		HVec:  toPoints(public.HVec),
+		F:     rangePartition(len(set)),
+	}
+
+	digits := make([]*big.Int, len(private.Digits))
+	for i := range digits {
+		digits[i] = new(big.Int).Set(private.Digits[i])
+	}
+
+	m := make([]*big.Int, len(private.M))
+	for i := range m {
+		m[i] = new(big.Int).Set(private.M[i])
+	}
+
+	ol := newOLCommitment(shape, m, digits)
+
+	return &DigitCommitment{
+		CL:       new(bn256.G1).Set(toG1(ol.Cl)),
+		CO:       new(bn256.G1).Set(toG1(ol.Co)),
+		nd:       public.Nd,
+		np:       public.Np,
+		digitSet: append([]int{}, set...),
+		gvec:     public.GVec,
+		hvec:     public.HVec,
+		digits:   digits,
+		m:        m,
+		ol:       ol,
+	}, nil
+}
+
+// WithDigitCommitment makes ProveRange use the digit commitment dc made by CommitDigits instead of committing the
+// digits again. Before any work on the proof, the prover checks that
+//   - dc has not been used for a proof before,
+//   - CL and CO are still the commitments CommitDigits made,
+//   - dc was made for the same parameter view: the same Nd, Np, digit set and GVec and HVec generators, including
+//     the blinding generator of WithValueGenerators, and
+//   - the digits and multiplicities of the private values are the committed ones.
+//
+// The commitments themselves are not recomputed, that is the work the option saves. The verifier does not need
+// the option. Provers other than the range prover ignore it.
+func WithDigitCommitment(dc *DigitCommitment) ProveOption {
+	return func(cfg *proveConfig) {
+		cfg.digitCommitment = dc
+	}
+}
+
+// open runs the checks of WithDigitCommitment, marks the commitment as used and returns its opening.
+func (dc *DigitCommitment) open(public *ReciprocalPublic, private *ReciprocalPrivate) (*olCommitment, error) {
+	dc.mu.Lock()
+	defer dc.mu.Unlock()
+
+	if dc.ol == nil {
+		return nil, fmt.Errorf("%w: not created by CommitDigits", ErrDigitCommitment)
+	}
+
+	if dc.used {
+		return nil, fmt.Errorf("%w: already used for a proof", ErrDigitCommitment)
+	}
+	dc.used = true
+
+	if dc.CL == nil || dc.CO == nil || !pointsEqual(dc.CL, toG1(dc.ol.Cl)) || !pointsEqual(dc.CO, toG1(dc.ol.Co)) {
+		return nil, fmt.Errorf("%w: CL or CO was modified", ErrDigitCommitment)
+	}
+
+	if dc.nd != public.Nd || dc.np != public.Np || !equalInts(dc.digitSet, public.digitSet()) {
+		return nil, fmt.Errorf("%w: made for Nd=%d, Np=%d, proving with Nd=%d, Np=%d",
+			ErrDigitCommitment, dc.nd, dc.np, public.Nd, public.Np)
+	}
+
+	if !samePoints(dc.gvec, public.GVec) || !samePoints(dc.hvec, public.HVec) {
+		return nil, fmt.Errorf("%w: made for other generators", ErrDigitCommitment)
+	}
+
+	for i := range dc.digits {
+		if dc.digits[i].Cmp(private.Digits[i]) != 0 {
+			return nil, fmt.Errorf("%w: digit %d differs", ErrDigitCommitment, i)
+		}
+	}
+
+	for i := range dc.m {
+		if dc.m[i].Cmp(private.M[i]) != 0 {
+			return nil, fmt.Errorf("%w: multiplicity %d differs", ErrDigitCommitment, i)
+		}
+	}
+
+	return dc.ol, nil
+}
+
+// samePoints reports whether a and b hold the same points. Views of one parameter set share their points, so the
+// pointer comparison usually decides without marshaling.
+func samePoints(a, b []*bn256.G1) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] && !pointsEqual(a[i], b[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// equalInts reports whether a and b hold the same integers.
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
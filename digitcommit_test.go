@@ -0,0 +1,107 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"testing"
+)
+
+func TestDigitCommitment(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	private, err := public.newPrivate(new(big.Int).SetUint64(0xab4f0540ab4f0540), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	com := public.CommitValue(private.X, private.S)
+
+	dc, err := public.CommitDigits(private)
+	if err != nil {
+		t.Fatalf("CommitDigits failed: %v", err)
+	}
+
+	proof, err := ProveRange(public, NewKeccakFS(), private, WithDigitCommitment(dc))
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	if !pointsEqual(proof.CL, dc.CL) || !pointsEqual(proof.CO, dc.CO) {
+		t.Error("Proof does not contain the precomputed commitments")
+	}
+
+	if err := VerifyRange(public, com, NewKeccakFS(), proof); err != nil {
+		t.Fatalf("VerifyRange failed: %v", err)
+	}
+
+	if _, err := ProveRange(public, NewKeccakFS(), private, WithDigitCommitment(dc)); !errors.Is(err, ErrDigitCommitment) {
+		t.Errorf("Expected ErrDigitCommitment for a reused commitment, got %v", err)
+	}
+
+	// Custom value generators change the blinding generator of CL, so both steps get the same option.
+	g, err := DeriveGenerator("external-scheme", "value")
+	if err != nil {
+		t.Fatalf("DeriveGenerator failed: %v", err)
+	}
+
+	h, err := DeriveGenerator("external-scheme", "blinding")
+	if err != nil {
+		t.Fatalf("DeriveGenerator failed: %v", err)
+	}
+
+	dc, err = public.CommitDigits(private, WithValueGenerators(g, h))
+	if err != nil {
+		t.Fatalf("CommitDigits failed: %v", err)
+	}
+
+	proof, err = ProveRange(public, NewKeccakFS(), private, WithValueGenerators(g, h), WithDigitCommitment(dc))
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	if err := VerifyRange(public, CommitValueWith(g, h, private.X, private.S), NewKeccakFS(), proof, WithValueGenerators(g, h)); err != nil {
+		t.Fatalf("VerifyRange failed: %v", err)
+	}
+
+	other, err := public.newPrivate(new(big.Int).SetUint64(0xab4f0540ab4f0541), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	for name, tc := range map[string]struct {
+		modify func(dc *DigitCommitment)
+		opts   []ProveOption
+		priv   *ReciprocalPrivate
+	}{
+		"other digits":          {priv: other},
+		"other generators":      {opts: []ProveOption{WithValueGenerators(g, h)}},
+		"modified CL":           {modify: func(dc *DigitCommitment) { dc.CL = new(bn256.G1).Add(dc.CL, public.G) }},
+		"not from CommitDigits": {modify: func(dc *DigitCommitment) { dc.ol = nil }},
+	} {
+		dc, err := public.CommitDigits(private)
+		if err != nil {
+			t.Fatalf("CommitDigits failed: %v", err)
+		}
+
+		if tc.modify != nil {
+			tc.modify(dc)
+		}
+
+		priv := private
+		if tc.priv != nil {
+			priv = tc.priv
+		}
+
+		if _, err := ProveRange(public, NewKeccakFS(), priv, append(tc.opts, WithDigitCommitment(dc))...); !errors.Is(err, ErrDigitCommitment) {
+			t.Errorf("%s: expected ErrDigitCommitment, got %v", name, err)
+		}
+	}
+}
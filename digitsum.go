@@ -0,0 +1,122 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+)
+
+// checkDigitSumStatement validates the parameters of a digit sum proof. The digits are those of the range proof, so
+// base must be the base Np of the parameters.
+func checkDigitSumStatement(public *ReciprocalPublic, base Base, digitSum *big.Int) error {
+	if err := public.Validate(); err != nil {
+		return err
+	}
+
+	if int(base) != public.Np {
+		return fmt.Errorf("%w: digit sum for base %d, parameters have Np=%d", ErrBaseMismatch, base, public.Np)
+	}
+
+	if digitSum == nil || digitSum.Sign() < 0 {
+		return errors.New("digit sum must be non-negative")
+	}
+
+	return nil
+}
+
+// digitSumChallenge absorbs the digit sum statement, so a proof is bound to the base and sum it was made for.
+func digitSumChallenge(fs FiatShamirEngine, base Base, digitSum *big.Int) error {
+	if fs == nil {
+		return errors.New("Fiat-Shamir engine cannot be nil")
+	}
+	if err := fs.AddNumber(big.NewInt(int64(base))); err != nil {
+		return err
+	}
+	return fs.AddNumber(digitSum)
+}
+
+// digitSumCircuit extends the range circuit by the linear constraint digitSum - sum(digits) = 0.
+func digitSumCircuit(public *ReciprocalPublic, e *big.Int, digitSum *big.Int) *GroupArithmeticCircuitPublic {
+	circuit := rangeCircuit(public.group(), e, negBasePowers(scalarField{bn256.Order}, public.Np, public.Nd))
+
+	row := zeroVector(circuit.Nw)
+	for i := 0; i < circuit.Nm; i++ {
+		row[i] = minus(bint(1))
+	}
+
+	circuit.Wl = append(circuit.Wl, row)
+	circuit.Al = append(circuit.Al, add(digitSum, nil))
+	circuit.Nl++
+
+	return circuit
+}
+
+// ProveDigitSum proves that the base-Np digits of the value committed in CommitValue(value, blinding) sum to the
+// public digitSum, without revealing the value. The constraint is added over the digits of the range proof, so the
+// proof also shows that the value lies in [0, Np^Nd). Use empty FiatShamirEngine for call.
+func ProveDigitSum(public *ReciprocalPublic, fs FiatShamirEngine, value, blinding *big.Int, base Base, digitSum *big.Int) (*ReciprocalProof, error) {
+	if err := checkFreshTranscript(fs); err != nil {
+		return nil, err
+	}
+
+	if err := checkDigitSumStatement(public, base, digitSum); err != nil {
+		return nil, err
+	}
+
+	if value == nil || blinding == nil {
+		return nil, errors.New("value and blinding cannot be nil")
+	}
+
+	private, err := public.newPrivate(value, blinding)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := big.NewInt(0)
+	for _, d := range private.Digits {
+		sum.Add(sum, d)
+	}
+
+	if sum.Cmp(digitSum) != 0 {
+		return nil, fmt.Errorf("digits of the value sum to %s, not %s", sum, digitSum)
+	}
+
+	if err := digitSumChallenge(fs, base, digitSum); err != nil {
+		return nil, err
+	}
+
+	return proveReciprocal(public, fs, private, rangeDomainTag(public, fs), func(e *big.Int) *GroupArithmeticCircuitPublic {
+		return digitSumCircuit(public, e, digitSum)
+	})
+}
+
+// VerifyDigitSum verifies a proof produced by ProveDigitSum that the base digits of the value committed in com sum
+// to digitSum. If err is nil then proof is valid. Use empty FiatShamirEngine for call. Of the options only WithMSM
+// applies; the RangeOptions are rejected.
+func VerifyDigitSum(public *ReciprocalPublic, fs FiatShamirEngine, com *bn256.G1, base Base, digitSum *big.Int, proof *ReciprocalProof, opts ...VerifyOption) error {
+	if err := checkFreshTranscript(fs); err != nil {
+		return err
+	}
+
+	if err := checkDigitSumStatement(public, base, digitSum); err != nil {
+		return err
+	}
+
+	msm, err := statementMSM(opts)
+	if err != nil {
+		return err
+	}
+
+	if err := digitSumChallenge(fs, base, digitSum); err != nil {
+		return err
+	}
+
+	return verifyReciprocal(public, com, fs, proof, rangeDomainTag(public, fs), func(e *big.Int) *GroupArithmeticCircuitPublic {
+		return digitSumCircuit(public, e, digitSum)
+	}, msm)
+}
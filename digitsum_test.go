@@ -0,0 +1,88 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"testing"
+)
+
+func TestDigitSum(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, Base16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	// 0x0f0f_1234 has the digits f, f, 1, 2, 3, 4 and zeros: 15+15+1+2+3+4 = 40.
+	value, blinding := big.NewInt(0x0f0f_1234), NewRandScalar()
+	com := public.CommitValue(value, blinding)
+
+	proof, err := ProveDigitSum(public, NewKeccakFS(), value, blinding, Base16, bint(40))
+	if err != nil {
+		t.Fatalf("ProveDigitSum failed: %v", err)
+	}
+
+	if err := VerifyDigitSum(public, NewKeccakFS(), com, Base16, bint(40), proof); err != nil {
+		t.Fatalf("VerifyDigitSum failed: %v", err)
+	}
+
+	if err := VerifyDigitSum(public, NewKeccakFS(), com, Base16, bint(40), proof, WithMSM(vectorPointScalarMul)); err != nil {
+		t.Errorf("VerifyDigitSum failed with WithMSM: %v", err)
+	}
+
+	if err := VerifyDigitSum(public, NewKeccakFS(), com, Base16, bint(40), proof, WithMSM(func(points []*bn256.G1, scalars []*big.Int) *bn256.G1 {
+		return vectorPointScalarMul(points[:len(points)-1], scalars)
+	})); !errors.Is(err, ErrMSMMismatch) {
+		t.Errorf("Expected ErrMSMMismatch, got %v", err)
+	}
+
+	if err := VerifyDigitSum(public, NewKeccakFS(), com, Base16, bint(40), proof, WithDigitCount(8)); err == nil {
+		t.Error("VerifyDigitSum accepted a range option")
+	}
+
+	if err := VerifyDigitSum(public, NewKeccakFS(), com, Base16, bint(41), proof); err == nil {
+		t.Error("Proof accepted for another digit sum")
+	}
+
+	if err := VerifyDigitSum(public, NewKeccakFS(), public.CommitValue(big.NewInt(0x0f0f_1243), blinding), Base16, bint(40), proof); err == nil {
+		t.Error("Proof accepted for another commitment")
+	}
+
+	if _, err := ProveDigitSum(public, NewKeccakFS(), value, blinding, Base16, bint(39)); err == nil {
+		t.Error("Proved a wrong digit sum")
+	}
+
+	// A forged witness for a wrong sum does not satisfy the extra constraint.
+	private, err := public.newPrivate(value, blinding)
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	fs := NewKeccakFS()
+	if err := digitSumChallenge(fs, Base16, bint(39)); err != nil {
+		t.Fatalf("digitSumChallenge failed: %v", err)
+	}
+
+	forged, err := proveReciprocal(public, fs, private, rangeDomainTag(public, fs), func(e *big.Int) *GroupArithmeticCircuitPublic {
+		return digitSumCircuit(public, e, bint(39))
+	})
+	if err != nil {
+		t.Fatalf("proveReciprocal failed: %v", err)
+	}
+
+	if err := VerifyDigitSum(public, NewKeccakFS(), com, Base16, bint(39), forged); err == nil {
+		t.Error("Forged proof for a wrong digit sum accepted")
+	}
+
+	if _, err := ProveDigitSum(public, NewKeccakFS(), value, blinding, Base10, bint(40)); !errors.Is(err, ErrBaseMismatch) {
+		t.Errorf("Expected ErrBaseMismatch, got %v", err)
+	}
+
+	if _, err := ProveDigitSum(public, NewKeccakFS(), value, blinding, Base16, bint(-1)); err == nil {
+		t.Error("Expected an error for a negative digit sum")
+	}
+}
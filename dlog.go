@@ -0,0 +1,47 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"github.com/cloudflare/bn256"
+	"math/big"
+)
+
+var errDLogFailed = errors.New("s*h does not match R + c*P")
+
+// dlogChallenge absorbs the statement of a discrete logarithm proof together with its nonce commitment R and returns
+// the Fiat-Shamir challenge. Every caller keeps its own statement encoding, so the transcript stays domain separated.
+type dlogChallenge func(R *bn256.G1) (*big.Int, error)
+
+// proveDLog is a Schnorr proof of knowledge of r with P = r*h: it returns the nonce commitment R = k*h and the
+// response s = k + c*r, where c is derived by challenge.
+func proveDLog(h *bn256.G1, r *big.Int, challenge dlogChallenge) (*bn256.G1, *big.Int, error) {
+	k := NewRandScalar()
+	R := new(bn256.G1).ScalarMult(h, k)
+
+	c, err := challenge(R)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return R, add(k, mul(c, r)), nil
+}
+
+// verifyDLog checks a proof of proveDLog for P = r*h. The caller checks R and s for nil and s for being canonical.
+func verifyDLog(h, P, R *bn256.G1, s *big.Int, challenge dlogChallenge) error {
+	c, err := challenge(R)
+	if err != nil {
+		return err
+	}
+
+	// s*h == R + c*P
+	expected := new(bn256.G1).Add(R, new(bn256.G1).ScalarMult(P, c))
+	if !pointsEqual(new(bn256.G1).ScalarMult(h, s), expected) {
+		return errDLogFailed
+	}
+
+	return nil
+}
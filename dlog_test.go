@@ -0,0 +1,53 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"testing"
+)
+
+func TestDLog(t *testing.T) {
+	h := new(bn256.G1).ScalarBaseMult(NewRandScalar())
+	r := NewRandScalar()
+	P := new(bn256.G1).ScalarMult(h, r)
+
+	challenge := func(fs FiatShamirEngine) dlogChallenge {
+		return func(R *bn256.G1) (*big.Int, error) {
+			if err := fs.AddPoint(P); err != nil {
+				return nil, err
+			}
+			if err := fs.AddPoint(R); err != nil {
+				return nil, err
+			}
+			return fs.GetChallenge(), nil
+		}
+	}
+
+	R, s, err := proveDLog(h, r, challenge(NewKeccakFS()))
+	if err != nil {
+		t.Fatalf("proveDLog failed: %v", err)
+	}
+
+	if err := verifyDLog(h, P, R, s, challenge(NewKeccakFS())); err != nil {
+		t.Fatalf("verifyDLog failed: %v", err)
+	}
+
+	if err := verifyDLog(h, P, R, add(s, bint(1)), challenge(NewKeccakFS())); !errors.Is(err, errDLogFailed) {
+		t.Errorf("Expected errDLogFailed for a tampered response, got %v", err)
+	}
+
+	// A proof for another discrete logarithm does not verify.
+	R, s, err = proveDLog(h, add(r, bint(1)), challenge(NewKeccakFS()))
+	if err != nil {
+		t.Fatalf("proveDLog failed: %v", err)
+	}
+
+	if err := verifyDLog(h, P, R, s, challenge(NewKeccakFS())); !errors.Is(err, errDLogFailed) {
+		t.Errorf("Expected errDLogFailed for a wrong witness, got %v", err)
+	}
+}
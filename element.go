@@ -0,0 +1,83 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+)
+
+// VectorElementRangeProof proves that one element of a committed vector lies in [0, Np^Nd) without revealing the
+// element or its index. Commitment = CommitValue(element, blinding) is a fresh commitment to the selected element,
+// Membership shows that it hides one of the vector elements and Range shows that its value is in range.
+type VectorElementRangeProof struct {
+	Commitment *bn256.G1
+	Membership *ArithmeticCircuitProof
+	Range      *ReciprocalProof
+}
+
+// ProveVectorElementRange proves that vector[index] lies in [0, Np^Nd) for the vector commitment
+// vectorCommitment = CommitSet(vector, vectorBlinding), hiding both the element and the index. The selected element
+// is committed again with blinding, and the proof combines a membership proof for that commitment in the vector
+// (see ProveMembershipCommitted) with a range proof for it, both over the same transcript. The vector can have up
+// to Nd elements. Use empty FiatShamirEngine for call.
+func ProveVectorElementRange(public *ReciprocalPublic, fs FiatShamirEngine, vectorCommitment *bn256.G1, index int, vector []*big.Int, vectorBlinding, blinding *big.Int) (*VectorElementRangeProof, error) {
+	if index < 0 || index >= len(vector) {
+		return nil, fmt.Errorf("index %d out of range [0, %d)", index, len(vector))
+	}
+
+	if vector[index] == nil || blinding == nil {
+		return nil, errors.New("element and blinding cannot be nil")
+	}
+
+	if err := checkBlinding(scalarField{bn256.Order}, blinding); err != nil {
+		return nil, err
+	}
+
+	value := vector[index]
+
+	private, err := public.newPrivate(value, blinding)
+	if err != nil {
+		return nil, fmt.Errorf("element %d: %w", index, err)
+	}
+
+	membership, err := ProveMembershipCommitted(public, fs, value, blinding, vectorCommitment, vector, vectorBlinding)
+	if err != nil {
+		return nil, err
+	}
+
+	// The range proof continues the transcript of the membership proof.
+	proof, err := NewProver(public).ProveChained(fs, private)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VectorElementRangeProof{
+		Commitment: public.CommitValue(value, blinding),
+		Membership: membership,
+		Range:      proof,
+	}, nil
+}
+
+// VerifyVectorElementRange verifies a proof produced by ProveVectorElementRange that one of the vectorLen elements
+// committed in vectorCommitment lies in [0, Np^Nd). If err is nil then proof is valid.
+// Use empty FiatShamirEngine for call.
+func VerifyVectorElementRange(public *ReciprocalPublic, fs FiatShamirEngine, vectorCommitment *bn256.G1, vectorLen int, proof *VectorElementRangeProof) error {
+	if proof == nil || proof.Commitment == nil || proof.Membership == nil || proof.Range == nil {
+		return errors.New("vector element range proof is incomplete")
+	}
+
+	if err := VerifyMembershipCommitted(public, fs, proof.Commitment, vectorCommitment, vectorLen, proof.Membership); err != nil {
+		return fmt.Errorf("membership: %w", err)
+	}
+
+	if err := newVerifier(public, nil).VerifyChained(proof.Commitment, fs, proof.Range); err != nil {
+		return fmt.Errorf("range: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,65 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestVectorElementRange(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	// Only the element at index 2 lies in [0, 16^16).
+	vector := []*big.Int{minus(bint(1)), new(big.Int).Lsh(bint(1), 70), bint(0xcafe), minus(bint(7))}
+	vectorBlinding := NewRandScalar()
+
+	vectorCom, err := public.CommitSet(vector, vectorBlinding)
+	if err != nil {
+		t.Fatalf("CommitSet failed: %v", err)
+	}
+
+	proof, err := ProveVectorElementRange(public, NewKeccakFS(), vectorCom, 2, vector, vectorBlinding, NewRandScalar())
+	if err != nil {
+		t.Fatalf("ProveVectorElementRange failed: %v", err)
+	}
+
+	if err := VerifyVectorElementRange(public, NewKeccakFS(), vectorCom, len(vector), proof); err != nil {
+		t.Fatalf("VerifyVectorElementRange failed: %v", err)
+	}
+
+	other, err := public.CommitSet([]*big.Int{bint(1), bint(2), bint(3), bint(4)}, vectorBlinding)
+	if err != nil {
+		t.Fatalf("CommitSet failed: %v", err)
+	}
+
+	if err := VerifyVectorElementRange(public, NewKeccakFS(), other, len(vector), proof); err == nil {
+		t.Error("Proof accepted for another vector")
+	}
+
+	swapped := *proof
+	swapped.Commitment = public.CommitValue(bint(0xcafe), NewRandScalar())
+	if err := VerifyVectorElementRange(public, NewKeccakFS(), vectorCom, len(vector), &swapped); err == nil {
+		t.Error("Proof accepted for another element commitment")
+	}
+
+	for _, index := range []int{0, 1, 3} {
+		if _, err := ProveVectorElementRange(public, NewKeccakFS(), vectorCom, index, vector, vectorBlinding, NewRandScalar()); !errors.Is(err, ErrValueOutOfRange) {
+			t.Errorf("Expected ErrValueOutOfRange for index %d, got %v", index, err)
+		}
+	}
+
+	if _, err := ProveVectorElementRange(public, NewKeccakFS(), vectorCom, 4, vector, vectorBlinding, NewRandScalar()); err == nil {
+		t.Error("Expected an error for an index out of range")
+	}
+
+	if err := VerifyVectorElementRange(public, NewKeccakFS(), vectorCom, len(vector), &VectorElementRangeProof{}); err == nil {
+		t.Error("Expected an error for an incomplete proof")
+	}
+}
@@ -0,0 +1,91 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+)
+
+// VectorEqualityProof proves that two Pedersen vector commitments comA = <vector, gvec> + sA*h and
+// comB = <vector, gvec> + sB*h (see CommitPolynomial) hide the same vector. It is a Schnorr proof of knowledge of r
+// with comA - comB = r*h: the difference is a commitment to the zero vector exactly when the vectors are equal, as
+// long as nobody knows a discrete logarithm relation between h and gvec.
+type VectorEqualityProof struct {
+	R *bn256.G1
+	S *big.Int
+}
+
+func vectorEqualityChallenge(fs FiatShamirEngine, h, comA, comB, R *bn256.G1) (*big.Int, error) {
+	for _, p := range []*bn256.G1{h, comA, comB, R} {
+		if err := fs.AddPoint(p); err != nil {
+			return nil, err
+		}
+	}
+	return fs.GetChallenge(), nil
+}
+
+// ProveVectorEquality proves that comA = CommitPolynomial(gvec, vector, h, sA) and
+// comB = CommitPolynomial(gvec, vector, h, sB) hide the same vector without revealing it. Use empty
+// FiatShamirEngine for call.
+func ProveVectorEquality(gvec []*bn256.G1, h *bn256.G1, fs FiatShamirEngine, comA, comB *bn256.G1, vector []*big.Int, sA, sB *big.Int) (*VectorEqualityProof, error) {
+	if fs == nil {
+		return nil, errors.New("Fiat-Shamir engine cannot be nil")
+	}
+
+	if comA == nil || comB == nil {
+		return nil, errors.New("commitments cannot be nil")
+	}
+
+	for _, opening := range []struct {
+		com      *bn256.G1
+		blinding *big.Int
+	}{{comA, sA}, {comB, sB}} {
+		com, err := CommitPolynomial(gvec, vector, h, opening.blinding)
+		if err != nil {
+			return nil, err
+		}
+		if !pointsEqual(com, opening.com) {
+			return nil, errors.New("opening does not match its commitment")
+		}
+	}
+
+	R, s, err := proveDLog(h, sub(sA, sB), func(R *bn256.G1) (*big.Int, error) {
+		return vectorEqualityChallenge(fs, h, comA, comB, R)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &VectorEqualityProof{R: R, S: s}, nil
+}
+
+// VerifyVectorEquality verifies that the commitments comA and comB hide the same vector. If err is nil then proof
+// is valid. The generators gvec are not needed, the difference of the commitments only depends on h.
+// Use empty FiatShamirEngine for call.
+func VerifyVectorEquality(h *bn256.G1, fs FiatShamirEngine, comA, comB *bn256.G1, proof *VectorEqualityProof) error {
+	if fs == nil {
+		return errors.New("Fiat-Shamir engine cannot be nil")
+	}
+
+	if h == nil || comA == nil || comB == nil {
+		return errors.New("blinding generator and commitments cannot be nil")
+	}
+
+	if proof == nil || proof.R == nil || !isCanonicalScalar(proof.S) {
+		return errors.New("invalid vector equality proof")
+	}
+
+	diff := new(bn256.G1).Add(comA, new(bn256.G1).Neg(comB))
+	err := verifyDLog(h, diff, proof.R, proof.S, func(R *bn256.G1) (*big.Int, error) {
+		return vectorEqualityChallenge(fs, h, comA, comB, R)
+	})
+	if errors.Is(err, errDLogFailed) {
+		return fmt.Errorf("failed to verify vector equality proof: %w", err)
+	}
+	return err
+}
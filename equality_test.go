@@ -0,0 +1,59 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestVectorEquality(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	gvec, h := public.GVec, public.BlindingGenerator()
+
+	vector := []*big.Int{bint(3), bint(1), bint(4), bint(1), bint(5)}
+	sA, sB := NewRandScalar(), NewRandScalar()
+
+	comA, err := CommitPolynomial(gvec, vector, h, sA)
+	if err != nil {
+		t.Fatalf("CommitPolynomial failed: %v", err)
+	}
+
+	comB, err := CommitPolynomial(gvec, vector, h, sB)
+	if err != nil {
+		t.Fatalf("CommitPolynomial failed: %v", err)
+	}
+
+	proof, err := ProveVectorEquality(gvec, h, NewKeccakFS(), comA, comB, vector, sA, sB)
+	if err != nil {
+		t.Fatalf("ProveVectorEquality failed: %v", err)
+	}
+
+	if err := VerifyVectorEquality(h, NewKeccakFS(), comA, comB, proof); err != nil {
+		t.Fatalf("Valid vector equality proof rejected: %v", err)
+	}
+
+	if err := VerifyVectorEquality(h, NewKeccakFS(), comB, comA, proof); err == nil {
+		t.Error("Proof accepted for swapped commitments")
+	}
+
+	other := append([]*big.Int{bint(2)}, vector[1:]...)
+	comC, err := CommitPolynomial(gvec, other, h, sB)
+	if err != nil {
+		t.Fatalf("CommitPolynomial failed: %v", err)
+	}
+
+	if err := VerifyVectorEquality(h, NewKeccakFS(), comA, comC, proof); err == nil {
+		t.Error("Proof accepted for a commitment to another vector")
+	}
+
+	if _, err := ProveVectorEquality(gvec, h, NewKeccakFS(), comA, comC, vector, sA, sB); err == nil {
+		t.Error("ProveVectorEquality should fail for a commitment to another vector")
+	}
+}
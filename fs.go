@@ -5,42 +5,310 @@
 package bulletproofs
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"github.com/cloudflare/bn256"
+	"golang.org/x/crypto/blake2b"
+	"hash"
 	"math/big"
 )
 
 // Domain separation constants for different protocols
 const (
-	DOMAIN_CIRCUIT = "EMZA-BP++-Circuit-v1"
-	DOMAIN_RANGE   = "EMZA-BP++-Range-v1"
-	DOMAIN_WNLA    = "EMZA-BP++-WNLA-v1"
+	DOMAIN_CIRCUIT       = "EMZA-BP++-Circuit-v1"
+	DOMAIN_RANGE         = "EMZA-BP++-Range-v1"
+	DOMAIN_WNLA          = "EMZA-BP++-WNLA-v1"
+	DOMAIN_INNER_PRODUCT = "EMZA-BP++-InnerProduct-v1"
 )
 
 type FiatShamirEngine interface {
 	AddPoint(*bn256.G1) error
 	AddNumber(*big.Int) error
+	// AddDomain absorbs a domain separation tag. Domains form a prefix of the transcript: they can be layered,
+	// e.g. an application domain followed by a protocol domain, and are absorbed in call order, but once any other
+	// input has been absorbed or a challenge derived AddDomain fails with ErrDomainAfterInput.
 	AddDomain(domain string) error
 	AddBytes([]byte) error
 	GetChallenge() *big.Int
 }
 
-type KeccakFS struct {
-	state   KeccakState
+// ScalarVectorAdder is an optional interface of a FiatShamirEngine. AddScalarVector absorbs len(v) followed by every
+// scalar of v. Engines without it get the length through AddNumber or AddBytes, see addLength, and every scalar
+// through AddNumber.
+type ScalarVectorAdder interface {
+	AddScalarVector(v []*big.Int) error
+}
+
+// PointVectorAdder is an optional interface of a FiatShamirEngine. AddPointVector absorbs len(v) followed by every
+// point of v. Engines without it get the length as for ScalarVectorAdder and every point through AddPoint.
+type PointVectorAdder interface {
+	AddPointVector(v []*bn256.G1) error
+}
+
+// ChallengesGetter is an optional interface of a FiatShamirEngine. GetChallenges derives n challenges and
+// GetChallenges(1)[0] equals GetChallenge(). Engines without it are asked for GetChallenge n times.
+type ChallengesGetter interface {
+	GetChallenges(n int) []*big.Int
+}
+
+// addScalarVector absorbs v with AddScalarVector if fs implements ScalarVectorAdder, and with the same inputs one by
+// one otherwise.
+func addScalarVector(fs FiatShamirEngine, v []*big.Int) error {
+	if a, ok := fs.(ScalarVectorAdder); ok {
+		return a.AddScalarVector(v)
+	}
+
+	if err := addLength(fs, len(v)); err != nil {
+		return err
+	}
+	for i := range v {
+		if err := fs.AddNumber(v[i]); err != nil {
+			return fmt.Errorf("scalar %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// addPointVector absorbs v with AddPointVector if fs implements PointVectorAdder, and with the same inputs one by
+// one otherwise.
+func addPointVector(fs FiatShamirEngine, v []*bn256.G1) error {
+	if a, ok := fs.(PointVectorAdder); ok {
+		return a.AddPointVector(v)
+	}
+
+	if err := addLength(fs, len(v)); err != nil {
+		return err
+	}
+	for i := range v {
+		if err := fs.AddPoint(v[i]); err != nil {
+			return fmt.Errorf("point %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// Uint64Adder is an optional interface of a FiatShamirEngine. AddUint64 absorbs v as 8 bytes big-endian, for
+// lengths, indices and other small integers. The encoding differs from the 32-byte one of AddNumber, so the same
+// value absorbs different bytes through the two. Engines without it get the same 8 bytes through AddBytes.
+type Uint64Adder interface {
+	AddUint64(v uint64) error
+}
+
+// addUint64 absorbs v with AddUint64 if fs implements Uint64Adder, and as its 8-byte big-endian encoding through
+// AddBytes otherwise.
+func addUint64(fs FiatShamirEngine, v uint64) error {
+	if a, ok := fs.(Uint64Adder); ok {
+		return a.AddUint64(v)
+	}
+
+	data := make([]byte, 8)
+	binary.BigEndian.PutUint64(data, v)
+	return fs.AddBytes(data)
+}
+
+// getChallenges derives n challenges with GetChallenges if fs implements ChallengesGetter, and by calling
+// GetChallenge n times otherwise.
+func getChallenges(fs FiatShamirEngine, n int) []*big.Int {
+	if g, ok := fs.(ChallengesGetter); ok {
+		return g.GetChallenges(n)
+	}
+
+	res := make([]*big.Int, max(n, 0))
+	for i := range res {
+		res[i] = fs.GetChallenge()
+	}
+	return res
+}
+
+// ErrTranscriptReused is returned when a proof is produced or verified with a Fiat-Shamir engine that has already
+// derived challenges, e.g. when the engine of ProveRange is passed on to VerifyRange. Use a fresh engine for every
+// proof and every verification, or chain proofs over one transcript with Prover.ProveChained and Verifier.VerifyChained.
+var ErrTranscriptReused = errors.New("Fiat-Shamir engine has already derived challenges")
+
+// ErrDomainAfterInput is returned by AddDomain once the transcript has absorbed other inputs or derived challenges.
+// A domain in the middle of a transcript is easy to add on one side only, so domains must come first.
+var ErrDomainAfterInput = errors.New("domain must be added before any other input")
+
+// ErrInvalidChallenge is returned when a FiatShamirEngine returns a challenge the protocols cannot use: zero, which
+// they invert or fold with, or one that makes a pole 1/(e+d) of the range argument undefined. A hashing engine does
+// so with negligible probability; MockFS and InteractiveFS return zero once their challenges are exhausted, and any
+// challenge the caller sets.
+var ErrInvalidChallenge = errors.New("challenge is zero or not invertible")
+
+// checkChallenges returns ErrInvalidChallenge if one of the challenges is zero modulo bn256.Order.
+func checkChallenges(challenges ...*big.Int) error {
+	for i, c := range challenges {
+		if c == nil || add(c, nil).Sign() == 0 {
+			return fmt.Errorf("%w: challenge %d of %d is zero", ErrInvalidChallenge, i+1, len(challenges))
+		}
+	}
+	return nil
+}
+
+// challengeCounter is implemented by engines that can tell whether they have derived challenges.
+type challengeCounter interface {
+	challengeCount() int
+}
+
+// checkFreshTranscript rejects an engine that has already derived challenges with ErrTranscriptReused. Inputs
+// absorbed beforehand, like an application domain or a statement, are fine. Engines that do not implement
+// challengeCounter are not checked.
+func checkFreshTranscript(fs FiatShamirEngine) error {
+	if c, ok := fs.(challengeCounter); ok && c.challengeCount() > 0 {
+		return ErrTranscriptReused
+	}
+	return nil
+}
+
+// hashFS implements FiatShamirEngine over any hash function. A challenge is the hash of everything absorbed so far,
+// including a call counter, reduced modulo bn256.Order.
+type hashFS struct {
+	state   hash.Hash
 	counter int
+	// absorbed is set by the first input other than a domain, see ErrDomainAfterInput.
+	absorbed bool
+
+	appDomain string
+	recorder  *TranscriptRecorder
+	version   TranscriptVersion
+
+	// Scratch buffers of GetChallenge for the counter encoding and the digest, so deriving a challenge does not
+	// allocate them every time.
+	counterBuf [32]byte
+	digestBuf  [32]byte
 }
 
-func NewKeccakFS() FiatShamirEngine {
-	return &KeccakFS{state: NewKeccakState()}
+// KeccakFS is the Fiat-Shamir transcript over Keccak256. It is the engine the KAT vectors are produced with.
+type KeccakFS struct {
+	hashFS
 }
 
-// AddDomain adds a domain separation tag to prevent cross-protocol attacks
-func (k *KeccakFS) AddDomain(domain string) error {
+// FSOption configures a transcript created by NewKeccakFS or NewBlake2bFS.
+type FSOption func(*hashFS)
+
+// WithAppDomain separates the transcripts of an application from those of all others. The domain is absorbed as
+// the very first input, before anything a protocol adds, so a proof made under one application domain does not
+// verify under another one or under none. Prover and verifier must use the same domain. An empty domain leaves
+// the transcript unchanged.
+func WithAppDomain(domain string) FSOption {
+	return func(k *hashFS) {
+		k.appDomain = domain
+	}
+}
+
+// TranscriptVersion selects the absorption rules of the protocols. Newer versions absorb additional inputs and
+// produce different challenges, so prover and verifier must use the same version.
+type TranscriptVersion int
+
+const (
+	// TranscriptV1 is the default. Most KAT vectors are produced with it, the others record their version.
+	TranscriptV1 TranscriptVersion = 1
+	// TranscriptV2 absorbs the round index at the start of every WNLA reduction round. Rounds are already separated
+	// by the challenge counter and the vector lengths; the index makes the separation explicit. Lengths and indices,
+	// including the length prefixes of AddScalarVector and AddPointVector, are absorbed as tagged lengths that
+	// cannot be confused with scalars or points (see addLength).
+	TranscriptV2 TranscriptVersion = 2
+)
+
+// WithTranscriptVersion selects the transcript version, TranscriptV1 if the option is not given.
+func WithTranscriptVersion(v TranscriptVersion) FSOption {
+	return func(k *hashFS) {
+		k.version = v
+	}
+}
+
+// versioned is implemented by engines that know their TranscriptVersion.
+type versioned interface {
+	transcriptVersion() TranscriptVersion
+}
+
+// transcriptVersion returns the version of fs, TranscriptV1 for engines that do not have one.
+func transcriptVersion(fs FiatShamirEngine) TranscriptVersion {
+	if v, ok := fs.(versioned); ok {
+		return v.transcriptVersion()
+	}
+	return TranscriptV1
+}
+
+func (k *hashFS) transcriptVersion() TranscriptVersion {
+	return max(k.version, TranscriptV1)
+}
+
+// lengthTag starts every length absorbed by TranscriptV2 and later. Both the bn256 group order and the field modulus
+// are 256-bit numbers starting with the byte 0x8f, so the first byte of a canonical scalar, and of a bn256 point
+// encoding, which starts with a coordinate below the modulus, is at most 0x8f. A tagged length therefore cannot be
+// read as the start of a canonical scalar or a bn256 point. AddNumber absorbs its value unreduced and AddBytes
+// absorbs any data, so inputs absorbed through them can still start with the tag.
+const lengthTag = 0xff
+
+// addLength absorbs a vector length, an index or another structural count. TranscriptV1 absorbs it with AddNumber,
+// exactly like a scalar; TranscriptV2 and later absorb lengthTag with AddBytes followed by n with addUint64.
+func addLength(fs FiatShamirEngine, n int) error {
+	if transcriptVersion(fs) < TranscriptV2 {
+		return fs.AddNumber(big.NewInt(int64(n)))
+	}
+
+	if err := fs.AddBytes([]byte{lengthTag}); err != nil {
+		return err
+	}
+	return addUint64(fs, uint64(n))
+}
+
+// WithTranscriptRecorder records every input absorbed by the transcript and every challenge it derives into r,
+// including the application domain of WithAppDomain. Recording does not change the challenges.
+func WithTranscriptRecorder(r *TranscriptRecorder) FSOption {
+	return func(k *hashFS) {
+		k.recorder = r
+	}
+}
+
+func newHashFS(state hash.Hash, opts []FSOption) hashFS {
+	k := hashFS{state: state}
+	for _, opt := range opts {
+		opt(&k)
+	}
+	if k.appDomain != "" {
+		// AddDomain only fails for an empty domain.
+		_ = k.AddDomain(k.appDomain)
+	}
+	return k
+}
+
+// record passes an absorbed input or a derived challenge to the recorder, if there is one.
+func (k *hashFS) record(op string, data []byte) {
+	if k.recorder != nil {
+		k.recorder.add(op, data)
+	}
+}
+
+func NewKeccakFS(opts ...FSOption) FiatShamirEngine {
+	return &KeccakFS{newHashFS(NewKeccakState(), opts)}
+}
+
+// Blake2bFS is the Fiat-Shamir transcript over BLAKE2b-256 with the same absorption rules as KeccakFS. It produces
+// different challenges, so prover and verifier must use the same engine. BLAKE2b hashes faster in software, but
+// range proofs are dominated by curve arithmetic: BenchmarkRangeProofFS shows no measurable difference there.
+type Blake2bFS struct {
+	hashFS
+}
+
+func NewBlake2bFS(opts ...FSOption) FiatShamirEngine {
+	// blake2b.New256 only fails for keys longer than 64 bytes.
+	h, _ := blake2b.New256(nil)
+	return &Blake2bFS{newHashFS(h, opts)}
+}
+
+// AddDomain adds a domain separation tag to prevent cross-protocol attacks. See FiatShamirEngine for the ordering.
+func (k *hashFS) AddDomain(domain string) error {
 	if domain == "" {
 		return errors.New("domain cannot be empty")
 	}
 
+	if k.absorbed || k.counter > 0 {
+		return fmt.Errorf("%w: %q", ErrDomainAfterInput, domain)
+	}
+
 	// Write domain tag followed by separator
 	if _, err := k.state.Write([]byte(domain)); err != nil {
 		return fmt.Errorf("failed to write domain tag: %w", err)
@@ -49,32 +317,39 @@ func (k *KeccakFS) AddDomain(domain string) error {
 		return fmt.Errorf("failed to write domain separator: %w", err)
 	}
 
+	k.record(TranscriptDomain, []byte(domain))
 	return nil
 }
 
-func (k *KeccakFS) AddPoint(p *bn256.G1) error {
+func (k *hashFS) AddPoint(p *bn256.G1) error {
 	if p == nil {
 		return errors.New("point cannot be nil")
 	}
 
-	if _, err := k.state.Write(p.Marshal()); err != nil {
+	data := p.Marshal()
+	if _, err := k.state.Write(data); err != nil {
 		return fmt.Errorf("failed to write point to transcript: %w", err)
 	}
+	k.absorbed = true
+	k.record(TranscriptPoint, data)
 	return nil
 }
 
-func (k *KeccakFS) AddNumber(v *big.Int) error {
+func (k *hashFS) AddNumber(v *big.Int) error {
 	if v == nil {
 		return errors.New("number cannot be nil")
 	}
 
-	if _, err := k.state.Write(scalarTo32Byte(v)); err != nil {
+	data := scalarTo32Byte(v)
+	if _, err := k.state.Write(data); err != nil {
 		return fmt.Errorf("failed to write number to transcript: %w", err)
 	}
+	k.absorbed = true
+	k.record(TranscriptNumber, data)
 	return nil
 }
 
-func (k *KeccakFS) AddBytes(data []byte) error {
+func (k *hashFS) AddBytes(data []byte) error {
 	if data == nil {
 		return errors.New("data cannot be nil")
 	}
@@ -82,14 +357,151 @@ func (k *KeccakFS) AddBytes(data []byte) error {
 	if _, err := k.state.Write(data); err != nil {
 		return fmt.Errorf("failed to write bytes to transcript: %w", err)
 	}
+	k.absorbed = true
+	k.record(TranscriptBytes, data)
+	return nil
+}
+
+// AddUint64 absorbs v as 8 bytes big-endian, see Uint64Adder.
+func (k *hashFS) AddUint64(v uint64) error {
+	var data [8]byte
+	binary.BigEndian.PutUint64(data[:], v)
+	if _, err := k.state.Write(data[:]); err != nil {
+		return fmt.Errorf("failed to write integer to transcript: %w", err)
+	}
+	k.absorbed = true
+	k.record(TranscriptUint64, data[:])
+	return nil
+}
+
+// AddScalarVector absorbs the length of v (see addLength) followed by every scalar of v. The length prefix keeps
+// adjacent vectors from being re-split into a different pair with the same concatenation.
+func (k *hashFS) AddScalarVector(v []*big.Int) error {
+	if err := addLength(k, len(v)); err != nil {
+		return err
+	}
+	for i := range v {
+		if err := k.AddNumber(v[i]); err != nil {
+			return fmt.Errorf("scalar %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// AddPointVector absorbs the length of v (see addLength) followed by every point of v. The transcript is the same
+// as for AddPoint called on every point, but the points are written in one call. Nothing is absorbed if a point is
+// nil.
+func (k *hashFS) AddPointVector(v []*bn256.G1) error {
+	for i := range v {
+		if v[i] == nil {
+			return fmt.Errorf("point %d: point cannot be nil", i)
+		}
+	}
+
+	if err := addLength(k, len(v)); err != nil {
+		return err
+	}
+	return k.addPoints(v)
+}
+
+// addPoints marshals the points into one buffer and writes it to the state at once, which is cheaper than a write
+// per point for long vectors. The points must not be nil.
+func (k *hashFS) addPoints(v []*bn256.G1) error {
+	buf := make([]byte, 0, len(v)*pointSize)
+	for i := range v {
+		buf = append(buf, v[i].Marshal()...)
+	}
+
+	if _, err := k.state.Write(buf); err != nil {
+		return fmt.Errorf("failed to write points to transcript: %w", err)
+	}
+	k.absorbed = true
+	for i := 0; i < len(buf); i += pointSize {
+		k.record(TranscriptPoint, buf[i:i+pointSize])
+	}
 	return nil
 }
 
-func (k *KeccakFS) GetChallenge() *big.Int {
+// appDomainer is implemented by engines that know the application domain they were created with.
+type appDomainer interface {
+	applicationDomain() string
+}
+
+// DomainTag returns the 4-byte domain tag of range proofs: the first bytes of Keccak256 of the application domain
+// of the transcript (see WithAppDomain), a zero byte and the generator domain of the public parameters, such as
+// DOMAIN_RANGE. Zero, which marks an unknown domain, is replaced by one. Either domain may be empty.
+func DomainTag(appDomain, generatorDomain string) uint32 {
+	tag := binary.BigEndian.Uint32(Keccak256([]byte(appDomain), []byte{0}, []byte(generatorDomain)))
+	if tag == 0 {
+		return 1
+	}
+	return tag
+}
+
+// rangeDomainTag returns the domain tag of a range proof over public with the transcript fs, or zero if the engine
+// does not know its application domain. Parameters without GeneratorLabels have the empty generator domain.
+func rangeDomainTag(public *ReciprocalPublic, fs FiatShamirEngine) uint32 {
+	generatorDomain := ""
+	if public.Labels != nil {
+		generatorDomain = public.Labels.Domain
+	}
+	return transcriptDomainTag(fs, generatorDomain)
+}
+
+// transcriptDomainTag returns DomainTag of the application domain of fs and the generator domain, or zero if the
+// engine does not know its application domain.
+func transcriptDomainTag(fs FiatShamirEngine, generatorDomain string) uint32 {
+	a, ok := fs.(appDomainer)
+	if !ok {
+		return 0
+	}
+	return DomainTag(a.applicationDomain(), generatorDomain)
+}
+
+// absorbDomainTag absorbs a known domain tag as 4 bytes big-endian. Prover and verifier absorb the tag of their own
+// domains, so a proof only verifies under the domains it was made for, whatever tag its header carries.
+func absorbDomainTag(fs FiatShamirEngine, tag uint32) error {
+	if tag == 0 {
+		return nil
+	}
+	return fs.AddBytes(binary.BigEndian.AppendUint32(nil, tag))
+}
+
+func (k *hashFS) applicationDomain() string {
+	return k.appDomain
+}
+
+func (k *hashFS) challengeCount() int {
+	return k.counter
+}
+
+// GetChallenge absorbs the counter and hashes the state. Sum has to copy the state because finalizing a sponge is
+// destructive: the squeezing Read of KeccakState pads and permutes the state in place and the transcript cannot
+// absorb anything afterwards. The copy is a few hundred bytes and costs less than the permutation itself, so the
+// challenge only avoids the allocations around it. BenchmarkGetChallenge measures the cost per challenge.
+func (k *hashFS) GetChallenge() *big.Int {
 	k.counter++
-	// Note: This AddNumber call needs error handling in calling code
-	k.AddNumber(bint(k.counter))
-	return new(big.Int).Mod(new(big.Int).SetBytes(k.state.Sum(nil)), bn256.Order)
+	// The counter is part of every challenge derivation rather than an input, so it is not recorded. It is encoded
+	// like scalarTo32Byte(bint(k.counter)). Writes to a hash state do not fail.
+	binary.BigEndian.PutUint64(k.counterBuf[24:], uint64(k.counter))
+	_, _ = k.state.Write(k.counterBuf[:])
+	c := new(big.Int).SetBytes(k.state.Sum(k.digestBuf[:0]))
+	c.Mod(c, bn256.Order)
+	if k.recorder != nil {
+		k.record(TranscriptChallenge, scalarTo32Byte(c))
+	}
+	return c
+}
+
+// GetChallenges derives n challenges by calling GetChallenge n times. Every call advances the counter and absorbs
+// it before hashing, so each challenge is bound to its index and to all challenges before it. GetChallenges(1)[0]
+// is the same value GetChallenge would return. A non-positive n returns no challenges and leaves the state as is.
+func (k *hashFS) GetChallenges(n int) []*big.Int {
+	res := make([]*big.Int, max(n, 0))
+	for i := range res {
+		res[i] = k.GetChallenge()
+	}
+	return res
 }
 
 func scalarTo32Byte(s *big.Int) []byte {
@@ -101,3 +513,15 @@ func scalarTo32Byte(s *big.Int) []byte {
 	res := make([]byte, 32-len(arr))
 	return append(res, arr...)
 }
+
+// addGroupPoint absorbs a point of any Group. BN256 points go through AddPoint so the transcript stays the same as
+// for the bn256-only API; other backends absorb the point encoding.
+func addGroupPoint(fs FiatShamirEngine, p Point) error {
+	if p == nil {
+		return errors.New("point cannot be nil")
+	}
+	if g1, ok := p.(*bn256.G1); ok {
+		return fs.AddPoint(g1)
+	}
+	return fs.AddBytes(p.Marshal())
+}
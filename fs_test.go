@@ -5,6 +5,7 @@
 package bulletproofs
 
 import (
+	"errors"
 	"github.com/cloudflare/bn256"
 	"math/big"
 	"testing"
@@ -112,6 +113,125 @@ func TestDomainSeparation(t *testing.T) {
 	}
 }
 
+func TestKeccakFSVectors(t *testing.T) {
+	a, b, c := bint(1), bint(2), bint(3)
+
+	// The same scalars split into vectors differently give different challenges.
+	fs1 := NewKeccakFS()
+	if err := addScalarVector(fs1, []*big.Int{a, b}); err != nil {
+		t.Fatalf("AddScalarVector failed: %v", err)
+	}
+	if err := addScalarVector(fs1, []*big.Int{c}); err != nil {
+		t.Fatalf("AddScalarVector failed: %v", err)
+	}
+
+	fs2 := NewKeccakFS()
+	if err := addScalarVector(fs2, []*big.Int{a}); err != nil {
+		t.Fatalf("AddScalarVector failed: %v", err)
+	}
+	if err := addScalarVector(fs2, []*big.Int{b, c}); err != nil {
+		t.Fatalf("AddScalarVector failed: %v", err)
+	}
+
+	if fs1.GetChallenge().Cmp(fs2.GetChallenge()) == 0 {
+		t.Error("Vector boundaries do not affect the challenge")
+	}
+
+	// A vector is absorbed as its length followed by the elements.
+	P := new(bn256.G1).ScalarBaseMult(bint(42))
+
+	fs3 := NewKeccakFS()
+	if err := addPointVector(fs3, []*bn256.G1{P, P}); err != nil {
+		t.Fatalf("AddPointVector failed: %v", err)
+	}
+
+	fs4 := NewKeccakFS()
+	_ = fs4.AddNumber(bint(2))
+	_ = fs4.AddPoint(P)
+	_ = fs4.AddPoint(P)
+
+	if fs3.GetChallenge().Cmp(fs4.GetChallenge()) != 0 {
+		t.Error("AddPointVector differs from a length prefix followed by the points")
+	}
+
+	if err := addPointVector(NewKeccakFS(), []*bn256.G1{P, nil}); err == nil {
+		t.Error("Should reject a nil point")
+	}
+
+	if err := addScalarVector(NewKeccakFS(), []*big.Int{nil}); err == nil {
+		t.Error("Should reject a nil scalar")
+	}
+}
+
+func TestKeccakFSChallenges(t *testing.T) {
+	fs1, fs2 := NewKeccakFS(), NewKeccakFS()
+	_ = fs1.AddNumber(bint(7))
+	_ = fs2.AddNumber(bint(7))
+
+	challenges := getChallenges(fs1, 3)
+	if len(challenges) != 3 {
+		t.Fatalf("Expected 3 challenges, got %d", len(challenges))
+	}
+
+	for i := range challenges {
+		if expected := fs2.GetChallenge(); challenges[i].Cmp(expected) != 0 {
+			t.Errorf("Challenge %d differs from the %d-th GetChallenge", i, i+1)
+		}
+	}
+
+	if challenges[0].Cmp(challenges[1]) == 0 || challenges[1].Cmp(challenges[2]) == 0 {
+		t.Error("Challenges are not distinct")
+	}
+
+	if got := getChallenges(NewKeccakFS(), 0); len(got) != 0 {
+		t.Errorf("Expected no challenges, got %d", len(got))
+	}
+}
+
+// baseEngine hides the optional interfaces of the engine it wraps, like an engine of another package that only
+// implements FiatShamirEngine.
+type baseEngine struct {
+	FiatShamirEngine
+}
+
+// transcriptVersion keeps the version of the wrapped engine, so both transcript versions can be compared.
+func (b baseEngine) transcriptVersion() TranscriptVersion {
+	return transcriptVersion(b.FiatShamirEngine)
+}
+
+func TestOptionalEngineInterfaces(t *testing.T) {
+	P := new(bn256.G1).ScalarBaseMult(bint(42))
+
+	absorb := func(fs FiatShamirEngine) []*big.Int {
+		if err := addScalarVector(fs, []*big.Int{bint(1), bint(2)}); err != nil {
+			t.Fatalf("addScalarVector failed: %v", err)
+		}
+		if err := addPointVector(fs, []*bn256.G1{P, P}); err != nil {
+			t.Fatalf("addPointVector failed: %v", err)
+		}
+		return getChallenges(fs, 2)
+	}
+
+	if _, ok := FiatShamirEngine(baseEngine{NewKeccakFS()}).(ScalarVectorAdder); ok {
+		t.Fatal("baseEngine implements ScalarVectorAdder")
+	}
+
+	for _, version := range []TranscriptVersion{TranscriptV1, TranscriptV2} {
+		expected := absorb(NewKeccakFS(WithTranscriptVersion(version)))
+		actual := absorb(baseEngine{NewKeccakFS(WithTranscriptVersion(version))})
+
+		for i := range expected {
+			if expected[i].Cmp(actual[i]) != 0 {
+				t.Errorf("V%d: challenge %d of an engine without the optional interfaces differs", version, i)
+			}
+		}
+	}
+
+	if err := addPointVector(baseEngine{NewKeccakFS()}, []*bn256.G1{P, nil}); err == nil {
+		t.Error("Should reject a nil point")
+	}
+}
+
 // TestErrorHandling tests the new error handling instead of panics
 func TestErrorHandling(t *testing.T) {
 	fs := NewKeccakFS()
@@ -152,3 +272,338 @@ func TestErrorHandling(t *testing.T) {
 		t.Errorf("Valid AddPoint should not fail: %v", err)
 	}
 }
+
+func TestBlake2bFS(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	private, err := public.newPrivate(bint(0xbeef), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	VCom := public.CommitValue(private.X, private.S)
+
+	proof, err := ProveRange(public, NewBlake2bFS(), private)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	if err := VerifyRange(public, VCom, NewBlake2bFS(), proof); err != nil {
+		t.Fatalf("VerifyRange failed: %v", err)
+	}
+
+	expectRejected(t, VerifyRange(public, VCom, NewKeccakFS(), proof), VerifyStageWNLA, ErrFinalCommitmentMismatch)
+
+	if NewBlake2bFS().GetChallenge().Cmp(NewKeccakFS().GetChallenge()) == 0 {
+		t.Error("Blake2b and Keccak transcripts give the same challenge")
+	}
+}
+
+func TestWithAppDomain(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	private, err := public.newPrivate(bint(0xbeef), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	VCom := public.CommitValue(private.X, private.S)
+
+	proof, err := ProveRange(public, NewKeccakFS(WithAppDomain("app-a")), private)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	if err := VerifyRange(public, VCom, NewKeccakFS(WithAppDomain("app-a")), proof); err != nil {
+		t.Fatalf("VerifyRange failed in the same app domain: %v", err)
+	}
+
+	// The header tag is dropped, so another app domain can only be caught by the transcript.
+	untagged := *proof
+	untagged.Domain = 0
+
+	for name, fs := range map[string]FiatShamirEngine{
+		"other app domain": NewKeccakFS(WithAppDomain("app-b")),
+		"no app domain":    NewKeccakFS(),
+		"other engine":     NewBlake2bFS(WithAppDomain("app-a")),
+	} {
+		t.Run(name, func(t *testing.T) {
+			expectRejected(t, VerifyRange(public, VCom, fs, &untagged), VerifyStageWNLA, ErrFinalCommitmentMismatch)
+		})
+	}
+
+	// The app domain is absorbed like AddDomain before anything else.
+	fs := NewKeccakFS()
+	if err := fs.AddDomain("app-a"); err != nil {
+		t.Fatalf("AddDomain failed: %v", err)
+	}
+	if fs.GetChallenge().Cmp(NewKeccakFS(WithAppDomain("app-a")).GetChallenge()) != 0 {
+		t.Error("WithAppDomain does not match AddDomain on a fresh transcript")
+	}
+
+	if NewKeccakFS(WithAppDomain("")).GetChallenge().Cmp(NewKeccakFS().GetChallenge()) != 0 {
+		t.Error("An empty app domain changed the transcript")
+	}
+}
+
+func TestAddDomainOrder(t *testing.T) {
+	layered := func(fs FiatShamirEngine, domains ...string) *big.Int {
+		for _, d := range domains {
+			if err := fs.AddDomain(d); err != nil {
+				t.Fatalf("AddDomain(%q) failed: %v", d, err)
+			}
+		}
+		return fs.GetChallenge()
+	}
+
+	// Domains may be layered before any other input; their order matters.
+	ab := layered(NewKeccakFS(WithAppDomain("app")), DOMAIN_RANGE)
+	if ab.Cmp(layered(NewKeccakFS(), "app", DOMAIN_RANGE)) != 0 {
+		t.Error("WithAppDomain is not the first layer")
+	}
+	if ab.Cmp(layered(NewKeccakFS(), DOMAIN_RANGE, "app")) == 0 {
+		t.Error("Layered domains in another order give the same challenge")
+	}
+
+	point := new(bn256.G1).ScalarBaseMult(bint(42))
+
+	for name, absorb := range map[string]func(fs FiatShamirEngine) error{
+		"number":       func(fs FiatShamirEngine) error { return fs.AddNumber(bint(1)) },
+		"point":        func(fs FiatShamirEngine) error { return fs.AddPoint(point) },
+		"bytes":        func(fs FiatShamirEngine) error { return fs.AddBytes([]byte{1}) },
+		"uint64":       func(fs FiatShamirEngine) error { return addUint64(fs, 1) },
+		"scalars":      func(fs FiatShamirEngine) error { return addScalarVector(fs, nil) },
+		"point vector": func(fs FiatShamirEngine) error { return addPointVector(fs, []*bn256.G1{point}) },
+		"challenge":    func(fs FiatShamirEngine) error { fs.GetChallenge(); return nil },
+	} {
+		for _, fs := range []FiatShamirEngine{NewKeccakFS(), NewBlake2bFS(WithAppDomain("app")), NewMockFS(bint(1))} {
+			if err := absorb(fs); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if err := fs.AddDomain(DOMAIN_RANGE); !errors.Is(err, ErrDomainAfterInput) {
+				t.Errorf("%s: expected ErrDomainAfterInput for %T, got %v", name, fs, err)
+			}
+		}
+	}
+
+	// A rejected input does not close the domain prefix.
+	fs := NewKeccakFS()
+	if err := fs.AddPoint(nil); err == nil {
+		t.Fatal("AddPoint accepted nil")
+	}
+	if err := fs.AddDomain(DOMAIN_RANGE); err != nil {
+		t.Errorf("AddDomain failed after a rejected input: %v", err)
+	}
+}
+
+func TestAddUint64(t *testing.T) {
+	challenge := func(fs FiatShamirEngine, absorb func(fs FiatShamirEngine) error) *big.Int {
+		if err := absorb(fs); err != nil {
+			t.Fatalf("Absorbing failed: %v", err)
+		}
+		return fs.GetChallenge()
+	}
+
+	c := challenge(NewKeccakFS(), func(fs FiatShamirEngine) error { return addUint64(fs, 0x0102030405060708) })
+
+	if c.Cmp(challenge(NewKeccakFS(), func(fs FiatShamirEngine) error { return fs.AddBytes([]byte{1, 2, 3, 4, 5, 6, 7, 8}) })) != 0 {
+		t.Error("AddUint64 does not absorb the 8-byte big-endian encoding")
+	}
+
+	if c.Cmp(challenge(baseEngine{NewKeccakFS()}, func(fs FiatShamirEngine) error { return addUint64(fs, 0x0102030405060708) })) != 0 {
+		t.Error("addUint64 of an engine without Uint64Adder absorbs different bytes")
+	}
+
+	if challenge(NewKeccakFS(), func(fs FiatShamirEngine) error { return addUint64(fs, 5) }).Cmp(
+		challenge(NewKeccakFS(), func(fs FiatShamirEngine) error { return fs.AddNumber(bint(5)) })) == 0 {
+		t.Error("AddUint64 and AddNumber absorb the same encoding")
+	}
+
+	recorder := &TranscriptRecorder{}
+	fs := NewKeccakFS(WithTranscriptRecorder(recorder))
+	_ = addUint64(fs, 16)
+	_ = fs.AddNumber(bint(16))
+	fs.GetChallenge()
+
+	entries := recorder.Entries()
+	if entries[0].Op != TranscriptUint64 || len(entries[0].Data) != 8 {
+		t.Errorf("Unexpected entry %+v", entries[0])
+	}
+
+	if err := ReplayTranscript(NewKeccakFS(), entries); err != nil {
+		t.Errorf("ReplayTranscript failed: %v", err)
+	}
+
+	if err := ReplayTranscript(baseEngine{NewKeccakFS()}, entries); err != nil {
+		t.Errorf("ReplayTranscript without Uint64Adder failed: %v", err)
+	}
+
+	entries[0].Data = entries[0].Data[1:]
+	if err := ReplayTranscript(NewKeccakFS(), entries); err == nil {
+		t.Error("ReplayTranscript accepted a 7-byte integer")
+	}
+
+	mock := NewMockFS()
+	_ = addUint64(mock, 16)
+	if mock.Entries[0].Op != TranscriptUint64 || new(big.Int).SetBytes(mock.Entries[0].Data).Cmp(bint(16)) != 0 {
+		t.Errorf("Unexpected MockFS entry %+v", mock.Entries[0])
+	}
+}
+
+func TestLengthEncoding(t *testing.T) {
+	v2 := func() FiatShamirEngine { return NewKeccakFS(WithTranscriptVersion(TranscriptV2)) }
+
+	challenge := func(fs FiatShamirEngine, absorb func(fs FiatShamirEngine) error) *big.Int {
+		if err := absorb(fs); err != nil {
+			t.Fatalf("Absorbing failed: %v", err)
+		}
+		return fs.GetChallenge()
+	}
+
+	vector := func(fs FiatShamirEngine) error { return addScalarVector(fs, []*big.Int{bint(7)}) }
+
+	v1 := challenge(NewKeccakFS(), vector)
+	if v1.Cmp(challenge(NewKeccakFS(), func(fs FiatShamirEngine) error {
+		_ = fs.AddNumber(bint(1))
+		return fs.AddNumber(bint(7))
+	})) != 0 {
+		t.Error("TranscriptV1 changed the length encoding")
+	}
+
+	tagged := challenge(v2(), vector)
+	if tagged.Cmp(challenge(NewKeccakFS(), func(fs FiatShamirEngine) error {
+		_ = fs.AddBytes([]byte{lengthTag, 0, 0, 0, 0, 0, 0, 0, 1})
+		return fs.AddNumber(bint(7))
+	})) != 0 {
+		t.Error("TranscriptV2 does not absorb the tagged length")
+	}
+
+	if tagged.Cmp(v1) == 0 {
+		t.Error("TranscriptV2 absorbs lengths like scalars")
+	}
+
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	set := []*big.Int{bint(1), bint(2), bint(3)}
+	setBlinding, blinding := NewRandScalar(), NewRandScalar()
+	setCom, err := public.CommitSet(set, setBlinding)
+	if err != nil {
+		t.Fatalf("CommitSet failed: %v", err)
+	}
+
+	proof, err := ProveMembershipCommitted(public, v2(), bint(2), blinding, setCom, set, setBlinding)
+	if err != nil {
+		t.Fatalf("ProveMembershipCommitted failed: %v", err)
+	}
+
+	com := public.CommitValue(bint(2), blinding)
+	if err := VerifyMembershipCommitted(public, v2(), com, setCom, len(set), proof); err != nil {
+		t.Errorf("VerifyMembershipCommitted failed: %v", err)
+	}
+
+	if err := VerifyMembershipCommitted(public, NewKeccakFS(), com, setCom, len(set), proof); err == nil {
+		t.Error("TranscriptV2 proof accepted by a TranscriptV1 verifier")
+	}
+}
+
+func BenchmarkRangeProofFS(b *testing.B) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		b.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	private, err := public.newPrivate(new(big.Int).SetUint64(0x123456789abcdef0), NewRandScalar())
+	if err != nil {
+		b.Fatalf("newPrivate failed: %v", err)
+	}
+
+	VCom := public.CommitValue(private.X, private.S)
+
+	engines := []struct {
+		name string
+		new  func(...FSOption) FiatShamirEngine
+	}{
+		{"Keccak", NewKeccakFS},
+		{"Blake2b", NewBlake2bFS},
+	}
+
+	for _, engine := range engines {
+		b.Run(engine.name+"/Prove", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := ProveRange(public, engine.new(), private); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+
+		proof, err := ProveRange(public, engine.new(), private)
+		if err != nil {
+			b.Fatalf("ProveRange failed: %v", err)
+		}
+
+		b.Run(engine.name+"/Verify", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := VerifyRange(public, VCom, engine.new(), proof); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkAddPointVector(b *testing.B) {
+	points := make([]*bn256.G1, 256)
+	for i := range points {
+		points[i] = new(bn256.G1).ScalarBaseMult(bint(i + 1))
+	}
+
+	b.Run("Vector", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			fs := NewKeccakFS()
+			if err := addPointVector(fs, points); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("PerPoint", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			fs := NewKeccakFS()
+			_ = fs.AddNumber(bint(len(points)))
+			for _, p := range points {
+				if err := fs.AddPoint(p); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+}
+
+func BenchmarkGetChallenge(b *testing.B) {
+	engines := []struct {
+		name string
+		new  func(...FSOption) FiatShamirEngine
+	}{
+		{"Keccak", NewKeccakFS},
+		{"Blake2b", NewBlake2bFS},
+	}
+
+	for _, engine := range engines {
+		b.Run(engine.name, func(b *testing.B) {
+			fs := engine.new()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				fs.GetChallenge()
+			}
+		})
+	}
+}
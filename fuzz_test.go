@@ -0,0 +1,153 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+)
+
+// The fuzz targets feed arbitrary bytes to the stream and CBOR decoders. A decoder must either fail or return a
+// proof that encodes again and decodes to the same encoding; it must never panic.
+//
+//	go test -run '^$' -fuzz FuzzUnmarshalRangeProof -fuzztime 30s .
+
+func FuzzUnmarshalRangeProof(f *testing.F) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		f.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	private, err := public.newPrivate(bint(0xabcdef), NewRandScalar())
+	if err != nil {
+		f.Fatalf("newPrivate failed: %v", err)
+	}
+
+	proof, err := ProveRange(public, NewKeccakFS(), private)
+	if err != nil {
+		f.Fatalf("ProveRange failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteRangeProof(&buf, proof); err != nil {
+		f.Fatalf("WriteRangeProof failed: %v", err)
+	}
+
+	encoded, err := proof.MarshalCBOR()
+	if err != nil {
+		f.Fatalf("MarshalCBOR failed: %v", err)
+	}
+
+	f.Add(buf.Bytes())
+	f.Add(encoded)
+	f.Add([]byte{})
+	f.Add([]byte{0x00, 0x00, 0x00, 0x10, 0x00, 0x00, 0x00, 0x10})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if decoded, err := ReadRangeProof(bytes.NewReader(data)); err == nil {
+			var out bytes.Buffer
+			if err := WriteRangeProof(&out, decoded); err != nil {
+				t.Fatalf("Decoded stream proof does not encode: %v", err)
+			}
+
+			again, err := ReadRangeProof(bytes.NewReader(out.Bytes()))
+			if err != nil {
+				t.Fatalf("Re-encoded stream proof does not decode: %v", err)
+			}
+
+			var out2 bytes.Buffer
+			if err := WriteRangeProof(&out2, again); err != nil || !bytes.Equal(out.Bytes(), out2.Bytes()) {
+				t.Fatalf("Stream encoding is not stable across a round trip")
+			}
+		}
+
+		decoded := new(ReciprocalProof)
+		if err := decoded.UnmarshalCBOR(data); err == nil {
+			out, err := decoded.MarshalCBOR()
+			if err != nil {
+				t.Fatalf("Decoded CBOR proof does not encode: %v", err)
+			}
+
+			again := new(ReciprocalProof)
+			if err := again.UnmarshalCBOR(out); err != nil {
+				t.Fatalf("Re-encoded CBOR proof does not decode: %v", err)
+			}
+
+			if out2, err := again.MarshalCBOR(); err != nil || !bytes.Equal(out, out2) {
+				t.Fatalf("CBOR encoding is not stable across a round trip")
+			}
+		}
+	})
+}
+
+func FuzzUnmarshalWNLAProof(f *testing.F) {
+	public := NewWeightNormLinearPublic(4, 2)
+
+	l := []*big.Int{bint(1), bint(2), bint(3), bint(4)}
+	n := []*big.Int{bint(5), bint(6)}
+
+	commitment, err := public.CommitWNLAChecked(l, n)
+	if err != nil {
+		f.Fatalf("CommitWNLAChecked failed: %v", err)
+	}
+
+	proof, err := ProveWNLA(public, commitment, NewKeccakFS(), l, n)
+	if err != nil {
+		f.Fatalf("ProveWNLA failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteWNLAProof(&buf, proof); err != nil {
+		f.Fatalf("WriteWNLAProof failed: %v", err)
+	}
+
+	encoded, err := proof.MarshalCBOR()
+	if err != nil {
+		f.Fatalf("MarshalCBOR failed: %v", err)
+	}
+
+	f.Add(buf.Bytes())
+	f.Add(encoded)
+	f.Add([]byte{})
+	f.Add([]byte{0xff, 0xff, 0xff, 0xff})
+	f.Add([]byte{0xa4, 0x61, 'r', 0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if decoded, err := ReadWNLAProof(bytes.NewReader(data)); err == nil {
+			var out bytes.Buffer
+			if err := WriteWNLAProof(&out, decoded); err != nil {
+				t.Fatalf("Decoded stream proof does not encode: %v", err)
+			}
+
+			again, err := ReadWNLAProof(bytes.NewReader(out.Bytes()))
+			if err != nil {
+				t.Fatalf("Re-encoded stream proof does not decode: %v", err)
+			}
+
+			var out2 bytes.Buffer
+			if err := WriteWNLAProof(&out2, again); err != nil || !bytes.Equal(out.Bytes(), out2.Bytes()) {
+				t.Fatalf("Stream encoding is not stable across a round trip")
+			}
+		}
+
+		decoded := new(WeightNormLinearArgumentProof)
+		if err := decoded.UnmarshalCBOR(data); err == nil {
+			out, err := decoded.MarshalCBOR()
+			if err != nil {
+				t.Fatalf("Decoded CBOR proof does not encode: %v", err)
+			}
+
+			again := new(WeightNormLinearArgumentProof)
+			if err := again.UnmarshalCBOR(out); err != nil {
+				t.Fatalf("Re-encoded CBOR proof does not decode: %v", err)
+			}
+
+			if out2, err := again.MarshalCBOR(); err != nil || !bytes.Equal(out, out2) {
+				t.Fatalf("CBOR encoding is not stable across a round trip")
+			}
+		}
+	})
+}
@@ -0,0 +1,377 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+)
+
+// fieldModulus is the prime p of the base field of bn256 G1: y^2 = x^3 + 3 (mod p).
+var fieldModulus, _ = new(big.Int).SetString("65000549695646603732796438742359905742825358107623003571877145026864184071783", 10)
+
+// maxDeriveAttempts bounds the try-and-increment loop. Roughly half of all x coordinates are on the curve, so the
+// bound is never reached in practice.
+const maxDeriveAttempts = 256
+
+// GeneratorLabels records the label every generator of a public parameter set was derived from, together with
+// the domain used for derivation. Entries are parallel to the corresponding generator vectors.
+type GeneratorLabels struct {
+	Domain       string
+	G            string
+	GVec, HVec   []string
+	GVec_, HVec_ []string
+}
+
+// DeriveGenerator deterministically derives a point of G1 from the domain and label using try-and-increment:
+// x = Keccak256(domain || 0x00 || label || 0x00 || counter) mod p for counter = 0, 1, ... until x^3 + 3 is a
+// square, then the even square root is taken as y. Nobody knows the discrete logarithm of the result with
+// respect to any other generator, and anyone can re-derive it from the label.
+func DeriveGenerator(domain, label string) (*bn256.G1, error) {
+	if domain == "" {
+		return nil, errors.New("domain cannot be empty")
+	}
+
+	for counter := 0; counter < maxDeriveAttempts; counter++ {
+		x := new(big.Int).SetBytes(Keccak256([]byte(domain), []byte{0x00}, []byte(label), []byte{0x00}, []byte{byte(counter)}))
+		x.Mod(x, fieldModulus)
+
+		rhs := new(big.Int).Exp(x, big.NewInt(3), fieldModulus)
+		rhs.Add(rhs, big.NewInt(3))
+		rhs.Mod(rhs, fieldModulus)
+
+		y := new(big.Int).ModSqrt(rhs, fieldModulus)
+		if y == nil {
+			continue
+		}
+
+		if y.Bit(0) == 1 {
+			y.Sub(fieldModulus, y)
+		}
+
+		p := new(bn256.G1)
+		if _, err := p.Unmarshal(append(scalarTo32Byte(x), scalarTo32Byte(y)...)); err != nil {
+			return nil, fmt.Errorf("failed to decode derived point: %w", err)
+		}
+
+		return p, nil
+	}
+
+	return nil, fmt.Errorf("failed to derive generator for label %q", label)
+}
+
+// deriveScalar deterministically derives a field element from the domain and label.
+func deriveScalar(domain, label string) (*big.Int, error) {
+	return hashToScalarWithRejection(Keccak256([]byte(domain), []byte{0x00}, []byte(label)))
+}
+
+func deriveGenerators(domain, prefix string, n int) ([]*bn256.G1, []string, error) {
+	points := make([]*bn256.G1, n)
+	labels := make([]string, n)
+
+	for i := range points {
+		labels[i] = fmt.Sprintf("%s-%d", prefix, i)
+
+		var err error
+		if points[i], err = DeriveGenerator(domain, labels[i]); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return points, labels, nil
+}
+
+// NewLabeledWeightNormLinearPublic deterministically derives the WNLA public parameters from the domain.
+// G is labeled "g", GVec entries "g-i", HVec entries "h-i". The C vector and Ro are derived from the labels
+// "c-i" and "ro" in the same domain.
+func NewLabeledWeightNormLinearPublic(domain string, lLen int, nLen int) (*WeightNormLinearPublic, error) {
+	labels := &GeneratorLabels{Domain: domain, G: "g"}
+
+	g, err := DeriveGenerator(domain, labels.G)
+	if err != nil {
+		return nil, err
+	}
+
+	gvec, glabels, err := deriveGenerators(domain, "g", nLen)
+	if err != nil {
+		return nil, err
+	}
+
+	hvec, hlabels, err := deriveGenerators(domain, "h", lLen)
+	if err != nil {
+		return nil, err
+	}
+
+	labels.GVec, labels.HVec = glabels, hlabels
+
+	c := make([]*big.Int, lLen)
+	for i := range c {
+		if c[i], err = deriveScalar(domain, fmt.Sprintf("c-%d", i)); err != nil {
+			return nil, err
+		}
+	}
+
+	ro, err := deriveScalar(domain, "ro")
+	if err != nil {
+		return nil, err
+	}
+
+	return &WeightNormLinearPublic{
+		G:      g,
+		GVec:   gvec,
+		HVec:   hvec,
+		C:      c,
+		Ro:     ro,
+		Mu:     mul(ro, ro),
+		Labels: labels,
+	}, nil
+}
+
+// NewReciprocalPublic deterministically derives range proof public parameters for Nd digits in base Np.
+// Generators are labeled by their role, with S = circuitBlindingSlots blinding slots of the arithmetic circuit:
+//
+//	G         "value"              value generator of the commitment
+//	HVec[0]   "blinding"           blinding generator of the commitment
+//	HVec[1:S] "circuit-blinding-i" blinding terms of the arithmetic circuit
+//	HVec[S:]  "witness-i"          committed witness vector of Nd+1 entries (digit reciprocals first)
+//	GVec      "digit-i"            digits
+//	GVec_     "wnla-g-i"           padding of GVec up to a power of 2 for WNLA
+//	HVec_     "wnla-h-i"           padding of HVec up to a power of 2 for WNLA
+func NewReciprocalPublic(domain string, Nd int, Np Base) (*ReciprocalPublic, error) {
+	if Nd < 1 {
+		return nil, fmt.Errorf("invalid dimensions: Nd=%d, Np=%d", Nd, Np)
+	}
+
+	if err := Np.Validate(); err != nil {
+		return nil, err
+	}
+
+	labels := &GeneratorLabels{Domain: domain, G: "value"}
+
+	g, err := DeriveGenerator(domain, labels.G)
+	if err != nil {
+		return nil, err
+	}
+
+	blinding, err := DeriveGenerator(domain, "blinding")
+	if err != nil {
+		return nil, err
+	}
+
+	circuitBlinding, circuitLabels, err := deriveGenerators(domain, "circuit-blinding", circuitBlindingSlots-1)
+	if err != nil {
+		return nil, err
+	}
+
+	witness, witnessLabels, err := deriveGenerators(domain, "witness", Nd+1)
+	if err != nil {
+		return nil, err
+	}
+
+	gvec, gvecLabels, err := deriveGenerators(domain, "digit", Nd)
+	if err != nil {
+		return nil, err
+	}
+
+	hvec := append(append([]*bn256.G1{blinding}, circuitBlinding...), witness...)
+	labels.HVec = append(append([]string{"blinding"}, circuitLabels...), witnessLabels...)
+	labels.GVec = gvecLabels
+
+	gvec_, gvecLabels_, err := deriveGenerators(domain, "wnla-g", powerOfTwo(len(gvec))-len(gvec))
+	if err != nil {
+		return nil, err
+	}
+
+	hvec_, hvecLabels_, err := deriveGenerators(domain, "wnla-h", powerOfTwo(len(hvec))-len(hvec))
+	if err != nil {
+		return nil, err
+	}
+
+	labels.GVec_, labels.HVec_ = gvecLabels_, hvecLabels_
+
+	return &ReciprocalPublic{
+		G:      g,
+		GVec:   gvec,
+		HVec:   hvec,
+		Nd:     Nd,
+		Np:     int(Np),
+		GVec_:  gvec_,
+		HVec_:  hvec_,
+		Labels: labels,
+	}, nil
+}
+
+// NewGroupReciprocalPublic deterministically derives range proof public parameters over the group for Nd digits in
+// base Np. The generators are derived with group.DeriveGenerator from the labels of NewReciprocalPublic, so over
+// BN256 the result has the generators of NewReciprocalPublic(domain, Nd, Np).
+func NewGroupReciprocalPublic(group Group, domain string, Nd int, Np Base) (*GroupReciprocalPublic, error) {
+	if group == nil {
+		return nil, errors.New("group cannot be nil")
+	}
+
+	if Nd < 1 {
+		return nil, fmt.Errorf("invalid dimensions: Nd=%d, Np=%d", Nd, Np)
+	}
+
+	if err := Np.Validate(); err != nil {
+		return nil, err
+	}
+
+	derive := func(prefix string, n int) ([]Point, error) {
+		res := make([]Point, n)
+		for i := range res {
+			var err error
+			if res[i], err = group.DeriveGenerator(domain, fmt.Sprintf("%s-%d", prefix, i)); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	}
+
+	g, err := group.DeriveGenerator(domain, "value")
+	if err != nil {
+		return nil, err
+	}
+
+	blinding, err := group.DeriveGenerator(domain, "blinding")
+	if err != nil {
+		return nil, err
+	}
+
+	circuitBlinding, err := derive("circuit-blinding", circuitBlindingSlots-1)
+	if err != nil {
+		return nil, err
+	}
+
+	witness, err := derive("witness", Nd+1)
+	if err != nil {
+		return nil, err
+	}
+
+	gvec, err := derive("digit", Nd)
+	if err != nil {
+		return nil, err
+	}
+
+	hvec := append(append([]Point{blinding}, circuitBlinding...), witness...)
+
+	gvec_, err := derive("wnla-g", powerOfTwo(len(gvec))-len(gvec))
+	if err != nil {
+		return nil, err
+	}
+
+	hvec_, err := derive("wnla-h", powerOfTwo(len(hvec))-len(hvec))
+	if err != nil {
+		return nil, err
+	}
+
+	return &GroupReciprocalPublic{
+		Group:  group,
+		G:      g,
+		GVec:   gvec,
+		HVec:   hvec,
+		Nd:     Nd,
+		Np:     int(Np),
+		GVec_:  gvec_,
+		HVec_:  hvec_,
+		Domain: domain,
+	}, nil
+}
+
+// labelOf searches the labeled vectors for the generator g.
+func (l *GeneratorLabels) labelOf(g *bn256.G1, G *bn256.G1, vecs [][]*bn256.G1, names [][]string) (string, bool) {
+	if l == nil || g == nil {
+		return "", false
+	}
+
+	if G != nil && pointsEqual(g, G) {
+		return l.G, true
+	}
+
+	for i, vec := range vecs {
+		for j := range vec {
+			if j < len(names[i]) && pointsEqual(g, vec[j]) {
+				return names[i][j], true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// check re-derives every labeled generator and compares it with the one in use.
+func (l *GeneratorLabels) check(G *bn256.G1, vecs [][]*bn256.G1, names [][]string) error {
+	if l == nil {
+		return errors.New("generators are not labeled")
+	}
+
+	expect := func(p *bn256.G1, label string) error {
+		derived, err := DeriveGenerator(l.Domain, label)
+		if err != nil {
+			return err
+		}
+		if p == nil || !pointsEqual(p, derived) {
+			return fmt.Errorf("generator %q does not match its derivation in domain %q", label, l.Domain)
+		}
+		return nil
+	}
+
+	if err := expect(G, l.G); err != nil {
+		return err
+	}
+
+	for i, vec := range vecs {
+		if len(vec) != len(names[i]) {
+			return fmt.Errorf("%d labels for %d generators", len(names[i]), len(vec))
+		}
+		for j := range vec {
+			if err := expect(vec[j], names[i][j]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// LabelOf returns the derivation label of the generator g if it is one of the labeled generators of p.
+func (p *WeightNormLinearPublic) LabelOf(g *bn256.G1) (string, bool) {
+	if p.Labels == nil {
+		return "", false
+	}
+	return p.Labels.labelOf(g, p.G, [][]*bn256.G1{p.GVec, p.HVec}, [][]string{p.Labels.GVec, p.Labels.HVec})
+}
+
+// CheckLabels re-derives every generator from its label and returns an error if any of them differs.
+func (p *WeightNormLinearPublic) CheckLabels() error {
+	if p.Labels == nil {
+		return errors.New("generators are not labeled")
+	}
+	return p.Labels.check(p.G, [][]*bn256.G1{p.GVec, p.HVec}, [][]string{p.Labels.GVec, p.Labels.HVec})
+}
+
+// LabelOf returns the derivation label of the generator g if it is one of the labeled generators of p.
+func (p *ReciprocalPublic) LabelOf(g *bn256.G1) (string, bool) {
+	if p.Labels == nil {
+		return "", false
+	}
+	return p.Labels.labelOf(g, p.G,
+		[][]*bn256.G1{p.GVec, p.HVec, p.GVec_, p.HVec_},
+		[][]string{p.Labels.GVec, p.Labels.HVec, p.Labels.GVec_, p.Labels.HVec_},
+	)
+}
+
+// CheckLabels re-derives every generator from its label and returns an error if any of them differs.
+func (p *ReciprocalPublic) CheckLabels() error {
+	if p.Labels == nil {
+		return errors.New("generators are not labeled")
+	}
+	return p.Labels.check(p.G,
+		[][]*bn256.G1{p.GVec, p.HVec, p.GVec_, p.HVec_},
+		[][]string{p.Labels.GVec, p.Labels.HVec, p.Labels.GVec_, p.Labels.HVec_},
+	)
+}
@@ -0,0 +1,173 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"encoding/hex"
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"testing"
+)
+
+func TestDeriveGenerator(t *testing.T) {
+	a, err := DeriveGenerator(DOMAIN_RANGE, "blinding")
+	if err != nil {
+		t.Fatalf("DeriveGenerator failed: %v", err)
+	}
+
+	b, err := DeriveGenerator(DOMAIN_RANGE, "blinding")
+	if err != nil {
+		t.Fatalf("DeriveGenerator failed: %v", err)
+	}
+
+	if !pointsEqual(a, b) {
+		t.Error("Derivation is not deterministic")
+	}
+
+	c, err := DeriveGenerator(DOMAIN_WNLA, "blinding")
+	if err != nil {
+		t.Fatalf("DeriveGenerator failed: %v", err)
+	}
+
+	if pointsEqual(a, c) {
+		t.Error("Different domains derived the same generator")
+	}
+
+	if _, err := DeriveGenerator("", "blinding"); err == nil {
+		t.Error("Should reject empty domain")
+	}
+}
+
+func TestReciprocalPublicLabels(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	if len(public.GVec)+len(public.GVec_) != 16 || len(public.HVec)+len(public.HVec_) != 32 {
+		t.Fatal("Unexpected generator vector sizes")
+	}
+
+	for g, want := range map[int]string{0: "blinding", 1: "circuit-blinding-0", 9: "witness-0", 25: "witness-16"} {
+		if label, ok := public.LabelOf(public.HVec[g]); !ok || label != want {
+			t.Errorf("HVec[%d]: expected label %q, got %q", g, want, label)
+		}
+	}
+
+	if label, _ := public.LabelOf(public.G); label != "value" {
+		t.Errorf("G: expected label \"value\", got %q", label)
+	}
+
+	if label, _ := public.LabelOf(public.HVec_[0]); label != "wnla-h-0" {
+		t.Errorf("HVec_[0]: expected label \"wnla-h-0\", got %q", label)
+	}
+
+	if _, ok := public.LabelOf(NewRandPoint()); ok {
+		t.Error("Unknown point should not have a label")
+	}
+
+	if err := public.CheckLabels(); err != nil {
+		t.Errorf("CheckLabels failed on derived parameters: %v", err)
+	}
+
+	public.HVec[0], public.HVec[1] = public.HVec[1], public.HVec[0]
+	if err := public.CheckLabels(); err == nil {
+		t.Error("CheckLabels should detect swapped generators")
+	}
+}
+
+func TestReciprocalRangeProofDerivedPublic(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	x := uint64(0xab4f0540ab4f0540)
+	digits := UInt64Hex(x)
+
+	private := &ReciprocalPrivate{
+		X:      new(big.Int).SetUint64(x),
+		M:      HexMapping(digits),
+		Digits: digits,
+		S:      NewRandScalar(),
+	}
+
+	VCom := public.CommitValue(private.X, private.S)
+	proof, err := ProveRange(public, NewKeccakFS(), private)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	if err := VerifyRange(public, VCom, NewKeccakFS(), proof); err != nil {
+		t.Fatalf("Range proof over derived parameters failed: %v", err)
+	}
+}
+
+func TestLabeledWeightNormLinearPublic(t *testing.T) {
+	public, err := NewLabeledWeightNormLinearPublic(DOMAIN_WNLA, 8, 4)
+	if err != nil {
+		t.Fatalf("NewLabeledWeightNormLinearPublic failed: %v", err)
+	}
+
+	if label, _ := public.LabelOf(public.HVec[3]); label != "h-3" {
+		t.Errorf("Expected label h-3, got %q", label)
+	}
+
+	if err := public.CheckLabels(); err != nil {
+		t.Errorf("CheckLabels failed: %v", err)
+	}
+
+	l := []*big.Int{bint(1), bint(2), bint(3), bint(4), bint(5), bint(6), bint(7), bint(8)}
+	n := []*big.Int{bint(9), bint(10), bint(11), bint(12)}
+
+	commitment, err := public.CommitWNLAChecked(l, n)
+	if err != nil {
+		t.Fatalf("CommitWNLAChecked failed: %v", err)
+	}
+	proof, err := ProveWNLA(public, commitment, NewKeccakFS(), l, n)
+	if err != nil {
+		t.Fatalf("ProveWNLA failed: %v", err)
+	}
+
+	if err := VerifyWNLA(public, proof, commitment, NewKeccakFS()); err != nil {
+		t.Fatalf("WNLA over derived parameters failed: %v", err)
+	}
+}
+
+// TestGeneratorVectors pins the encodings of the first generators derived for DOMAIN_RANGE and DOMAIN_WNLA. Provers
+// and verifiers on different platforms derive their parameters independently, so any change of the derivation
+// breaks every existing proof and must show up here.
+func TestGeneratorVectors(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	wnla, err := NewLabeledWeightNormLinearPublic(DOMAIN_WNLA, 2, 2)
+	if err != nil {
+		t.Fatalf("NewLabeledWeightNormLinearPublic failed: %v", err)
+	}
+
+	for _, v := range []struct {
+		name     string
+		point    *bn256.G1
+		expected string
+	}{
+		{"range value", public.G, "27b952b43a3d4a3ca30c7b825716918a5ce5b100b32eea3ad9d0faed53c1a92222d46952b17544011c20a693bc18ccba3a0ee33df10567abb2c2333c7e4ad58c"},
+		{"range blinding", public.HVec[0], "15cf2262c7c9127d31aa98793373d0ee9b4cb37781b5064c175eb798d8eb88b15d72738f7329e71375a54db6221fb3fed0c8d82243c8260b5c2d9645690cc510"},
+		{"range digit-0", public.GVec[0], "709894681c51a8a1946f5917074318b3d81dae794b1458f0c9f1b93f6065d3e21a92c0fcd794b91ae51e8cc4853c1a073dffc42a585cac9fe4fa789843b94a90"},
+		{"range digit-1", public.GVec[1], "58b639ca98a52833ee1daa070837a7d0cdcf8d99cef4269664fecc9f4598b9a76b99d527beab0538976378241b5df83bb640d22ed8b70dbdb5d9f09c0d40108e"},
+		{"range witness-0", public.HVec[circuitBlindingSlots], "803a91a2ba22aa9f6320a8af5e4537dd758925616303caf83da794edbb87982a2f506b99f910389f3600c6e6a6b5b810f9bc67d8bba1bc6c4e97c812f154b9ae"},
+		{"wnla g", wnla.G, "43e0ea9c33fad8097a5b7c56e2a273e58e68d1fec1a6764bbe05d3f7d6097d103f7a0abaf931d558be9fbe1425c5309d2cc42ad71d9397b65c9c44e466c3e68c"},
+		{"wnla g-0", wnla.GVec[0], "8251820bb62426d5b2aa4de1fd1083da6b5b2e51f54d952446f57418f4141483791037d36aec9e10675ccbdf9d30d9a964102ab764eb069c269f2c3525f63d9c"},
+		{"wnla g-1", wnla.GVec[1], "453fc2f6c395017c05e162b79c0238b69eff6a2d388a151b4129772c8f15a67f0fad91515f3fc5d273e6bca395e58b87f8b8eee9cc2baf9250262ccdf39d249a"},
+		{"wnla h-0", wnla.HVec[0], "619f103347818c503b9f747dccb84a210c6322006105c5c8111347c62a00433e4374811f160d61cb747b0cf2078da4fbe1d85dc5f2f8b73caa5a686c1c5a27ae"},
+		{"wnla h-1", wnla.HVec[1], "1024dfdd887bbe95fcc4ff9cb0d60e66f4779dfa2771960c2cf5c8deebe5040b509c60aed4969cdd3d8fb7e4f851c87fae4374f2f6c4be0450b2c2a3663f5a94"},
+	} {
+		if got := hex.EncodeToString(v.point.Marshal()); got != v.expected {
+			t.Errorf("%s: derived %s, expected %s", v.name, got, v.expected)
+		}
+	}
+}
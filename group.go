@@ -0,0 +1,339 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"io"
+	"math/big"
+)
+
+// Point is an element of a Group. Points are treated as immutable values: group operations always return new
+// points and never modify their arguments.
+type Point interface {
+	Marshal() []byte
+}
+
+// Group abstracts the prime order group the protocols run over, so the same protocol code can be used with
+// different curve backends. BN256 is the default backend used by the rest of the package.
+//
+// Neither backend is constant time: cloudflare/bn256 multiplies with a double-and-add that branches on the scalar
+// bits, and Secp256k1 is built on math/big. Proving runs scalar multiplications on the secret witness, so a prover
+// whose timing can be measured by an attacker leaks information about it with either backend.
+type Group interface {
+	// Name identifies the backend, e.g. "bn256".
+	Name() string
+	// Order returns the prime order of the group, which is also the modulus of the scalar field.
+	Order() *big.Int
+	// Identity returns the neutral element.
+	Identity() Point
+	// Add returns a + b.
+	Add(a, b Point) Point
+	// ScalarMult returns k*p.
+	ScalarMult(p Point, k *big.Int) Point
+	// Equal reports whether a and b are the same element.
+	Equal(a, b Point) bool
+	// Unmarshal decodes a point encoded with Point.Marshal.
+	Unmarshal(data []byte) (Point, error)
+	// DeriveGenerator deterministically derives a generator with unknown discrete logarithm from the domain and
+	// label.
+	DeriveGenerator(domain, label string) (Point, error)
+}
+
+// BN256 is the cloudflare/bn256 G1 backend. Its points are *bn256.G1.
+var BN256 Group = bn256Group{}
+
+type bn256Group struct{}
+
+func (bn256Group) Name() string {
+	return "bn256"
+}
+
+func (bn256Group) Order() *big.Int {
+	return bn256.Order
+}
+
+func (bn256Group) Identity() Point {
+	return new(bn256.G1).ScalarBaseMult(big.NewInt(0))
+}
+
+func (bn256Group) Add(a, b Point) Point {
+	return new(bn256.G1).Add(a.(*bn256.G1), b.(*bn256.G1))
+}
+
+func (bn256Group) ScalarMult(p Point, k *big.Int) Point {
+	return new(bn256.G1).ScalarMult(p.(*bn256.G1), add(k, nil))
+}
+
+func (bn256Group) Equal(a, b Point) bool {
+	return pointsEqual(a.(*bn256.G1), b.(*bn256.G1))
+}
+
+func (bn256Group) Unmarshal(data []byte) (Point, error) {
+	p, err := unmarshalPoint(data)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (bn256Group) DeriveGenerator(domain, label string) (Point, error) {
+	return DeriveGenerator(domain, label)
+}
+
+// toPoints converts bn256 points to group points. Nil entries stay nil interfaces.
+func toPoints(v []*bn256.G1) []Point {
+	res := make([]Point, len(v))
+	for i := range v {
+		if v[i] != nil {
+			res[i] = v[i]
+		}
+	}
+	return res
+}
+
+// toG1 converts a point of the BN256 group back to *bn256.G1.
+func toG1(p Point) *bn256.G1 {
+	if p == nil {
+		return nil
+	}
+	return p.(*bn256.G1)
+}
+
+// toPoint converts a bn256 point to a group point, keeping nil a nil interface.
+func toPoint(p *bn256.G1) Point {
+	if p == nil {
+		return nil
+	}
+	return p
+}
+
+func toG1s(v []Point) []*bn256.G1 {
+	res := make([]*bn256.G1, len(v))
+	for i := range v {
+		res[i] = toG1(v[i])
+	}
+	return res
+}
+
+// For points of an arbitrary Group
+
+func groupVectorPointScalarMul(g Group, points []Point, a []*big.Int) Point {
+	res := g.Identity()
+	for i := range points {
+		if i < len(a) {
+			res = g.Add(res, g.ScalarMult(points[i], a[i]))
+		}
+	}
+	return res
+}
+
+func groupVectorPointsAdd(g Group, a, b []Point) []Point {
+	res := make([]Point, max(len(a), len(b)))
+	for i := range res {
+		switch {
+		case i >= len(a):
+			res[i] = b[i]
+		case i >= len(b):
+			res[i] = a[i]
+		default:
+			res[i] = g.Add(a[i], b[i])
+		}
+	}
+	return res
+}
+
+func groupVectorPointMulOnScalar(g Group, v []Point, k *big.Int) []Point {
+	res := make([]Point, len(v))
+	for i := range res {
+		res[i] = g.ScalarMult(v[i], k)
+	}
+	return res
+}
+
+// scalarField implements the scalar helpers from math_scalars.go and math_vectors.go modulo the order of an
+// arbitrary group. Shorter vectors are treated as padded with zeros.
+type scalarField struct {
+	order *big.Int
+}
+
+func (f scalarField) add(x, y *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).Add(zeroIfNil(x), zeroIfNil(y)), f.order)
+}
+
+func (f scalarField) sub(x, y *big.Int) *big.Int {
+	return new(big.Int).Mod(new(big.Int).Sub(zeroIfNil(x), zeroIfNil(y)), f.order)
+}
+
+func (f scalarField) mul(x, y *big.Int) *big.Int {
+	if x == nil || y == nil {
+		return big.NewInt(0)
+	}
+	return new(big.Int).Mod(new(big.Int).Mul(x, y), f.order)
+}
+
+func (f scalarField) inv(x *big.Int) *big.Int {
+	return new(big.Int).ModInverse(x, f.order)
+}
+
+func (f scalarField) neg(x *big.Int) *big.Int {
+	return f.sub(nil, x)
+}
+
+func (f scalarField) pow(x *big.Int, y int) *big.Int {
+	if y < 0 {
+		return new(big.Int).Exp(f.inv(x), big.NewInt(-int64(y)), f.order)
+	}
+	return new(big.Int).Exp(x, big.NewInt(int64(y)), f.order)
+}
+
+func (f scalarField) isCanonical(x *big.Int) bool {
+	return x != nil && x.Sign() >= 0 && x.Cmp(f.order) < 0
+}
+
+func (f scalarField) vectorAdd(a, b []*big.Int) []*big.Int {
+	res := make([]*big.Int, max(len(a), len(b)))
+	for i := range res {
+		res[i] = f.add(scalarAt(a, i), scalarAt(b, i))
+	}
+	return res
+}
+
+func (f scalarField) vectorSub(a, b []*big.Int) []*big.Int {
+	res := make([]*big.Int, max(len(a), len(b)))
+	for i := range res {
+		res[i] = f.sub(scalarAt(a, i), scalarAt(b, i))
+	}
+	return res
+}
+
+func (f scalarField) vectorMulOnScalar(a []*big.Int, c *big.Int) []*big.Int {
+	res := make([]*big.Int, len(a))
+	for i := range res {
+		res[i] = f.mul(a[i], c)
+	}
+	return res
+}
+
+func (f scalarField) vectorMul(a, b []*big.Int) *big.Int {
+	res := big.NewInt(0)
+	for i := 0; i < min(len(a), len(b)); i++ {
+		res = f.add(res, f.mul(a[i], b[i]))
+	}
+	return res
+}
+
+// weightVectorMul returns sum(a[i]*b[i]*mu^(i+1)).
+func (f scalarField) weightVectorMul(a, b []*big.Int, mu *big.Int) *big.Int {
+	res := big.NewInt(0)
+	exp := new(big.Int).Set(mu)
+	for i := 0; i < min(len(a), len(b)); i++ {
+		res = f.add(res, f.mul(f.mul(a[i], b[i]), exp))
+		exp = f.mul(exp, mu)
+	}
+	return res
+}
+
+func (f scalarField) vectorTensorMul(a, b []*big.Int) []*big.Int {
+	res := make([]*big.Int, 0, len(a)*len(b))
+	for i := range b {
+		res = append(res, f.vectorMulOnScalar(a, b[i])...)
+	}
+	return res
+}
+
+// e returns the powers v^0, ..., v^(n-1).
+func (f scalarField) e(v *big.Int, n int) []*big.Int {
+	val := big.NewInt(1)
+	res := make([]*big.Int, n)
+	for i := range res {
+		res[i] = val
+		val = f.mul(val, v)
+	}
+	return res
+}
+
+// vectorMulOnMatrix returns the product of the row vector a with the matrix m.
+func (f scalarField) vectorMulOnMatrix(a []*big.Int, m [][]*big.Int) []*big.Int {
+	res := make([]*big.Int, len(m[0]))
+	column := make([]*big.Int, len(m))
+	for j := range res {
+		for i := range m {
+			column[i] = m[i][j]
+		}
+		res[j] = f.vectorMul(a, column)
+	}
+	return res
+}
+
+// diagInv returns the n*n diagonal matrix of x^-1, ..., x^-n.
+func (f scalarField) diagInv(x *big.Int, n int) [][]*big.Int {
+	res := zeroMatrix(n, n)
+	inv := f.inv(x)
+	val := new(big.Int).Set(inv)
+	for i := range res {
+		res[i][i] = val
+		val = f.mul(val, inv)
+	}
+	return res
+}
+
+// challenge derives the next challenge of fs reduced into the field.
+func (f scalarField) challenge(fs FiatShamirEngine) *big.Int {
+	return new(big.Int).Mod(fs.GetChallenge(), f.order)
+}
+
+// checkChallenges works like the package level checkChallenges for the field.
+func (f scalarField) checkChallenges(challenges ...*big.Int) error {
+	for i, c := range challenges {
+		if c == nil || f.add(c, nil).Sign() == 0 {
+			return fmt.Errorf("%w: challenge %d of %d is zero", ErrInvalidChallenge, i+1, len(challenges))
+		}
+	}
+	return nil
+}
+
+// randScalar draws a random scalar of the field. Over BN256 it is NewRandScalar, other orders reduce 64 bytes of
+// randReader output, so the bias is negligible for any group order up to 256 bits. It panics like NewRandScalar if
+// the random source fails.
+func (f scalarField) randScalar() *big.Int {
+	if f.order.Cmp(bn256.Order) == 0 {
+		return NewRandScalar()
+	}
+
+	entropy := make([]byte, 64)
+	if _, err := io.ReadFull(randReader, entropy); err != nil {
+		panic(fmt.Sprintf("Failed to generate random scalar: %v", err))
+	}
+	return new(big.Int).Mod(new(big.Int).SetBytes(entropy), f.order)
+}
+
+func scalarAt(v []*big.Int, i int) *big.Int {
+	if i < len(v) {
+		return v[i]
+	}
+	return nil
+}
+
+// deriveGroupScalar deterministically derives a scalar of the field from the domain and label. A 512-bit hash
+// output is reduced, so the bias is negligible for any group order up to 256 bits.
+func deriveGroupScalar(f scalarField, domain, label string) (*big.Int, error) {
+	if domain == "" {
+		return nil, errors.New("domain cannot be empty")
+	}
+
+	wide := append(
+		Keccak256([]byte(domain), []byte{0x00}, []byte(label), []byte{0x00}),
+		Keccak256([]byte(domain), []byte{0x00}, []byte(label), []byte{0x01})...,
+	)
+
+	res := new(big.Int).Mod(new(big.Int).SetBytes(wide), f.order)
+	if res.Sign() == 0 {
+		return nil, fmt.Errorf("derived zero scalar for label %q", label)
+	}
+	return res, nil
+}
@@ -0,0 +1,239 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// schnorrGroup is the subgroup of quadratic residues modulo the safe prime p = 2q + 1. It is only used to check
+// that the protocols work over a backend other than bn256.
+type schnorrGroup struct {
+	p, q *big.Int
+}
+
+type schnorrElement struct {
+	v *big.Int
+}
+
+func (e *schnorrElement) Marshal() []byte {
+	return scalarTo32Byte(e.v)
+}
+
+func newSchnorrGroup() schnorrGroup {
+	q, _ := new(big.Int).SetString("4000000000000000000000000000000000000000000000000000000000017fbf", 16)
+	return schnorrGroup{p: new(big.Int).Add(new(big.Int).Lsh(q, 1), big.NewInt(1)), q: q}
+}
+
+func (g schnorrGroup) Name() string {
+	return "schnorr-test"
+}
+
+func (g schnorrGroup) Order() *big.Int {
+	return g.q
+}
+
+func (g schnorrGroup) Identity() Point {
+	return &schnorrElement{big.NewInt(1)}
+}
+
+func (g schnorrGroup) Add(a, b Point) Point {
+	v := new(big.Int).Mul(a.(*schnorrElement).v, b.(*schnorrElement).v)
+	return &schnorrElement{v.Mod(v, g.p)}
+}
+
+func (g schnorrGroup) ScalarMult(p Point, k *big.Int) Point {
+	return &schnorrElement{new(big.Int).Exp(p.(*schnorrElement).v, new(big.Int).Mod(k, g.q), g.p)}
+}
+
+func (g schnorrGroup) Equal(a, b Point) bool {
+	return a.(*schnorrElement).v.Cmp(b.(*schnorrElement).v) == 0
+}
+
+func (g schnorrGroup) Unmarshal(data []byte) (Point, error) {
+	v := new(big.Int).SetBytes(data)
+	if v.Sign() == 0 || v.Cmp(g.p) >= 0 || new(big.Int).Exp(v, g.q, g.p).Cmp(big.NewInt(1)) != 0 {
+		return nil, errors.New("not a group element")
+	}
+	return &schnorrElement{v}, nil
+}
+
+func (g schnorrGroup) DeriveGenerator(domain, label string) (Point, error) {
+	h := new(big.Int).SetBytes(Keccak256([]byte(domain), []byte{0x00}, []byte(label)))
+	h.Mod(h, g.p)
+	if h.Cmp(big.NewInt(2)) < 0 {
+		return nil, errors.New("degenerate generator")
+	}
+	return &schnorrElement{h.Exp(h, big.NewInt(2), g.p)}, nil
+}
+
+func TestGroupWNLA(t *testing.T) {
+	for _, group := range []Group{BN256, Secp256k1, newSchnorrGroup()} {
+		t.Run(group.Name(), func(t *testing.T) {
+			public, err := NewGroupWNLAPublic(group, DOMAIN_WNLA, 16, 8)
+			if err != nil {
+				t.Fatalf("NewGroupWNLAPublic failed: %v", err)
+			}
+
+			f := public.field()
+
+			l := make([]*big.Int, 16)
+			for i := range l {
+				l[i] = new(big.Int).Mod(NewRandScalar(), f.order)
+			}
+
+			n := make([]*big.Int, 8)
+			for i := range n {
+				n[i] = new(big.Int).Mod(NewRandScalar(), f.order)
+			}
+
+			commitment := public.Commit(l, n)
+
+			proof, err := ProveGroupWNLA(public, commitment, NewKeccakFS(), l, n)
+			if err != nil {
+				t.Fatalf("ProveGroupWNLA failed: %v", err)
+			}
+
+			if err := VerifyGroupWNLA(public, proof, commitment, NewKeccakFS()); err != nil {
+				t.Fatalf("VerifyGroupWNLA failed: %v", err)
+			}
+
+			tampered := *proof
+			tampered.L = append([]*big.Int{f.add(proof.L[0], big.NewInt(1))}, proof.L[1:]...)
+
+			if err := VerifyGroupWNLA(public, &tampered, commitment, NewKeccakFS()); err == nil {
+				t.Error("Tampered proof accepted")
+			}
+		})
+	}
+}
+
+func TestGroupWNLAMatchesBN256API(t *testing.T) {
+	public := NewWeightNormLinearPublic(8, 4)
+
+	l := []*big.Int{bint(1), bint(2), bint(3), bint(4), bint(5), bint(6), bint(7), bint(8)}
+	n := []*big.Int{bint(9), bint(10), bint(11), bint(12)}
+
+	commitment, err := public.CommitWNLAChecked(l, n)
+	if err != nil {
+		t.Fatalf("CommitWNLAChecked failed: %v", err)
+	}
+
+	if !BN256.Equal(public.group().Commit(l, n), commitment) {
+		t.Fatal("Group commitment differs from CommitWNLA")
+	}
+
+	proof, err := ProveGroupWNLA(public.group(), commitment, NewKeccakFS(), l, n)
+	if err != nil {
+		t.Fatalf("ProveGroupWNLA failed: %v", err)
+	}
+
+	// A proof over the BN256 backend is an ordinary WNLA proof.
+	if err := VerifyWNLA(public, proof.bn256(), commitment, NewKeccakFS()); err != nil {
+		t.Fatalf("VerifyWNLA rejected a BN256 group proof: %v", err)
+	}
+}
+
+func groupRangePrivate(t *testing.T, public *GroupReciprocalPublic, x *big.Int) *ReciprocalPrivate {
+	digits, err := Base(public.Np).Digits(x, public.Nd)
+	if err != nil {
+		t.Fatalf("Digits failed: %v", err)
+	}
+
+	m, err := Base(public.Np).Mapping(digits)
+	if err != nil {
+		t.Fatalf("Mapping failed: %v", err)
+	}
+
+	return &ReciprocalPrivate{
+		X:      x,
+		M:      m,
+		Digits: digits,
+		S:      public.field().randScalar(),
+	}
+}
+
+func TestGroupRange(t *testing.T) {
+	for _, group := range []Group{BN256, Secp256k1, newSchnorrGroup()} {
+		t.Run(group.Name(), func(t *testing.T) {
+			public, err := NewGroupReciprocalPublic(group, DOMAIN_RANGE, 16, Base16)
+			if err != nil {
+				t.Fatalf("NewGroupReciprocalPublic failed: %v", err)
+			}
+
+			private := groupRangePrivate(t, public, big.NewInt(0xab12cd))
+			V := public.CommitValue(private.X, private.S)
+
+			proof, err := ProveGroupRange(public, NewKeccakFS(), private)
+			if err != nil {
+				t.Fatalf("ProveGroupRange failed: %v", err)
+			}
+
+			if err := VerifyGroupRange(public, V, NewKeccakFS(), proof); err != nil {
+				t.Fatalf("VerifyGroupRange failed: %v", err)
+			}
+
+			other := public.CommitValue(big.NewInt(0xab12ce), private.S)
+			if err := VerifyGroupRange(public, other, NewKeccakFS(), proof); err == nil {
+				t.Error("Proof accepted for another commitment")
+			}
+
+			tampered := *proof.GroupArithmeticCircuitProof
+			tampered.CS = group.Add(proof.CS, public.G)
+			if err := VerifyGroupRange(public, V, NewKeccakFS(), &GroupReciprocalProof{
+				GroupArithmeticCircuitProof: &tampered,
+				V:                           proof.V,
+			}); err == nil {
+				t.Error("Tampered proof accepted")
+			}
+
+			// A value of more than Nd digits cannot be proven.
+			if _, err := ProveGroupRange(public, NewKeccakFS(), &ReciprocalPrivate{
+				X:      new(big.Int).Lsh(big.NewInt(1), 64),
+				M:      private.M,
+				Digits: private.Digits,
+				S:      private.S,
+			}); !errors.Is(err, ErrDigitMismatch) {
+				t.Errorf("Expected ErrDigitMismatch, got %v", err)
+			}
+
+			if _, err := ProveGroupRange(public, NewKeccakFS(), private, WithDigitCount(4)); err == nil {
+				t.Error("ProveGroupRange accepted a range option")
+			}
+		})
+	}
+}
+
+func TestGroupRangeMatchesBN256API(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, Base16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	groupPublic, err := NewGroupReciprocalPublic(BN256, DOMAIN_RANGE, 16, Base16)
+	if err != nil {
+		t.Fatalf("NewGroupReciprocalPublic failed: %v", err)
+	}
+
+	for i := range public.HVec {
+		if !BN256.Equal(public.HVec[i], groupPublic.HVec[i]) {
+			t.Fatalf("HVec[%d] differs from NewReciprocalPublic", i)
+		}
+	}
+
+	private := groupRangePrivate(t, groupPublic, big.NewInt(1234))
+
+	proof, err := ProveGroupRange(groupPublic, NewKeccakFS(), private)
+	if err != nil {
+		t.Fatalf("ProveGroupRange failed: %v", err)
+	}
+
+	// A proof over the BN256 backend is an ordinary range proof.
+	if err := VerifyRange(public, public.CommitValue(private.X, private.S), NewKeccakFS(), proof.bn256()); err != nil {
+		t.Fatalf("VerifyRange rejected a BN256 group proof: %v", err)
+	}
+}
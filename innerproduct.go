@@ -0,0 +1,161 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+)
+
+// The inner-product argument proves knowledge of a, b of length k with
+// Com = <a, b>*U + <a, GVec> + <b, HVec>
+// as in the classic Bulletproofs argument. It runs the WNLA with Ro = Mu = 1 and no linear part, where the norm
+// becomes the plain square sum |n|^2. With i^2 = -1, which exists as the bn256 order is 1 mod 4, the witness
+// n = ((a+b)/2, i*(a-b)/2) has |n|^2 = sum((a+b)^2 - (a-b)^2)/4 = <a, b>. Its commitment over the WNLA generators W
+// is the one above for
+// GVec[j] = (W[j] + i*W[k+j])/2, HVec[j] = (W[j] - i*W[k+j])/2, U = G
+// so the folding rounds, transcript and the single multi-scalar multiplication on the verifier side are shared with
+// ProveWNLA and VerifyWNLA.
+
+// InnerProductPublic holds the parameters of the inner-product argument for vectors of length n. Use
+// NewInnerProductPublic: GVec and HVec are derived from the WNLA generators and are only used to commit, so other
+// values would commit to a different relation than the one the argument proves.
+type InnerProductPublic struct {
+	// WNLA holds the parameters the argument runs on: Ro = Mu = 1, 2n GVec generators and no HVec or C.
+	WNLA *WeightNormLinearPublic
+	// U is the base point of <a, b>, it is WNLA.G.
+	U *bn256.G1
+	// GVec and HVec are the generators of a and b.
+	GVec []*bn256.G1
+	HVec []*bn256.G1
+}
+
+// NewInnerProductPublic deterministically derives the parameters of the inner-product argument for vectors of
+// length n from the domain (for example DOMAIN_INNER_PRODUCT). The WNLA generators are labeled as in
+// NewLabeledWeightNormLinearPublic with 2n GVec entries.
+func NewInnerProductPublic(domain string, n int) (*InnerProductPublic, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("vector length %d must be at least 1", n)
+	}
+
+	wnla, err := NewLabeledWeightNormLinearPublic(domain, 0, 2*n)
+	if err != nil {
+		return nil, err
+	}
+
+	wnla.Ro, wnla.Mu = bint(1), bint(1)
+
+	half := inv(bint(2))
+	halfI := mul(half, innerProductI())
+
+	public := &InnerProductPublic{
+		WNLA: wnla,
+		U:    wnla.G,
+		GVec: make([]*bn256.G1, n),
+		HVec: make([]*bn256.G1, n),
+	}
+
+	for j := 0; j < n; j++ {
+		w0 := new(bn256.G1).ScalarMult(wnla.GVec[j], half)
+		w1 := new(bn256.G1).ScalarMult(wnla.GVec[n+j], halfI)
+
+		public.GVec[j] = new(bn256.G1).Add(w0, w1)
+		public.HVec[j] = new(bn256.G1).Add(w0, new(bn256.G1).Neg(w1))
+	}
+
+	return public, nil
+}
+
+// innerProductI returns a square root of -1 modulo the bn256 order.
+func innerProductI() *big.Int {
+	return new(big.Int).ModSqrt(minus(bint(1)), bn256.Order)
+}
+
+// Validate checks the WNLA parameters and the shape the argument relies on. It does not recompute GVec and HVec.
+func (p *InnerProductPublic) Validate() error {
+	if p == nil {
+		return errors.New("inner-product public parameters cannot be nil")
+	}
+
+	if err := p.WNLA.Validate(); err != nil {
+		return err
+	}
+
+	if p.WNLA.Ro.Cmp(bint(1)) != 0 || p.WNLA.Mu.Cmp(bint(1)) != 0 {
+		return errors.New("inner-product argument requires Ro = Mu = 1")
+	}
+
+	if len(p.WNLA.HVec) != 0 {
+		return fmt.Errorf("inner-product argument requires no WNLA HVec, got %d", len(p.WNLA.HVec))
+	}
+
+	if len(p.GVec) != len(p.HVec) || len(p.WNLA.GVec) != 2*len(p.GVec) {
+		return fmt.Errorf("len(GVec)=%d, len(HVec)=%d do not match len(WNLA.GVec)=%d", len(p.GVec), len(p.HVec), len(p.WNLA.GVec))
+	}
+
+	if p.U == nil || !pointsEqual(p.U, p.WNLA.G) {
+		return errors.New("U must be WNLA.G")
+	}
+
+	return nil
+}
+
+// Commit returns <a, b>*U + <a, GVec> + <b, HVec>.
+func (p *InnerProductPublic) Commit(a, b []*big.Int) *bn256.G1 {
+	C := new(bn256.G1).ScalarMult(p.U, vectorMul(a, b))
+	C.Add(C, vectorPointScalarMul(p.GVec, a))
+	C.Add(C, vectorPointScalarMul(p.HVec, b))
+	return C
+}
+
+// witness returns the WNLA witness n = ((a+b)/2, i*(a-b)/2) with |n|^2 = <a, b>.
+func (p *InnerProductPublic) witness(a, b []*big.Int) ([]*big.Int, error) {
+	if len(a) != len(p.GVec) || len(b) != len(p.HVec) {
+		return nil, fmt.Errorf("witness sizes a=%d, b=%d do not match generators GVec=%d, HVec=%d", len(a), len(b), len(p.GVec), len(p.HVec))
+	}
+
+	half := inv(bint(2))
+	halfI := mul(half, innerProductI())
+
+	n := make([]*big.Int, 2*len(a))
+	for j := range a {
+		if a[j] == nil || b[j] == nil {
+			return nil, fmt.Errorf("a[%d] and b[%d] cannot be nil", j, j)
+		}
+
+		n[j] = mul(add(a[j], b[j]), half)
+		n[len(a)+j] = mul(sub(a[j], b[j]), halfI)
+	}
+
+	return n, nil
+}
+
+// ProveInnerProduct generates the inner-product argument for vectors a and b that satisfy the commitment
+// Com = public.Commit(a, b).
+// Use empty FiatShamirEngine for call.
+func ProveInnerProduct(public *InnerProductPublic, Com *bn256.G1, fs FiatShamirEngine, a, b []*big.Int) (*WeightNormLinearArgumentProof, error) {
+	if err := public.Validate(); err != nil {
+		return nil, err
+	}
+
+	n, err := public.witness(a, b)
+	if err != nil {
+		return nil, err
+	}
+
+	return ProveWNLA(public.WNLA, Com, fs, []*big.Int{}, n)
+}
+
+// VerifyInnerProduct verifies the inner-product argument. If err is nil then proof is valid.
+// Use empty FiatShamirEngine for call.
+func VerifyInnerProduct(public *InnerProductPublic, proof *WeightNormLinearArgumentProof, Com *bn256.G1, fs FiatShamirEngine) error {
+	if err := public.Validate(); err != nil {
+		return err
+	}
+
+	return VerifyWNLA(public.WNLA, proof, Com, fs)
+}
@@ -0,0 +1,81 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"testing"
+)
+
+func TestInnerProduct(t *testing.T) {
+	public, err := NewInnerProductPublic(DOMAIN_INNER_PRODUCT, 8)
+	if err != nil {
+		t.Fatalf("NewInnerProductPublic failed: %v", err)
+	}
+
+	a := []*big.Int{bint(1), bint(2), bint(3), bint(4), bint(5), bint(6), bint(7), bint(8)}
+	b := []*big.Int{bint(8), bint(7), bint(6), bint(5), bint(4), bint(3), bint(2), bint(1)}
+
+	// The commitment binds the inner product <a, b> and is the WNLA commitment to the transformed witness.
+	expected := new(bn256.G1).ScalarMult(public.U, bint(120))
+	expected.Add(expected, vectorPointScalarMul(public.GVec, a))
+	expected.Add(expected, vectorPointScalarMul(public.HVec, b))
+
+	Com := public.Commit(a, b)
+	if !pointsEqual(Com, expected) {
+		t.Fatal("Commit does not commit to <a, b>")
+	}
+
+	n, err := public.witness(a, b)
+	if err != nil {
+		t.Fatalf("witness failed: %v", err)
+	}
+	if !pointsEqual(public.WNLA.CommitWNLA(nil, n), Com) {
+		t.Fatal("WNLA commitment of the witness does not match Commit")
+	}
+
+	proof, err := ProveInnerProduct(public, Com, NewKeccakFS(), a, b)
+	if err != nil {
+		t.Fatalf("ProveInnerProduct failed: %v", err)
+	}
+
+	if err := VerifyInnerProduct(public, proof, Com, NewKeccakFS()); err != nil {
+		t.Fatalf("VerifyInnerProduct failed: %v", err)
+	}
+
+	// The argument is the WNLA over the same parameters.
+	if err := VerifyWNLA(public.WNLA, proof, Com, NewKeccakFS()); err != nil {
+		t.Fatalf("VerifyWNLA rejected an inner-product proof: %v", err)
+	}
+
+	// A commitment to the same vectors with another inner product is rejected.
+	other := new(bn256.G1).Add(Com, public.U)
+	if err := VerifyInnerProduct(public, proof, other, NewKeccakFS()); err == nil {
+		t.Error("Proof accepted for another inner product")
+	}
+
+	proof, err = ProveInnerProduct(public, other, NewKeccakFS(), a, b)
+	if err != nil {
+		t.Fatalf("ProveInnerProduct failed: %v", err)
+	}
+	if err := VerifyInnerProduct(public, proof, other, NewKeccakFS()); err == nil {
+		t.Error("Proof accepted for a commitment the witness does not open")
+	}
+
+	if _, err := ProveInnerProduct(public, Com, NewKeccakFS(), a, b[1:]); err == nil {
+		t.Error("Expected an error for vectors of different lengths")
+	}
+
+	weighted := *public
+	weighted.WNLA = NewWeightNormLinearPublic(0, 16)
+	if _, err := ProveInnerProduct(&weighted, Com, NewKeccakFS(), a, b); err == nil {
+		t.Error("Expected an error for weighted parameters")
+	}
+
+	if _, err := NewInnerProductPublic(DOMAIN_INNER_PRODUCT, 0); err == nil {
+		t.Error("Expected an error for an empty vector length")
+	}
+}
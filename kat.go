@@ -0,0 +1,172 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+)
+
+// BulletproofsKATVector represents a single KAT test case for range proofs
+type BulletproofsKATVector struct {
+	Description  string `json:"description"`
+	Value        string `json:"value"`                // Hex string of the value to prove
+	BitLength    int    `json:"bit_length"`           // Number of bits for the range
+	Base         Base   `json:"base"`                 // Number system base (e.g., 16 for hex)
+	ShouldVerify bool   `json:"should_verify"`        // Expected verification result
+	ErrorType    string `json:"error_type,omitempty"` // Type of error expected (for negative tests)
+
+	// Filled by generateKATVectors. The public parameters are NewReciprocalPublic(Domain, Nd, Base).
+	Domain     string `json:"domain,omitempty"`
+	Nd         int    `json:"nd,omitempty"`
+	Blinding   string `json:"blinding,omitempty"`   // Hex string of the commitment blinding
+	Commitment string `json:"commitment,omitempty"` // Hex of the marshaled value commitment
+	Proof      string `json:"proof,omitempty"`      // Hex of the proof as written by WriteRangeProof
+
+	// TranscriptVersion of the KeccakFS the proof is produced with, TranscriptV1 if omitted.
+	TranscriptVersion TranscriptVersion `json:"transcript_version,omitempty"`
+}
+
+// BulletproofsKAT contains all test vectors
+type BulletproofsKAT struct {
+	Description string                  `json:"description"`
+	TestVectors []BulletproofsKATVector `json:"test_vectors"`
+}
+
+// katConfig describes one vector for generateKATVectors.
+type katConfig struct {
+	Description string
+	Value       *big.Int
+	BitLength   int
+	Base        Base
+	Blinding    *big.Int          // drawn from the seeded source if nil
+	Version     TranscriptVersion // TranscriptV1 if zero
+}
+
+// katDigits returns the digit count Nd for which Base^Nd == 2^BitLength.
+func katDigits(bitLength int, base Base) (int, error) {
+	bits := 0
+	for p := Base(1); p < base; p *= 2 {
+		bits++
+	}
+
+	if base < 2 || 1<<bits != base || bitLength <= 0 || bitLength%bits != 0 {
+		return 0, fmt.Errorf("range of %d bits cannot be expressed in base %d", bitLength, base)
+	}
+
+	return bitLength / bits, nil
+}
+
+// katReader is a deterministic stream of Keccak256(seed, counter) blocks with a 64-bit big-endian counter. It stands
+// in for the random source while the KAT vectors are generated, so the same configs always give the same proofs.
+type katReader struct {
+	seed    []byte
+	counter uint64
+	buf     []byte
+}
+
+func (r *katReader) Read(p []byte) (int, error) {
+	for n := 0; n < len(p); {
+		if len(r.buf) == 0 {
+			r.buf = Keccak256(r.seed, binary.BigEndian.AppendUint64(nil, r.counter))
+			r.counter++
+		}
+		c := copy(p[n:], r.buf)
+		r.buf = r.buf[c:]
+		n += c
+	}
+	return len(p), nil
+}
+
+// generateKATVectors produces a proof for every config and records it together with its inputs. The public
+// parameters are derived deterministically with NewReciprocalPublic(DOMAIN_RANGE, Nd, Base), so other
+// implementations can rebuild them and check the recorded commitments and proofs under the recorded transcript
+// version. The randomness of every proof is drawn from a katReader seeded with the description of its config, so
+// the vectors are reproducible. It replaces randReader while running and must not run concurrently with other
+// proofs.
+func generateKATVectors(configs []katConfig) (BulletproofsKAT, error) {
+	defer func(r io.Reader) { randReader = r }(randReader)
+
+	kat := BulletproofsKAT{
+		Description: "Bulletproofs++ Range Proof Known Answer Tests",
+		TestVectors: make([]BulletproofsKATVector, 0, len(configs)),
+	}
+
+	for _, cfg := range configs {
+		randReader = &katReader{seed: []byte("EMZA-BP++-KAT-" + cfg.Description)}
+
+		Nd, err := katDigits(cfg.BitLength, cfg.Base)
+		if err != nil {
+			return kat, fmt.Errorf("%s: %w", cfg.Description, err)
+		}
+
+		public, err := NewReciprocalPublic(DOMAIN_RANGE, Nd, cfg.Base)
+		if err != nil {
+			return kat, fmt.Errorf("%s: %w", cfg.Description, err)
+		}
+
+		blinding := cfg.Blinding
+		if blinding == nil {
+			blinding = NewRandScalar()
+		}
+
+		private, err := public.newPrivate(cfg.Value, blinding)
+		if err != nil {
+			return kat, fmt.Errorf("%s: %w", cfg.Description, err)
+		}
+
+		proof, err := ProveRange(public, NewKeccakFS(WithTranscriptVersion(cfg.Version)), private)
+		if err != nil {
+			return kat, fmt.Errorf("%s: %w", cfg.Description, err)
+		}
+
+		var buf bytes.Buffer
+		if err := WriteRangeProof(&buf, proof); err != nil {
+			return kat, fmt.Errorf("%s: %w", cfg.Description, err)
+		}
+
+		kat.TestVectors = append(kat.TestVectors, BulletproofsKATVector{
+			Description:  cfg.Description,
+			Value:        "0x" + cfg.Value.Text(16),
+			BitLength:    cfg.BitLength,
+			Base:         cfg.Base,
+			ShouldVerify: true,
+			Domain:       DOMAIN_RANGE,
+			Nd:           Nd,
+			Blinding:     hex.EncodeToString(scalarTo32Byte(blinding)),
+			Commitment:   hex.EncodeToString(public.CommitValue(private.X, private.S).Marshal()),
+			Proof:        hex.EncodeToString(buf.Bytes()),
+		})
+		if cfg.Version > TranscriptV1 {
+			kat.TestVectors[len(kat.TestVectors)-1].TranscriptVersion = cfg.Version
+		}
+	}
+
+	return kat, nil
+}
+
+// marshalKATVectors encodes the vectors as indented JSON, as they are stored in testdata/vectors.json.
+func marshalKATVectors(kat BulletproofsKAT) ([]byte, error) {
+	data, err := json.MarshalIndent(kat, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// writeKATVectors writes the vectors as indented JSON to path.
+func writeKATVectors(path string, kat BulletproofsKAT) error {
+	data, err := marshalKATVectors(kat)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
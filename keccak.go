@@ -22,7 +22,13 @@ func NewKeccakState() KeccakState {
 	return sha3.NewLegacyKeccak256().(KeccakState)
 }
 
-// Keccak256 calculates and returns the Keccak256 hash of the input data.
+// Keccak256 calculates and returns the 32-byte Keccak-256 hash of the concatenation of the chunks.
+//
+// This is the legacy Keccak-256 used by Ethereum (original Keccak padding), not the standardized SHA3-256, so the
+// two produce different digests for the same input. The chunks are concatenated without separators or length
+// prefixes: Keccak256(a, b) equals Keccak256(append(a, b...)), so callers that need unambiguous input must encode
+// boundaries themselves. Transcripts and derived generators depend on this function, so its output is stable
+// across versions.
 func Keccak256(data ...[]byte) []byte {
 	b := make([]byte, 32)
 	d := sha3.NewLegacyKeccak256().(KeccakState)
@@ -0,0 +1,36 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestKeccak256(t *testing.T) {
+	for _, tc := range []struct {
+		chunks   [][]byte
+		expected string
+	}{
+		// Legacy Keccak-256, SHA3-256 of the empty string is a7ffc6f8...
+		{nil, "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470"},
+		{[][]byte{[]byte("abc")}, "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+		// Chunks are concatenated without separators.
+		{[][]byte{[]byte("a"), []byte("bc")}, "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+		{[][]byte{[]byte("ab"), {}, []byte("c")}, "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45"},
+	} {
+		got := Keccak256(tc.chunks...)
+		if hex.EncodeToString(got) != tc.expected {
+			t.Errorf("Keccak256(%q) = %x, expected %s", tc.chunks, got, tc.expected)
+		}
+	}
+
+	input := []byte("abc")
+	Keccak256(input)
+	if !bytes.Equal(input, []byte("abc")) {
+		t.Error("Keccak256 modified its input")
+	}
+}
@@ -0,0 +1,159 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+)
+
+// LinearRangeProof proves a <= coeff*x + constant <= b for a value commitment to x and public coeff, constant, a
+// and b. Lower is a range proof for coeff*x + constant - a and Upper a range proof for b - coeff*x - constant.
+type LinearRangeProof struct {
+	Lower *ReciprocalProof
+	Upper *ReciprocalProof
+}
+
+// linearCommitments returns the commitments coeff*com + (constant-a)*G and -coeff*com + (b-constant)*G to the
+// distances of coeff*x + constant from both bounds.
+func linearCommitments(public *ReciprocalPublic, com *bn256.G1, coeff, constant, a, b *big.Int) (*bn256.G1, *bn256.G1) {
+	scaled := new(bn256.G1).ScalarMult(com, add(coeff, nil))
+
+	lower := new(bn256.G1).Add(scaled, new(bn256.G1).ScalarMult(public.G, sub(constant, a)))
+	upper := new(bn256.G1).Add(new(bn256.G1).Neg(scaled), new(bn256.G1).ScalarMult(public.G, sub(b, constant)))
+	return lower, upper
+}
+
+// absorbLinearStatement absorbs the public coefficients and bounds into fs before the range proofs.
+func absorbLinearStatement(fs FiatShamirEngine, coeff, constant, a, b *big.Int) error {
+	for _, x := range []*big.Int{coeff, constant, a, b} {
+		if err := fs.AddNumber(add(x, nil)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkLinearStatement(coeff, constant, a, b *big.Int) error {
+	if coeff == nil || constant == nil || a == nil || b == nil {
+		return errors.New("coefficient, constant and bounds cannot be nil")
+	}
+
+	if add(coeff, nil).Sign() == 0 {
+		return errors.New("coefficient cannot be zero")
+	}
+
+	if a.Cmp(b) > 0 {
+		return errors.New("lower bound is greater than upper bound")
+	}
+
+	return nil
+}
+
+// ProveLinearRange proves that a <= coeff*x + constant <= b for the value commitment CommitValue(value, blinding)
+// without revealing the value. The commitment to coeff*x + constant is formed homomorphically as
+// coeff*com + constant*G, and range proofs for its distances to a and b run one after the other over the same
+// transcript, after the public coefficients and bounds. Both distances must fit into [0, Np^Nd), so b - a must as
+// well. Like all statements over commitments the linear combination is taken mod bn256.Order, so negative
+// coefficients and constants work as expected. If it is outside of [a, b] no proof is produced and an error
+// wrapping ErrValueOutOfRange is returned. Use empty FiatShamirEngine for call.
+func ProveLinearRange(public *ReciprocalPublic, fs FiatShamirEngine, value, blinding, coeff, constant, a, b *big.Int) (*LinearRangeProof, error) {
+	if err := public.Validate(); err != nil {
+		return nil, err
+	}
+
+	if fs == nil {
+		return nil, errors.New("Fiat-Shamir engine cannot be nil")
+	}
+
+	if value == nil || blinding == nil {
+		return nil, errors.New("value and blinding cannot be nil")
+	}
+
+	if err := checkLinearStatement(coeff, constant, a, b); err != nil {
+		return nil, err
+	}
+
+	// The distances are taken mod the order, as the commitments do: a value below a wraps around to a huge
+	// distance that does not fit into the digits.
+	y := add(mul(coeff, value), constant)
+	s := mul(coeff, blinding)
+
+	lowerPrivate, err := public.newPrivate(sub(y, a), s)
+	if err != nil {
+		return nil, fmt.Errorf("linear combination is below the lower bound: %w", err)
+	}
+
+	upperPrivate, err := public.newPrivate(sub(b, y), minus(s))
+	if err != nil {
+		return nil, fmt.Errorf("linear combination is above the upper bound: %w", err)
+	}
+
+	if err := checkFreshTranscript(fs); err != nil {
+		return nil, err
+	}
+
+	if err := absorbLinearStatement(fs, coeff, constant, a, b); err != nil {
+		return nil, err
+	}
+
+	prover := NewProver(public)
+
+	lowerProof, err := prover.ProveChained(fs, lowerPrivate)
+	if err != nil {
+		return nil, err
+	}
+
+	// The second proof continues the transcript of the first one.
+	upperProof, err := prover.ProveChained(fs, upperPrivate)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LinearRangeProof{Lower: lowerProof, Upper: upperProof}, nil
+}
+
+// VerifyLinearRange verifies a proof produced by ProveLinearRange that the value committed in com satisfies
+// a <= coeff*x + constant <= b. If err is nil then proof is valid. Use empty FiatShamirEngine for call.
+func VerifyLinearRange(public *ReciprocalPublic, fs FiatShamirEngine, com *bn256.G1, coeff, constant, a, b *big.Int, proof *LinearRangeProof) error {
+	if err := public.Validate(); err != nil {
+		return err
+	}
+
+	if fs == nil {
+		return errors.New("Fiat-Shamir engine cannot be nil")
+	}
+
+	if com == nil {
+		return errors.New("commitment cannot be nil")
+	}
+
+	if err := checkLinearStatement(coeff, constant, a, b); err != nil {
+		return err
+	}
+
+	if proof == nil || proof.Lower == nil || proof.Upper == nil {
+		return errors.New("linear range proof is incomplete")
+	}
+
+	if err := checkFreshTranscript(fs); err != nil {
+		return err
+	}
+
+	if err := absorbLinearStatement(fs, coeff, constant, a, b); err != nil {
+		return err
+	}
+
+	lower, upper := linearCommitments(public, com, coeff, constant, a, b)
+	verifier := NewVerifier(public)
+
+	if err := verifier.VerifyChained(lower, fs, proof.Lower); err != nil {
+		return err
+	}
+
+	return verifier.VerifyChained(upper, fs, proof.Upper)
+}
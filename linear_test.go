@@ -0,0 +1,70 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLinearRange(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	x, s := bint(1000), NewRandScalar()
+	com := public.CommitValue(x, s)
+
+	// 3x + 5 = 3005
+	coeff, constant := bint(3), bint(5)
+	a, b := bint(3000), bint(4000)
+
+	proof, err := ProveLinearRange(public, NewKeccakFS(), x, s, coeff, constant, a, b)
+	if err != nil {
+		t.Fatalf("ProveLinearRange failed: %v", err)
+	}
+
+	if err := VerifyLinearRange(public, NewKeccakFS(), com, coeff, constant, a, b, proof); err != nil {
+		t.Fatalf("Valid linear range proof rejected: %v", err)
+	}
+
+	for name, statement := range map[string][4]int{
+		"other coefficient": {4, 5, 3000, 4000},
+		"other constant":    {3, 6, 3000, 4000},
+		"other lower bound": {3, 5, 3001, 4000},
+		"other upper bound": {3, 5, 3000, 3999},
+	} {
+		if err := VerifyLinearRange(public, NewKeccakFS(), com, bint(statement[0]), bint(statement[1]), bint(statement[2]), bint(statement[3]), proof); err == nil {
+			t.Errorf("Proof accepted for %s", name)
+		}
+	}
+
+	if err := VerifyLinearRange(public, NewKeccakFS(), public.CommitValue(bint(1001), s), coeff, constant, a, b, proof); err == nil {
+		t.Error("Proof accepted for a commitment to another value")
+	}
+
+	// Bounds that are met exactly, and a negative coefficient: -2x + 2100 = 100.
+	if _, err := ProveLinearRange(public, NewKeccakFS(), x, s, coeff, constant, bint(3005), bint(3005)); err != nil {
+		t.Errorf("ProveLinearRange failed for tight bounds: %v", err)
+	}
+
+	proof, err = ProveLinearRange(public, NewKeccakFS(), x, s, bint(-2), bint(2100), bint(0), bint(100))
+	if err != nil {
+		t.Fatalf("ProveLinearRange failed for a negative coefficient: %v", err)
+	}
+
+	if err := VerifyLinearRange(public, NewKeccakFS(), com, bint(-2), bint(2100), bint(0), bint(100), proof); err != nil {
+		t.Errorf("Proof rejected for a negative coefficient: %v", err)
+	}
+
+	if _, err := ProveLinearRange(public, NewKeccakFS(), x, s, coeff, constant, bint(3006), bint(4000)); !errors.Is(err, ErrValueOutOfRange) {
+		t.Error("ProveLinearRange should fail outside of the range")
+	}
+
+	if _, err := ProveLinearRange(public, NewKeccakFS(), x, s, bint(0), constant, a, b); err == nil {
+		t.Error("ProveLinearRange should reject a zero coefficient")
+	}
+}
@@ -5,14 +5,51 @@
 package bulletproofs
 
 import (
+	"bytes"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"github.com/cloudflare/bn256"
+	"io"
 	"math/big"
 )
 
+// randReader is the source of entropy for every random scalar and point of the package. Tests may replace it to
+// make random values reproducible.
+var randReader io.Reader = rand.Reader
+
+// RandOption configures the random source of SecureRandScalar, SecureRandPoint, RandScalarVector and
+// RandPointVector.
+type RandOption func(*randConfig)
+
+type randConfig struct {
+	reader io.Reader
+}
+
+// WithRandReader draws the entropy from r instead of crypto/rand, e.g. from a hardware RNG or from a seeded stream
+// for reproducible test data. r must deliver uniformly random bytes for secret values like blindings. A nil r keeps
+// the default source.
+func WithRandReader(r io.Reader) RandOption {
+	return func(cfg *randConfig) {
+		cfg.reader = r
+	}
+}
+
+// newRandReader returns the reader selected by opts, randReader by default.
+func newRandReader(opts []RandOption) io.Reader {
+	cfg := randConfig{}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.reader == nil {
+		return randReader
+	}
+	return cfg.reader
+}
+
 // NewRandPoint creates a new random point, panicking if random generation fails
 // This is for internal use in setup/testing where crypto failure should be fatal
 func NewRandPoint() *bn256.G1 {
@@ -34,11 +71,11 @@ func NewRandScalar() *big.Int {
 }
 
 // SecureRandScalar generates a cryptographically secure random scalar with entropy validation
-// Uses rejection sampling for unbiased field element generation
-func SecureRandScalar() (*big.Int, error) {
+// Uses rejection sampling for unbiased field element generation. See WithRandReader for another random source.
+func SecureRandScalar(opts ...RandOption) (*big.Int, error) {
 	// Use 64 bytes of entropy for extra security margin
 	entropy := make([]byte, 64)
-	if _, err := rand.Read(entropy); err != nil {
+	if _, err := io.ReadFull(newRandReader(opts), entropy); err != nil {
 		return nil, fmt.Errorf("insufficient entropy for scalar generation: %w", err)
 	}
 
@@ -59,9 +96,9 @@ func SecureRandScalar() (*big.Int, error) {
 }
 
 // SecureRandPoint generates a cryptographically secure random group element with validation
-func SecureRandPoint() (*bn256.G1, error) {
+func SecureRandPoint(opts ...RandOption) (*bn256.G1, error) {
 	// Generate secure scalar first
-	scalar, err := SecureRandScalar()
+	scalar, err := SecureRandScalar(opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate scalar for point: %w", err)
 	}
@@ -90,6 +127,93 @@ func SecureRandPoint() (*bn256.G1, error) {
 	return point, nil
 }
 
+// RandScalarVector returns n random scalars generated with SecureRandScalar(opts...).
+func RandScalarVector(n int, opts ...RandOption) ([]*big.Int, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("invalid vector length %d", n)
+	}
+
+	res := make([]*big.Int, n)
+	for i := range res {
+		var err error
+		if res[i], err = SecureRandScalar(opts...); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// RandPointVector returns n random points generated with SecureRandPoint(opts...).
+func RandPointVector(n int, opts ...RandOption) ([]*bn256.G1, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("invalid vector length %d", n)
+	}
+
+	res := make([]*bn256.G1, n)
+	for i := range res {
+		var err error
+		if res[i], err = SecureRandPoint(opts...); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// ErrBlindingKeyTooShort is returned by DeterministicBlinding for a secret key shorter than minBlindingKeyLen bytes.
+var ErrBlindingKeyTooShort = errors.New("blinding key is too short")
+
+// minBlindingKeyLen is the shortest secret key DeterministicBlinding accepts, 32 bytes to match its 256-bit HMAC.
+const minBlindingKeyLen = 32
+
+// DeterministicBlinding derives a blinding factor in [1, bn256.Order) from a secret key and the committed value,
+// following the HMAC-SHA256 nonce generation of RFC 6979, section 3.2. The seed is len(secretKey) as 4 bytes
+// big-endian, secretKey and value, so different (secretKey, value) pairs never share a seed. The same inputs always
+// give the same blinding, so nodes sharing the key commit to a value identically. Only the commitment is
+// deterministic: the proofs draw their other blindings from the random source, so proofs of the same value differ.
+//
+// Anyone who knows the key can recompute the blinding and open the commitment, so the key must stay secret and have
+// at least 32 bytes of entropy. Keys shorter than 32 bytes are rejected with ErrBlindingKeyTooShort; without a key
+// the blinding would follow from the value alone.
+func DeterministicBlinding(secretKey, value []byte) (*big.Int, error) {
+	if len(secretKey) < minBlindingKeyLen {
+		return nil, fmt.Errorf("%w: %d bytes, need at least %d", ErrBlindingKeyTooShort, len(secretKey), minBlindingKeyLen)
+	}
+
+	seed := binary.BigEndian.AppendUint32(nil, uint32(len(secretKey)))
+	seed = append(append(seed, secretKey...), value...)
+
+	hmacSum := func(key []byte, data ...[]byte) []byte {
+		mac := hmac.New(sha256.New, key)
+		for _, d := range data {
+			mac.Write(d)
+		}
+		return mac.Sum(nil)
+	}
+
+	v := bytes.Repeat([]byte{0x01}, sha256.Size)
+	k := make([]byte, sha256.Size)
+
+	k = hmacSum(k, v, []byte{0x00}, seed)
+	v = hmacSum(k, v)
+	k = hmacSum(k, v, []byte{0x01}, seed)
+	v = hmacSum(k, v)
+
+	for {
+		v = hmacSum(k, v)
+
+		// bits2int: keep the leftmost qlen bits
+		t := new(big.Int).SetBytes(v)
+		t.Rsh(t, uint(8*sha256.Size-bn256.Order.BitLen()))
+
+		if t.Sign() > 0 && t.Cmp(bn256.Order) < 0 {
+			return t, nil
+		}
+
+		k = hmacSum(k, v, []byte{0x00})
+		v = hmacSum(k, v)
+	}
+}
+
 // hashToScalarWithRejection uses rejection sampling to generate unbiased field elements
 func hashToScalarWithRejection(entropy []byte) (*big.Int, error) {
 	// Maximum attempts to prevent infinite loops
@@ -0,0 +1,171 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"bytes"
+	"errors"
+	"github.com/cloudflare/bn256"
+	"io"
+	"math/big"
+	"testing"
+)
+
+func TestRandVectors(t *testing.T) {
+	scalars, err := RandScalarVector(4)
+	if err != nil {
+		t.Fatalf("RandScalarVector failed: %v", err)
+	}
+
+	if len(scalars) != 4 || scalars[0].Cmp(scalars[1]) == 0 {
+		t.Errorf("Unexpected scalars: %v", scalars)
+	}
+
+	for i, s := range scalars {
+		if !isCanonicalScalar(s) {
+			t.Errorf("Scalar %d is not reduced: %v", i, s)
+		}
+	}
+
+	points, err := RandPointVector(3)
+	if err != nil {
+		t.Fatalf("RandPointVector failed: %v", err)
+	}
+
+	if len(points) != 3 || pointsEqual(points[0], points[1]) {
+		t.Errorf("Unexpected points: %v", points)
+	}
+
+	if _, err := RandScalarVector(-1); err == nil {
+		t.Error("Expected an error for a negative length")
+	}
+
+	// Both use the package RNG reader.
+	defer func(r io.Reader) { randReader = r }(randReader)
+
+	randReader = bytes.NewReader(make([]byte, 64))
+	if _, err := RandScalarVector(1); err == nil {
+		t.Error("All-zero entropy accepted")
+	}
+
+	randReader = bytes.NewReader(nil)
+	if _, err := RandPointVector(1); err == nil {
+		t.Error("Exhausted reader accepted")
+	}
+}
+
+func TestWithRandReader(t *testing.T) {
+	seeded := func() RandOption { return WithRandReader(&katReader{seed: []byte("rand-reader")}) }
+
+	a, err := RandScalarVector(3, seeded())
+	if err != nil {
+		t.Fatalf("RandScalarVector failed: %v", err)
+	}
+
+	b, err := RandScalarVector(3, seeded())
+	if err != nil {
+		t.Fatalf("RandScalarVector failed: %v", err)
+	}
+
+	for i := range a {
+		if a[i].Cmp(b[i]) != 0 {
+			t.Errorf("Scalar %d differs for the same seeded reader", i)
+		}
+	}
+
+	p, err := RandPointVector(2, seeded())
+	if err != nil {
+		t.Fatalf("RandPointVector failed: %v", err)
+	}
+
+	if !pointsEqual(p[0], new(bn256.G1).ScalarBaseMult(a[0])) {
+		t.Error("RandPointVector did not draw from the reader")
+	}
+
+	if _, err := SecureRandScalar(WithRandReader(bytes.NewReader(make([]byte, 64)))); err == nil {
+		t.Error("All-zero entropy accepted")
+	}
+
+	if _, err := SecureRandPoint(WithRandReader(bytes.NewReader(nil))); err == nil {
+		t.Error("Exhausted reader accepted")
+	}
+
+	// A nil reader keeps the default source.
+	if _, err := RandScalarVector(2, WithRandReader(nil)); err != nil {
+		t.Errorf("RandScalarVector failed with a nil reader: %v", err)
+	}
+}
+
+func TestDeterministicBlinding(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef!")
+
+	blinding := func(key, value []byte) *big.Int {
+		s, err := DeterministicBlinding(key, value)
+		if err != nil {
+			t.Fatalf("DeterministicBlinding failed: %v", err)
+		}
+		return s
+	}
+
+	s := blinding(key, []byte{0x2a})
+	if s.Sign() <= 0 || s.Cmp(bn256.Order) >= 0 {
+		t.Fatalf("Blinding %v out of range", s)
+	}
+
+	if blinding(key, []byte{0x2a}).Cmp(s) != 0 {
+		t.Error("Blinding is not deterministic")
+	}
+
+	for name, other := range map[string]*big.Int{
+		"other value":  blinding(key, []byte{0x2b}),
+		"other key":    blinding(append([]byte{0x00}, key[1:]...), []byte{0x2a}),
+		"moved border": blinding(key[:32], append([]byte{key[32]}, 0x2a)),
+	} {
+		if other.Cmp(s) == 0 {
+			t.Errorf("Same blinding for %s", name)
+		}
+	}
+
+	for _, short := range [][]byte{nil, {}, key[:31]} {
+		if _, err := DeterministicBlinding(short, []byte{0x2a}); !errors.Is(err, ErrBlindingKeyTooShort) {
+			t.Errorf("Expected ErrBlindingKeyTooShort for a %d-byte key, got %v", len(short), err)
+		}
+	}
+
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	a, err := public.DeterministicPrivate(key, bint(0xbeef))
+	if err != nil {
+		t.Fatalf("DeterministicPrivate failed: %v", err)
+	}
+
+	b, err := public.DeterministicPrivate(key, bint(0xbeef))
+	if err != nil {
+		t.Fatalf("DeterministicPrivate failed: %v", err)
+	}
+
+	com := public.CommitValue(a.X, a.S)
+	if !pointsEqual(com, public.CommitValue(b.X, b.S)) {
+		t.Fatal("Nodes with the same key derived different commitments")
+	}
+
+	if _, err := public.DeterministicPrivate(key[:16], bint(0xbeef)); !errors.Is(err, ErrBlindingKeyTooShort) {
+		t.Errorf("Expected ErrBlindingKeyTooShort from DeterministicPrivate, got %v", err)
+	}
+
+	for _, private := range []*ReciprocalPrivate{a, b} {
+		proof, err := ProveRange(public, NewKeccakFS(), private)
+		if err != nil {
+			t.Fatalf("ProveRange failed: %v", err)
+		}
+
+		if err := VerifyRange(public, com, NewKeccakFS(), proof); err != nil {
+			t.Fatalf("VerifyRange failed for the shared commitment: %v", err)
+		}
+	}
+}
@@ -5,6 +5,8 @@
 package bulletproofs
 
 import (
+	"crypto/subtle"
+	"encoding/binary"
 	"github.com/cloudflare/bn256"
 	"math/big"
 )
@@ -33,6 +35,82 @@ func powerOfTwo(x int) (p2 int) {
 	return
 }
 
+// isCanonicalScalar reports whether x is a reduced field element in [0, bn256.Order).
+func isCanonicalScalar(x *big.Int) bool {
+	return x != nil && x.Sign() >= 0 && x.Cmp(bn256.Order) < 0
+}
+
+// ConditionalSelect returns a if cond is 1 and b if cond is 0, following crypto/subtle semantics; cond must be 0
+// or 1. The selection is done without branching on the fixed 32-byte encodings of the reduced scalars. big.Int
+// arithmetic itself is not constant time, so this only keeps the choice of cond from leaking through the selection.
+func ConditionalSelect(cond int, a, b *big.Int) *big.Int {
+	res := scalarTo32Byte(add(b, nil))
+	subtle.ConstantTimeCopy(cond, res, scalarTo32Byte(add(a, nil)))
+	return new(big.Int).SetBytes(res)
+}
+
+// HashToScalar deterministically hashes the domain and data to a field element mod bn256.Order. Two Keccak256
+// outputs give 512 bits that are reduced mod the order, as for the generator derivation scalars, so the result is
+// uniform up to a negligible bias. Every input is prefixed with its length, so moving bytes between the domain and
+// the data or between data items changes the result. Use a distinct domain for every purpose.
+func HashToScalar(domain string, data ...[]byte) *big.Int {
+	input := make([][]byte, 0, 2*len(data)+4)
+
+	appendPart := func(part []byte) {
+		input = append(input, binary.BigEndian.AppendUint64(nil, uint64(len(part))), part)
+	}
+
+	appendPart([]byte(domain))
+	for _, d := range data {
+		appendPart(d)
+	}
+
+	wide := append(
+		Keccak256(append(input, []byte{0x00})...),
+		Keccak256(append(input, []byte{0x01})...)...,
+	)
+
+	return new(big.Int).Mod(new(big.Int).SetBytes(wide), bn256.Order)
+}
+
+// ScalarAdd returns x+y mod bn256.Order, with the same reduction the package uses internally. Like all Scalar*
+// helpers it accepts unreduced and negative inputs, treats a nil operand as zero and always returns a new value in
+// [0, bn256.Order).
+func ScalarAdd(x, y *big.Int) *big.Int {
+	return add(x, y)
+}
+
+// ScalarSub returns x-y mod bn256.Order, see ScalarAdd.
+func ScalarSub(x, y *big.Int) *big.Int {
+	return sub(x, y)
+}
+
+// ScalarMul returns x*y mod bn256.Order, see ScalarAdd.
+func ScalarMul(x, y *big.Int) *big.Int {
+	return mul(x, y)
+}
+
+// ScalarNeg returns -x mod bn256.Order, see ScalarAdd. The negation of zero is zero.
+func ScalarNeg(x *big.Int) *big.Int {
+	return minus(x)
+}
+
+// ScalarPow returns base^exp mod bn256.Order by square-and-multiply, reducing after every step. A nil base is
+// treated as zero and base^0 is 1.
+func ScalarPow(base *big.Int, exp uint) *big.Int {
+	res := bint(1)
+	sq := add(base, nil)
+
+	for ; exp > 0; exp >>= 1 {
+		if exp&1 == 1 {
+			res = mul(res, sq)
+		}
+		sq = mul(sq, sq)
+	}
+
+	return res
+}
+
 func bint(v int) *big.Int {
 	return new(big.Int).Mod(new(big.Int).SetInt64(int64(v)), bn256.Order)
 }
@@ -0,0 +1,106 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"testing"
+)
+
+func TestConditionalSelect(t *testing.T) {
+	a, b := minus(bint(5)), bint(7)
+
+	if got := ConditionalSelect(1, a, b); got.Cmp(a) != 0 {
+		t.Errorf("cond=1: expected %v, got %v", a, got)
+	}
+
+	if got := ConditionalSelect(0, a, b); got.Cmp(b) != 0 {
+		t.Errorf("cond=0: expected %v, got %v", b, got)
+	}
+
+	// Inputs are reduced before selecting.
+	if got := ConditionalSelect(1, big.NewInt(-5), b); got.Cmp(a) != 0 {
+		t.Errorf("Unreduced input: expected %v, got %v", a, got)
+	}
+}
+
+func TestScalarPow(t *testing.T) {
+	bases := []*big.Int{bint(0), bint(1), bint(2), NewRandScalar(), minus(bint(1)), new(big.Int).Add(bn256.Order, bint(3))}
+	exps := []uint{0, 1, 2, 3, 64, 1000, 1<<31 + 7, ^uint(0)}
+
+	for _, base := range bases {
+		for _, exp := range exps {
+			expected := new(big.Int).Exp(base, new(big.Int).SetUint64(uint64(exp)), bn256.Order)
+			if res := ScalarPow(base, exp); res.Cmp(expected) != 0 {
+				t.Errorf("ScalarPow(%v, %d) = %v, expected %v", base, exp, res, expected)
+			}
+		}
+	}
+
+	if ScalarPow(nil, 5).Sign() != 0 {
+		t.Error("Expected zero for a nil base")
+	}
+}
+
+func TestHashToScalar(t *testing.T) {
+	x := HashToScalar("test", []byte("data"))
+	if !isCanonicalScalar(x) {
+		t.Fatalf("Result is not reduced: %v", x)
+	}
+
+	if HashToScalar("test", []byte("data")).Cmp(x) != 0 {
+		t.Error("HashToScalar is not deterministic")
+	}
+
+	// Inputs are length-prefixed, so regrouping the same bytes changes the result.
+	for name, y := range map[string]*big.Int{
+		"domain":  HashToScalar("other", []byte("data")),
+		"split":   HashToScalar("test", []byte("da"), []byte("ta")),
+		"shifted": HashToScalar("testd", []byte("ata")),
+		"empty":   HashToScalar("test", []byte("data"), nil),
+	} {
+		if y.Cmp(x) == 0 {
+			t.Errorf("%s: expected a different scalar", name)
+		}
+	}
+}
+
+func TestScalarArithmetic(t *testing.T) {
+	q := bn256.Order
+	qm1 := new(big.Int).Sub(q, bint(1))
+
+	for _, tc := range []struct {
+		name     string
+		got      *big.Int
+		expected *big.Int
+	}{
+		{"(q-1)+1", ScalarAdd(qm1, bint(1)), bint(0)},
+		{"(q-1)+(q-1)", ScalarAdd(qm1, qm1), new(big.Int).Sub(q, bint(2))},
+		{"q+5", ScalarAdd(q, bint(5)), bint(5)},
+		{"-1+0", ScalarAdd(big.NewInt(-1), nil), qm1},
+		{"0-1", ScalarSub(bint(0), bint(1)), qm1},
+		{"q-q", ScalarSub(q, q), bint(0)},
+		{"nil-nil", ScalarSub(nil, nil), bint(0)},
+		{"(q-1)*(q-1)", ScalarMul(qm1, qm1), bint(1)},
+		{"q*7", ScalarMul(q, bint(7)), bint(0)},
+		{"nil*7", ScalarMul(nil, bint(7)), bint(0)},
+		{"-1", ScalarNeg(bint(1)), qm1},
+		{"-0", ScalarNeg(bint(0)), bint(0)},
+		{"-q", ScalarNeg(q), bint(0)},
+		{"-(q-1)", ScalarNeg(qm1), bint(1)},
+		{"-nil", ScalarNeg(nil), bint(0)},
+	} {
+		if tc.got.Cmp(tc.expected) != 0 {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.expected, tc.got)
+		}
+	}
+
+	// The results are new values, the inputs stay untouched.
+	x := new(big.Int).Set(qm1)
+	if ScalarAdd(x, bint(1)) == x || x.Cmp(qm1) != 0 {
+		t.Error("ScalarAdd modified its input")
+	}
+}
@@ -5,11 +5,20 @@
 package bulletproofs
 
 import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
 	"github.com/cloudflare/bn256"
 	"math/big"
+	"sync"
 )
 
 // For scalars *big.Int
+//
+// Vectors of different lengths are treated as padded with zeros. The helpers never append to their arguments:
+// a slice with spare capacity shares its backing array with the caller, so padding in place would overwrite the
+// caller's data.
 
 func zeroVector(n int) []*big.Int {
 	res := make([]*big.Int, n)
@@ -28,34 +37,18 @@ func oneVector(n int) []*big.Int {
 }
 
 func vectorAdd(a []*big.Int, b []*big.Int) []*big.Int {
-	for len(a) < len(b) {
-		a = append(a, bint(0))
-	}
-
-	for len(b) < len(a) {
-		b = append(b, bint(0))
-	}
-
-	res := make([]*big.Int, len(a))
+	res := make([]*big.Int, max(len(a), len(b)))
 	for i := 0; i < len(res); i++ {
-		res[i] = add(a[i], b[i])
+		res[i] = add(scalarAt(a, i), scalarAt(b, i))
 	}
 
 	return res
 }
 
 func vectorSub(a []*big.Int, b []*big.Int) []*big.Int {
-	for len(a) < len(b) {
-		a = append(a, bint(0))
-	}
-
-	for len(b) < len(a) {
-		b = append(b, bint(0))
-	}
-
-	res := make([]*big.Int, len(a))
+	res := make([]*big.Int, max(len(a), len(b)))
 	for i := 0; i < len(res); i++ {
-		res[i] = sub(a[i], b[i])
+		res[i] = sub(scalarAt(a, i), scalarAt(b, i))
 	}
 
 	return res
@@ -70,34 +63,18 @@ func vectorMulOnScalar(a []*big.Int, c *big.Int) []*big.Int {
 }
 
 func vectorMul(a []*big.Int, b []*big.Int) *big.Int {
-	for len(a) < len(b) {
-		a = append(a, bint(0))
-	}
-
-	for len(b) < len(a) {
-		b = append(b, bint(0))
-	}
-
 	res := big.NewInt(0)
-	for i := 0; i < len(a); i++ {
+	for i := 0; i < min(len(a), len(b)); i++ {
 		res = add(res, mul(a[i], b[i]))
 	}
 	return res
 }
 
 func weightVectorMul(a []*big.Int, b []*big.Int, mu *big.Int) *big.Int {
-	for len(a) < len(b) {
-		a = append(a, bint(0))
-	}
-
-	for len(b) < len(a) {
-		b = append(b, bint(0))
-	}
-
 	res := big.NewInt(0)
-	exp := new(big.Int).Set(mu)
+	exp := add(mu, nil)
 
-	for i := 0; i < len(a); i++ {
+	for i := 0; i < min(len(a), len(b)); i++ {
 		res = add(res, mul(mul(a[i], b[i]), exp))
 		exp = mul(exp, mu)
 	}
@@ -111,48 +88,126 @@ func vectorPointScalarMul(g []*bn256.G1, a []*big.Int) *bn256.G1 {
 		return new(bn256.G1).ScalarBaseMult(bint(0))
 	}
 
-	for len(a) < len(g) {
-		a = append(a, bint(0))
-	}
-
-	res := new(bn256.G1).ScalarMult(g[0], a[0])
+	// bn256 scalar multiplication expects a non-negative scalar, so reduce first.
+	res := new(bn256.G1).ScalarMult(g[0], add(scalarAt(a, 0), nil))
 	for i := 1; i < len(g); i++ {
-		res.Add(res, new(bn256.G1).ScalarMult(g[i], a[i]))
+		res.Add(res, new(bn256.G1).ScalarMult(g[i], add(scalarAt(a, i), nil)))
 	}
 	return res
 }
 
-func vectorPointsAdd(a, b []*bn256.G1) []*bn256.G1 {
-	for len(a) < len(b) {
-		a = append(a, new(bn256.G1).ScalarBaseMult(bint(0)))
-	}
+// pointsEqual compares two points by their marshaled affine encoding. The comparison relies on bn256.G1.Marshal
+// being canonical: it normalizes the point to affine coordinates, writes both coordinates reduced to [0, p) and the
+// identity as 64 zero bytes, so equal points always marshal to the same bytes and different points never do. The
+// comparison runs in constant time. In verification both operands are public, so timing does not leak anything
+// there, but the helper is safe to use with secret-dependent points as well.
+func pointsEqual(a, b *bn256.G1) bool {
+	return subtle.ConstantTimeCompare(a.Marshal(), b.Marshal()) == 1
+}
 
-	for len(b) < len(a) {
-		b = append(b, new(bn256.G1).ScalarBaseMult(bint(0)))
-	}
+// scalarMultBits is the number of ladder steps of maskedScalarMult, enough for every reduced scalar.
+var scalarMultBits = bn256.Order.BitLen()
+
+// ladderMask holds the start point A of maskedScalarMult, derived like a generator so that nobody knows its discrete
+// logarithm with respect to any point a caller passes in or to another mask, and the multiple 2^scalarMultBits*A
+// every result carries.
+type ladderMask struct {
+	start, end *bn256.G1
+}
 
-	res := make([]*bn256.G1, len(a))
+// scalarMultMasks returns two independent masks, so that the masked products of a sum differ in their masks.
+var scalarMultMasks = sync.OnceValue(func() [2]ladderMask {
+	var res [2]ladderMask
 	for i := range res {
-		res[i] = new(bn256.G1).Add(a[i], b[i])
+		start, err := DeriveGenerator(DOMAIN_RANGE, fmt.Sprintf("constant-time-ladder-mask-%d", i))
+		if err != nil {
+			// The labels are fixed, so the derivation either always succeeds or always fails.
+			panic(err)
+		}
+
+		end := new(bn256.G1).Set(start)
+		for j := 0; j < scalarMultBits; j++ {
+			end.Add(end, end)
+		}
+
+		res[i] = ladderMask{start: start, end: end}
 	}
 	return res
+})
+
+// maskedScalarMult returns k*p + M for the public point M = mask.end with a sequence of point operations that does
+// not depend on k, unlike bn256.G1.ScalarMult, whose double-and-add loop runs for the bit length of k and only adds
+// for its one bits. Every one of the scalarMultBits steps doubles and adds, and the bit only selects which of the
+// two results is kept.
+//
+// bn256 point addition takes shortcuts for the identity and for equal operands. The accumulator starts at the mask
+// point A instead of the identity, so before step i it is 2^i*A + m*p for a prefix m of k; it is the identity, or
+// the sum of a step has equal or opposite operands, only if 2^i*A is a small multiple of p, which would reveal the
+// discrete logarithm of A. The mask stays in the result for the same reason: adding two results where one is k*p
+// for k = 0 would take the identity shortcut, adding two results k*p + M0 and k'*p' + M1 with different masks
+// does not. Callers compare against targets with the masks added instead.
+//
+// onAdd, if not nil, sees the operands of every addition, in order, for tests. The field arithmetic of bn256 runs in
+// constant time. k is reduced first; a reduction of an unreduced k and the word length of k are visible to a timing
+// attacker, as with any *big.Int.
+func maskedScalarMult(p *bn256.G1, k *big.Int, mask ladderMask, onAdd func(a, b *bn256.G1)) *bn256.G1 {
+	kk := add(k, nil)
+
+	r := new(bn256.G1).Set(mask.start)
+	for i := scalarMultBits - 1; i >= 0; i-- {
+		if onAdd != nil {
+			onAdd(r, r)
+		}
+		doubled := new(bn256.G1).Add(r, r)
+
+		if onAdd != nil {
+			onAdd(doubled, p)
+		}
+		sum := new(bn256.G1).Add(doubled, p)
+
+		r = [2]*bn256.G1{doubled, sum}[kk.Bit(i)]
+	}
+
+	return r
 }
 
-func vectorPointMulOnScalar(g []*bn256.G1, a *big.Int) []*bn256.G1 {
-	res := make([]*bn256.G1, len(g))
-	for i := range res {
-		res[i] = new(bn256.G1).ScalarMult(g[i], a)
+// ErrNonCanonicalPoint is returned when a point encoding is not the one bn256.G1.Marshal produces for it.
+var ErrNonCanonicalPoint = errors.New("point encoding is not canonical")
+
+// unmarshalPoint decodes a 64-byte point and rejects every encoding other than the canonical one. bn256.G1.Unmarshal
+// does not reduce the coordinates, so x+p and x encode the same point; accepting both would let anyone change the
+// bytes of a proof without changing the proof.
+func unmarshalPoint(data []byte) (*bn256.G1, error) {
+	p := new(bn256.G1)
+	if _, err := p.Unmarshal(data); err != nil {
+		return nil, err
 	}
-	return res
+	if len(data) != pointSize || !bytes.Equal(p.Marshal(), data) {
+		return nil, ErrNonCanonicalPoint
+	}
+	return p, nil
 }
 
-func vectorTensorMul(a, b []*big.Int) []*big.Int {
-	res := make([]*big.Int, 0, len(a)*len(b))
+// AddPointVectors returns the element-wise sum a[i]+b[i] of two point vectors of the same length. It runs the same
+// point addition the WNLA uses to fold generator vectors.
+func AddPointVectors(a, b []*bn256.G1) ([]*bn256.G1, error) {
+	if len(a) != len(b) {
+		return nil, fmt.Errorf("vector lengths differ: %d and %d", len(a), len(b))
+	}
 
-	for i := range b {
-		res = append(res, vectorMulOnScalar(a, b[i])...)
+	for i := range a {
+		if a[i] == nil || b[i] == nil {
+			return nil, fmt.Errorf("point %d cannot be nil", i)
+		}
 	}
-	return res
+
+	return toG1s(groupVectorPointsAdd(BN256, toPoints(a), toPoints(b))), nil
+}
+
+// ScalePointVector returns s*v[i] for every point of v, with s reduced modulo bn256.Order. It runs the same
+// scalar multiplication the WNLA uses to fold generator vectors. The points must not be nil.
+func ScalePointVector(v []*bn256.G1, s *big.Int) []*bn256.G1 {
+	return toG1s(groupVectorPointMulOnScalar(BN256, toPoints(v), s))
 }
 
 func e(v *big.Int, a int) []*big.Int {
@@ -166,6 +221,15 @@ func e(v *big.Int, a int) []*big.Int {
 	return res
 }
 
+// Hadamard returns the element-wise product a[i] * b[i] mod bn256.Order. The vectors must have the same length.
+func Hadamard(a, b []*big.Int) ([]*big.Int, error) {
+	if len(a) != len(b) {
+		return nil, fmt.Errorf("vector lengths %d and %d do not match", len(a), len(b))
+	}
+
+	return hadamardMul(a, b), nil
+}
+
 func hadamardMul(a, b []*big.Int) []*big.Int {
 	res := make([]*big.Int, len(a))
 	for i := range res {
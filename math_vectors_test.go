@@ -0,0 +1,178 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"bytes"
+	"errors"
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"testing"
+)
+
+func TestHadamard(t *testing.T) {
+	a := []*big.Int{bint(1), bint(2), minus(bint(3))}
+	b := []*big.Int{bint(4), bint(5), bint(6)}
+
+	res, err := Hadamard(a, b)
+	if err != nil {
+		t.Fatalf("Hadamard failed: %v", err)
+	}
+
+	expected := []*big.Int{bint(4), bint(10), minus(bint(18))}
+	for i := range expected {
+		if res[i].Cmp(expected[i]) != 0 {
+			t.Errorf("res[%d] = %v, expected %v", i, res[i], expected[i])
+		}
+	}
+
+	if _, err := Hadamard(a, b[:2]); err == nil {
+		t.Error("Expected an error for vectors of different lengths")
+	}
+}
+
+func TestPointVectorHelpers(t *testing.T) {
+	v := []*bn256.G1{NewRandPoint(), NewRandPoint(), NewRandPoint(), NewRandPoint()}
+	v0, v1 := reduceVector(v)
+	a, b := NewRandScalar(), NewRandScalar()
+
+	sum, err := AddPointVectors(ScalePointVector(v0, a), ScalePointVector(v1, b))
+	if err != nil {
+		t.Fatalf("AddPointVectors failed: %v", err)
+	}
+
+	folded := FoldPoints(v, a, b)
+	for i := range folded {
+		if !pointsEqual(sum[i], folded[i]) {
+			t.Errorf("Entry %d differs from FoldPoints", i)
+		}
+	}
+
+	// Scalars are reduced, so unreduced and negative scalars are accepted.
+	unreduced := ScalePointVector(v, new(big.Int).Add(a, bn256.Order))
+	negative := ScalePointVector(v, new(big.Int).Sub(a, bn256.Order))
+	for i, p := range ScalePointVector(v, a) {
+		if !pointsEqual(p, unreduced[i]) || !pointsEqual(p, negative[i]) {
+			t.Errorf("Entry %d depends on the representation of the scalar", i)
+		}
+	}
+
+	if _, err := AddPointVectors(v, v[1:]); err == nil {
+		t.Error("Expected an error for vectors of different lengths")
+	}
+
+	if _, err := AddPointVectors(v, []*bn256.G1{v[0], nil, v[2], v[3]}); err == nil {
+		t.Error("Expected an error for a nil point")
+	}
+}
+
+func TestCanonicalPointEncoding(t *testing.T) {
+	// Base field modulus of bn256.
+	fieldP, _ := new(big.Int).SetString("65000549695646603732796438742359905742825358107623003571877145026864184071783", 10)
+
+	x := NewRandScalar()
+	a := new(bn256.G1).ScalarBaseMult(x)
+
+	// The same point reached through different projective representations marshals to the same bytes.
+	b := new(bn256.G1).Add(new(bn256.G1).ScalarBaseMult(sub(x, bint(1))), new(bn256.G1).ScalarBaseMult(bint(1)))
+	if !pointsEqual(a, b) {
+		t.Fatal("Equal points marshal differently")
+	}
+
+	decoded, err := unmarshalPoint(a.Marshal())
+	if err != nil {
+		t.Fatalf("unmarshalPoint failed: %v", err)
+	}
+	if !pointsEqual(a, decoded) {
+		t.Error("Decoded point differs")
+	}
+
+	if _, err := unmarshalPoint(make([]byte, pointSize)); err != nil {
+		t.Errorf("Identity rejected: %v", err)
+	}
+
+	// Adding p to a coordinate encodes the same point, which bn256.G1.Unmarshal accepts. The generator (1, 2)
+	// leaves room for x+p in 32 bytes.
+	enc := new(bn256.G1).ScalarBaseMult(bint(1)).Marshal()
+	shifted := new(big.Int).Add(new(big.Int).SetBytes(enc[:32]), fieldP)
+	malleated := append(shifted.FillBytes(make([]byte, 32)), enc[32:]...)
+
+	if _, err := new(bn256.G1).Unmarshal(malleated); err != nil {
+		t.Fatalf("bn256 rejected the shifted encoding, the check below is not exercised: %v", err)
+	}
+
+	if _, err := unmarshalPoint(malleated); !errors.Is(err, ErrNonCanonicalPoint) {
+		t.Errorf("Expected ErrNonCanonicalPoint, got %v", err)
+	}
+
+	if _, err := BN256.Unmarshal(malleated); !errors.Is(err, ErrNonCanonicalPoint) {
+		t.Errorf("Expected ErrNonCanonicalPoint from the group, got %v", err)
+	}
+
+	if _, err := readPoint(bytes.NewReader(malleated)); !errors.Is(err, ErrNonCanonicalPoint) {
+		t.Errorf("Expected ErrNonCanonicalPoint from the stream decoder, got %v", err)
+	}
+}
+
+func TestScalarMultConstantTime(t *testing.T) {
+	p := new(bn256.G1).ScalarBaseMult(bint(7))
+	order := new(big.Int).Set(bn256.Order)
+
+	for _, mask := range scalarMultMasks() {
+		for _, k := range []*big.Int{
+			bint(0),
+			bint(1),
+			bint(2),
+			new(big.Int).Sub(order, bint(1)),
+			order,
+			big.NewInt(-5),
+			new(big.Int).Lsh(bint(1), 300),
+			NewRandScalar(),
+		} {
+			expected := new(bn256.G1).ScalarMult(p, add(k, nil))
+			expected.Add(expected, mask.end)
+
+			if !pointsEqual(maskedScalarMult(p, k, mask, nil), expected) {
+				t.Errorf("maskedScalarMult differs from ScalarMult for %s", k)
+			}
+		}
+	}
+}
+
+// TestScalarMultIdentityHits counts the additions of the ladder that take a shortcut in bn256: an operand that is
+// the identity, equal operands outside of a doubling, or opposite operands. Small scalars used to run into them.
+func TestScalarMultIdentityHits(t *testing.T) {
+	identity := new(bn256.G1).ScalarBaseMult(bint(0))
+	isIdentity := func(a *bn256.G1) bool {
+		return pointsEqual(a, identity)
+	}
+
+	for _, p := range []*bn256.G1{
+		new(bn256.G1).ScalarBaseMult(bint(1)),
+		new(bn256.G1).ScalarBaseMult(bint(7)),
+	} {
+		for k := 0; k < 32; k++ {
+			hits := 0
+			onAdd := func(a, b *bn256.G1) {
+				doubling := a == b
+				if isIdentity(a) || isIdentity(b) || (!doubling && pointsEqual(a, b)) ||
+					isIdentity(new(bn256.G1).Add(a, b)) {
+					hits++
+				}
+			}
+
+			masks := scalarMultMasks()
+			sum := maskedScalarMult(p, bint(k), masks[0], onAdd)
+			other := maskedScalarMult(p, bint(k), masks[1], onAdd)
+
+			// The combination of VerifyOpeningConstantTime, e.g. for a zero value and blinding.
+			onAdd(sum, other)
+
+			if hits != 0 {
+				t.Errorf("k=%d: %d additions hit the identity or equal operands", k, hits)
+			}
+		}
+	}
+}
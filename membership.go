@@ -0,0 +1,217 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+)
+
+// checkSetSize ensures a set of n elements fits into the range proof parameters: the set occupies n witness slots
+// of HVec and one multiplication gate, backed by GVec, per element.
+func checkSetSize(public *ReciprocalPublic, n int) error {
+	if n < 1 || n > public.Nd {
+		return fmt.Errorf("set size %d out of range [1, %d]", n, public.Nd)
+	}
+	return nil
+}
+
+// CommitSet creates the vector commitment to the set elements used by ProveMembershipCommitted:
+// SetCom = blinding*HVec[0] + <elements, HVec[circuitBlindingSlots:]>. It is the circuit commitment of
+// v = [0, elements...], so sets of up to Nd elements are supported.
+func (p *ReciprocalPublic) CommitSet(elements []*big.Int, blinding *big.Int) (*bn256.G1, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := checkSetSize(p, len(elements)); err != nil {
+		return nil, err
+	}
+
+	if blinding == nil {
+		return nil, errors.New("blinding cannot be nil")
+	}
+
+	for i := range elements {
+		if elements[i] == nil {
+			return nil, fmt.Errorf("set element %d cannot be nil", i)
+		}
+	}
+
+	res := new(bn256.G1).ScalarMult(p.HVec[0], add(blinding, nil))
+	res.Add(res, vectorPointScalarMul(p.HVec[circuitBlindingSlots:circuitBlindingSlots+len(elements)], elements))
+	return res, nil
+}
+
+// membershipCircuit builds the circuit proving that the value x committed in V[0] = CommitValue(x, s) is one of
+// the n elements e_j committed in V[1] = CommitSet(e, s'). It shows prod(x - e_j) = 0 with a chain of n
+// multiplication gates: wl[0] = 1, wl[j]*wr[j] = wl[j+1] and wl[n-1]*wr[n-1] = 0, where wr[j] = x - e_j. The
+// o-wires hold x and the elements and are linked to the committed vectors v_0 = [x, 0...] and v_1 = [0, e...].
+func membershipCircuit(public *ReciprocalPublic, n int) *ArithmeticCircuitPublic {
+	Nm := n
+	No := n + 1
+	Nv := n + 1
+	Nw := Nm + Nm + No
+
+	Wm := zeroMatrix(Nm, Nw)
+	for j := 0; j+1 < Nm; j++ {
+		Wm[j][j+1] = bint(1)
+	}
+
+	// Rows for v_0, rows for v_1, one row per difference and the row fixing wl[0].
+	Nl := 2*Nv + n + 1
+	Wl := zeroMatrix(Nl, Nw)
+	al := zeroVector(Nl)
+
+	// v_0[0] = x
+	Wl[0][2*Nm] = minus(bint(1))
+
+	// v_1[j+1] = e_j
+	for j := 0; j < n; j++ {
+		Wl[Nv+1+j][2*Nm+1+j] = minus(bint(1))
+	}
+
+	// wr[j] - x + e_j = 0
+	for j := 0; j < n; j++ {
+		row := 2*Nv + j
+		Wl[row][Nm+j] = bint(1)
+		Wl[row][2*Nm] = minus(bint(1))
+		Wl[row][2*Nm+1+j] = bint(1)
+	}
+
+	// wl[0] - 1 = 0
+	Wl[Nl-1][0] = bint(1)
+	al[Nl-1] = minus(bint(1))
+
+	hLen := Nv + circuitBlindingSlots
+
+	return &ArithmeticCircuitPublic{
+		Nm:   Nm,
+		Nl:   Nl,
+		Nv:   Nv,
+		Nw:   Nw,
+		No:   No,
+		K:    2,
+		G:    public.G,
+		GVec: public.GVec[:Nm],
+		HVec: public.HVec[:hLen],
+		Wm:   Wm,
+		Wl:   Wl,
+		Am:   zeroVector(Nm),
+		Al:   al,
+		Fl:   true,
+		Fm:   false,
+		F: func(typ PartitionType, index int) *int {
+			if typ == PartitionLL && index < No { // map all to ll
+				return &index
+			}
+
+			return nil
+		},
+		GVec_: append(append([]*bn256.G1{}, public.GVec[Nm:]...), public.GVec_...),
+		HVec_: append(append([]*bn256.G1{}, public.HVec[hLen:]...), public.HVec_...),
+	}
+}
+
+// membershipChallenge absorbs the set size, so a proof is bound to the size of the set it was made for.
+func membershipChallenge(fs FiatShamirEngine, n int) error {
+	if fs == nil {
+		return errors.New("Fiat-Shamir engine cannot be nil")
+	}
+	return addLength(fs, n)
+}
+
+// ProveMembershipCommitted proves that the value committed in CommitValue(value, blinding) is one of the elements
+// committed in setCommitment = CommitSet(setElements, setBlinding), without revealing the value, the elements or
+// which element matched. The verifier only needs the two commitments and the size of the set.
+// Use empty FiatShamirEngine for call.
+func ProveMembershipCommitted(public *ReciprocalPublic, fs FiatShamirEngine, value, blinding *big.Int, setCommitment *bn256.G1, setElements []*big.Int, setBlinding *big.Int) (*ArithmeticCircuitProof, error) {
+	if err := checkFreshTranscript(fs); err != nil {
+		return nil, err
+	}
+
+	if value == nil || blinding == nil {
+		return nil, errors.New("value and blinding cannot be nil")
+	}
+
+	if setCommitment == nil {
+		return nil, errors.New("set commitment cannot be nil")
+	}
+
+	com, err := public.CommitSet(setElements, setBlinding)
+	if err != nil {
+		return nil, err
+	}
+
+	if !pointsEqual(com, setCommitment) {
+		return nil, errors.New("set elements do not match the set commitment")
+	}
+
+	n := len(setElements)
+	x := add(value, nil)
+
+	wl := make([]*big.Int, n)
+	wr := make([]*big.Int, n)
+
+	acc := bint(1)
+	for j := range setElements {
+		wl[j] = acc
+		wr[j] = sub(x, setElements[j])
+		acc = mul(acc, wr[j])
+	}
+
+	if acc.Sign() != 0 {
+		return nil, errors.New("value is not in the set")
+	}
+
+	v0 := zeroVector(n + 1)
+	v0[0] = x
+
+	v1 := append([]*big.Int{bint(0)}, setElements...)
+
+	private := &ArithmeticCircuitPrivate{
+		V:  [][]*big.Int{v0, v1},
+		Sv: []*big.Int{blinding, setBlinding},
+		Wl: wl,
+		Wr: wr,
+		Wo: append([]*big.Int{x}, setElements...),
+	}
+
+	if err := membershipChallenge(fs, n); err != nil {
+		return nil, err
+	}
+
+	V := []*bn256.G1{public.CommitValue(x, blinding), setCommitment}
+	return ProveCircuit(membershipCircuit(public, n), V, fs, private)
+}
+
+// VerifyMembershipCommitted verifies a proof produced by ProveMembershipCommitted that the value committed in com
+// is one of the setSize elements committed in setCommitment. If err is nil then proof is valid.
+// Use empty FiatShamirEngine for call.
+func VerifyMembershipCommitted(public *ReciprocalPublic, fs FiatShamirEngine, com, setCommitment *bn256.G1, setSize int, proof *ArithmeticCircuitProof) error {
+	if err := checkFreshTranscript(fs); err != nil {
+		return err
+	}
+
+	if err := public.Validate(); err != nil {
+		return err
+	}
+
+	if err := checkSetSize(public, setSize); err != nil {
+		return err
+	}
+
+	if com == nil || setCommitment == nil {
+		return errors.New("commitments cannot be nil")
+	}
+
+	if err := membershipChallenge(fs, setSize); err != nil {
+		return err
+	}
+
+	return VerifyCircuit(membershipCircuit(public, setSize), []*bn256.G1{com, setCommitment}, fs, proof)
+}
@@ -0,0 +1,102 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"testing"
+)
+
+func TestMembershipCommitted(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	set := []*big.Int{bint(17), bint(4242), minus(bint(5)), bint(0), bint(99)}
+	setBlinding := NewRandScalar()
+
+	setCom, err := public.CommitSet(set, setBlinding)
+	if err != nil {
+		t.Fatalf("CommitSet failed: %v", err)
+	}
+
+	for _, value := range set {
+		blinding := NewRandScalar()
+		com := public.CommitValue(value, blinding)
+
+		proof, err := ProveMembershipCommitted(public, NewKeccakFS(), value, blinding, setCom, set, setBlinding)
+		if err != nil {
+			t.Fatalf("ProveMembershipCommitted(%v) failed: %v", value, err)
+		}
+
+		if err := VerifyMembershipCommitted(public, NewKeccakFS(), com, setCom, len(set), proof); err != nil {
+			t.Fatalf("VerifyMembershipCommitted(%v) failed: %v", value, err)
+		}
+
+		if err := VerifyMembershipCommitted(public, NewKeccakFS(), public.CommitValue(bint(18), blinding), setCom, len(set), proof); err == nil {
+			t.Errorf("Proof for %v accepted for another value commitment", value)
+		}
+
+		other, err := public.CommitSet([]*big.Int{bint(1), bint(2), bint(3), bint(4), value}, setBlinding)
+		if err != nil {
+			t.Fatalf("CommitSet failed: %v", err)
+		}
+
+		if err := VerifyMembershipCommitted(public, NewKeccakFS(), com, other, len(set), proof); err == nil {
+			t.Errorf("Proof for %v accepted for another set commitment", value)
+		}
+	}
+
+	blinding := NewRandScalar()
+
+	if _, err := ProveMembershipCommitted(public, NewKeccakFS(), bint(18), blinding, setCom, set, setBlinding); err == nil {
+		t.Error("Proved membership of a value outside of the set")
+	}
+
+	if _, err := ProveMembershipCommitted(public, NewKeccakFS(), bint(17), blinding, setCom, set[1:], setBlinding); err == nil {
+		t.Error("Expected an error for elements that do not match the set commitment")
+	}
+
+	// A forged witness for a value outside of the set breaks the link between the wires and the commitments.
+	x := bint(18)
+	wl, wr := make([]*big.Int, len(set)), make([]*big.Int, len(set))
+	for j := range set {
+		wl[j], wr[j] = bint(0), sub(x, set[j])
+	}
+	wl[0] = bint(1)
+	wr[0] = bint(0)
+
+	V := []*bn256.G1{public.CommitValue(x, blinding), setCom}
+	forged, err := ProveCircuit(membershipCircuit(public, len(set)), V, membershipTranscript(t, len(set)), &ArithmeticCircuitPrivate{
+		V:  [][]*big.Int{append([]*big.Int{x}, zeroVector(len(set))...), append([]*big.Int{bint(0)}, set...)},
+		Sv: []*big.Int{blinding, setBlinding},
+		Wl: wl,
+		Wr: wr,
+		Wo: append([]*big.Int{x}, set...),
+	})
+	if err != nil {
+		t.Fatalf("ProveCircuit failed: %v", err)
+	}
+
+	if err := VerifyMembershipCommitted(public, NewKeccakFS(), V[0], setCom, len(set), forged); err == nil {
+		t.Error("Forged membership proof accepted")
+	}
+
+	for _, size := range []int{0, 17} {
+		if err := VerifyMembershipCommitted(public, NewKeccakFS(), V[0], setCom, size, forged); err == nil {
+			t.Errorf("Expected an error for set size %d", size)
+		}
+	}
+}
+
+func membershipTranscript(t *testing.T, n int) FiatShamirEngine {
+	fs := NewKeccakFS()
+	if err := membershipChallenge(fs, n); err != nil {
+		t.Fatalf("membershipChallenge failed: %v", err)
+	}
+	return fs
+}
@@ -0,0 +1,183 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+)
+
+// ErrChallengesExhausted is reported by MockFS when more challenges are requested than were preset.
+var ErrChallengesExhausted = errors.New("preset challenges exhausted")
+
+// MockFSEntry is one input absorbed by MockFS or one challenge it returned, recorded as KeccakFS records it with
+// WithTranscriptRecorder.
+type MockFSEntry = TranscriptEntry
+
+// MockFS is a FiatShamirEngine for tests that returns preset challenges instead of hashing the transcript, so the
+// algebra of a protocol can be checked independently of the hash and transcripts can be compared across
+// implementations. Everything absorbed and every challenge returned is recorded in Entries.
+//
+// GetChallenge cannot fail, so once the preset challenges are exhausted it returns zero and Err reports
+// ErrChallengesExhausted; every following Add call returns that error as well. The provers and verifiers that
+// invert their challenges reject zero with ErrInvalidChallenge; check Err after running any other protocol.
+type MockFS struct {
+	Entries []MockFSEntry
+
+	challenges []*big.Int
+	err        error
+}
+
+// NewMockFS creates a MockFS returning the challenges in order.
+func NewMockFS(challenges ...*big.Int) *MockFS {
+	return &MockFS{challenges: challenges}
+}
+
+// Err returns ErrChallengesExhausted if more challenges were requested than preset, nil otherwise.
+func (m *MockFS) Err() error {
+	return m.err
+}
+
+func (m *MockFS) record(op string, data []byte) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.Entries = append(m.Entries, MockFSEntry{Op: op, Data: append([]byte{}, data...)})
+	return nil
+}
+
+func (m *MockFS) AddPoint(p *bn256.G1) error {
+	if p == nil {
+		return errors.New("point cannot be nil")
+	}
+	return m.record(TranscriptPoint, p.Marshal())
+}
+
+func (m *MockFS) AddNumber(v *big.Int) error {
+	if v == nil {
+		return errors.New("number cannot be nil")
+	}
+	return m.record(TranscriptNumber, scalarTo32Byte(v))
+}
+
+// AddDomain records the domain. Like KeccakFS it fails with ErrDomainAfterInput once anything else was recorded.
+func (m *MockFS) AddDomain(domain string) error {
+	if domain == "" {
+		return errors.New("domain cannot be empty")
+	}
+	for _, e := range m.Entries {
+		if e.Op != TranscriptDomain {
+			return fmt.Errorf("%w: %q", ErrDomainAfterInput, domain)
+		}
+	}
+	return m.record(TranscriptDomain, []byte(domain))
+}
+
+func (m *MockFS) AddBytes(data []byte) error {
+	if data == nil {
+		return errors.New("data cannot be nil")
+	}
+	return m.record(TranscriptBytes, data)
+}
+
+func (m *MockFS) AddUint64(v uint64) error {
+	var data [8]byte
+	binary.BigEndian.PutUint64(data[:], v)
+	return m.record(TranscriptUint64, data[:])
+}
+
+// AddScalarVector records len(v) followed by every scalar of v, as KeccakFS absorbs them.
+func (m *MockFS) AddScalarVector(v []*big.Int) error {
+	if err := m.AddNumber(big.NewInt(int64(len(v)))); err != nil {
+		return err
+	}
+	for i := range v {
+		if err := m.AddNumber(v[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddPointVector records len(v) followed by every point of v, as KeccakFS absorbs them.
+func (m *MockFS) AddPointVector(v []*bn256.G1) error {
+	if err := m.AddNumber(big.NewInt(int64(len(v)))); err != nil {
+		return err
+	}
+	for i := range v {
+		if err := m.AddPoint(v[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetChallenge returns the next preset challenge, or zero once they are exhausted (see Err).
+func (m *MockFS) GetChallenge() *big.Int {
+	if len(m.challenges) == 0 {
+		m.err = ErrChallengesExhausted
+		return big.NewInt(0)
+	}
+
+	c := new(big.Int).Set(m.challenges[0])
+	m.challenges = m.challenges[1:]
+
+	_ = m.record(TranscriptChallenge, scalarTo32Byte(c))
+	return c
+}
+
+// GetChallenges returns the next n preset challenges.
+func (m *MockFS) GetChallenges(n int) []*big.Int {
+	res := make([]*big.Int, max(n, 0))
+	for i := range res {
+		res[i] = m.GetChallenge()
+	}
+	return res
+}
+
+// InteractiveFS is a FiatShamirEngine for running a protocol interactively: instead of hashing the transcript it
+// returns the challenges the caller supplies with SetNextChallenge, e.g. to study the interactive protocol or to
+// test soundness against adversarially chosen challenges. Like MockFS it records everything absorbed and every
+// challenge returned in Entries.
+//
+// Challenges that are set in advance are returned in order. When none is left, Challenger is asked for the next
+// one with the transcript so far, so it can react to the messages of the prover like an interactive verifier. If
+// there is neither, GetChallenge returns zero and Err reports ErrChallengesExhausted. Challenges the protocols cannot
+// use, like zero or the negation of a digit, are rejected with ErrInvalidChallenge instead of causing a panic.
+type InteractiveFS struct {
+	MockFS
+
+	Challenger func(transcript []TranscriptEntry) *big.Int
+}
+
+// NewInteractiveFS creates an InteractiveFS without challenges.
+func NewInteractiveFS() *InteractiveFS {
+	return &InteractiveFS{}
+}
+
+// SetNextChallenge queues c, reduced mod bn256.Order, as the next challenge. A nil c is taken as zero.
+func (f *InteractiveFS) SetNextChallenge(c *big.Int) {
+	f.challenges = append(f.challenges, add(c, nil))
+}
+
+// GetChallenge returns the next queued challenge or the one chosen by Challenger.
+func (f *InteractiveFS) GetChallenge() *big.Int {
+	if len(f.challenges) == 0 && f.Challenger != nil {
+		f.SetNextChallenge(f.Challenger(f.Entries))
+	}
+	return f.MockFS.GetChallenge()
+}
+
+// GetChallenges returns the next n challenges, see GetChallenge.
+func (f *InteractiveFS) GetChallenges(n int) []*big.Int {
+	res := make([]*big.Int, max(n, 0))
+	for i := range res {
+		res[i] = f.GetChallenge()
+	}
+	return res
+}
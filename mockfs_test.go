@@ -0,0 +1,202 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+var _ FiatShamirEngine = (*MockFS)(nil)
+
+func TestMockFS(t *testing.T) {
+	public := NewWeightNormLinearPublic(16, 8)
+
+	l, err := RandScalarVector(16)
+	if err != nil {
+		t.Fatalf("RandScalarVector failed: %v", err)
+	}
+
+	n, err := RandScalarVector(8)
+	if err != nil {
+		t.Fatalf("RandScalarVector failed: %v", err)
+	}
+
+	commitment, err := public.CommitWNLAChecked(l, n)
+	if err != nil {
+		t.Fatalf("CommitWNLAChecked failed: %v", err)
+	}
+
+	challenges := []*big.Int{bint(2), bint(3), bint(5)}
+
+	proverFS := NewMockFS(challenges...)
+	proof, err := ProveWNLA(public, commitment, proverFS, l, n)
+	if err != nil {
+		t.Fatalf("ProveWNLA failed: %v", err)
+	}
+
+	verifierFS := NewMockFS(challenges...)
+	if err := VerifyWNLA(public, proof, commitment, verifierFS); err != nil {
+		t.Fatalf("VerifyWNLA failed: %v", err)
+	}
+
+	if proverFS.Err() != nil || verifierFS.Err() != nil {
+		t.Fatalf("Unexpected errors: prover %v, verifier %v", proverFS.Err(), verifierFS.Err())
+	}
+
+	// Prover and verifier absorb the same data and see the same challenges.
+	if len(proverFS.Entries) != len(verifierFS.Entries) {
+		t.Fatalf("Transcript lengths differ: %d and %d", len(proverFS.Entries), len(verifierFS.Entries))
+	}
+
+	rounds := 0
+	for i, e := range proverFS.Entries {
+		if e.Op != verifierFS.Entries[i].Op || !bytes.Equal(e.Data, verifierFS.Entries[i].Data) {
+			t.Errorf("Entry %d differs: %s and %s", i, e.Op, verifierFS.Entries[i].Op)
+		}
+		if e.Op == "challenge" {
+			if new(big.Int).SetBytes(e.Data).Cmp(challenges[rounds]) != 0 {
+				t.Errorf("Challenge %d is not the preset one", rounds)
+			}
+			rounds++
+		}
+	}
+
+	if rounds != len(proof.X) {
+		t.Errorf("Expected %d challenges, recorded %d", len(proof.X), rounds)
+	}
+
+	// A proof made for other challenges does not verify.
+	if err := VerifyWNLA(public, proof, commitment, NewMockFS(bint(2), bint(3), bint(11))); err == nil {
+		t.Error("Proof accepted for other challenges")
+	}
+
+	short := NewMockFS(bint(1))
+	short.GetChallenges(2)
+	if !errors.Is(short.Err(), ErrChallengesExhausted) {
+		t.Errorf("Expected ErrChallengesExhausted, got %v", short.Err())
+	}
+
+	if err := short.AddNumber(bint(1)); !errors.Is(err, ErrChallengesExhausted) {
+		t.Errorf("Expected ErrChallengesExhausted from AddNumber, got %v", err)
+	}
+}
+
+var _ FiatShamirEngine = (*InteractiveFS)(nil)
+
+func TestInteractiveFS(t *testing.T) {
+	public := NewWeightNormLinearPublic(16, 8)
+
+	l, err := RandScalarVector(16)
+	if err != nil {
+		t.Fatalf("RandScalarVector failed: %v", err)
+	}
+
+	n, err := RandScalarVector(8)
+	if err != nil {
+		t.Fatalf("RandScalarVector failed: %v", err)
+	}
+
+	commitment, err := public.CommitWNLAChecked(l, n)
+	if err != nil {
+		t.Fatalf("CommitWNLAChecked failed: %v", err)
+	}
+
+	// The verifier picks every challenge after seeing the prover's messages of the round.
+	proverFS := NewInteractiveFS()
+	proverFS.Challenger = func(transcript []TranscriptEntry) *big.Int {
+		return new(big.Int).SetBytes(Keccak256(transcript[len(transcript)-1].Data))
+	}
+
+	proof, err := ProveWNLA(public, commitment, proverFS, l, n)
+	if err != nil {
+		t.Fatalf("ProveWNLA failed: %v", err)
+	}
+
+	var challenges []*big.Int
+	for _, e := range proverFS.Entries {
+		if e.Op == TranscriptChallenge {
+			challenges = append(challenges, new(big.Int).SetBytes(e.Data))
+		}
+	}
+
+	if len(challenges) != len(proof.X) || proverFS.Err() != nil {
+		t.Fatalf("Expected %d challenges, got %d (%v)", len(proof.X), len(challenges), proverFS.Err())
+	}
+
+	verifierFS := NewInteractiveFS()
+	for _, c := range challenges {
+		verifierFS.SetNextChallenge(c)
+	}
+
+	if err := VerifyWNLA(public, proof, commitment, verifierFS); err != nil {
+		t.Fatalf("VerifyWNLA failed: %v", err)
+	}
+
+	// The proof only holds for the challenges it was answered to.
+	tampered := NewInteractiveFS()
+	for i, c := range challenges {
+		if i == len(challenges)-1 {
+			c = add(c, bint(1))
+		}
+		tampered.SetNextChallenge(c)
+	}
+
+	if err := VerifyWNLA(public, proof, commitment, tampered); !errors.Is(err, ErrFinalCommitmentMismatch) {
+		t.Errorf("Expected ErrFinalCommitmentMismatch, got %v", err)
+	}
+
+	empty := NewInteractiveFS()
+	if empty.GetChallenge().Sign() != 0 || !errors.Is(empty.Err(), ErrChallengesExhausted) {
+		t.Error("Expected ErrChallengesExhausted without challenges")
+	}
+}
+
+func TestInvalidChallenges(t *testing.T) {
+	public, proof, private := newVerifierTestProof(t)
+	VCom := public.CommitValue(private.X, private.S)
+
+	// An exhausted script returns zero challenges to both sides, which must fail instead of panicking.
+	if _, err := ProveRange(public, NewMockFS(), private); !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("Expected ErrInvalidChallenge from the prover, got %v", err)
+	}
+
+	if err := VerifyRange(public, VCom, NewMockFS(), proof); !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("Expected ErrInvalidChallenge from the verifier, got %v", err)
+	}
+
+	// A challenger choosing e = -d for a digit d makes a pole undefined.
+	pole := NewInteractiveFS()
+	pole.SetNextChallenge(minus(bint(3)))
+	if err := VerifyRange(public, VCom, pole, proof); !errors.Is(err, ErrInvalidChallenge) {
+		t.Errorf("Expected ErrInvalidChallenge for e = -3, got %v", err)
+	}
+
+	// Zero challenges after a valid e reach the circuit and the WNLA rounds.
+	for _, valid := range []int{1, 5, 6} {
+		script := NewInteractiveFS()
+		for i := 0; i < valid; i++ {
+			script.SetNextChallenge(bint(i + 2))
+		}
+		script.Challenger = func([]TranscriptEntry) *big.Int {
+			return bint(0)
+		}
+
+		if _, err := ProveRange(public, script, private); !errors.Is(err, ErrInvalidChallenge) {
+			t.Errorf("%d valid challenges: expected ErrInvalidChallenge from the prover, got %v", valid, err)
+		}
+
+		script = NewInteractiveFS()
+		for i := 0; i < valid; i++ {
+			script.SetNextChallenge(bint(i + 2))
+		}
+
+		if err := VerifyRange(public, VCom, script, proof); !errors.Is(err, ErrInvalidChallenge) {
+			t.Errorf("%d valid challenges: expected ErrInvalidChallenge from the verifier, got %v", valid, err)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"github.com/cloudflare/bn256"
+	"math/big"
+)
+
+// generatorTableWindows is the number of bytes of a reduced scalar, one table entry per byte.
+const generatorTableWindows = 32
+
+// generatorTable precomputes 256^w*P for every byte position w of the points of a parameter set, so that a
+// multi-scalar multiplication over them needs no doublings: every scalar byte b at position w adds 256^w*P to bucket
+// b, and the buckets are summed with their weights at the end. Points are looked up by identity, as the parameter
+// views share them; other points are multiplied directly. A table must not be modified once built.
+type generatorTable struct {
+	shifted map[*bn256.G1][]*bn256.G1
+}
+
+func newGeneratorTable(points []*bn256.G1) *generatorTable {
+	t := &generatorTable{shifted: make(map[*bn256.G1][]*bn256.G1, len(points))}
+	window := big.NewInt(256)
+
+	for _, p := range points {
+		if p == nil || t.shifted[p] != nil {
+			continue
+		}
+
+		shifted := make([]*bn256.G1, generatorTableWindows)
+		shifted[0] = new(bn256.G1).Set(p)
+		for w := 1; w < len(shifted); w++ {
+			shifted[w] = new(bn256.G1).ScalarMult(shifted[w-1], window)
+		}
+		t.shifted[p] = shifted
+	}
+
+	return t
+}
+
+// msm computes sum(scalars[i]*points[i]) like vectorPointScalarMul. It is an MSMFunc.
+func (t *generatorTable) msm(points []*bn256.G1, scalars []*big.Int) *bn256.G1 {
+	var buckets [256]*bn256.G1
+	res := new(bn256.G1).ScalarBaseMult(bint(0))
+
+	var buf [32]byte
+	for i := range points {
+		s := add(scalarAt(scalars, i), nil)
+
+		shifted, ok := t.shifted[points[i]]
+		if !ok {
+			res.Add(res, new(bn256.G1).ScalarMult(points[i], s))
+			continue
+		}
+
+		s.FillBytes(buf[:])
+		for w := range shifted {
+			b := buf[len(buf)-1-w]
+			if b == 0 {
+				continue
+			}
+			if buckets[b] == nil {
+				buckets[b] = new(bn256.G1).Set(shifted[w])
+			} else {
+				buckets[b].Add(buckets[b], shifted[w])
+			}
+		}
+	}
+
+	// sum(b*buckets[b]) as the sum of the running sums from the top bucket down.
+	running := new(bn256.G1).ScalarBaseMult(bint(0))
+	for b := len(buckets) - 1; b > 0; b-- {
+		if buckets[b] != nil {
+			running.Add(running, buckets[b])
+		}
+		res.Add(res, running)
+	}
+
+	return res
+}
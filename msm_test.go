@@ -0,0 +1,48 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"testing"
+)
+
+func TestGeneratorTable(t *testing.T) {
+	points := make([]*bn256.G1, 6)
+	for i := range points {
+		points[i] = new(bn256.G1).ScalarBaseMult(NewRandScalar())
+	}
+
+	// The last point is not in the table and is multiplied directly.
+	table := newGeneratorTable(points[:5])
+
+	scalars := []*big.Int{
+		bint(0),
+		bint(1),
+		sub(bint(0), bint(1)),
+		new(big.Int).Add(bn256.Order, bint(7)),
+		minus(bint(3)),
+		NewRandScalar(),
+	}
+
+	if !pointsEqual(table.msm(points, scalars), vectorPointScalarMul(points, scalars)) {
+		t.Error("Table MSM differs from the built-in one")
+	}
+
+	// Equal scalars fill the same buckets.
+	same := []*big.Int{bint(0x0102), bint(0x0102), bint(0x0102), bint(0x0102), bint(0x0102)}
+	if !pointsEqual(table.msm(points[:5], same), vectorPointScalarMul(points[:5], same)) {
+		t.Error("Table MSM differs for equal scalars")
+	}
+
+	if !pointsEqual(table.msm(nil, nil), vectorPointScalarMul(nil, nil)) {
+		t.Error("Empty table MSM is not the identity")
+	}
+
+	if err := checkMSM(newGeneratorTable(nil).msm); err != nil {
+		t.Errorf("checkMSM rejected the table MSM: %v", err)
+	}
+}
@@ -0,0 +1,197 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+)
+
+// checkNonMembershipSet ensures the public set fits into the range proof parameters and has no missing elements.
+// The circuit uses one multiplication gate per element and one for the inverse, all backed by GVec, so sets of up
+// to Nd-1 elements are supported.
+func checkNonMembershipSet(public *ReciprocalPublic, set []*big.Int) error {
+	if len(set) < 1 || len(set) > public.Nd-1 {
+		return fmt.Errorf("set size %d out of range [1, %d]", len(set), public.Nd-1)
+	}
+
+	for i := range set {
+		if set[i] == nil {
+			return fmt.Errorf("set element %d cannot be nil", i)
+		}
+	}
+
+	return nil
+}
+
+// nonMembershipCircuit builds the circuit proving that the value x committed in V = CommitValue(x, s) differs from
+// every element e_j of the public set. The inverses 1/(x - e_j) all exist exactly when no difference is zero, which
+// is the case exactly when their product has an inverse. The circuit multiplies the differences with a chain of n
+// gates, wl[0] = 1, wl[j]*wr[j] = wl[j+1] with wr[j] = x - e_j, and shows knowledge of the inverse of the product
+// with a last gate wl[n]*wr[n] = 1. The single o-wire holds x and is linked to the committed vector v = [x].
+func nonMembershipCircuit(public *ReciprocalPublic, set []*big.Int) *ArithmeticCircuitPublic {
+	n := len(set)
+	Nm := n + 1
+	No := 1
+	Nv := 1
+	Nw := Nm + Nm + No
+
+	Wm := zeroMatrix(Nm, Nw)
+	for j := 0; j < n; j++ {
+		Wm[j][j+1] = bint(1)
+	}
+
+	am := zeroVector(Nm)
+	am[n] = bint(1)
+
+	// The row for v, one row per difference and the row fixing wl[0].
+	Nl := Nv + n + 1
+	Wl := zeroMatrix(Nl, Nw)
+	al := zeroVector(Nl)
+
+	// v[0] = x
+	Wl[0][2*Nm] = minus(bint(1))
+
+	// wr[j] - x + e_j = 0
+	for j := 0; j < n; j++ {
+		row := Nv + j
+		Wl[row][Nm+j] = bint(1)
+		Wl[row][2*Nm] = minus(bint(1))
+		al[row] = add(set[j], nil)
+	}
+
+	// wl[0] - 1 = 0
+	Wl[Nl-1][0] = bint(1)
+	al[Nl-1] = minus(bint(1))
+
+	hLen := Nv + circuitBlindingSlots
+
+	return &ArithmeticCircuitPublic{
+		Nm:   Nm,
+		Nl:   Nl,
+		Nv:   Nv,
+		Nw:   Nw,
+		No:   No,
+		K:    1,
+		G:    public.G,
+		GVec: public.GVec[:Nm],
+		HVec: public.HVec[:hLen],
+		Wm:   Wm,
+		Wl:   Wl,
+		Am:   am,
+		Al:   al,
+		Fl:   true,
+		Fm:   false,
+		F: func(typ PartitionType, index int) *int {
+			if typ == PartitionLL && index < No { // map all to ll
+				return &index
+			}
+
+			return nil
+		},
+		GVec_: append(append([]*bn256.G1{}, public.GVec[Nm:]...), public.GVec_...),
+		HVec_: append(append([]*bn256.G1{}, public.HVec[hLen:]...), public.HVec_...),
+	}
+}
+
+// nonMembershipChallenge absorbs the public set, reduced modulo the group order, with AddScalarVector. The elements
+// only enter the circuit as constants, so without them in the transcript a proof would not be bound to the set it
+// was made for.
+func nonMembershipChallenge(fs FiatShamirEngine, set []*big.Int) error {
+	if fs == nil {
+		return errors.New("Fiat-Shamir engine cannot be nil")
+	}
+
+	elements := make([]*big.Int, len(set))
+	for i, e := range set {
+		elements[i] = add(e, nil)
+	}
+
+	return addScalarVector(fs, elements)
+}
+
+// ProveNonMembership proves that the value committed in CommitValue(value, blinding) is none of the elements of the
+// public set, without revealing the value. The proof shows knowledge of the inverse of prod(value - e_j), which
+// only exists if the value differs from every element. Elements are compared modulo the group order. The set can
+// have up to Nd-1 elements. Use empty FiatShamirEngine for call.
+func ProveNonMembership(public *ReciprocalPublic, fs FiatShamirEngine, value *big.Int, set []*big.Int, blinding *big.Int) (*ArithmeticCircuitProof, error) {
+	if err := checkFreshTranscript(fs); err != nil {
+		return nil, err
+	}
+
+	if err := public.Validate(); err != nil {
+		return nil, err
+	}
+
+	if value == nil || blinding == nil {
+		return nil, errors.New("value and blinding cannot be nil")
+	}
+
+	if err := checkNonMembershipSet(public, set); err != nil {
+		return nil, err
+	}
+
+	n := len(set)
+	x := add(value, nil)
+
+	wl := make([]*big.Int, n+1)
+	wr := make([]*big.Int, n+1)
+
+	acc := bint(1)
+	for j := range set {
+		wl[j] = acc
+		wr[j] = sub(x, set[j])
+		acc = mul(acc, wr[j])
+	}
+
+	if acc.Sign() == 0 {
+		return nil, errors.New("value is in the set")
+	}
+
+	wl[n] = acc
+	wr[n] = inv(acc)
+
+	private := &ArithmeticCircuitPrivate{
+		V:  [][]*big.Int{{x}},
+		Sv: []*big.Int{blinding},
+		Wl: wl,
+		Wr: wr,
+		Wo: []*big.Int{x},
+	}
+
+	if err := nonMembershipChallenge(fs, set); err != nil {
+		return nil, err
+	}
+
+	return ProveCircuit(nonMembershipCircuit(public, set), []*bn256.G1{public.CommitValue(x, blinding)}, fs, private)
+}
+
+// VerifyNonMembership verifies a proof produced by ProveNonMembership that the value committed in com is none of
+// the elements of the public set. If err is nil then proof is valid. Use empty FiatShamirEngine for call.
+func VerifyNonMembership(public *ReciprocalPublic, fs FiatShamirEngine, com *bn256.G1, set []*big.Int, proof *ArithmeticCircuitProof) error {
+	if err := checkFreshTranscript(fs); err != nil {
+		return err
+	}
+
+	if err := public.Validate(); err != nil {
+		return err
+	}
+
+	if err := checkNonMembershipSet(public, set); err != nil {
+		return err
+	}
+
+	if com == nil {
+		return errors.New("commitment cannot be nil")
+	}
+
+	if err := nonMembershipChallenge(fs, set); err != nil {
+		return err
+	}
+
+	return VerifyCircuit(nonMembershipCircuit(public, set), []*bn256.G1{com}, fs, proof)
+}
@@ -0,0 +1,109 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"testing"
+)
+
+func TestNonMembership(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	set := []*big.Int{bint(17), bint(4242), minus(bint(5)), bint(0), bint(99)}
+
+	for _, value := range []*big.Int{bint(18), bint(1), minus(bint(4)), new(big.Int).Add(bn256.Order, bint(1))} {
+		blinding := NewRandScalar()
+		com := public.CommitValue(value, blinding)
+
+		proof, err := ProveNonMembership(public, NewKeccakFS(), value, set, blinding)
+		if err != nil {
+			t.Fatalf("ProveNonMembership(%v) failed: %v", value, err)
+		}
+
+		if err := VerifyNonMembership(public, NewKeccakFS(), com, set, proof); err != nil {
+			t.Fatalf("VerifyNonMembership(%v) failed: %v", value, err)
+		}
+
+		if err := VerifyNonMembership(public, NewKeccakFS(), public.CommitValue(bint(17), blinding), set, proof); err == nil {
+			t.Errorf("Proof for %v accepted for another value commitment", value)
+		}
+
+		if err := VerifyNonMembership(public, NewKeccakFS(), com, []*big.Int{bint(17), bint(4242), minus(bint(5)), bint(0), bint(98)}, proof); err == nil {
+			t.Errorf("Proof for %v accepted for another set", value)
+		}
+	}
+
+	blinding := NewRandScalar()
+
+	for _, value := range append(set, new(big.Int).Add(bn256.Order, bint(17))) {
+		if _, err := ProveNonMembership(public, NewKeccakFS(), value, set, blinding); err == nil {
+			t.Errorf("Proved non-membership of the set element %v", value)
+		}
+	}
+
+	// A forged witness for an element of the set cannot satisfy the inverse gate: the product of the differences is
+	// zero and zero times anything is not one.
+	x := set[2]
+	n := len(set)
+	wl, wr := make([]*big.Int, n+1), make([]*big.Int, n+1)
+	acc := bint(1)
+	for j := range set {
+		wl[j], wr[j] = acc, sub(x, set[j])
+		acc = mul(acc, wr[j])
+	}
+	wl[n], wr[n] = acc, bint(1)
+
+	fs := NewKeccakFS()
+	if err := nonMembershipChallenge(fs, set); err != nil {
+		t.Fatalf("nonMembershipChallenge failed: %v", err)
+	}
+
+	com := public.CommitValue(x, blinding)
+	forged, err := ProveCircuit(nonMembershipCircuit(public, set), []*bn256.G1{com}, fs, &ArithmeticCircuitPrivate{
+		V:  [][]*big.Int{{x}},
+		Sv: []*big.Int{blinding},
+		Wl: wl,
+		Wr: wr,
+		Wo: []*big.Int{x},
+	})
+	if err != nil {
+		t.Fatalf("ProveCircuit failed: %v", err)
+	}
+
+	if err := VerifyNonMembership(public, NewKeccakFS(), com, set, forged); err == nil {
+		t.Error("Forged non-membership proof accepted")
+	}
+
+	for _, s := range [][]*big.Int{nil, zeroVector(16), {bint(1), nil}} {
+		if err := VerifyNonMembership(public, NewKeccakFS(), com, s, forged); err == nil {
+			t.Errorf("Expected an error for the set %v", s)
+		}
+	}
+}
+
+func TestNonMembershipChallenge(t *testing.T) {
+	set := []*big.Int{bint(17), new(big.Int).Add(bn256.Order, bint(4242))}
+
+	for _, version := range []TranscriptVersion{TranscriptV1, TranscriptV2} {
+		fs := NewKeccakFS(WithTranscriptVersion(version))
+		if err := nonMembershipChallenge(fs, set); err != nil {
+			t.Fatalf("nonMembershipChallenge failed: %v", err)
+		}
+
+		expected := NewKeccakFS(WithTranscriptVersion(version))
+		if err := addScalarVector(expected, []*big.Int{bint(17), bint(4242)}); err != nil {
+			t.Fatalf("AddScalarVector failed: %v", err)
+		}
+
+		if fs.GetChallenge().Cmp(expected.GetChallenge()) != 0 {
+			t.Errorf("TranscriptV%d: the set is not absorbed as a reduced scalar vector", version)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"github.com/cloudflare/bn256"
+	"math/big"
+)
+
+// nonZeroCircuit is the circuit for knowledge of v committed in V and its inverse: a single multiplication gate
+// wl * wr = 1 with the linear constraint wl = v. Zero has no inverse, so no witness exists for v = 0.
+// It reuses the generators of the range proof parameters, so V = CommitValue(v, s).
+func nonZeroCircuit(public *ReciprocalPublic) *ArithmeticCircuitPublic {
+	return &ArithmeticCircuitPublic{
+		Nm: 1,
+		Nl: 1,
+		Nv: 1,
+		Nw: 2,
+		No: 0,
+		K:  1,
+
+		G:    public.G,
+		GVec: public.GVec[:1],
+		HVec: public.HVec[:1+circuitBlindingSlots],
+
+		Wm: [][]*big.Int{{bint(0), bint(0)}},
+		Wl: [][]*big.Int{{minus(bint(1)), bint(0)}},
+		Am: []*big.Int{bint(1)},
+		Al: []*big.Int{bint(0)},
+		Fl: true,
+		Fm: false,
+
+		F: func(typ PartitionType, index int) *int {
+			return nil
+		},
+
+		GVec_: append(append([]*bn256.G1{}, public.GVec[1:]...), public.GVec_...),
+		HVec_: append(append([]*bn256.G1{}, public.HVec[1+circuitBlindingSlots:]...), public.HVec_...),
+	}
+}
+
+// ProveNonZero proves that the commitment CommitValue(value, blinding) hides a value other than zero without
+// revealing it. The proof shows knowledge of the inverse of the value, which only exists for non-zero values.
+// Use empty FiatShamirEngine for call.
+func ProveNonZero(public *ReciprocalPublic, fs FiatShamirEngine, value, blinding *big.Int) (*ArithmeticCircuitProof, error) {
+	if err := public.Validate(); err != nil {
+		return nil, err
+	}
+
+	if value == nil || blinding == nil {
+		return nil, errors.New("value and blinding cannot be nil")
+	}
+
+	v := NewScalar(value)
+	vInv, err := v.Inv()
+	if err != nil {
+		return nil, errors.New("value is zero")
+	}
+
+	circuit := nonZeroCircuit(public)
+
+	private := &ArithmeticCircuitPrivate{
+		V:  [][]*big.Int{{v.BigInt()}},
+		Sv: []*big.Int{blinding},
+		Wl: []*big.Int{v.BigInt()},
+		Wr: []*big.Int{vInv.BigInt()},
+		Wo: []*big.Int{},
+	}
+
+	return ProveCircuit(circuit, []*bn256.G1{public.CommitValue(v.BigInt(), blinding)}, fs, private)
+}
+
+// VerifyNonZero verifies a proof produced by ProveNonZero that the commitment com hides a non-zero value.
+// If err is nil then proof is valid. Use empty FiatShamirEngine for call.
+func VerifyNonZero(public *ReciprocalPublic, fs FiatShamirEngine, com *bn256.G1, proof *ArithmeticCircuitProof) error {
+	if err := public.Validate(); err != nil {
+		return err
+	}
+
+	if com == nil {
+		return errors.New("commitment cannot be nil")
+	}
+
+	return VerifyCircuit(nonZeroCircuit(public), []*bn256.G1{com}, fs, proof)
+}
@@ -0,0 +1,58 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"testing"
+)
+
+func TestNonZero(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	value, blinding := big.NewInt(1000), NewRandScalar()
+	com := public.CommitValue(value, blinding)
+
+	proof, err := ProveNonZero(public, NewKeccakFS(), value, blinding)
+	if err != nil {
+		t.Fatalf("ProveNonZero failed: %v", err)
+	}
+
+	if err := VerifyNonZero(public, NewKeccakFS(), com, proof); err != nil {
+		t.Fatalf("VerifyNonZero failed: %v", err)
+	}
+
+	// The proof is bound to the commitment.
+	if err := VerifyNonZero(public, NewKeccakFS(), public.CommitValue(big.NewInt(1001), blinding), proof); err == nil {
+		t.Error("Proof accepted for another commitment")
+	}
+
+	if _, err := ProveNonZero(public, NewKeccakFS(), big.NewInt(0), blinding); err == nil {
+		t.Error("Proved that zero is non-zero")
+	}
+
+	// Forged witnesses for zero: wl = 0 cannot satisfy wl * wr = 1 and wl = 1 does not match the commitment.
+	zero := public.CommitValue(bint(0), blinding)
+	for _, w := range [][2]int64{{0, 1}, {1, 1}} {
+		forged, err := ProveCircuit(nonZeroCircuit(public), []*bn256.G1{zero}, NewKeccakFS(), &ArithmeticCircuitPrivate{
+			V:  [][]*big.Int{{bint(0)}},
+			Sv: []*big.Int{blinding},
+			Wl: []*big.Int{big.NewInt(w[0])},
+			Wr: []*big.Int{big.NewInt(w[1])},
+			Wo: []*big.Int{},
+		})
+		if err != nil {
+			t.Fatalf("ProveCircuit failed: %v", err)
+		}
+
+		if err := VerifyNonZero(public, NewKeccakFS(), zero, forged); err == nil {
+			t.Errorf("Forged proof for zero with wl=%d, wr=%d accepted", w[0], w[1])
+		}
+	}
+}
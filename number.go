@@ -4,8 +4,15 @@
 // license that can be found in the LICENSE file.
 package bulletproofs
 
-import "math/big"
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/big"
+)
 
+// UInt64Hex returns the 16 hex digits of x, least significant first: resp[i] is the digit of 16^i, the order
+// ReciprocalPrivate expects.
 func UInt64Hex(x uint64) []*big.Int {
 	resp := make([]*big.Int, 16)
 	for i := 0; i < 16; i++ {
@@ -15,6 +22,7 @@ func UInt64Hex(x uint64) []*big.Int {
 	return resp
 }
 
+// HexMapping returns the multiplicity of every hex digit 0..15 among the digits. It does not depend on their order.
 func HexMapping(digits []*big.Int) []*big.Int {
 	resp := zeroVector(16)
 
@@ -25,3 +33,322 @@ func HexMapping(digits []*big.Int) []*big.Int {
 
 	return resp
 }
+
+// Base is the number system base of a range proof: values are decomposed into digits 0..Base-1. Plain integers
+// convert with Base(n); use Validate before relying on a converted value.
+type Base int
+
+// Common bases. Base16 is the most efficient choice for 64-bit values.
+const (
+	Base2   Base = 2
+	Base10  Base = 10
+	Base16  Base = 16
+	Base256 Base = 256
+)
+
+// Validate rejects bases below 2, which have no digit decomposition.
+func (b Base) Validate() error {
+	if b < 2 {
+		return fmt.Errorf("invalid base %d: must be at least 2", int(b))
+	}
+	return nil
+}
+
+// DigitCount returns the number of digits in base b that covers every value of the given bit length: the smallest n
+// with b^n >= 2^bits. Parameters with n digits, e.g. NewReciprocalPublic(domain, n, b), prove [0, b^n), which
+// contains [0, 2^bits).
+func (b Base) DigitCount(bits int) (int, error) {
+	if err := b.Validate(); err != nil {
+		return 0, err
+	}
+
+	if bits < 1 {
+		return 0, fmt.Errorf("invalid bit length %d", bits)
+	}
+
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(bits))
+	base := big.NewInt(int64(b))
+
+	n := 0
+	for p := big.NewInt(1); p.Cmp(limit) < 0; p.Mul(p, base) {
+		n++
+	}
+
+	return n, nil
+}
+
+// DigitOption configures Base.Digits and Base.Mapping.
+type DigitOption func(*digitConfig)
+
+type digitConfig struct {
+	constantTime bool
+	order        DigitOrder
+}
+
+// DigitOrder is the order of the digits returned by Base.Digits.
+type DigitOrder int
+
+const (
+	// LeastSignificantFirst puts the digit of b^i at index i. It is the default and the order of
+	// ReciprocalPrivate.Digits.
+	LeastSignificantFirst DigitOrder = iota
+	// MostSignificantFirst puts the digit of b^(n-1-i) at index i, for implementations using the opposite
+	// convention. Such digits must be reversed before they are used as ReciprocalPrivate.Digits.
+	MostSignificantFirst
+)
+
+// WithDigitOrder makes Base.Digits return the digits in the given order. Base.Mapping counts digits and does not
+// depend on the order.
+func WithDigitOrder(order DigitOrder) DigitOption {
+	return func(cfg *digitConfig) {
+		cfg.order = order
+	}
+}
+
+// WithConstantTime makes Base.Digits and Base.Mapping run with control flow and memory accesses that do not depend
+// on the digits, for provers exposed to local timing attacks, e.g. another tenant on the same machine measuring
+// cache accesses or run times. The fast variants divide by the base until the value is used up and count digits
+// by indexing with them, which leaks the value's structure to such an attacker.
+//
+// The constant-time decomposition supports the bases 2, 4, 16 and 256, where a digit is a fixed group of bits, and
+// values of up to 256 bits. It cannot hide what math/big reveals anyway: the value passed in is a *big.Int whose
+// length in machine words is observable, the returned digits are *big.Int again, and the proof arithmetic that
+// follows is not constant-time. BenchmarkDigits compares both variants.
+//
+// The provers that decompose the value themselves, e.g. Prove64, ProveRangeStream, ProveRangeAggregated and
+// DeterministicPrivate, use the constant-time decomposition whenever the base supports it, and every range prover
+// counts the digits of its witness in constant time when checking it. The option is for callers that build a
+// ReciprocalPrivate from Base.Digits and Base.Mapping themselves.
+func WithConstantTime() DigitOption {
+	return func(cfg *digitConfig) {
+		cfg.constantTime = true
+	}
+}
+
+func newDigitConfig(opts []DigitOption) *digitConfig {
+	cfg := &digitConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// Digits returns the n digits of x in base b, least significant first unless WithDigitOrder says otherwise. It
+// fails with ErrValueOutOfRange if x is negative or does not fit into n digits, which includes every n less than 1.
+// See WithConstantTime for the constant-time variant.
+func (b Base) Digits(x *big.Int, n int, opts ...DigitOption) ([]*big.Int, error) {
+	if x == nil {
+		return nil, errors.New("value cannot be nil")
+	}
+
+	if n < 1 {
+		return nil, fmt.Errorf("%w: digit count %d must be at least 1", ErrValueOutOfRange, n)
+	}
+
+	cfg := newDigitConfig(opts)
+
+	var digits []*big.Int
+	var err error
+	if cfg.constantTime {
+		digits, err = digitDecomposeConstantTime(x, int(b), n)
+	} else {
+		digits, err = digitDecompose(x, int(b), n)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.order == MostSignificantFirst {
+		digits = reverseDigits(digits)
+	}
+
+	return digits, nil
+}
+
+// reverseDigits returns the digits in reverse order.
+func reverseDigits(digits []*big.Int) []*big.Int {
+	res := make([]*big.Int, len(digits))
+	for i, d := range digits {
+		res[len(digits)-1-i] = d
+	}
+	return res
+}
+
+// composeDigits returns sum digits[i] * base^i, the value of least significant first digits.
+func composeDigits(digits []*big.Int, base int) *big.Int {
+	res := new(big.Int)
+	b := big.NewInt(int64(base))
+	for i := len(digits) - 1; i >= 0; i-- {
+		res.Mul(res, b)
+		res.Add(res, digits[i])
+	}
+	return res
+}
+
+// Mapping returns the multiplicity of every digit 0..b-1 among the digits, the M vector of ReciprocalPrivate. See
+// WithConstantTime for the constant-time variant.
+func (b Base) Mapping(digits []*big.Int, opts ...DigitOption) ([]*big.Int, error) {
+	if err := b.Validate(); err != nil {
+		return nil, err
+	}
+
+	for i, d := range digits {
+		if d == nil || d.Sign() < 0 || d.Cmp(big.NewInt(int64(b))) >= 0 {
+			return nil, fmt.Errorf("digit %d is not in [0, %d)", i, int(b))
+		}
+	}
+
+	if newDigitConfig(opts).constantTime {
+		return digitMappingConstantTime(digits, b.set()), nil
+	}
+	return digitMapping(digits, b.set()), nil
+}
+
+// set returns the digits 0..b-1.
+func (b Base) set() []int {
+	res := make([]int, max(int(b), 0))
+	for i := range res {
+		res[i] = i
+	}
+	return res
+}
+
+// ErrValueOutOfRange is returned for a value that cannot be written with the digits of the public parameters, so
+// no range proof exists for it.
+var ErrValueOutOfRange = errors.New("value outside of the provable range")
+
+// digitDecompose returns the n digits of x in the given base, least significant first.
+// It fails if x is negative or does not fit into n digits.
+func digitDecompose(x *big.Int, base, n int) ([]*big.Int, error) {
+	if err := Base(base).Validate(); err != nil {
+		return nil, err
+	}
+	if x.Sign() < 0 {
+		return nil, fmt.Errorf("%w: value cannot be negative", ErrValueOutOfRange)
+	}
+
+	b := big.NewInt(int64(base))
+	rest := new(big.Int).Set(x)
+
+	res := make([]*big.Int, n)
+	for i := range res {
+		res[i] = new(big.Int)
+		rest.DivMod(rest, b, res[i])
+	}
+
+	if rest.Sign() != 0 {
+		return nil, fmt.Errorf("%w: value does not fit into %d digits of base %d", ErrValueOutOfRange, n, base)
+	}
+
+	return res, nil
+}
+
+// constantTimeDigitBits returns the number of bits per digit for the bases supported by the constant-time
+// decomposition, zero for other bases.
+func constantTimeDigitBits(base int) int {
+	switch base {
+	case 2:
+		return 1
+	case 4:
+		return 2
+	case 16:
+		return 4
+	case 256:
+		return 8
+	}
+	return 0
+}
+
+// digitDecomposeConstantTime works like digitDecompose for the bases of constantTimeDigitBits and values of up to
+// 256 bits. The value is written into a fixed 32-byte buffer and every digit is read from the same bit positions
+// whatever its value; whether the value fits into n digits is decided after all bits have been visited.
+func digitDecomposeConstantTime(x *big.Int, base, n int) ([]*big.Int, error) {
+	if err := Base(base).Validate(); err != nil {
+		return nil, err
+	}
+
+	k := constantTimeDigitBits(base)
+	if k == 0 {
+		return nil, fmt.Errorf("constant-time decomposition supports the bases 2, 4, 16 and 256, got %d", base)
+	}
+
+	if x.Sign() < 0 {
+		return nil, fmt.Errorf("%w: value cannot be negative", ErrValueOutOfRange)
+	}
+
+	if x.BitLen() > 256 {
+		return nil, fmt.Errorf("%w: constant-time decomposition supports values of up to 256 bits", ErrValueOutOfRange)
+	}
+
+	var buf [32]byte
+	x.FillBytes(buf[:])
+
+	mask := byte(base - 1)
+	digit := func(i int) byte {
+		bit := i * k
+		return (buf[len(buf)-1-bit/8] >> (bit % 8)) & mask
+	}
+
+	res := make([]*big.Int, n)
+	for i := range res {
+		if i*k < 8*len(buf) {
+			res[i] = big.NewInt(int64(digit(i)))
+		} else {
+			res[i] = big.NewInt(0)
+		}
+	}
+
+	var overflow byte
+	for i := n; i*k < 8*len(buf); i++ {
+		overflow |= digit(i)
+	}
+
+	if overflow != 0 {
+		return nil, fmt.Errorf("%w: value does not fit into %d digits of base %d", ErrValueOutOfRange, n, base)
+	}
+
+	return res, nil
+}
+
+// digitMappingConstantTime works like digitMapping, but compares every digit with every entry of the set instead
+// of indexing with it. Every digit must be in the set and fit into 32 bits.
+func digitMappingConstantTime(digits []*big.Int, set []int) []*big.Int {
+	counts := make([]int, len(set))
+
+	for _, d := range digits {
+		v := int32(d.Int64())
+		for j := range set {
+			counts[j] += subtle.ConstantTimeEq(v, int32(set[j]))
+		}
+	}
+
+	resp := make([]*big.Int, len(set))
+	for j := range resp {
+		resp[j] = big.NewInt(int64(counts[j]))
+	}
+
+	return resp
+}
+
+// digitMapping returns the multiplicity of every digit value of the set among the digits, in the order of the set.
+// Every digit must be in the set.
+func digitMapping(digits []*big.Int, set []int) []*big.Int {
+	resp := zeroVector(len(set))
+
+	for _, d := range digits {
+		i := digitIndex(set, d)
+		resp[i] = add(resp[i], bint(1))
+	}
+
+	return resp
+}
+
+// digitIndex returns the position of the digit d in the set or -1 if it is not in the set.
+func digitIndex(set []int, d *big.Int) int {
+	for i, v := range set {
+		if d.IsInt64() && d.Int64() == int64(v) {
+			return i
+		}
+	}
+	return -1
+}
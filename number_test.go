@@ -5,7 +5,9 @@
 package bulletproofs
 
 import (
+	"errors"
 	"fmt"
+	"math/big"
 	"testing"
 )
 
@@ -14,3 +16,266 @@ func TestUInt64Hex(t *testing.T) {
 	fmt.Println(UInt64Hex(x))             // [0 4 5 0 15 4 11 10 0 4 5 0 15 4 11 10]
 	fmt.Println(HexMapping(UInt64Hex(x))) // [4 0 0 0 4 2 0 0 0 0 2 2 0 0 0 2]
 }
+
+func TestBase(t *testing.T) {
+	for _, b := range []Base{Base2, Base10, Base16, Base256, Base(3)} {
+		if err := b.Validate(); err != nil {
+			t.Errorf("Base %d rejected: %v", b, err)
+		}
+	}
+
+	for _, b := range []Base{Base(0), Base(1), Base(-16)} {
+		if err := b.Validate(); err == nil {
+			t.Errorf("Base %d accepted", b)
+		}
+
+		if _, err := NewReciprocalPublic(DOMAIN_RANGE, 4, b); err == nil {
+			t.Errorf("NewReciprocalPublic accepted base %d", b)
+		}
+	}
+
+	digits, err := Base10.Digits(big.NewInt(2024), 5)
+	if err != nil {
+		t.Fatalf("Digits failed: %v", err)
+	}
+
+	if fmt.Sprint(digits) != "[4 2 0 2 0]" {
+		t.Errorf("Unexpected digits %v", digits)
+	}
+
+	m, err := Base10.Mapping(digits)
+	if err != nil {
+		t.Fatalf("Mapping failed: %v", err)
+	}
+
+	if fmt.Sprint(m) != "[2 0 2 0 1 0 0 0 0 0]" {
+		t.Errorf("Unexpected mapping %v", m)
+	}
+
+	hex := UInt64Hex(0xab4f0540ab4f0540)
+	m, err = Base16.Mapping(hex)
+	if err != nil {
+		t.Fatalf("Mapping failed: %v", err)
+	}
+
+	if fmt.Sprint(m) != fmt.Sprint(HexMapping(hex)) {
+		t.Errorf("Base16 mapping %v differs from HexMapping %v", m, HexMapping(hex))
+	}
+
+	if _, err := Base10.Digits(big.NewInt(100000), 5); !errors.Is(err, ErrValueOutOfRange) {
+		t.Errorf("Expected ErrValueOutOfRange, got %v", err)
+	}
+
+	if _, err := Base10.Mapping([]*big.Int{big.NewInt(10)}); err == nil {
+		t.Error("Expected an error for a digit outside of the base")
+	}
+
+	if _, err := Base(1).Digits(big.NewInt(1), 5); err == nil {
+		t.Error("Expected an error for base 1")
+	}
+
+	for _, n := range []int{0, -1} {
+		if _, err := Base16.Digits(big.NewInt(0), n); !errors.Is(err, ErrValueOutOfRange) {
+			t.Errorf("Expected ErrValueOutOfRange for %d digits, got %v", n, err)
+		}
+
+		if _, err := Base16.Digits(big.NewInt(0), n, WithConstantTime()); !errors.Is(err, ErrValueOutOfRange) {
+			t.Errorf("Expected ErrValueOutOfRange for %d constant-time digits, got %v", n, err)
+		}
+	}
+}
+
+func TestDigitCount(t *testing.T) {
+	for _, tc := range []struct {
+		base     Base
+		bits, nd int
+	}{
+		{Base2, 8, 8},
+		{Base2, 64, 64},
+		{Base10, 8, 3},
+		{Base10, 64, 20},
+		{Base16, 64, 16},
+		{Base16, 65, 17},
+		{Base256, 128, 16},
+		{Base(3), 1, 1},
+	} {
+		nd, err := tc.base.DigitCount(tc.bits)
+		if err != nil {
+			t.Fatalf("DigitCount(%d) in base %d failed: %v", tc.bits, tc.base, err)
+		}
+
+		if nd != tc.nd {
+			t.Errorf("DigitCount(%d) in base %d is %d, expected %d", tc.bits, tc.base, nd, tc.nd)
+		}
+
+		// The largest value of the bit length fits, and nd is the smallest such count.
+		max := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), uint(tc.bits)), big.NewInt(1))
+		if _, err := tc.base.Digits(max, nd); err != nil {
+			t.Errorf("%d bits do not fit into %d digits of base %d: %v", tc.bits, nd, tc.base, err)
+		}
+		if _, err := tc.base.Digits(max, nd-1); !errors.Is(err, ErrValueOutOfRange) {
+			t.Errorf("%d bits fit into %d digits of base %d", tc.bits, nd-1, tc.base)
+		}
+	}
+
+	if _, err := Base(1).DigitCount(8); err == nil {
+		t.Error("Expected an error for base 1")
+	}
+
+	if _, err := Base16.DigitCount(0); err == nil {
+		t.Error("Expected an error for bit length 0")
+	}
+}
+
+func TestDigitsConstantTime(t *testing.T) {
+	values := []*big.Int{
+		big.NewInt(0),
+		big.NewInt(1),
+		new(big.Int).SetUint64(0xab4f0540ab4f0540),
+		new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 253), big.NewInt(1)),
+	}
+
+	for _, base := range []Base{Base2, 4, Base16, Base256} {
+		for _, x := range values {
+			n := 256 / constantTimeDigitBits(int(base))
+
+			fast, err := base.Digits(x, n)
+			if err != nil {
+				t.Fatalf("Digits failed: %v", err)
+			}
+
+			ct, err := base.Digits(x, n, WithConstantTime())
+			if err != nil {
+				t.Fatalf("Constant-time Digits failed: %v", err)
+			}
+
+			for i := range fast {
+				if fast[i].Cmp(ct[i]) != 0 {
+					t.Fatalf("Base %d, value %s: digit %d differs: %s and %s", base, x, i, fast[i], ct[i])
+				}
+			}
+
+			fastM, err := base.Mapping(fast)
+			if err != nil {
+				t.Fatalf("Mapping failed: %v", err)
+			}
+
+			ctM, err := base.Mapping(ct, WithConstantTime())
+			if err != nil {
+				t.Fatalf("Constant-time Mapping failed: %v", err)
+			}
+
+			for i := range fastM {
+				if fastM[i].Cmp(ctM[i]) != 0 {
+					t.Fatalf("Base %d, value %s: multiplicity %d differs", base, x, i)
+				}
+			}
+		}
+	}
+
+	// More digits than the buffer holds are zero.
+	digits, err := Base16.Digits(big.NewInt(0x21), 70, WithConstantTime())
+	if err != nil {
+		t.Fatalf("Digits failed: %v", err)
+	}
+	if len(digits) != 70 || digits[0].Int64() != 1 || digits[1].Int64() != 2 || digits[69].Sign() != 0 {
+		t.Errorf("Unexpected digits %v", digits)
+	}
+
+	for _, tc := range []struct {
+		name string
+		base Base
+		x    *big.Int
+		n    int
+	}{
+		{"does not fit", Base16, big.NewInt(0x100), 2},
+		{"negative", Base16, big.NewInt(-1), 2},
+		{"above 256 bits", Base2, new(big.Int).Lsh(big.NewInt(1), 256), 300},
+	} {
+		if _, err := tc.base.Digits(tc.x, tc.n, WithConstantTime()); !errors.Is(err, ErrValueOutOfRange) {
+			t.Errorf("%s: expected ErrValueOutOfRange, got %v", tc.name, err)
+		}
+	}
+
+	if _, err := Base10.Digits(big.NewInt(1), 2, WithConstantTime()); err == nil {
+		t.Error("Expected an error for a base that is not a supported power of two")
+	}
+}
+
+func BenchmarkDigits(b *testing.B) {
+	x := new(big.Int).SetUint64(0xab4f0540ab4f0540)
+
+	for _, variant := range []struct {
+		name string
+		opts []DigitOption
+	}{
+		{"Fast", nil},
+		{"ConstantTime", []DigitOption{WithConstantTime()}},
+	} {
+		b.Run(variant.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				digits, err := Base16.Digits(x, 16, variant.opts...)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if _, err := Base16.Mapping(digits, variant.opts...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestNewPrivateConstantTime(t *testing.T) {
+	x := new(big.Int).SetUint64(0xab4f0540ab4f0540)
+
+	for _, tc := range []struct {
+		base     Base
+		digitSet []int
+	}{
+		{Base16, nil},
+		{Base2, nil},
+		{Base10, nil},
+		{Base16, []int{0, 4, 5, 10, 11, 15}},
+	} {
+		nd, err := tc.base.DigitCount(64)
+		if err != nil {
+			t.Fatalf("DigitCount failed: %v", err)
+		}
+
+		public, err := NewReciprocalPublic(DOMAIN_RANGE, nd, tc.base)
+		if err != nil {
+			t.Fatalf("NewReciprocalPublic failed: %v", err)
+		}
+		public.DigitSet = tc.digitSet
+
+		private, err := public.newPrivate(x, NewRandScalar())
+		if err != nil {
+			t.Fatalf("Base %d: newPrivate failed: %v", tc.base, err)
+		}
+
+		digits, err := tc.base.Digits(x, nd)
+		if err != nil {
+			t.Fatalf("Digits failed: %v", err)
+		}
+
+		if fmt.Sprint(private.Digits) != fmt.Sprint(digits) {
+			t.Errorf("Base %d: digits %v, expected %v", tc.base, private.Digits, digits)
+		}
+
+		if err := private.validate(public); err != nil {
+			t.Errorf("Base %d: witness rejected: %v", tc.base, err)
+		}
+	}
+
+	// 0x...0540 has the digit 0 and 4, the set only allows 0 and 5.
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, Base16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+	public.DigitSet = []int{0, 5}
+
+	if _, err := public.newPrivate(x, NewRandScalar()); !errors.Is(err, ErrValueOutOfRange) {
+		t.Errorf("Expected ErrValueOutOfRange for a digit outside of the set, got %v", err)
+	}
+}
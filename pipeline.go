@@ -0,0 +1,120 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"runtime"
+	"sync"
+)
+
+// ProveRequest is the input of ProveRangeStream: a value and the blinding of its commitment
+// CommitValue(Value, Blinding).
+type ProveRequest struct {
+	Value    *big.Int
+	Blinding *big.Int
+}
+
+// ProveResult is the output of ProveRangeStream for one request. Index is the position of the request in the input
+// channel, starting at zero. Exactly one of Bundle and Err is set.
+type ProveResult struct {
+	Index   int
+	Request ProveRequest
+	Bundle  *ProofBundle
+	Err     error
+}
+
+// ProveRangeStream proves the range of every value received from in with a pool of workers and sends one
+// ProveResult per request to the returned channel, e.g. for the outputs of a mint. Every proof gets a fresh
+// NewKeccakFS transcript, so the proofs are independent of each other and each one verifies with VerifyBundle.
+// The workers share one Prover, see NewProver. A non-positive workers uses runtime.GOMAXPROCS(0).
+//
+// Results arrive in the order the proofs complete, not in the order of the requests; use Index to match them. The
+// returned channel is closed once in is closed and all results have been sent, or once ctx is done. After
+// cancellation requests that have not been proven yet are dropped without a result.
+func ProveRangeStream(ctx context.Context, public *ReciprocalPublic, in <-chan ProveRequest, workers int) <-chan ProveResult {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	type job struct {
+		index   int
+		request ProveRequest
+	}
+
+	jobs := make(chan job)
+	out := make(chan ProveResult, workers)
+
+	// The dispatcher numbers the requests, so the workers can read them concurrently.
+	go func() {
+		defer close(jobs)
+		for i := 0; ; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case req, ok := <-in:
+				if !ok {
+					return
+				}
+				select {
+				case <-ctx.Done():
+					return
+				case jobs <- job{index: i, request: req}:
+				}
+			}
+		}
+	}()
+
+	prover := NewProver(public)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				res := ProveResult{Index: j.index, Request: j.request}
+				res.Bundle, res.Err = prover.proveRequest(j.request)
+
+				select {
+				case <-ctx.Done():
+					return
+				case out <- res:
+				}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// proveRequest proves the range of the value of req with a fresh transcript.
+func (p *Prover) proveRequest(req ProveRequest) (*ProofBundle, error) {
+	if req.Value == nil {
+		return nil, errors.New("value cannot be nil")
+	}
+
+	if req.Blinding == nil {
+		return nil, errors.New("blinding cannot be nil")
+	}
+
+	if _, err := p.coefficients(); err != nil {
+		return nil, err
+	}
+
+	private, err := p.public.newPrivate(req.Value, req.Blinding)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.proveBundle(NewKeccakFS(), private)
+}
@@ -0,0 +1,79 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestProveRangeStream(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	requests := []ProveRequest{
+		{Value: big.NewInt(0), Blinding: NewRandScalar()},
+		{Value: new(big.Int).SetUint64(0xab4f0540ab4f0540), Blinding: NewRandScalar()},
+		{Value: new(big.Int).Lsh(big.NewInt(1), 64), Blinding: NewRandScalar()},
+		{Value: big.NewInt(42), Blinding: NewRandScalar()},
+	}
+
+	in := make(chan ProveRequest)
+	go func() {
+		defer close(in)
+		for _, req := range requests {
+			in <- req
+		}
+	}()
+
+	seen := make(map[int]bool)
+	for res := range ProveRangeStream(context.Background(), public, in, 2) {
+		if seen[res.Index] {
+			t.Errorf("Duplicate result for request %d", res.Index)
+		}
+		seen[res.Index] = true
+
+		if res.Request.Value.Cmp(requests[res.Index].Value) != 0 {
+			t.Errorf("Result %d carries the wrong request", res.Index)
+		}
+
+		if res.Index == 2 {
+			if !errors.Is(res.Err, ErrValueOutOfRange) {
+				t.Errorf("Expected ErrValueOutOfRange, got %v", res.Err)
+			}
+			continue
+		}
+
+		if res.Err != nil {
+			t.Fatalf("Request %d failed: %v", res.Index, res.Err)
+		}
+
+		req := requests[res.Index]
+		if !pointsEqual(res.Bundle.Commitment, public.CommitValue(req.Value, req.Blinding)) {
+			t.Errorf("Request %d: bundle commitment does not match the request", res.Index)
+		}
+
+		if err := VerifyBundle(public, NewKeccakFS(), res.Bundle); err != nil {
+			t.Errorf("Request %d: bundle rejected: %v", res.Index, err)
+		}
+	}
+
+	if len(seen) != len(requests) {
+		t.Errorf("Expected %d results, got %d", len(requests), len(seen))
+	}
+
+	// Cancellation closes the results even though the input stays open.
+	ctx, cancel := context.WithCancel(context.Background())
+	out := ProveRangeStream(ctx, public, make(chan ProveRequest), 0)
+	cancel()
+
+	for range out {
+		t.Error("Unexpected result after cancellation")
+	}
+}
@@ -0,0 +1,148 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+)
+
+// PolynomialEvalProof proves that a polynomial commitment C = CommitPolynomial(gvec, coeffs, h, blinding) opens to
+// a polynomial p with p(z) = y. It is a Sigma protocol for the linear relation <coeffs, (1, z, z^2, ...)> = y:
+// the prover commits to a random polynomial d with A = <d, gvec> + rd*h and reveals T = d(z); for the challenge e
+// it answers F = d + e*coeffs and RF = rd + e*blinding. The proof size is linear in the degree.
+type PolynomialEvalProof struct {
+	A  *bn256.G1
+	T  *big.Int
+	F  []*big.Int
+	RF *big.Int
+}
+
+// CommitPolynomial creates a Pedersen vector commitment to the polynomial coefficients (lowest degree first).
+// Com = <coeffs, gvec> + blinding*h
+func CommitPolynomial(gvec []*bn256.G1, coeffs []*big.Int, h *bn256.G1, blinding *big.Int) (*bn256.G1, error) {
+	if len(coeffs) > len(gvec) {
+		return nil, fmt.Errorf("%d coefficients do not fit %d generators", len(coeffs), len(gvec))
+	}
+
+	if h == nil || blinding == nil {
+		return nil, errors.New("blinding generator and blinding cannot be nil")
+	}
+
+	res := vectorPointScalarMul(gvec[:len(coeffs)], coeffs)
+	res.Add(res, new(bn256.G1).ScalarMult(h, blinding))
+	return res, nil
+}
+
+// EvalPolynomial returns p(z) = sum coeffs[i] * z^i mod bn256.Order.
+func EvalPolynomial(coeffs []*big.Int, z *big.Int) *big.Int {
+	return vectorMul(coeffs, e(z, len(coeffs)))
+}
+
+func evalChallenge(fs FiatShamirEngine, com *bn256.G1, z, y *big.Int, A *bn256.G1, T *big.Int) (*big.Int, error) {
+	if err := fs.AddPoint(com); err != nil {
+		return nil, err
+	}
+	for _, x := range []*big.Int{z, y} {
+		if err := fs.AddNumber(x); err != nil {
+			return nil, err
+		}
+	}
+	if err := fs.AddPoint(A); err != nil {
+		return nil, err
+	}
+	if err := fs.AddNumber(T); err != nil {
+		return nil, err
+	}
+	return fs.GetChallenge(), nil
+}
+
+// EvalProof proves that the commitment CommitPolynomial(gvec, coeffs, h, blinding) opens to a polynomial that
+// evaluates to EvalPolynomial(coeffs, z) at z. Use empty FiatShamirEngine for call.
+func EvalProof(gvec []*bn256.G1, h *bn256.G1, fs FiatShamirEngine, coeffs []*big.Int, blinding, z *big.Int) (*PolynomialEvalProof, error) {
+	if fs == nil {
+		return nil, errors.New("Fiat-Shamir engine cannot be nil")
+	}
+
+	if z == nil {
+		return nil, errors.New("evaluation point cannot be nil")
+	}
+
+	com, err := CommitPolynomial(gvec, coeffs, h, blinding)
+	if err != nil {
+		return nil, err
+	}
+
+	d := make([]*big.Int, len(coeffs))
+	for i := range d {
+		d[i] = NewRandScalar()
+	}
+	rd := NewRandScalar()
+
+	A, err := CommitPolynomial(gvec, d, h, rd)
+	if err != nil {
+		return nil, err
+	}
+
+	T := EvalPolynomial(d, z)
+
+	c, err := evalChallenge(fs, com, z, EvalPolynomial(coeffs, z), A, T)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PolynomialEvalProof{
+		A:  A,
+		T:  T,
+		F:  vectorAdd(d, vectorMulOnScalar(coeffs, c)),
+		RF: add(rd, mul(c, blinding)),
+	}, nil
+}
+
+// EvalVerify verifies that the polynomial committed in com evaluates to y at z. If err is nil then proof is valid.
+// Use empty FiatShamirEngine for call.
+func EvalVerify(gvec []*bn256.G1, h *bn256.G1, fs FiatShamirEngine, com *bn256.G1, z, y *big.Int, proof *PolynomialEvalProof) error {
+	if fs == nil {
+		return errors.New("Fiat-Shamir engine cannot be nil")
+	}
+
+	if com == nil || z == nil || y == nil {
+		return errors.New("commitment, evaluation point and value cannot be nil")
+	}
+
+	if proof == nil || proof.A == nil || !isCanonicalScalar(proof.T) || !isCanonicalScalar(proof.RF) {
+		return errors.New("invalid evaluation proof")
+	}
+
+	for i := range proof.F {
+		if !isCanonicalScalar(proof.F[i]) {
+			return fmt.Errorf("%w: F[%d]", ErrNonCanonicalScalar, i)
+		}
+	}
+
+	c, err := evalChallenge(fs, com, z, y, proof.A, proof.T)
+	if err != nil {
+		return err
+	}
+
+	// <F, gvec> + RF*h == A + c*com
+	lhs, err := CommitPolynomial(gvec, proof.F, h, proof.RF)
+	if err != nil {
+		return err
+	}
+
+	if !pointsEqual(lhs, new(bn256.G1).Add(proof.A, new(bn256.G1).ScalarMult(com, c))) {
+		return errors.New("failed to verify evaluation proof: commitment mismatch")
+	}
+
+	// F(z) == T + c*y
+	if EvalPolynomial(proof.F, z).Cmp(add(proof.T, mul(c, y))) != 0 {
+		return errors.New("failed to verify evaluation proof: evaluation mismatch")
+	}
+
+	return nil
+}
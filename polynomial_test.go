@@ -0,0 +1,50 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestPolynomialEvalProof(t *testing.T) {
+	public := NewWeightNormLinearPublic(1, 8)
+
+	// p(x) = 3 + 2x + x^3, p(5) = 138
+	coeffs := []*big.Int{bint(3), bint(2), bint(0), bint(1)}
+	z, y := bint(5), bint(138)
+
+	if got := EvalPolynomial(coeffs, z); got.Cmp(y) != 0 {
+		t.Fatalf("Expected p(5) = 138, got %v", got)
+	}
+
+	blinding := NewRandScalar()
+
+	com, err := CommitPolynomial(public.GVec, coeffs, public.HVec[0], blinding)
+	if err != nil {
+		t.Fatalf("CommitPolynomial failed: %v", err)
+	}
+
+	proof, err := EvalProof(public.GVec, public.HVec[0], NewKeccakFS(), coeffs, blinding, z)
+	if err != nil {
+		t.Fatalf("EvalProof failed: %v", err)
+	}
+
+	if err := EvalVerify(public.GVec, public.HVec[0], NewKeccakFS(), com, z, y, proof); err != nil {
+		t.Fatalf("EvalVerify failed: %v", err)
+	}
+
+	if err := EvalVerify(public.GVec, public.HVec[0], NewKeccakFS(), com, z, bint(139), proof); err == nil {
+		t.Error("Proof accepted for a wrong value")
+	}
+
+	if err := EvalVerify(public.GVec, public.HVec[0], NewKeccakFS(), com, bint(6), y, proof); err == nil {
+		t.Error("Proof accepted for a wrong evaluation point")
+	}
+
+	if _, err := CommitPolynomial(public.GVec[:3], coeffs, public.HVec[0], blinding); err == nil {
+		t.Error("Expected an error for more coefficients than generators")
+	}
+}
@@ -0,0 +1,170 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"sync"
+)
+
+// Prover generates range proofs for one set of public parameters. Everything that depends on the parameters only
+// is computed lazily on the first proof and then shared. A Prover is safe for concurrent use by multiple
+// goroutines: the shared state is built exactly once under sync.Once and is read-only afterwards.
+type Prover struct {
+	public *ReciprocalPublic
+
+	once   sync.Once
+	coeffs *rangeCoefficients
+	err    error // result of the one-time parameter checks
+}
+
+// rangeCoefficients holds the parameter-dependent circuit coefficients shared by all proofs. It must not be modified
+// once built.
+type rangeCoefficients struct {
+	negBasePowers []*big.Int
+}
+
+// NewProver creates a Prover for the public parameters. The parameters must not be modified while the Prover is
+// in use.
+func NewProver(public *ReciprocalPublic) *Prover {
+	return &Prover{public: public}
+}
+
+func (p *Prover) coefficients() (*rangeCoefficients, error) {
+	p.once.Do(func() {
+		if p.err = p.public.Validate(); p.err != nil {
+			return
+		}
+
+		p.coeffs = &rangeCoefficients{
+			negBasePowers: negBasePowers(scalarField{bn256.Order}, p.public.Np, p.public.Nd),
+		}
+	})
+	return p.coeffs, p.err
+}
+
+// Prove generates zero knowledge proof that the committed value lies in [0, Np^Nd) range. See ProveRange.
+// Use empty FiatShamirEngine for call: an engine that has already derived challenges is rejected with
+// ErrTranscriptReused. Use ProveChained to continue a transcript.
+func (p *Prover) Prove(fs FiatShamirEngine, private *ReciprocalPrivate, opts ...ProveOption) (*ReciprocalProof, error) {
+	if err := checkFreshTranscript(fs); err != nil {
+		return nil, err
+	}
+
+	return p.ProveChained(fs, private, opts...)
+}
+
+// ProveChained works like Prove but continues the transcript fs as it is, even after it has derived challenges.
+// Statements made of several arguments use it to chain them over one transcript, e.g. two range proofs whose
+// second one depends on the challenges of the first. The verifier must chain the same arguments in the same order
+// with Verifier.VerifyChained.
+func (p *Prover) ProveChained(fs FiatShamirEngine, private *ReciprocalPrivate, opts ...ProveOption) (*ReciprocalProof, error) {
+	coeffs, err := p.coefficients()
+	if err != nil {
+		return nil, err
+	}
+
+	public, err := p.public.rangeParameters(fs, newProveConfig(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	// The tag is taken from the parameters as given: the views of rangeParameters drop the generator labels.
+	group := public.group()
+	return proveReciprocal(public, fs, private, rangeDomainTag(p.public, fs), func(e *big.Int) *GroupArithmeticCircuitPublic {
+		return rangeCircuit(group, e, coeffs.negBasePowers[:public.Nd])
+	}, opts...)
+}
+
+// proveReciprocal runs the reciprocal argument for the value committed by private over the circuit built for the
+// challenge e. Statements extending the range circuit must absorb their public inputs into fs beforehand. The domain
+// tag (see rangeDomainTag) is absorbed and recorded in the proof header.
+func proveReciprocal(public *ReciprocalPublic, fs FiatShamirEngine, private *ReciprocalPrivate, tag uint32, newCircuit func(e *big.Int) *GroupArithmeticCircuitPublic, opts ...ProveOption) (*ReciprocalProof, error) {
+	if fs == nil {
+		return nil, errors.New("Fiat-Shamir engine cannot be nil")
+	}
+
+	if err := private.validate(public); err != nil {
+		return nil, err
+	}
+
+	var ol *olCommitment
+	if dc := newProveConfig(opts).digitCommitment; dc != nil {
+		var err error
+		if ol, err = dc.open(public, private); err != nil {
+			return nil, err
+		}
+	}
+
+	proof, err := proveGroupReciprocal(public.group(), fs, private, tag, newCircuit, ol, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return proof.bn256(), nil
+}
+
+// proveGroupReciprocal works like proveReciprocal over an arbitrary group. The caller validates private and passes
+// the opened digit commitment, if any, as ol.
+func proveGroupReciprocal(public *GroupReciprocalPublic, fs FiatShamirEngine, private *ReciprocalPrivate, tag uint32, newCircuit func(e *big.Int) *GroupArithmeticCircuitPublic, ol *olCommitment, opts []ProveOption) (*GroupReciprocalProof, error) {
+	f := public.field()
+
+	if err := absorbDomainTag(fs, tag); err != nil {
+		return nil, err
+	}
+
+	// The value commitment is absorbed before the first challenge, so every challenge depends on it.
+	vCom := public.CommitValue(private.X, private.S)
+	if err := addGroupPoint(fs, vCom); err != nil {
+		return nil, err
+	}
+
+	e := f.challenge(fs)
+	if err := checkPoleChallenge(f, e, public.digitSet()); err != nil {
+		return nil, err
+	}
+
+	// The poles are the only work that depends on the digits, Nd inversions out of a proof dominated by the
+	// digit-independent commitments and WNLA rounds (see BenchmarkProveSparse). Skipping zero digits would save
+	// nothing measurable and make the prover's timing depend on which digits are zero, so every digit is treated
+	// alike.
+	r := make([]*big.Int, public.Nd)
+	for j := range r {
+		r[j] = f.inv(f.add(private.Digits[j], e))
+	}
+
+	rBlind := f.randScalar()
+	rCom := public.CommitPoles(r, rBlind)
+
+	v := []*big.Int{private.X}
+	v = append(v, r...)
+
+	circuit := newCircuit(e)
+
+	prv := &ArithmeticCircuitPrivate{
+		V:  [][]*big.Int{v},
+		Sv: []*big.Int{f.add(private.S, rBlind)},
+		Wl: private.Digits,
+		Wr: r,
+		Wo: private.M,
+	}
+
+	V := circuit.Commit(prv.V[0], prv.Sv[0])
+
+	circuitProof, err := proveCircuit(circuit, []Point{V}, fs, prv, ol, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GroupReciprocalProof{
+		GroupArithmeticCircuitProof: circuitProof,
+		V:                           rCom,
+		Nd:                          public.Nd,
+		Np:                          public.Np,
+		Domain:                      tag,
+	}, nil
+}
@@ -0,0 +1,257 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"bytes"
+	"errors"
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"sync"
+	"testing"
+)
+
+// TestProverConcurrent proves against one shared Prover from many goroutines. Run with -race to check that the
+// lazily built coefficients are shared safely.
+func TestProverConcurrent(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	prover := NewProver(public)
+
+	const workers = 8
+
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			x := uint64(0x1111111111111111) * uint64(i+1)
+			digits := UInt64Hex(x)
+
+			private := &ReciprocalPrivate{
+				X:      new(big.Int).SetUint64(x),
+				M:      HexMapping(digits),
+				Digits: digits,
+				S:      NewRandScalar(),
+			}
+
+			proof, err := prover.Prove(NewKeccakFS(), private)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			errs[i] = VerifyRange(public, public.CommitValue(private.X, private.S), NewKeccakFS(), proof)
+		}(i)
+	}
+
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Proof %d failed verification: %v", i, err)
+		}
+	}
+}
+
+func TestValueCommitmentAbsorbed(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	private, err := public.newPrivate(bint(0xcafe), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	challenges := make([]*big.Int, 64)
+	for i := range challenges {
+		challenges[i] = NewRandScalar()
+	}
+
+	proverFS := NewMockFS(challenges...)
+	proof, err := ProveRange(public, proverFS, private)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	vCom := public.CommitValue(private.X, private.S)
+
+	verifierFS := NewMockFS(challenges...)
+	if err := VerifyRange(public, vCom, verifierFS, proof); err != nil {
+		t.Fatalf("VerifyRange failed: %v", err)
+	}
+
+	// Both sides absorb the value commitment before anything else.
+	for name, fs := range map[string]*MockFS{"prover": proverFS, "verifier": verifierFS} {
+		if fs.Err() != nil {
+			t.Fatalf("%s: %v", name, fs.Err())
+		}
+		if e := fs.Entries[0]; e.Op != "point" || !bytes.Equal(e.Data, vCom.Marshal()) {
+			t.Errorf("%s: first transcript entry is %s, not the value commitment", name, e.Op)
+		}
+	}
+
+	// With a hash transcript another commitment changes every challenge. Even with the challenges fixed the proof
+	// is rejected, because the verifier also uses the commitment in the circuit equation.
+	other := public.CommitValue(bint(0xcaff), private.S)
+	expectRejected(t, VerifyRange(public, other, NewMockFS(challenges...), proof), VerifyStageWNLA, ErrFinalCommitmentMismatch)
+}
+
+// TestStatementTranscriptReuse checks that the provers and verifiers of the statements built on the range argument
+// reject a used engine before looking at their other arguments.
+func TestStatementTranscriptReuse(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	value, blinding := bint(5), NewRandScalar()
+	com := public.CommitValue(value, blinding)
+	set := []*big.Int{bint(1), bint(5)}
+
+	for name, call := range map[string]func(fs FiatShamirEngine) error{
+		"ProveBit": func(fs FiatShamirEngine) error {
+			_, err := ProveBit(public, fs, value, blinding, 0, 1)
+			return err
+		},
+		"VerifyBit": func(fs FiatShamirEngine) error { return VerifyBit(public, fs, com, 0, 1, nil) },
+		"ProveDigitSum": func(fs FiatShamirEngine) error {
+			_, err := ProveDigitSum(public, fs, value, blinding, Base16, bint(5))
+			return err
+		},
+		"VerifyDigitSum": func(fs FiatShamirEngine) error { return VerifyDigitSum(public, fs, com, Base16, bint(5), nil) },
+		"ProveMembershipCommitted": func(fs FiatShamirEngine) error {
+			_, err := ProveMembershipCommitted(public, fs, value, blinding, com, set, blinding)
+			return err
+		},
+		"VerifyMembershipCommitted": func(fs FiatShamirEngine) error {
+			return VerifyMembershipCommitted(public, fs, com, com, len(set), nil)
+		},
+		"ProveNonMembership": func(fs FiatShamirEngine) error {
+			_, err := ProveNonMembership(public, fs, bint(7), set, blinding)
+			return err
+		},
+		"VerifyNonMembership": func(fs FiatShamirEngine) error { return VerifyNonMembership(public, fs, com, set, nil) },
+		"ProveRangeAggregated": func(fs FiatShamirEngine) error {
+			_, err := ProveRangeAggregated(public, fs, []*big.Int{value}, []*big.Int{blinding})
+			return err
+		},
+		"VerifyRangeAggregated": func(fs FiatShamirEngine) error {
+			return VerifyRangeAggregated(public, []*bn256.G1{com}, fs, nil)
+		},
+	} {
+		used := NewKeccakFS()
+		used.GetChallenge()
+
+		if err := call(used); !errors.Is(err, ErrTranscriptReused) {
+			t.Errorf("%s: expected ErrTranscriptReused, got %v", name, err)
+		}
+	}
+}
+
+func TestTranscriptReuse(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	private, err := public.newPrivate(bint(0xbeef), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	VCom := public.CommitValue(private.X, private.S)
+
+	fs := NewKeccakFS(WithAppDomain("app"))
+	proof, err := ProveRange(public, fs, private)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	if err := VerifyRange(public, VCom, fs, proof); !errors.Is(err, ErrTranscriptReused) {
+		t.Errorf("Expected ErrTranscriptReused when verifying with the prover's engine, got %v", err)
+	}
+
+	if _, err := ProveRange(public, fs, private); !errors.Is(err, ErrTranscriptReused) {
+		t.Errorf("Expected ErrTranscriptReused for a second proof, got %v", err)
+	}
+
+	verifierFS := NewKeccakFS(WithAppDomain("app"))
+	if err := VerifyRange(public, VCom, verifierFS, proof); err != nil {
+		t.Fatalf("VerifyRange failed: %v", err)
+	}
+
+	if err := VerifyRange(public, VCom, verifierFS, proof); !errors.Is(err, ErrTranscriptReused) {
+		t.Errorf("Expected ErrTranscriptReused for a second verification, got %v", err)
+	}
+
+	// Proofs chained over one transcript verify when the verifier chains them in the same order.
+	other, err := public.newPrivate(bint(0xcafe), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+	otherCom := public.CommitValue(other.X, other.S)
+
+	fs = NewKeccakFS()
+	first, err := NewProver(public).ProveChained(fs, private)
+	if err != nil {
+		t.Fatalf("ProveChained failed: %v", err)
+	}
+
+	second, err := NewProver(public).ProveChained(fs, other)
+	if err != nil {
+		t.Fatalf("ProveChained failed for the second proof: %v", err)
+	}
+
+	verifier := NewVerifier(public)
+	verifierFS = NewKeccakFS()
+	if err := verifier.VerifyChained(VCom, verifierFS, first); err != nil {
+		t.Fatalf("VerifyChained failed: %v", err)
+	}
+
+	if err := verifier.VerifyChained(otherCom, verifierFS, second); err != nil {
+		t.Errorf("VerifyChained failed for the second proof: %v", err)
+	}
+
+	if err := VerifyRange(public, otherCom, NewKeccakFS(), second); err == nil {
+		t.Error("A chained proof verified on a fresh transcript")
+	}
+}
+
+// BenchmarkProveSparse compares a value with all digits set to a small one with mostly zero digits. Both take the
+// same time: see the note on the poles in proveReciprocal.
+func BenchmarkProveSparse(b *testing.B) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		b.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	prover := NewProver(public)
+
+	for _, bc := range []struct {
+		name  string
+		value uint64
+	}{{"dense", 0xab4f0540ab4f0541}, {"sparse", 5}} {
+		private, err := public.newPrivate(new(big.Int).SetUint64(bc.value), NewRandScalar())
+		if err != nil {
+			b.Fatalf("newPrivate failed: %v", err)
+		}
+
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := prover.Prove(NewKeccakFS(), private); err != nil {
+					b.Fatalf("Prove failed: %v", err)
+				}
+			}
+		})
+	}
+}
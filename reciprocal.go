@@ -5,201 +5,870 @@
 package bulletproofs
 
 import (
+	"errors"
+	"fmt"
 	"github.com/cloudflare/bn256"
 	"math/big"
 )
 
+// GroupReciprocalPublic contains the public values of the reciprocal range proof over an arbitrary Group, with the
+// dimensions and generator layout of ReciprocalPublic. ReciprocalPublic is the same structure fixed to the BN256
+// backend; it adds the derivation labels and the RangeOptions on top.
+type GroupReciprocalPublic struct {
+	Group  Group
+	G      Point
+	GVec   []Point // Nd
+	HVec   []Point // Nd+1+circuitBlindingSlots
+	Nd, Np int
+
+	// Vectors of points that will be used in WNLA protocol
+	GVec_ []Point // 2^n - Nd
+	HVec_ []Point // 2^n - (Nd+1+circuitBlindingSlots)
+
+	// Optional generator domain, set by NewGroupReciprocalPublic. It enters the domain tag of the proofs (see
+	// DomainTag) like the domain of GeneratorLabels does for ReciprocalPublic.
+	Domain string
+
+	// Optional legal digit values, see ReciprocalPublic.DigitSet.
+	DigitSet []int
+}
+
+// GroupReciprocalProof is the range proof over an arbitrary Group, see ReciprocalProof.
+type GroupReciprocalProof struct {
+	*GroupArithmeticCircuitProof
+	V Point
+
+	Nd, Np int
+	Domain uint32
+}
+
+func (p *GroupReciprocalPublic) field() scalarField {
+	return scalarField{p.Group.Order()}
+}
+
+func (p *ReciprocalPublic) group() *GroupReciprocalPublic {
+	res := &GroupReciprocalPublic{
+		Group:    BN256,
+		G:        toPoint(p.G),
+		GVec:     toPoints(p.GVec),
+		HVec:     toPoints(p.HVec),
+		Nd:       p.Nd,
+		Np:       p.Np,
+		GVec_:    toPoints(p.GVec_),
+		HVec_:    toPoints(p.HVec_),
+		DigitSet: p.DigitSet,
+	}
+	if p.Labels != nil {
+		res.Domain = p.Labels.Domain
+	}
+	return res
+}
+
+func (p *ReciprocalProof) group() *GroupReciprocalProof {
+	if p == nil {
+		return nil
+	}
+
+	return &GroupReciprocalProof{
+		GroupArithmeticCircuitProof: p.ArithmeticCircuitProof.group(),
+		V:                           toPoint(p.V),
+		Nd:                          p.Nd,
+		Np:                          p.Np,
+		Domain:                      p.Domain,
+	}
+}
+
+func (p *GroupReciprocalProof) bn256() *ReciprocalProof {
+	return &ReciprocalProof{
+		ArithmeticCircuitProof: p.GroupArithmeticCircuitProof.bn256(),
+		V:                      toG1(p.V),
+		Nd:                     p.Nd,
+		Np:                     p.Np,
+		Domain:                 p.Domain,
+	}
+}
+
+// CommitValue creates the value commitment VCom = v*G + s*HVec[0] over the group, see ReciprocalPublic.CommitValue.
+func (p *GroupReciprocalPublic) CommitValue(v *big.Int, s *big.Int) Point {
+	g := p.Group
+	return g.Add(g.ScalarMult(p.G, v), g.ScalarMult(p.HVec[0], s))
+}
+
+// CommitValue creates the value commitment VCom = v*G + s*HVec[0].
+// HVec[0] is the blinding generator: it is the first of the circuitBlindingSlots blinding slots of the arithmetic
+// circuit (see CommitCircuit) and never carries witness values. The digits are committed with GVec and the digit
+// reciprocals with HVec[circuitBlindingSlots:], so the blinding generator must differ from all of them, which
+// VerifyRange checks.
 func (p *ReciprocalPublic) CommitValue(v *big.Int, s *big.Int) *bn256.G1 {
-	res := new(bn256.G1).ScalarMult(p.G, v)
-	res.Add(res, new(bn256.G1).ScalarMult(p.HVec[0], s))
+	return CommitValueWith(p.G, p.HVec[0], v, s)
+}
+
+// CommitValueWith creates the value commitment value*g + blinding*h with caller-supplied generators, e.g. those of
+// an external commitment scheme. A range proof for such a commitment is produced and verified with the
+// WithValueGenerators(g, h) option.
+//
+// The commitment only binds the value if nobody knows the discrete logarithm of h with respect to g: with
+// h = x*g anyone can open it to any value. For a range proof, g and h must in addition be independent of the
+// generators of the parameters, which WithValueGenerators can only check for equality.
+func CommitValueWith(g, h *bn256.G1, value, blinding *big.Int) *bn256.G1 {
+	res := new(bn256.G1).ScalarMult(g, add(value, nil))
+	res.Add(res, new(bn256.G1).ScalarMult(h, add(blinding, nil)))
+	return res
+}
+
+// CommitValueChecked works like CommitValue but fails with ErrValueOutOfRange if v is not in [0, Np^Nd), so a value
+// no range proof can be produced for is caught when it is committed.
+func (p *ReciprocalPublic) CommitValueChecked(v *big.Int, s *big.Int) (*bn256.G1, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	if v == nil || s == nil {
+		return nil, errors.New("value and blinding cannot be nil")
+	}
+
+	if _, _, err := p.decompose(v); err != nil {
+		return nil, err
+	}
+
+	return p.CommitValue(v, s), nil
+}
+
+// VerifyOpening reports whether com = CommitValue(value, blinding). It is not a proof: checking an opening requires
+// knowing value and blinding, so it only serves tests and provers checking their own inputs before proving.
+func VerifyOpening(public *ReciprocalPublic, com *bn256.G1, value, blinding *big.Int) bool {
+	if public.Validate() != nil || com == nil || value == nil || blinding == nil {
+		return false
+	}
+	return pointsEqual(public.CommitValue(value, blinding), com)
+}
+
+// VerifyOpeningConstantTime works like VerifyOpening, but recomputes the commitment with scalar multiplications
+// whose sequence of operations does not depend on value and blinding (see maskedScalarMult) and compares it with com
+// in constant time. Use it where an opening is revealed to a verifier over a private channel while someone else can
+// measure how long the verifier takes, e.g. another tenant on the same machine or a client timing a service's
+// responses: the running time of VerifyOpening depends on the bits of the value and leaks part of it.
+//
+// It is about three times slower than VerifyOpening, see BenchmarkVerifyOpening. The checks of the public inputs,
+// and the *big.Int handling of the value, are not constant-time (see WithConstantTime for the limits of math/big).
+func VerifyOpeningConstantTime(public *ReciprocalPublic, com *bn256.G1, value, blinding *big.Int) bool {
+	if public.Validate() != nil || com == nil || value == nil || blinding == nil {
+		return false
+	}
+
+	// The products carry different masks, so the sum takes no shortcut for a zero value or blinding either.
+	masks := scalarMultMasks()
+	expected := maskedScalarMult(public.G, value, masks[0], nil)
+	expected.Add(expected, maskedScalarMult(public.HVec[0], blinding, masks[1], nil))
+
+	target := new(bn256.G1).Add(com, masks[0].end)
+	target.Add(target, masks[1].end)
+
+	return pointsEqual(expected, target)
+}
+
+// RerandomizeCommitment returns com + deltaBlinding*H, where H = HVec[0] is the blinding generator of CommitValue
+// (see BlindingGenerator). The result commits to the same value and cannot be linked to com without knowing
+// deltaBlinding: if com = CommitValue(v, s), the new commitment opens to v with blinding s + deltaBlinding.
+// Proofs are bound to the commitment they were made for, so the owner has to prove again with the new blinding.
+func (p *ReciprocalPublic) RerandomizeCommitment(com *bn256.G1, deltaBlinding *big.Int) *bn256.G1 {
+	res := new(bn256.G1).ScalarMult(p.HVec[0], add(deltaBlinding, nil))
+	res.Add(res, com)
+	return res
+}
+
+// Validate checks that the public parameters have the dimensions the range proof relies on, that no generator is
+// missing, that G is a valid point other than the identity and that the blinding generator is not reused anywhere
+// else.
+func (p *ReciprocalPublic) Validate() error {
+	if p == nil {
+		return errors.New("range proof public parameters cannot be nil")
+	}
+
+	return p.group().Validate()
+}
+
+// Validate checks the parameters like ReciprocalPublic.Validate does.
+func (p *GroupReciprocalPublic) Validate() error {
+	if p == nil {
+		return errors.New("range proof public parameters cannot be nil")
+	}
+
+	if p.Group == nil {
+		return errors.New("group cannot be nil")
+	}
+
+	if p.Nd < 1 {
+		return fmt.Errorf("invalid dimensions: Nd=%d, Np=%d", p.Nd, p.Np)
+	}
+
+	if err := Base(p.Np).Validate(); err != nil {
+		return err
+	}
+
+	if p.G == nil {
+		return errors.New("generator G cannot be nil")
+	}
+
+	if err := checkBasePoint(p.Group, p.G); err != nil {
+		return err
+	}
+
+	if p.DigitSet != nil {
+		if len(p.DigitSet) == 0 {
+			return errors.New("digit set cannot be empty")
+		}
+
+		for i, d := range p.DigitSet {
+			if d < 0 || d >= p.Np {
+				return fmt.Errorf("digit set entry %d is outside of [0, %d)", d, p.Np)
+			}
+			if i > 0 && d <= p.DigitSet[i-1] {
+				return errors.New("digit set must be strictly ascending")
+			}
+		}
+	}
+
+	if len(p.GVec) != p.Nd {
+		return fmt.Errorf("len(GVec)=%d does not match Nd=%d", len(p.GVec), p.Nd)
+	}
+
+	if len(p.HVec) != reciprocalHVecLen(p.Nd) {
+		return fmt.Errorf("len(HVec)=%d does not match Nd+1+%d=%d", len(p.HVec), circuitBlindingSlots, reciprocalHVecLen(p.Nd))
+	}
+
+	for _, vec := range []struct {
+		name   string
+		points []Point
+	}{{"GVec", p.GVec}, {"HVec", p.HVec}, {"GVec_", p.GVec_}, {"HVec_", p.HVec_}} {
+		for i, g := range vec.points {
+			if g == nil {
+				return fmt.Errorf("%s[%d] cannot be nil", vec.name, i)
+			}
+		}
+	}
+
+	if err := p.checkBlindingGenerator(); err != nil {
+		return err
+	}
+
+	// The WNLA runs over GVec||GVec_ and HVec||HVec_, which the generators pad to a power of 2. A view for fewer
+	// digits moves generators from GVec and HVec into the padding, so only the sum is checked.
+	for _, vec := range []struct {
+		name            string
+		points, padding []Point
+	}{{"GVec", p.GVec, p.GVec_}, {"HVec", p.HVec, p.HVec_}} {
+		if n := len(vec.points) + len(vec.padding); powerOfTwo(n) != n {
+			return fmt.Errorf("len(%s_)=%d does not pad len(%s)=%d to a power of 2: %s_ needs %d more generators",
+				vec.name, len(vec.padding), vec.name, len(vec.points), vec.name, powerOfTwo(n)-n)
+		}
+	}
+
+	return nil
+}
+
+// reciprocalHVecLen returns the length of HVec for Nd digits: the circuitBlindingSlots blinding generators, where
+// HVec[0] doubles as the blinding generator of the value commitment, followed by the Nv = Nd+1 witness generators
+// of the value and the digit reciprocals.
+func reciprocalHVecLen(Nd int) int {
+	return Nd + 1 + circuitBlindingSlots
+}
+
+// ErrDigitCapacity is returned when a digit multiplicity does not fit into the circuit. The range circuit places
+// the multiplicities into the Nd+1 witness slots, so with fewer slots than digits only the first Nd+1 digit values
+// can be proven. Use Nd >= Np-1, or a DigitSet of at most Nd+1 digits, to prove every digit.
+var ErrDigitCapacity = errors.New("digit multiplicities do not fit into the witness slots")
+
+// ErrDigitMismatch is returned when the digits of a witness do not encode its value in base Np, least significant
+// digit first.
+var ErrDigitMismatch = errors.New("digits do not encode the value")
+
+// ErrInvalidMapping is returned when the digit multiplicities M of a witness, e.g. from HexMapping or
+// Base.Mapping, do not fit the public parameters or do not count the digits of the witness.
+var ErrInvalidMapping = errors.New("invalid digit multiplicities")
+
+// ErrDegenerateBlinding is returned when the blinding of a value commitment is not a canonical scalar or is within
+// minBlinding of zero, on either side. With a zero blinding the commitment is a deterministic function of the
+// value: equal values become linkable and small values can be found by trying them. A blinding like 1 or -1 is
+// just as easy to guess; a random scalar falls into these ranges with probability below 2^-189.
+var ErrDegenerateBlinding = errors.New("blinding must be in [2^64, order - 2^64]")
+
+// minBlinding bounds the blindings checkBlinding rejects as guessable: s < minBlinding or s > order - minBlinding.
+var minBlinding = new(big.Int).Lsh(big.NewInt(1), 64)
+
+// checkBlinding rejects blinding values of the field f that do not hide the committed value.
+func checkBlinding(f scalarField, s *big.Int) error {
+	if s.Cmp(minBlinding) < 0 || s.Cmp(new(big.Int).Sub(f.order, minBlinding)) > 0 {
+		return ErrDegenerateBlinding
+	}
+	return nil
+}
+
+// validate checks that the witness is complete and matches the dimensions of the public parameters.
+func (p *ReciprocalPrivate) validate(public *ReciprocalPublic) error {
+	return p.validateGroup(public.group())
+}
+
+// validateGroup works like validate for parameters over an arbitrary group.
+func (p *ReciprocalPrivate) validateGroup(public *GroupReciprocalPublic) error {
+	f := public.field()
+
+	if p == nil {
+		return errors.New("range proof private values cannot be nil")
+	}
+
+	if p.X == nil || p.S == nil {
+		return errors.New("value and blinding cannot be nil")
+	}
+
+	if err := checkBlinding(f, p.S); err != nil {
+		return err
+	}
+
+	if len(p.Digits) != public.Nd {
+		return fmt.Errorf("expected %d digits, got %d", public.Nd, len(p.Digits))
+	}
+
+	set := public.digitSet()
+
+	if len(p.M) != len(set) {
+		return fmt.Errorf("%w: expected %d digit multiplicities for Np=%d, got %d",
+			ErrInvalidMapping, len(set), public.Np, len(p.M))
+	}
+
+	for i := range p.Digits {
+		if p.Digits[i] == nil {
+			return fmt.Errorf("digit %d cannot be nil", i)
+		}
+	}
+
+	for i := range p.M {
+		if p.M[i] == nil {
+			return fmt.Errorf("multiplicity %d cannot be nil", i)
+		}
+
+		if p.M[i].Sign() < 0 || p.M[i].Cmp(bint(public.Nd)) > 0 {
+			return fmt.Errorf("%w: multiplicity %d is %s, not in [0, Nd=%d]", ErrInvalidMapping, i, p.M[i], public.Nd)
+		}
+
+		if i > public.Nd && p.M[i].Sign() != 0 {
+			return fmt.Errorf("%w: digit %d is used, but Nd=%d only has room for %d digits: base Np=%d needs Nd >= %d",
+				ErrDigitCapacity, set[i], public.Nd, public.Nd+1, public.Np, len(p.M)-1)
+		}
+	}
+
+	// A mapping that does not count the digits only fails deep inside the WNLA, so compare it here. The digits are
+	// secret: they are counted in constant time, and every digit is legal exactly if all of them are counted.
+	for i, d := range p.Digits {
+		if d.Sign() < 0 || d.BitLen() > 31 {
+			return fmt.Errorf("%w: digit %d is %s, which is not a legal digit", ErrInvalidMapping, i, d)
+		}
+	}
+
+	expected := digitMappingConstantTime(p.Digits, set)
+	if counted := sumInts(expected); counted != len(p.Digits) {
+		for i, d := range p.Digits {
+			if digitIndex(set, d) < 0 {
+				return fmt.Errorf("%w: digit %d is %s, which is not a legal digit", ErrInvalidMapping, i, d)
+			}
+		}
+	}
+
+	for i := range p.M {
+		if p.M[i].Cmp(expected[i]) != 0 {
+			return fmt.Errorf("%w: multiplicity of digit %d is %s, the digits contain it %s times",
+				ErrInvalidMapping, set[i], p.M[i], expected[i])
+		}
+	}
+
+	// Digits of another value, e.g. the right digits in the opposite order, would also only fail in the WNLA.
+	x := f.add(p.X, nil)
+	if f.add(composeDigits(p.Digits, public.Np), nil).Cmp(x) != 0 {
+		if f.add(composeDigits(reverseDigits(p.Digits), public.Np), nil).Cmp(x) == 0 {
+			return fmt.Errorf("%w: the digits are most significant first, expected least significant first",
+				ErrDigitMismatch)
+		}
+		return ErrDigitMismatch
+	}
+
+	return nil
+}
+
+// digitSet returns the legal digit values: DigitSet if set, otherwise 0..Np-1.
+func (p *ReciprocalPublic) digitSet() []int {
+	if p.DigitSet != nil {
+		return p.DigitSet
+	}
+
+	return Base(p.Np).set()
+}
+
+func (p *GroupReciprocalPublic) digitSet() []int {
+	if p.DigitSet != nil {
+		return p.DigitSet
+	}
+
+	return Base(p.Np).set()
+}
+
+// decompose returns the Nd digits of x in base Np and their multiplicities. It fails with ErrValueOutOfRange if x
+// is negative, does not fit into Nd digits or has a digit outside of the digit set.
+//
+// The value is secret, so for the bases of the constant-time decomposition and values of up to 256 bits the digits
+// are extracted and counted as with WithConstantTime; other bases fall back to the variable-time decomposition.
+func (p *ReciprocalPublic) decompose(x *big.Int) (digits, m []*big.Int, err error) {
+	set := p.digitSet()
+
+	if constantTimeDigitBits(p.Np) == 0 || x.BitLen() > 256 {
+		if digits, err = digitDecompose(x, p.Np, p.Nd); err != nil {
+			return nil, nil, err
+		}
+	} else if digits, err = digitDecomposeConstantTime(x, p.Np, p.Nd); err != nil {
+		return nil, nil, err
+	}
+
+	// Every digit is in [0, Np), so it is legal exactly if all of them are counted.
+	m = digitMappingConstantTime(digits, set)
+	if sumInts(m) != len(digits) {
+		for i, d := range digits {
+			if digitIndex(set, d) < 0 {
+				return nil, nil, fmt.Errorf("%w: digit %d is %v, which is not in the digit set", ErrValueOutOfRange, i, d)
+			}
+		}
+	}
+
+	return digits, m, nil
+}
+
+// sumInts returns the sum of small non-negative values, such as digit multiplicities.
+func sumInts(v []*big.Int) int {
+	res := 0
+	for _, x := range v {
+		res += int(x.Int64())
+	}
 	return res
 }
 
+// newPrivate decomposes x into Nd digits of base Np and builds the range proof witness for the commitment
+// CommitValue(x, s). It fails if x is negative, does not fit into the range or uses an illegal digit.
+func (p *ReciprocalPublic) newPrivate(x, s *big.Int) (*ReciprocalPrivate, error) {
+	digits, m, err := p.decompose(x)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReciprocalPrivate{
+		X:      x,
+		M:      m,
+		Digits: digits,
+		S:      s,
+	}, nil
+}
+
+// DeterministicPrivate builds the range proof witness for x with the blinding DeterministicBlinding(secretKey, x),
+// where x is encoded as 32 bytes big-endian, and fails like it for a short key. Every node holding secretKey derives the same commitment
+// CommitValue(x, S) for x. The proofs themselves still draw fresh nonces: reusing nonces across different
+// transcripts would reveal the witness, so proofs of the same value differ between nodes but all verify for the
+// shared commitment.
+func (p *ReciprocalPublic) DeterministicPrivate(secretKey []byte, x *big.Int) (*ReciprocalPrivate, error) {
+	if x == nil {
+		return nil, errors.New("value cannot be nil")
+	}
+
+	if x.Sign() < 0 {
+		return nil, errors.New("value cannot be negative")
+	}
+
+	s, err := DeterministicBlinding(secretKey, scalarTo32Byte(x))
+	if err != nil {
+		return nil, err
+	}
+
+	return p.newPrivate(x, s)
+}
+
+// BlindingGenerator returns the generator used for the blinding term of CommitValue.
+func (p *ReciprocalPublic) BlindingGenerator() *bn256.G1 {
+	return p.HVec[0]
+}
+
+// checkBlindingGenerator ensures the blinding generator does not appear anywhere else in the parameters.
+// If it did, a prover knowing the relation could move value between the blinding term and the argument
+// generators and open the commitment to a different value. Reuse is reported as ErrDuplicateGenerator.
+func (p *ReciprocalPublic) checkBlindingGenerator() error {
+	return p.group().checkBlindingGenerator()
+}
+
+func (p *GroupReciprocalPublic) checkBlindingGenerator() error {
+	if len(p.HVec) == 0 || p.HVec[0] == nil {
+		return errors.New("blinding generator HVec[0] is missing")
+	}
+
+	g, h := p.Group, p.HVec[0]
+
+	if p.G != nil && g.Equal(h, p.G) {
+		return fmt.Errorf("%w: blinding generator HVec[0] equals the value generator G", ErrDuplicateGenerator)
+	}
+
+	for _, vec := range []struct {
+		name   string
+		points []Point
+	}{{"GVec", p.GVec}, {"GVec_", p.GVec_}, {"HVec_", p.HVec_}} {
+		for i, point := range vec.points {
+			if point != nil && g.Equal(h, point) {
+				return fmt.Errorf("%w: blinding generator HVec[0] is reused as %s[%d]", ErrDuplicateGenerator, vec.name, i)
+			}
+		}
+	}
+
+	for i, point := range p.HVec[1:] {
+		if point != nil && g.Equal(h, point) {
+			return fmt.Errorf("%w: blinding generator HVec[0] is reused as HVec[%d]", ErrDuplicateGenerator, i+1)
+		}
+	}
+
+	return nil
+}
+
+// CommitPoles commits to the pole values r with blinding s: s*HVec[0] + <r, HVec[circuitBlindingSlots:]>.
 func (p *ReciprocalPublic) CommitPoles(r []*big.Int, s *big.Int) *bn256.G1 {
 	res := new(bn256.G1).ScalarMult(p.HVec[0], s)
-	res.Add(res, vectorPointScalarMul(p.HVec[9:], r))
+	res.Add(res, vectorPointScalarMul(p.HVec[circuitBlindingSlots:], r))
 	return res
 }
 
+// CommitPoles commits to the pole values r with blinding s, see ReciprocalPublic.CommitPoles.
+func (p *GroupReciprocalPublic) CommitPoles(r []*big.Int, s *big.Int) Point {
+	g := p.Group
+	return g.Add(g.ScalarMult(p.HVec[0], s), groupVectorPointScalarMul(g, p.HVec[circuitBlindingSlots:], r))
+}
+
 // ProveRange generates zero knowledge proof that corresponding to the committed digits vector value lies in [0, 2^n) range.
-// Use empty FiatShamirEngine for call.
-func ProveRange(public *ReciprocalPublic, fs FiatShamirEngine, private *ReciprocalPrivate) *ReciprocalProof {
-	vCom := public.CommitValue(private.X, private.S)
-	fs.AddPoint(vCom)
+// Use empty FiatShamirEngine for call: an engine that has already derived challenges is rejected with
+// ErrTranscriptReused, so proofs sharing a transcript must be chained with Prover.ProveChained instead.
+func ProveRange(public *ReciprocalPublic, fs FiatShamirEngine, private *ReciprocalPrivate, opts ...ProveOption) (*ReciprocalProof, error) {
+	return NewProver(public).Prove(fs, private, opts...)
+}
 
-	e := fs.GetChallenge()
+// VerifyRange verifies BP++ reciprocal argument range proof on arithmetic circuits. If err is nil then proof is valid.
+// Pass the RangeOptions the proof was produced with, like WithDigitCount. Use empty FiatShamirEngine for call; proofs
+// made with Prover.ProveChained are verified with Verifier.VerifyChained. See WithMSM for plugging in another
+// multi-scalar multiplication.
+func VerifyRange(public *ReciprocalPublic, V *bn256.G1, fs FiatShamirEngine, proof *ReciprocalProof, opts ...VerifyOption) error {
+	return newVerifier(public, opts).Verify(V, fs, proof)
+}
 
-	Nm := public.Nd
-	No := public.Np
+// ProveGroupRange generates zero knowledge proof that the value committed in public.CommitValue(private.X, private.S)
+// lies in [0, Np^Nd) over the group of public. The RangeOptions and WithDigitCommitment are not supported; WithLowMemory
+// applies as for ProveRange. Use empty FiatShamirEngine for call.
+func ProveGroupRange(public *GroupReciprocalPublic, fs FiatShamirEngine, private *ReciprocalPrivate, opts ...ProveOption) (*GroupReciprocalProof, error) {
+	if err := public.Validate(); err != nil {
+		return nil, err
+	}
 
-	Nv := public.Nd + 1
-	Nl := Nv
-	Nw := public.Nd + public.Nd + public.Np
+	if cfg := newProveConfig(opts); cfg.digitCount != 0 || cfg.valueG != nil || cfg.valueH != nil || cfg.digitCommitment != nil {
+		return nil, errors.New("range options are not supported over an arbitrary group")
+	}
 
-	r := make([]*big.Int, public.Nd)
-	for j := range r {
-		r[j] = inv(add(private.Digits[j], e))
+	if fs == nil {
+		return nil, errors.New("Fiat-Shamir engine cannot be nil")
 	}
 
-	rBlind := NewRandScalar()
-	rCom := public.CommitPoles(r, rBlind)
+	if err := checkFreshTranscript(fs); err != nil {
+		return nil, err
+	}
 
-	v := []*big.Int{private.X}
-	v = append(v, r...)
+	if err := private.validateGroup(public); err != nil {
+		return nil, err
+	}
 
-	wL := private.Digits
-	wR := r
-	wO := private.M
+	powers := negBasePowers(public.field(), public.Np, public.Nd)
 
-	am := oneVector(Nm)
-	Wm := zeroMatrix(Nm, Nw)
+	return proveGroupReciprocal(public, fs, private, transcriptDomainTag(fs, public.Domain), func(e *big.Int) *GroupArithmeticCircuitPublic {
+		return rangeCircuit(public, e, powers)
+	}, nil, opts)
+}
 
-	for i := 0; i < Nm; i++ {
-		Wm[i][i+Nm] = minus(e)
+// VerifyGroupRange verifies a proof of ProveGroupRange for the value commitment V. If err is nil then proof is valid.
+// Of the options only WithMSM applies, and only over BN256; the RangeOptions are rejected. Use empty
+// FiatShamirEngine for call.
+func VerifyGroupRange(public *GroupReciprocalPublic, V Point, fs FiatShamirEngine, proof *GroupReciprocalProof, opts ...VerifyOption) error {
+	if err := public.Validate(); err != nil {
+		return err
 	}
 
-	al := zeroVector(Nl)
-	Wl := zeroMatrix(Nl, Nw)
+	msm, err := statementMSM(opts)
+	if err != nil {
+		return err
+	}
 
-	// v
-	base := bint(public.Np)
-	for i := 0; i < Nm; i++ {
-		Wl[0][i] = minus(pow(base, i))
+	if err := checkFreshTranscript(fs); err != nil {
+		return err
 	}
 
-	// r
-	for i := 0; i < Nm; i++ {
-		for j := 0; j < Nm; j++ {
-			Wl[i+1][j+Nm] = bint(1)
-		}
+	powers := negBasePowers(public.field(), public.Np, public.Nd)
+
+	return verifyGroupReciprocal(public, V, fs, proof, transcriptDomainTag(fs, public.Domain), func(e *big.Int) *GroupArithmeticCircuitPublic {
+		return rangeCircuit(public, e, powers)
+	}, msm)
+}
+
+// WithDigitCount proves or verifies the range [0, Np^nd) with the first nd digit generators of the parameters
+// instead of all Nd, so one parameter set serves several range widths. The private digits must be the nd digits of
+// the value, e.g. Base(Np).Digits(x, nd), with their multiplicities. The proof header records nd, and the verifier
+// must pass the same option. Zero keeps Nd.
+func WithDigitCount(nd int) RangeOption {
+	return func(cfg *proveConfig) {
+		cfg.digitCount = nd
 	}
+}
 
-	for i := 0; i < Nm; i++ {
-		Wl[i+1][i+Nm] = bint(0)
+// WithValueGenerators proves or verifies the range of a value commitment created with CommitValueWith(g, h, ...)
+// instead of CommitValue: g replaces G and h the blinding generator HVec[0] of the parameters. Both generators are
+// absorbed into the transcript before the value commitment, and the verifier must pass the same option. See
+// CommitValueWith for the requirements on g and h.
+func WithValueGenerators(g, h *bn256.G1) RangeOption {
+	return func(cfg *proveConfig) {
+		cfg.valueG, cfg.valueH = g, h
 	}
+}
 
-	for i := 0; i < Nm; i++ {
-		for j := 0; j < No; j++ {
-			Wl[i+1][j+2*Nm] = minus(inv(add(e, bint(j))))
-		}
-	}
-
-	circuit := &ArithmeticCircuitPublic{
-		Nm:   Nm,
-		Nl:   Nl,
-		Nv:   Nv,
-		Nw:   Nw,
-		No:   No,
-		K:    1,
-		G:    public.G,
-		GVec: public.GVec,
-		HVec: public.HVec,
-		Wm:   Wm,
-		Wl:   Wl,
-		Am:   am,
-		Al:   al,
-		Fl:   true,
-		Fm:   false,
-		F: func(typ PartitionType, index int) *int {
-			if typ == PartitionLL && index < No { // map all to ll
-				return &index
+// withValueGenerators returns a view of the parameters with g as G and h as HVec[0]. It rejects generators that are
+// equal to each other or to any other generator of the parameters.
+func (p *ReciprocalPublic) withValueGenerators(g, h *bn256.G1) (*ReciprocalPublic, error) {
+	if g == nil || h == nil {
+		return nil, errors.New("value generators cannot be nil")
+	}
+
+	for _, vec := range []struct {
+		name   string
+		points []*bn256.G1
+	}{{"GVec", p.GVec}, {"HVec", p.HVec[1:]}, {"GVec_", p.GVec_}, {"HVec_", p.HVec_}} {
+		for _, point := range vec.points {
+			if pointsEqual(g, point) || pointsEqual(h, point) {
+				return nil, fmt.Errorf("value generators are reused in %s", vec.name)
 			}
+		}
+	}
 
-			return nil
-		},
-		GVec_: public.GVec_,
-		HVec_: public.HVec_,
+	res := *p
+	res.G = g
+	res.HVec = append([]*bn256.G1{h}, p.HVec[1:]...)
+	res.Labels = nil
+
+	if err := res.Validate(); err != nil {
+		return nil, err
 	}
 
-	prv := &ArithmeticCircuitPrivate{
-		V:  [][]*big.Int{v},
-		Sv: []*big.Int{add(private.S, rBlind)},
-		Wl: wL,
-		Wr: wR,
-		Wo: wO,
+	return &res, nil
+}
+
+// rangeParameters returns the view of the parameters selected by the options and absorbs the custom value
+// generators, if any, into fs.
+func (p *ReciprocalPublic) rangeParameters(fs FiatShamirEngine, cfg *proveConfig) (*ReciprocalPublic, error) {
+	public, err := p.rangeView(cfg)
+	if err != nil {
+		return nil, err
 	}
 
-	V := circuit.CommitCircuit(prv.V[0], prv.Sv[0])
+	if cfg.valueG == nil && cfg.valueH == nil {
+		return public, nil
+	}
 
-	return &ReciprocalProof{
-		ArithmeticCircuitProof: ProveCircuit(circuit, []*bn256.G1{V}, fs, prv),
-		V:                      rCom,
+	if fs == nil {
+		return nil, errors.New("Fiat-Shamir engine cannot be nil")
 	}
+
+	if err := fs.AddPoint(cfg.valueG); err != nil {
+		return nil, err
+	}
+
+	if err := fs.AddPoint(cfg.valueH); err != nil {
+		return nil, err
+	}
+
+	return public, nil
 }
 
-// VerifyRange verifies BP++ reciprocal argument range proof on arithmetic circuits. If err is nil then proof is valid.
-// Use empty FiatShamirEngine for call.
-func VerifyRange(public *ReciprocalPublic, V *bn256.G1, fs FiatShamirEngine, proof *ReciprocalProof) error {
-	fs.AddPoint(V)
+// rangeView returns the view of the parameters selected by the options without touching a transcript.
+func (p *ReciprocalPublic) rangeView(cfg *proveConfig) (*ReciprocalPublic, error) {
+	public, err := p.withDigitCount(cfg.digitCount)
+	if err != nil {
+		return nil, err
+	}
 
-	e := fs.GetChallenge()
+	if cfg.valueG == nil && cfg.valueH == nil {
+		return public, nil
+	}
 
-	Nm := public.Nd
-	No := public.Np
+	return public.withValueGenerators(cfg.valueG, cfg.valueH)
+}
 
-	Nv := public.Nd + 1
-	Nl := Nv
-	Nw := public.Nd + public.Nd + public.Np
+// ErrDigitCount is returned when WithDigitCount asks for more digits than the parameters have.
+var ErrDigitCount = errors.New("digit count does not fit the public parameters")
 
-	am := oneVector(Nm)
-	Wm := zeroMatrix(Nm, Nw)
+// withDigitCount returns a view of the parameters for nd digits. The generators of the unused digits move into
+// GVec_ and HVec_, so the WNLA vectors keep their length. The points are shared, not copied.
+func (p *ReciprocalPublic) withDigitCount(nd int) (*ReciprocalPublic, error) {
+	if nd == 0 || nd == p.Nd {
+		return p, nil
+	}
 
-	for i := 0; i < Nm; i++ {
-		Wm[i][i+Nm] = minus(e)
+	if nd < 1 || nd > p.Nd {
+		return nil, fmt.Errorf("%w: nd=%d, Nd=%d", ErrDigitCount, nd, p.Nd)
+	}
+
+	hLen := reciprocalHVecLen(nd)
+
+	res := *p
+	res.Nd = nd
+	res.GVec = p.GVec[:nd:nd]
+	res.HVec = p.HVec[:hLen:hLen]
+	res.GVec_ = append(append([]*bn256.G1{}, p.GVec[nd:]...), p.GVec_...)
+	res.HVec_ = append(append([]*bn256.G1{}, p.HVec[hLen:]...), p.HVec_...)
+
+	if l := p.Labels; l != nil && len(l.GVec) == p.Nd && len(l.HVec) == len(p.HVec) {
+		res.Labels = &GeneratorLabels{
+			Domain: l.Domain,
+			G:      l.G,
+			GVec:   l.GVec[:nd:nd],
+			HVec:   l.HVec[:hLen:hLen],
+			GVec_:  append(append([]string{}, l.GVec[nd:]...), l.GVec_...),
+			HVec_:  append(append([]string{}, l.HVec[hLen:]...), l.HVec_...),
+		}
+	} else {
+		res.Labels = nil
+	}
+
+	return &res, nil
+}
+
+// negBasePowers returns -(Np^i) for i < Nd in the field f, the coefficients binding the digits to the committed
+// value.
+func negBasePowers(f scalarField, Np, Nd int) []*big.Int {
+	base := bint(Np)
+	res := make([]*big.Int, Nd)
+	for i := range res {
+		res[i] = f.neg(f.pow(base, i))
+	}
+	return res
+}
+
+// checkPoleChallenge returns ErrInvalidChallenge if the challenge e is zero or e+d is zero in the field f for a
+// digit d of the set, so that the pole 1/(e+d) of the reciprocal argument does not exist.
+func checkPoleChallenge(f scalarField, e *big.Int, set []int) error {
+	if err := f.checkChallenges(e); err != nil {
+		return err
 	}
 
-	al := zeroVector(Nl)
+	for _, d := range set {
+		if f.add(e, bint(d)).Sign() == 0 {
+			return fmt.Errorf("%w: e+%d is zero", ErrInvalidChallenge, d)
+		}
+	}
+	return nil
+}
+
+// rangeCircuit builds the arithmetic circuit of the reciprocal range argument for challenge e.
+// The digit constraint coefficients depend on Np and Nd only and are passed in precomputed.
+func rangeCircuit(public *GroupReciprocalPublic, e *big.Int, negBasePowers []*big.Int) *GroupArithmeticCircuitPublic {
+	return newRangeTemplate(public, negBasePowers).circuit(e)
+}
+
+// rangeTemplate holds the parts of the range circuit that do not depend on the challenge e: the dimensions, the
+// generators together with their WNLA concatenation, Am, Al and the v and r coefficients of Wl. It must not be
+// modified once built; the circuits built from it share these values.
+type rangeTemplate struct {
+	public GroupArithmeticCircuitPublic
+	set    []int
+}
+
+func newRangeTemplate(public *GroupReciprocalPublic, negBasePowers []*big.Int) *rangeTemplate {
+	set := public.digitSet()
+
+	Nm := public.Nd
+	No := len(set)
+
+	Nv := public.Nd + 1
+	Nl := Nv
+	Nw := public.Nd + public.Nd + No
+
 	Wl := zeroMatrix(Nl, Nw)
 
 	// v
-	base := bint(public.Np)
 	for i := 0; i < Nm; i++ {
-		Wl[0][i] = minus(pow(base, i))
+		Wl[0][i] = negBasePowers[i]
 	}
 
 	// r
 	for i := 0; i < Nm; i++ {
 		for j := 0; j < Nm; j++ {
-			Wl[i+1][j+Nm] = bint(1)
+			if i != j {
+				Wl[i+1][j+Nm] = bint(1)
+			}
 		}
 	}
 
-	for i := 0; i < Nm; i++ {
-		Wl[i+1][i+Nm] = bint(0)
+	c := GroupArithmeticCircuitPublic{
+		Group: public.Group,
+		Nm:    Nm,
+		Nl:    Nl,
+		Nv:    Nv,
+		Nw:    Nw,
+		No:    No,
+		K:     1,
+		G:     public.G,
+		GVec:  public.GVec,
+		HVec:  public.HVec,
+		Wl:    Wl,
+		Am:    oneVector(Nm),
+		Al:    zeroVector(Nl),
+		Fl:    true,
+		Fm:    false,
+		F:     rangePartition(No),
+		GVec_: public.GVec_,
+		HVec_: public.HVec_,
 	}
+	c.wnlaGVec, c.wnlaHVec = c.wnlaGenerators()
+
+	return &rangeTemplate{public: c, set: set}
+}
 
+// circuit returns the range circuit for challenge e. Only Wm and the poles -1/(e+d) in Wl depend on e; the
+// other values are shared with the template.
+func (t *rangeTemplate) circuit(e *big.Int) *GroupArithmeticCircuitPublic {
+	c := t.public
+	f := c.field()
+	Nm := c.Nm
+
+	c.Wm = zeroMatrix(Nm, c.Nw)
 	for i := 0; i < Nm; i++ {
-		for j := 0; j < No; j++ {
-			Wl[i+1][j+2*Nm] = minus(inv(add(e, bint(j))))
-		}
-	}
-
-	circuit := &ArithmeticCircuitPublic{
-		Nm:   Nm,
-		Nl:   Nl,
-		Nv:   Nv,
-		Nw:   Nw,
-		No:   No,
-		K:    1,
-		G:    public.G,
-		GVec: public.GVec,
-		HVec: public.HVec,
-		Wm:   Wm,
-		Wl:   Wl,
-		Am:   am,
-		Al:   al,
-		Fl:   true,
-		Fm:   false,
-		F: func(typ PartitionType, index int) *int {
-			if typ == PartitionLL && index < No { // map all to ll
-				return &index
-			}
+		c.Wm[i][i+Nm] = f.neg(e)
+	}
 
-			return nil
-		},
-		GVec_: public.GVec_,
-		HVec_: public.HVec_,
+	poles := make([]*big.Int, c.No)
+	for j := range poles {
+		poles[j] = f.neg(f.inv(f.add(e, bint(t.set[j]))))
+	}
+
+	c.Wl = make([][]*big.Int, c.Nl)
+	c.Wl[0] = t.public.Wl[0]
+	for i := 1; i < c.Nl; i++ {
+		c.Wl[i] = append([]*big.Int{}, t.public.Wl[i]...)
+		copy(c.Wl[i][2*Nm:], poles)
 	}
 
-	return VerifyCircuit(circuit, []*bn256.G1{new(bn256.G1).Add(V, proof.V)}, fs, proof.ArithmeticCircuitProof)
+	return &c
+}
+
+// rangePartition is the partition function of the range circuit: the No digit multiplicities of the o-wires are
+// all mapped to ll.
+func rangePartition(No int) func(typ PartitionType, index int) *int {
+	return func(typ PartitionType, index int) *int {
+		if typ == PartitionLL && index < No { // map all to ll
+			return &index
+		}
+
+		return nil
+	}
 }
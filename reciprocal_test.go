@@ -5,8 +5,11 @@
 package bulletproofs
 
 import (
-	"github.com/davecgh/go-spew/spew"
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
 	"math/big"
+	"strings"
 	"testing"
 )
 
@@ -45,10 +48,922 @@ func TestReciprocalRangeProofUInt64(t *testing.T) {
 
 	VCom := public.CommitValue(private.X, private.S)
 
-	proof := ProveRange(public, NewKeccakFS(), private)
-	spew.Dump(proof)
+	proof, err := ProveRange(public, NewKeccakFS(), private)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+	t.Log(proof.DebugString())
 
 	if err := VerifyRange(public, VCom, NewKeccakFS(), proof); err != nil {
 		panic(err)
 	}
 }
+
+func TestBlindingGeneratorIndependence(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	if err := public.checkBlindingGenerator(); err != nil {
+		t.Fatalf("Derived parameters should have an independent blinding generator: %v", err)
+	}
+
+	if label, _ := public.LabelOf(public.BlindingGenerator()); label != "blinding" {
+		t.Errorf("Blinding generator should be labeled \"blinding\", got %q", label)
+	}
+
+	x := uint64(0x1234)
+	digits := UInt64Hex(x)
+
+	private := &ReciprocalPrivate{
+		X:      new(big.Int).SetUint64(x),
+		M:      HexMapping(digits),
+		Digits: digits,
+		S:      NewRandScalar(),
+	}
+
+	VCom := public.CommitValue(private.X, private.S)
+	proof, err := ProveRange(public, NewKeccakFS(), private)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	// Reusing a digit generator as the blinding generator must be rejected.
+	overlapping := *public
+	overlapping.HVec = append([]*bn256.G1{public.GVec[3]}, public.HVec[1:]...)
+
+	if err := VerifyRange(&overlapping, VCom, NewKeccakFS(), proof); err == nil || !strings.Contains(err.Error(), "GVec[3]") {
+		t.Errorf("Expected blinding generator reuse error, got %v", err)
+	}
+
+	overlapping.HVec = append([]*bn256.G1{public.HVec[12]}, public.HVec[1:]...)
+
+	if err := VerifyRange(&overlapping, VCom, NewKeccakFS(), proof); err == nil || !strings.Contains(err.Error(), "HVec[12]") {
+		t.Errorf("Expected blinding generator reuse error, got %v", err)
+	}
+
+	// Every other place the blinding generator could be reused, e.g. by slicing the padding generators off the
+	// wrong offset, fails Validate.
+	blinding := public.BlindingGenerator()
+	for name, reuse := range map[string]func(p *ReciprocalPublic){
+		"G":    func(p *ReciprocalPublic) { p.G = blinding },
+		"GVec": func(p *ReciprocalPublic) { p.GVec = append([]*bn256.G1{blinding}, p.GVec[1:]...) },
+		"HVec": func(p *ReciprocalPublic) {
+			p.HVec = append(append([]*bn256.G1{}, p.HVec[:5]...), append([]*bn256.G1{blinding}, p.HVec[6:]...)...)
+		},
+		"GVec_": func(p *ReciprocalPublic) { p.GVec_ = append(append([]*bn256.G1{}, p.GVec_...), blinding) },
+		"HVec_": func(p *ReciprocalPublic) { p.HVec_ = append([]*bn256.G1{blinding}, p.HVec_[1:]...) },
+	} {
+		reused := *public
+		reuse(&reused)
+
+		err := reused.Validate()
+		if !errors.Is(err, ErrDuplicateGenerator) || !strings.Contains(err.Error(), name) {
+			t.Errorf("%s: expected ErrDuplicateGenerator, got %v", name, err)
+		}
+	}
+}
+
+func TestVerifyOpening(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	value, blinding := bint(42), NewRandScalar()
+	com := public.CommitValue(value, blinding)
+
+	if !VerifyOpening(public, com, value, blinding) {
+		t.Error("Valid opening rejected")
+	}
+
+	// Openings are field elements, an unreduced value opens the same commitment.
+	if !VerifyOpening(public, public.CommitValue(big.NewInt(-1), blinding), minus(bint(1)), blinding) {
+		t.Error("Negative value does not commit to its reduction")
+	}
+
+	if VerifyOpening(public, com, bint(43), blinding) {
+		t.Error("Wrong value accepted")
+	}
+
+	if VerifyOpening(public, com, value, add(blinding, bint(1))) {
+		t.Error("Wrong blinding accepted")
+	}
+
+	if VerifyOpening(public, nil, value, blinding) || VerifyOpening(nil, com, value, blinding) {
+		t.Error("Missing inputs accepted")
+	}
+}
+
+func TestVerifyOpeningConstantTime(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	for _, value := range []*big.Int{bint(0), bint(42), minus(bint(1)), NewRandScalar()} {
+		blinding := NewRandScalar()
+		com := public.CommitValue(value, blinding)
+
+		if !VerifyOpeningConstantTime(public, com, value, blinding) {
+			t.Errorf("Valid opening of %s rejected", value)
+		}
+
+		if VerifyOpeningConstantTime(public, com, add(value, bint(1)), blinding) {
+			t.Errorf("Wrong value for %s accepted", value)
+		}
+
+		if VerifyOpeningConstantTime(public, com, value, add(blinding, bint(1))) {
+			t.Errorf("Wrong blinding for %s accepted", value)
+		}
+	}
+
+	com := public.CommitValue(bint(42), bint(1))
+	if VerifyOpeningConstantTime(public, nil, bint(42), bint(1)) || VerifyOpeningConstantTime(nil, com, bint(42), bint(1)) ||
+		VerifyOpeningConstantTime(public, com, nil, bint(1)) {
+		t.Error("Missing inputs accepted")
+	}
+}
+
+func BenchmarkVerifyOpening(b *testing.B) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		b.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	value, blinding := new(big.Int).SetUint64(0xab4f0540ab4f0540), NewRandScalar()
+	com := public.CommitValue(value, blinding)
+
+	for _, variant := range []struct {
+		name   string
+		verify func(*ReciprocalPublic, *bn256.G1, *big.Int, *big.Int) bool
+	}{
+		{"Plain", VerifyOpening},
+		{"ConstantTime", VerifyOpeningConstantTime},
+	} {
+		b.Run(variant.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if !variant.verify(public, com, value, blinding) {
+					b.Fatal("opening rejected")
+				}
+			}
+		})
+	}
+}
+
+func TestRerandomizeCommitment(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	value, blinding, delta := bint(0xabcd), NewRandScalar(), NewRandScalar()
+	com := public.CommitValue(value, blinding)
+
+	rerandomized := public.RerandomizeCommitment(com, delta)
+	if pointsEqual(rerandomized, com) {
+		t.Fatal("Commitment did not change")
+	}
+
+	if !VerifyOpening(public, rerandomized, value, add(blinding, delta)) {
+		t.Fatal("Re-randomized commitment does not open with blinding s + delta")
+	}
+
+	// The old proof does not carry over, a new one has to be made for the new blinding.
+	private, err := public.newPrivate(value, add(blinding, delta))
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	proof, err := ProveRange(public, NewKeccakFS(), private)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	if err := VerifyRange(public, rerandomized, NewKeccakFS(), proof); err != nil {
+		t.Fatalf("VerifyRange failed for the re-randomized commitment: %v", err)
+	}
+
+	expectRejected(t, VerifyRange(public, com, NewKeccakFS(), proof), VerifyStageWNLA, ErrFinalCommitmentMismatch)
+}
+
+func TestDegenerateBlinding(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	high := new(big.Int).Sub(bn256.Order, minBlinding)
+
+	for _, s := range []*big.Int{
+		bint(0),
+		bint(1),
+		bint(2),
+		new(big.Int).Sub(minBlinding, bint(1)),
+		new(big.Int).Add(high, bint(1)),
+		sub(bint(0), bint(1)),
+		big.NewInt(-1),
+		new(big.Int).Set(bn256.Order),
+		new(big.Int).Add(bn256.Order, bint(5)),
+	} {
+		private, err := public.newPrivate(bint(42), s)
+		if err != nil {
+			t.Fatalf("newPrivate failed: %v", err)
+		}
+
+		if _, err := ProveRange(public, NewKeccakFS(), private); !errors.Is(err, ErrDegenerateBlinding) {
+			t.Errorf("Expected ErrDegenerateBlinding for S=%v, got %v", s, err)
+		}
+	}
+
+	// The bounds themselves are accepted.
+	for _, s := range []*big.Int{minBlinding, high} {
+		private, err := public.newPrivate(bint(42), s)
+		if err != nil {
+			t.Fatalf("newPrivate failed: %v", err)
+		}
+
+		if _, err := ProveRange(public, NewKeccakFS(), private); err != nil {
+			t.Errorf("ProveRange failed for S=%v: %v", s, err)
+		}
+	}
+
+	if _, err := ProveRangeAggregated(public, NewKeccakFS(), []*big.Int{bint(1), bint(2)}, []*big.Int{NewRandScalar(), bint(0)}); !errors.Is(err, ErrDegenerateBlinding) {
+		t.Errorf("Expected ErrDegenerateBlinding from ProveRangeAggregated, got %v", err)
+	}
+}
+
+func TestCommitValueChecked(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 4, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	blinding := NewRandScalar()
+
+	for _, v := range []*big.Int{bint(0), bint(0xffff)} {
+		com, err := public.CommitValueChecked(v, blinding)
+		if err != nil {
+			t.Fatalf("CommitValueChecked(%v) failed: %v", v, err)
+		}
+		if !pointsEqual(com, public.CommitValue(v, blinding)) {
+			t.Errorf("CommitValueChecked(%v) differs from CommitValue", v)
+		}
+	}
+
+	for _, v := range []*big.Int{bint(0x10000), big.NewInt(-1), new(big.Int).Set(bn256.Order)} {
+		if _, err := public.CommitValueChecked(v, blinding); !errors.Is(err, ErrValueOutOfRange) {
+			t.Errorf("Expected ErrValueOutOfRange for %v, got %v", v, err)
+		}
+	}
+
+	// Building the witness reports the same error.
+	if _, err := public.DeterministicPrivate([]byte(strings.Repeat("key", 11)), bint(0x10000)); !errors.Is(err, ErrValueOutOfRange) {
+		t.Errorf("Expected ErrValueOutOfRange from DeterministicPrivate, got %v", err)
+	}
+}
+
+func TestDigitSet(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	decimal := *public
+	decimal.DigitSet = []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+
+	// Binary coded decimal 2024101599.
+	private, err := decimal.newPrivate(bint(0x2024101599), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	if len(private.M) != 10 {
+		t.Fatalf("Expected 10 digit multiplicities, got %d", len(private.M))
+	}
+
+	proof, err := ProveRange(&decimal, NewKeccakFS(), private)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	com := decimal.CommitValue(private.X, private.S)
+	if err := VerifyRange(&decimal, com, NewKeccakFS(), proof); err != nil {
+		t.Fatalf("VerifyRange failed: %v", err)
+	}
+
+	if _, err := decimal.newPrivate(bint(0x20241a), NewRandScalar()); !errors.Is(err, ErrValueOutOfRange) {
+		t.Errorf("Expected ErrValueOutOfRange for a digit outside of the set, got %v", err)
+	}
+
+	if _, err := decimal.CommitValueChecked(bint(0xf), NewRandScalar()); !errors.Is(err, ErrValueOutOfRange) {
+		t.Errorf("Expected ErrValueOutOfRange from CommitValueChecked, got %v", err)
+	}
+
+	// A proof over the full digit set does not verify for the restricted one.
+	hex, err := public.newPrivate(bint(0x1a), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	hexProof, err := ProveRange(public, NewKeccakFS(), hex)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	// A digit outside of the set leaves a pole the reciprocal sum cannot cancel, which shows in the WNLA base case.
+	expectRejected(t, VerifyRange(&decimal, public.CommitValue(hex.X, hex.S), NewKeccakFS(), hexProof), VerifyStageWNLA, ErrFinalCommitmentMismatch)
+
+	for _, set := range [][]int{{}, {0, 16}, {-1, 2}, {1, 1}, {3, 2}} {
+		invalid := *public
+		invalid.DigitSet = set
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Expected an error for the digit set %v", set)
+		}
+	}
+}
+
+func TestDigitCapacity(t *testing.T) {
+	// Nd=4 leaves room for the multiplicities of the digits 0..4 only.
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 4, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	private, err := public.newPrivate(bint(0x1234), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	proof, err := ProveRange(public, NewKeccakFS(), private)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	if err := VerifyRange(public, public.CommitValue(private.X, private.S), NewKeccakFS(), proof); err != nil {
+		t.Fatalf("VerifyRange failed: %v", err)
+	}
+
+	private, err = public.newPrivate(bint(0xffff), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	if _, err := ProveRange(public, NewKeccakFS(), private); !errors.Is(err, ErrDigitCapacity) {
+		t.Errorf("Expected ErrDigitCapacity, got %v", err)
+	}
+
+	// A digit set that fits into the slots proves every allowed digit.
+	restricted := *public
+	restricted.DigitSet = []int{0, 1, 10, 14, 15}
+
+	private, err = restricted.newPrivate(bint(0xfe10), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	proof, err = ProveRange(&restricted, NewKeccakFS(), private)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	if err := VerifyRange(&restricted, restricted.CommitValue(private.X, private.S), NewKeccakFS(), proof); err != nil {
+		t.Fatalf("VerifyRange failed: %v", err)
+	}
+}
+
+func TestPaddingGenerators(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	if len(public.HVec)+len(public.HVec_) != 32 {
+		t.Fatalf("HVec is padded to %d generators, expected 32", len(public.HVec)+len(public.HVec_))
+	}
+
+	for name, tc := range map[string]struct {
+		modify   func(p *ReciprocalPublic)
+		expected string
+	}{
+		"short HVec_": {func(p *ReciprocalPublic) { p.HVec_ = p.HVec_[:len(p.HVec_)-1] }, "HVec_ needs 1 more generators"},
+		"no HVec_":    {func(p *ReciprocalPublic) { p.HVec_ = nil }, "HVec_ needs 6 more generators"},
+		"long GVec_":  {func(p *ReciprocalPublic) { p.GVec_ = p.HVec_[:1] }, "GVec_ needs 15 more generators"},
+	} {
+		modified := *public
+		modified.Labels = nil
+		tc.modify(&modified)
+
+		if err := modified.Validate(); err == nil || !strings.Contains(err.Error(), tc.expected) {
+			t.Errorf("%s: expected %q, got %v", name, tc.expected, err)
+		}
+	}
+}
+
+func TestNilInputs(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	private, err := public.newPrivate(bint(42), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	VCom := public.CommitValue(private.X, private.S)
+
+	proof, err := ProveRange(public, NewKeccakFS(), private)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	missingGenerator := *public
+	missingGenerator.HVec = append([]*bn256.G1{nil}, public.HVec[1:]...)
+
+	missingDigitGenerator := *public
+	missingDigitGenerator.GVec = append([]*bn256.G1{}, public.GVec...)
+	missingDigitGenerator.GVec[7] = nil
+
+	if err := missingDigitGenerator.Validate(); err == nil || !strings.Contains(err.Error(), "GVec[7] cannot be nil") {
+		t.Errorf("Expected an error naming GVec[7], got %v", err)
+	}
+
+	wnlaPublic := NewWeightNormLinearPublic(4, 2)
+
+	cases := map[string]func() error{
+		"ProveRange nil public": func() error {
+			_, err := ProveRange(nil, NewKeccakFS(), private)
+			return err
+		},
+		"ProveRange empty public": func() error {
+			_, err := ProveRange(&ReciprocalPublic{}, NewKeccakFS(), private)
+			return err
+		},
+		"ProveRange nil generator": func() error {
+			_, err := ProveRange(&missingGenerator, NewKeccakFS(), private)
+			return err
+		},
+		"ProveRange nil fs": func() error {
+			_, err := ProveRange(public, nil, private)
+			return err
+		},
+		"ProveRange nil private": func() error {
+			_, err := ProveRange(public, NewKeccakFS(), nil)
+			return err
+		},
+		"ProveRange empty private": func() error {
+			_, err := ProveRange(public, NewKeccakFS(), &ReciprocalPrivate{})
+			return err
+		},
+		"VerifyRange nil public": func() error {
+			return VerifyRange(nil, VCom, NewKeccakFS(), proof)
+		},
+		"VerifyRange nil commitment": func() error {
+			return VerifyRange(public, nil, NewKeccakFS(), proof)
+		},
+		"VerifyRange nil fs": func() error {
+			return VerifyRange(public, VCom, nil, proof)
+		},
+		"VerifyRange nil proof": func() error {
+			return VerifyRange(public, VCom, NewKeccakFS(), nil)
+		},
+		"VerifyRange empty proof": func() error {
+			return VerifyRange(public, VCom, NewKeccakFS(), &ReciprocalProof{})
+		},
+		"VerifyRange empty circuit proof": func() error {
+			return VerifyRange(public, VCom, NewKeccakFS(), &ReciprocalProof{V: proof.V, ArithmeticCircuitProof: &ArithmeticCircuitProof{}})
+		},
+		"VerifyCircuit nil public": func() error {
+			return VerifyCircuit(nil, []*bn256.G1{VCom}, NewKeccakFS(), proof.ArithmeticCircuitProof)
+		},
+		"ProveCircuit nil public": func() error {
+			_, err := ProveCircuit(nil, []*bn256.G1{VCom}, NewKeccakFS(), nil)
+			return err
+		},
+		"ProveWNLA nil public": func() error {
+			_, err := ProveWNLA(nil, VCom, NewKeccakFS(), nil, nil)
+			return err
+		},
+		"ProveWNLA nil fs": func() error {
+			_, err := ProveWNLA(wnlaPublic, VCom, nil, make([]*big.Int, 4), make([]*big.Int, 2))
+			return err
+		},
+		"VerifyWNLA nil proof": func() error {
+			return VerifyWNLA(wnlaPublic, nil, VCom, NewKeccakFS())
+		},
+		"VerifyWNLA empty proof": func() error {
+			return VerifyWNLA(wnlaPublic, &WeightNormLinearArgumentProof{}, VCom, NewKeccakFS())
+		},
+		"ProveSum nil public": func() error {
+			_, err := ProveSum(nil, NewKeccakFS(), []*bn256.G1{VCom}, []*big.Int{private.X}, []*big.Int{private.S}, private.X)
+			return err
+		},
+		"ProveSum nil value": func() error {
+			_, err := ProveSum(public, NewKeccakFS(), []*bn256.G1{VCom}, []*big.Int{nil}, []*big.Int{private.S}, private.X)
+			return err
+		},
+		"VerifySum nil proof": func() error {
+			return VerifySum(public, NewKeccakFS(), []*bn256.G1{VCom}, private.X, nil)
+		},
+		"ProveGreaterEqual nil opening": func() error {
+			_, err := ProveGreaterEqual(public, NewKeccakFS(), VCom, VCom, private.X, nil, private.S, private.S)
+			return err
+		},
+		"VerifyGreaterEqual nil public": func() error {
+			return VerifyGreaterEqual(nil, NewKeccakFS(), VCom, VCom, proof)
+		},
+	}
+
+	for name, call := range cases {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("panicked: %v", r)
+				}
+			}()
+
+			if err := call(); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+
+	if _, err := wnlaPublic.CommitWNLAChecked(make([]*big.Int, 5), nil); err == nil || !strings.Contains(err.Error(), "len(l)=5") {
+		t.Errorf("Expected an error naming l, got %v", err)
+	}
+
+	if _, err := wnlaPublic.CommitWNLAChecked([]*big.Int{bint(1), nil}, nil); err == nil || !strings.Contains(err.Error(), "l[1]") {
+		t.Errorf("Expected an error naming l[1], got %v", err)
+	}
+
+	if _, err := (&WeightNormLinearPublic{}).CommitWNLAChecked(nil, nil); err == nil {
+		t.Error("CommitWNLAChecked should fail for invalid parameters")
+	}
+}
+
+func TestWithDigitCount(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 32, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	narrow, err := public.withDigitCount(16)
+	if err != nil {
+		t.Fatalf("withDigitCount failed: %v", err)
+	}
+
+	if err := narrow.Validate(); err != nil {
+		t.Fatalf("Sliced parameters are invalid: %v", err)
+	}
+
+	if err := narrow.CheckLabels(); err != nil {
+		t.Fatalf("Sliced labels do not match: %v", err)
+	}
+
+	if len(narrow.GVec)+len(narrow.GVec_) != len(public.GVec)+len(public.GVec_) ||
+		len(narrow.HVec)+len(narrow.HVec_) != len(public.HVec)+len(public.HVec_) {
+		t.Fatal("Slicing changed the WNLA vector lengths")
+	}
+
+	private, err := narrow.newPrivate(new(big.Int).SetUint64(0xab4f0540ab4f0540), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	com := public.CommitValue(private.X, private.S)
+
+	proof, err := ProveRange(public, NewKeccakFS(), private, WithDigitCount(16))
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	if proof.Nd != 16 {
+		t.Errorf("Expected the proof header to record Nd=16, got %d", proof.Nd)
+	}
+
+	if err := VerifyRange(public, com, NewKeccakFS(), proof, WithDigitCount(16)); err != nil {
+		t.Fatalf("VerifyRange failed: %v", err)
+	}
+
+	if err := VerifyRange(public, com, NewKeccakFS(), proof); !errors.Is(err, ErrBaseMismatch) {
+		t.Errorf("Expected ErrBaseMismatch without the option, got %v", err)
+	}
+
+	if err := VerifyRange(public, com, NewKeccakFS(), proof, WithDigitCount(24)); !errors.Is(err, ErrBaseMismatch) {
+		t.Errorf("Expected ErrBaseMismatch for another digit count, got %v", err)
+	}
+
+	// Digits for the full parameters do not match the requested width.
+	wide, err := public.newPrivate(bint(1), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	if _, err := ProveRange(public, NewKeccakFS(), wide, WithDigitCount(16)); err == nil {
+		t.Error("Expected an error for digits of another width")
+	}
+
+	for _, nd := range []int{-1, 33} {
+		if _, err := ProveRange(public, NewKeccakFS(), private, WithDigitCount(nd)); !errors.Is(err, ErrDigitCount) {
+			t.Errorf("nd=%d: expected ErrDigitCount, got %v", nd, err)
+		}
+	}
+}
+
+func TestWithValueGenerators(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	g, err := DeriveGenerator("external-scheme", "value")
+	if err != nil {
+		t.Fatalf("DeriveGenerator failed: %v", err)
+	}
+
+	h, err := DeriveGenerator("external-scheme", "blinding")
+	if err != nil {
+		t.Fatalf("DeriveGenerator failed: %v", err)
+	}
+
+	private, err := public.newPrivate(bint(0xc0ffee), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	com := CommitValueWith(g, h, private.X, private.S)
+
+	if !pointsEqual(CommitValueWith(public.G, public.HVec[0], private.X, private.S), public.CommitValue(private.X, private.S)) {
+		t.Fatal("CommitValue differs from CommitValueWith with the parameter generators")
+	}
+
+	proof, err := ProveRange(public, NewKeccakFS(), private, WithValueGenerators(g, h))
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	if err := VerifyRange(public, com, NewKeccakFS(), proof, WithValueGenerators(g, h)); err != nil {
+		t.Fatalf("VerifyRange failed: %v", err)
+	}
+
+	expectRejected(t, VerifyRange(public, com, NewKeccakFS(), proof), VerifyStageWNLA, ErrFinalCommitmentMismatch)
+
+	expectRejected(t, VerifyRange(public, com, NewKeccakFS(), proof, WithValueGenerators(h, g)), VerifyStageWNLA, ErrFinalCommitmentMismatch)
+
+	expectRejected(t, VerifyRange(public, public.CommitValue(private.X, private.S), NewKeccakFS(), proof, WithValueGenerators(g, h)), VerifyStageWNLA, ErrFinalCommitmentMismatch)
+
+	for name, gens := range map[string][2]*bn256.G1{
+		"equal generators": {g, g},
+		"reused GVec":      {g, public.GVec[0]},
+		"reused HVec":      {public.HVec[9], h},
+		"nil generator":    {g, nil},
+	} {
+		if _, err := ProveRange(public, NewKeccakFS(), private, WithValueGenerators(gens[0], gens[1])); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestInvalidMapping(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	x := uint64(0xab4f0540ab4f0540)
+	digits := UInt64Hex(x)
+	m := HexMapping(digits)
+
+	valid := &ReciprocalPrivate{X: new(big.Int).SetUint64(x), M: m, Digits: digits, S: NewRandScalar()}
+	if _, err := ProveRange(public, NewKeccakFS(), valid); err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	withM := func(m []*big.Int) *ReciprocalPrivate {
+		private := *valid
+		private.M = m
+		return &private
+	}
+
+	swapped := append([]*big.Int{}, m...)
+	swapped[0], swapped[1] = swapped[1], swapped[0]
+
+	negative := append([]*big.Int{}, m...)
+	negative[1] = bint(-1)
+
+	huge := append([]*big.Int{}, m...)
+	huge[1] = new(big.Int).Add(bn256.Order, bint(1))
+
+	outside := *valid
+	outside.Digits = append([]*big.Int{bint(16)}, digits[1:]...)
+
+	for name, private := range map[string]*ReciprocalPrivate{
+		"short":         withM(m[:15]),
+		"long":          withM(append(append([]*big.Int{}, m...), bint(0))),
+		"swapped":       withM(swapped),
+		"negative":      withM(negative),
+		"above order":   withM(huge),
+		"illegal digit": &outside,
+	} {
+		if _, err := ProveRange(public, NewKeccakFS(), private); !errors.Is(err, ErrInvalidMapping) {
+			t.Errorf("%s: expected ErrInvalidMapping, got %v", name, err)
+		}
+	}
+}
+
+func TestDigitOrder(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	x := new(big.Int).SetUint64(0xab4f0540ab4f0541)
+
+	msb, err := Base16.Digits(x, 16, WithDigitOrder(MostSignificantFirst))
+	if err != nil {
+		t.Fatalf("Digits failed: %v", err)
+	}
+
+	if msb[0].Cmp(bint(0xa)) != 0 || msb[15].Cmp(bint(1)) != 0 {
+		t.Errorf("Digits are not most significant first: %v", msb)
+	}
+
+	m, err := Base16.Mapping(msb)
+	if err != nil {
+		t.Fatalf("Mapping failed: %v", err)
+	}
+
+	// The mapping counts digits, so it is the same in both orders, and only the digit order check notices.
+	reversed := &ReciprocalPrivate{X: x, M: m, Digits: msb, S: NewRandScalar()}
+	_, err = ProveRange(public, NewKeccakFS(), reversed)
+	if !errors.Is(err, ErrDigitMismatch) || !strings.Contains(err.Error(), "most significant first") {
+		t.Errorf("Expected ErrDigitMismatch for reversed digits, got %v", err)
+	}
+
+	other := &ReciprocalPrivate{X: new(big.Int).Add(x, bint(16)), M: m, Digits: UInt64Hex(x.Uint64()), S: NewRandScalar()}
+	if _, err := ProveRange(public, NewKeccakFS(), other); !errors.Is(err, ErrDigitMismatch) {
+		t.Errorf("Expected ErrDigitMismatch for digits of another value, got %v", err)
+	}
+
+	reversed.Digits = reverseDigits(msb)
+	if _, err := ProveRange(public, NewKeccakFS(), reversed); err != nil {
+		t.Errorf("ProveRange failed for digits in the expected order: %v", err)
+	}
+}
+
+func TestCustomBasePoint(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	public.G, err = DeriveGenerator("my-app", "value")
+	if err != nil {
+		t.Fatalf("DeriveGenerator failed: %v", err)
+	}
+
+	if err := public.Validate(); err != nil {
+		t.Fatalf("Validate rejected a derived G: %v", err)
+	}
+
+	private, err := public.newPrivate(bint(0xc0ffee), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	com := public.CommitValue(private.X, private.S)
+
+	proof, err := ProveRange(public, NewKeccakFS(), private)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	if err := VerifyRange(public, com, NewKeccakFS(), proof); err != nil {
+		t.Fatalf("VerifyRange failed: %v", err)
+	}
+
+	other := *public
+	other.G, err = DeriveGenerator("other-app", "value")
+	if err != nil {
+		t.Fatalf("DeriveGenerator failed: %v", err)
+	}
+
+	expectRejected(t, VerifyRange(&other, com, NewKeccakFS(), proof), VerifyStageWNLA, ErrFinalCommitmentMismatch)
+
+	identity := *public
+	identity.G = new(bn256.G1).ScalarBaseMult(bint(0))
+	if err := identity.Validate(); !errors.Is(err, ErrInvalidGenerator) {
+		t.Errorf("Expected ErrInvalidGenerator for the identity, got %v", err)
+	}
+
+	wnla := NewWeightNormLinearPublic(4, 2)
+	wnla.G = identity.G
+	if err := wnla.Validate(); !errors.Is(err, ErrInvalidGenerator) {
+		t.Errorf("Expected ErrInvalidGenerator for the WNLA identity, got %v", err)
+	}
+
+	group, err := NewGroupWNLAPublic(Secp256k1, DOMAIN_WNLA, 4, 2)
+	if err != nil {
+		t.Fatalf("NewGroupWNLAPublic failed: %v", err)
+	}
+	group.G = Secp256k1.Identity()
+	if err := group.Validate(); !errors.Is(err, ErrInvalidGenerator) {
+		t.Errorf("Expected ErrInvalidGenerator for the secp256k1 identity, got %v", err)
+	}
+}
+
+// BenchmarkRangeMatrix proves and verifies ranges over bit lengths, bases and batch sizes. A batch of one is a
+// ProveRange proof, larger batches are ProveRangeAggregated proofs of that many values. Every value is the largest
+// one of its bit length. Combinations whose parameters cannot hold the digits of such a value (see
+// ErrDigitCapacity) are skipped with the reason. Run a slice of the matrix with e.g.
+// -bench 'RangeMatrix/bits=64/'.
+func BenchmarkRangeMatrix(b *testing.B) {
+	for _, bits := range []int{8, 16, 32, 64, 128} {
+		for _, base := range []Base{Base2, Base10, Base16, Base256} {
+			for _, batch := range []int{1, 2, 4} {
+				b.Run(fmt.Sprintf("bits=%d/base=%d/batch=%d", bits, base, batch), func(b *testing.B) {
+					benchmarkRange(b, bits, base, batch)
+				})
+			}
+		}
+	}
+}
+
+func benchmarkRange(b *testing.B, bits int, base Base, batch int) {
+	nd, err := base.DigitCount(bits)
+	if err != nil {
+		b.Fatalf("DigitCount failed: %v", err)
+	}
+
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, batch*nd, base)
+	if err != nil {
+		b.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	values := make([]*big.Int, batch)
+	blindings := make([]*big.Int, batch)
+	coms := make([]*bn256.G1, batch)
+	for k := range values {
+		values[k] = new(big.Int).Sub(new(big.Int).Lsh(bint(1), uint(bits)), bint(k+1))
+		blindings[k] = NewRandScalar()
+		coms[k] = public.CommitValue(values[k], blindings[k])
+	}
+
+	var prove func() error
+	var verify func() error
+
+	if batch == 1 {
+		private, err := public.newPrivate(values[0], blindings[0])
+		if err != nil {
+			b.Skipf("%d bits in %d digits of base %d: %v", bits, nd, base, err)
+		}
+
+		proof, err := ProveRange(public, NewKeccakFS(), private)
+		if err != nil {
+			b.Skipf("%d bits in %d digits of base %d: %v", bits, nd, base, err)
+		}
+
+		prove = func() error {
+			_, err := ProveRange(public, NewKeccakFS(), private)
+			return err
+		}
+		verify = func() error {
+			return VerifyRange(public, coms[0], NewKeccakFS(), proof)
+		}
+	} else {
+		proof, err := ProveRangeAggregated(public, NewKeccakFS(), values, blindings)
+		if err != nil {
+			b.Skipf("%d values of %d bits in %d digits of base %d: %v", batch, bits, nd, base, err)
+		}
+
+		prove = func() error {
+			_, err := ProveRangeAggregated(public, NewKeccakFS(), values, blindings)
+			return err
+		}
+		verify = func() error {
+			return VerifyRangeAggregated(public, coms, NewKeccakFS(), proof)
+		}
+	}
+
+	if err := verify(); err != nil {
+		b.Fatalf("Verification failed: %v", err)
+	}
+
+	b.Run("Prove", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := prove(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("Verify", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := verify(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,97 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+)
+
+// Scalar is an element of the bn256 scalar field. Every operation reduces modulo bn256.Order, so a Scalar is
+// always in [0, order). Scalars are immutable: operations return a new value and never modify their operands.
+// The zero value is the field element 0.
+type Scalar struct {
+	v *big.Int
+}
+
+// NewScalar returns x reduced modulo bn256.Order. A nil x is treated as zero.
+func NewScalar(x *big.Int) Scalar {
+	if x == nil {
+		return Scalar{}
+	}
+	return Scalar{new(big.Int).Mod(x, bn256.Order)}
+}
+
+// ScalarFromBytes decodes a 32-byte big-endian scalar. Values outside of [0, order) are rejected with
+// ErrNonCanonicalScalar, so every Scalar has exactly one encoding.
+func ScalarFromBytes(b []byte) (Scalar, error) {
+	if len(b) != scalarSize {
+		return Scalar{}, fmt.Errorf("invalid scalar length %d", len(b))
+	}
+
+	x := new(big.Int).SetBytes(b)
+	if !isCanonicalScalar(x) {
+		return Scalar{}, ErrNonCanonicalScalar
+	}
+
+	return Scalar{x}, nil
+}
+
+// Bytes returns the 32-byte big-endian encoding of s.
+func (s Scalar) Bytes() []byte {
+	return scalarTo32Byte(s.BigInt())
+}
+
+// BigInt returns s as a new big.Int in [0, order).
+func (s Scalar) BigInt() *big.Int {
+	if s.v == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(s.v)
+}
+
+// Add returns s + x.
+func (s Scalar) Add(x Scalar) Scalar {
+	return Scalar{add(s.v, x.v)}
+}
+
+// Sub returns s - x.
+func (s Scalar) Sub(x Scalar) Scalar {
+	return Scalar{sub(s.v, x.v)}
+}
+
+// Mul returns s * x.
+func (s Scalar) Mul(x Scalar) Scalar {
+	return Scalar{mul(s.v, x.v)}
+}
+
+// Neg returns -s.
+func (s Scalar) Neg() Scalar {
+	return Scalar{minus(zeroIfNil(s.v))}
+}
+
+// Inv returns 1/s. Zero has no inverse and returns an error.
+func (s Scalar) Inv() (Scalar, error) {
+	if s.IsZero() {
+		return Scalar{}, fmt.Errorf("zero scalar has no inverse")
+	}
+	return Scalar{inv(s.v)}, nil
+}
+
+// IsZero reports whether s is the field element 0.
+func (s Scalar) IsZero() bool {
+	return s.v == nil || s.v.Sign() == 0
+}
+
+// Equal reports whether s and x are the same field element.
+func (s Scalar) Equal(x Scalar) bool {
+	return s.BigInt().Cmp(x.BigInt()) == 0
+}
+
+// String returns the decimal representation of s.
+func (s Scalar) String() string {
+	return s.BigInt().String()
+}
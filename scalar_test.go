@@ -0,0 +1,68 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"testing"
+)
+
+func TestScalar(t *testing.T) {
+	a := NewScalar(big.NewInt(7))
+	b := NewScalar(new(big.Int).Add(bn256.Order, big.NewInt(5))) // reduced to 5
+
+	if !b.Equal(NewScalar(big.NewInt(5))) {
+		t.Errorf("NewScalar did not reduce: %v", b)
+	}
+
+	if got := a.Add(b); !got.Equal(NewScalar(big.NewInt(12))) {
+		t.Errorf("7 + 5 = %v", got)
+	}
+
+	if got := b.Sub(a); !got.Equal(NewScalar(big.NewInt(-2))) || got.BigInt().Sign() < 0 {
+		t.Errorf("5 - 7 = %v", got)
+	}
+
+	if got := a.Mul(b); !got.Equal(NewScalar(big.NewInt(35))) {
+		t.Errorf("7 * 5 = %v", got)
+	}
+
+	if got := a.Neg().Add(a); !got.IsZero() {
+		t.Errorf("-7 + 7 = %v", got)
+	}
+
+	aInv, err := a.Inv()
+	if err != nil {
+		t.Fatalf("Inv failed: %v", err)
+	}
+
+	if got := a.Mul(aInv); !got.Equal(NewScalar(big.NewInt(1))) {
+		t.Errorf("7 * 1/7 = %v", got)
+	}
+
+	if _, err := (Scalar{}).Inv(); err == nil {
+		t.Error("Zero should not have an inverse")
+	}
+
+	decoded, err := ScalarFromBytes(a.Bytes())
+	if err != nil || !decoded.Equal(a) {
+		t.Errorf("Bytes round trip failed: %v, %v", decoded, err)
+	}
+
+	if _, err := ScalarFromBytes(scalarTo32Byte(bn256.Order)); !errors.Is(err, ErrNonCanonicalScalar) {
+		t.Errorf("Expected ErrNonCanonicalScalar, got %v", err)
+	}
+
+	if _, err := ScalarFromBytes(make([]byte, 31)); err == nil {
+		t.Error("Short encoding accepted")
+	}
+
+	// Operations do not modify their operands.
+	if !a.Equal(NewScalar(big.NewInt(7))) {
+		t.Errorf("Operand modified: %v", a)
+	}
+}
@@ -0,0 +1,264 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Secp256k1 is the secp256k1 backend: y^2 = x^3 + 7 over the field of p = 2^256 - 2^32 - 977, with the prime group
+// order n as scalar field. Points are encoded as 33-byte compressed SEC1 points, the identity as the single byte
+// 0x00, which matches the encoding used by Bitcoin tooling.
+//
+// The implementation uses math/big and is variable time, like the BN256 backend (see Group): proving and committing
+// run scalar multiplications on secret values.
+//
+// WNLA, arithmetic circuit and range proofs run over it with ProveGroupWNLA, ProveGroupCircuit and ProveGroupRange.
+// The other statements of the package, like bit and digit sum proofs or aggregated ranges, use bn256.
+var Secp256k1 Group = secp256k1Group{}
+
+var (
+	secp256k1P, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	secp256k1N, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+	secp256k1Gx, _ = new(big.Int).SetString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", 16)
+	secp256k1Gy, _ = new(big.Int).SetString("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", 16)
+)
+
+const secp256k1PointSize = 33
+
+// secp256k1Point is an affine point, the identity has nil coordinates.
+type secp256k1Point struct {
+	x, y *big.Int
+}
+
+func (p *secp256k1Point) isIdentity() bool {
+	return p.x == nil
+}
+
+// Marshal returns the compressed SEC1 encoding of the point.
+func (p *secp256k1Point) Marshal() []byte {
+	if p.isIdentity() {
+		return []byte{0x00}
+	}
+
+	res := make([]byte, 1, secp256k1PointSize)
+	res[0] = 0x02 | byte(p.y.Bit(0))
+	return append(res, scalarTo32Byte(p.x)...)
+}
+
+// secp256k1Jacobian is a point in Jacobian coordinates (X/Z^2, Y/Z^3), the identity has Z = 0.
+type secp256k1Jacobian struct {
+	x, y, z *big.Int
+}
+
+func secp256k1Mod(x *big.Int) *big.Int {
+	return x.Mod(x, secp256k1P)
+}
+
+func (p *secp256k1Point) jacobian() *secp256k1Jacobian {
+	if p.isIdentity() {
+		return &secp256k1Jacobian{big.NewInt(1), big.NewInt(1), big.NewInt(0)}
+	}
+	return &secp256k1Jacobian{new(big.Int).Set(p.x), new(big.Int).Set(p.y), big.NewInt(1)}
+}
+
+func (p *secp256k1Jacobian) affine() *secp256k1Point {
+	if p.z.Sign() == 0 {
+		return &secp256k1Point{}
+	}
+
+	zinv := new(big.Int).ModInverse(p.z, secp256k1P)
+	zinv2 := secp256k1Mod(new(big.Int).Mul(zinv, zinv))
+
+	x := secp256k1Mod(new(big.Int).Mul(p.x, zinv2))
+	y := secp256k1Mod(new(big.Int).Mul(p.y, secp256k1Mod(new(big.Int).Mul(zinv2, zinv))))
+	return &secp256k1Point{x, y}
+}
+
+// double uses the dbl-2009-l formulas for a = 0.
+func (p *secp256k1Jacobian) double() *secp256k1Jacobian {
+	if p.z.Sign() == 0 || p.y.Sign() == 0 {
+		return &secp256k1Jacobian{big.NewInt(1), big.NewInt(1), big.NewInt(0)}
+	}
+
+	a := secp256k1Mod(new(big.Int).Mul(p.x, p.x))
+	b := secp256k1Mod(new(big.Int).Mul(p.y, p.y))
+	c := secp256k1Mod(new(big.Int).Mul(b, b))
+
+	d := new(big.Int).Add(p.x, b)
+	d.Mul(d, d)
+	d.Sub(d, a)
+	d.Sub(d, c)
+	d = secp256k1Mod(d.Lsh(d, 1))
+
+	e := new(big.Int).Mul(a, big.NewInt(3))
+	f := secp256k1Mod(new(big.Int).Mul(e, e))
+
+	x3 := secp256k1Mod(new(big.Int).Sub(f, new(big.Int).Lsh(d, 1)))
+
+	y3 := new(big.Int).Sub(d, x3)
+	y3.Mul(y3, e)
+	y3 = secp256k1Mod(y3.Sub(y3, new(big.Int).Lsh(c, 3)))
+
+	z3 := new(big.Int).Mul(p.y, p.z)
+	z3 = secp256k1Mod(z3.Lsh(z3, 1))
+
+	return &secp256k1Jacobian{x3, y3, z3}
+}
+
+// add uses the add-2007-bl formulas.
+func (p *secp256k1Jacobian) add(q *secp256k1Jacobian) *secp256k1Jacobian {
+	if p.z.Sign() == 0 {
+		return q
+	}
+	if q.z.Sign() == 0 {
+		return p
+	}
+
+	z1z1 := secp256k1Mod(new(big.Int).Mul(p.z, p.z))
+	z2z2 := secp256k1Mod(new(big.Int).Mul(q.z, q.z))
+
+	u1 := secp256k1Mod(new(big.Int).Mul(p.x, z2z2))
+	u2 := secp256k1Mod(new(big.Int).Mul(q.x, z1z1))
+
+	s1 := secp256k1Mod(new(big.Int).Mul(p.y, secp256k1Mod(new(big.Int).Mul(q.z, z2z2))))
+	s2 := secp256k1Mod(new(big.Int).Mul(q.y, secp256k1Mod(new(big.Int).Mul(p.z, z1z1))))
+
+	h := secp256k1Mod(new(big.Int).Sub(u2, u1))
+	r := secp256k1Mod(new(big.Int).Lsh(new(big.Int).Sub(s2, s1), 1))
+
+	if h.Sign() == 0 {
+		if r.Sign() == 0 {
+			return p.double()
+		}
+		return &secp256k1Jacobian{big.NewInt(1), big.NewInt(1), big.NewInt(0)}
+	}
+
+	i := new(big.Int).Lsh(h, 1)
+	i = secp256k1Mod(i.Mul(i, i))
+	j := secp256k1Mod(new(big.Int).Mul(h, i))
+	v := secp256k1Mod(new(big.Int).Mul(u1, i))
+
+	x3 := new(big.Int).Mul(r, r)
+	x3.Sub(x3, j)
+	x3 = secp256k1Mod(x3.Sub(x3, new(big.Int).Lsh(v, 1)))
+
+	y3 := new(big.Int).Sub(v, x3)
+	y3.Mul(y3, r)
+	y3 = secp256k1Mod(y3.Sub(y3, new(big.Int).Lsh(new(big.Int).Mul(s1, j), 1)))
+
+	z3 := new(big.Int).Add(p.z, q.z)
+	z3.Mul(z3, z3)
+	z3.Sub(z3, z1z1)
+	z3.Sub(z3, z2z2)
+	z3 = secp256k1Mod(z3.Mul(z3, h))
+
+	return &secp256k1Jacobian{x3, y3, z3}
+}
+
+type secp256k1Group struct{}
+
+func (secp256k1Group) Name() string {
+	return "secp256k1"
+}
+
+func (secp256k1Group) Order() *big.Int {
+	return secp256k1N
+}
+
+func (secp256k1Group) Identity() Point {
+	return &secp256k1Point{}
+}
+
+func (secp256k1Group) Add(a, b Point) Point {
+	return a.(*secp256k1Point).jacobian().add(b.(*secp256k1Point).jacobian()).affine()
+}
+
+func (secp256k1Group) ScalarMult(p Point, k *big.Int) Point {
+	k = new(big.Int).Mod(k, secp256k1N)
+
+	base := p.(*secp256k1Point).jacobian()
+	res := (&secp256k1Point{}).jacobian()
+
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		res = res.double()
+		if k.Bit(i) == 1 {
+			res = res.add(base)
+		}
+	}
+
+	return res.affine()
+}
+
+func (secp256k1Group) Equal(a, b Point) bool {
+	pa, pb := a.(*secp256k1Point), b.(*secp256k1Point)
+	if pa.isIdentity() || pb.isIdentity() {
+		return pa.isIdentity() == pb.isIdentity()
+	}
+	return pa.x.Cmp(pb.x) == 0 && pa.y.Cmp(pb.y) == 0
+}
+
+// secp256k1Y returns the square root of x^3 + 7 with the requested parity, or nil if x is not on the curve.
+func secp256k1Y(x *big.Int, odd bool) *big.Int {
+	rhs := new(big.Int).Exp(x, big.NewInt(3), secp256k1P)
+	rhs.Add(rhs, big.NewInt(7))
+	secp256k1Mod(rhs)
+
+	y := new(big.Int).ModSqrt(rhs, secp256k1P)
+	if y == nil {
+		return nil
+	}
+
+	if (y.Bit(0) == 1) != odd {
+		y.Sub(secp256k1P, y)
+	}
+	return y
+}
+
+// Unmarshal decodes a compressed SEC1 point. Coordinates must be canonical and on the curve.
+func (secp256k1Group) Unmarshal(data []byte) (Point, error) {
+	if len(data) == 1 && data[0] == 0x00 {
+		return &secp256k1Point{}, nil
+	}
+
+	if len(data) != secp256k1PointSize || (data[0] != 0x02 && data[0] != 0x03) {
+		return nil, errors.New("secp256k1: invalid point encoding")
+	}
+
+	x := new(big.Int).SetBytes(data[1:])
+	if x.Cmp(secp256k1P) >= 0 {
+		return nil, errors.New("secp256k1: x coordinate is not canonical")
+	}
+
+	y := secp256k1Y(x, data[0] == 0x03)
+	if y == nil {
+		return nil, errors.New("secp256k1: point is not on the curve")
+	}
+
+	return &secp256k1Point{x, y}, nil
+}
+
+// DeriveGenerator derives a point with unknown discrete logarithm using the same try-and-increment construction as
+// the bn256 DeriveGenerator: x = Keccak256(domain || 0x00 || label || 0x00 || counter) mod p for counter = 0, 1, ...
+// until x^3 + 7 is a square, then the even square root is taken as y. The cofactor of secp256k1 is 1, so every
+// curve point is a group element.
+func (secp256k1Group) DeriveGenerator(domain, label string) (Point, error) {
+	if domain == "" {
+		return nil, errors.New("domain cannot be empty")
+	}
+
+	for counter := 0; counter < maxDeriveAttempts; counter++ {
+		x := new(big.Int).SetBytes(Keccak256([]byte(domain), []byte{0x00}, []byte(label), []byte{0x00}, []byte{byte(counter)}))
+		secp256k1Mod(x)
+
+		if y := secp256k1Y(x, false); y != nil {
+			return &secp256k1Point{x, y}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("failed to derive generator for label %q", label)
+}
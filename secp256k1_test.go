@@ -0,0 +1,60 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func TestSecp256k1Group(t *testing.T) {
+	g := Secp256k1
+	base := &secp256k1Point{secp256k1Gx, secp256k1Gy}
+
+	// 2G and 3G from the SEC test vectors.
+	for k, expected := range map[int64]string{
+		1: "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+		2: "02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5",
+		3: "02f9308a019258c31049344f85f89d5229b531c845836f99b08601f113bce036f9",
+	} {
+		if got := hex.EncodeToString(g.ScalarMult(base, big.NewInt(k)).Marshal()); got != expected {
+			t.Errorf("%d*G = %s, expected %s", k, got, expected)
+		}
+	}
+
+	if !g.Equal(g.Add(base, base), g.ScalarMult(base, big.NewInt(2))) {
+		t.Error("G + G differs from 2*G")
+	}
+
+	if !g.Equal(g.ScalarMult(base, secp256k1N), g.Identity()) {
+		t.Error("n*G is not the identity")
+	}
+
+	if !g.Equal(g.Add(base, g.ScalarMult(base, new(big.Int).Sub(secp256k1N, big.NewInt(1)))), g.Identity()) {
+		t.Error("G + (n-1)*G is not the identity")
+	}
+
+	p, err := g.DeriveGenerator(DOMAIN_WNLA, "g")
+	if err != nil {
+		t.Fatalf("DeriveGenerator failed: %v", err)
+	}
+
+	for _, point := range []Point{p, g.Identity(), g.ScalarMult(base, big.NewInt(12345))} {
+		decoded, err := g.Unmarshal(point.Marshal())
+		if err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if !g.Equal(decoded, point) {
+			t.Error("Point changed across a round trip")
+		}
+	}
+
+	invalid := append([]byte{0x02}, bytes.Repeat([]byte{0xff}, 32)...)
+	if _, err := g.Unmarshal(invalid); err == nil {
+		t.Error("Should reject a non-canonical x coordinate")
+	}
+}
@@ -0,0 +1,253 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"io"
+	"math/big"
+)
+
+// Streaming binary encoding of the proofs.
+//
+// Points are written as the 64 bytes of bn256.G1.Marshal(), scalars as 32 bytes big-endian. Reading rejects any
+// other encoding of a point with ErrNonCanonicalPoint. Every vector is preceded by its element count as a 4-byte
+// big-endian integer:
+//
+//	WNLA proof:  len(R) || R || len(X) || X || len(L) || L || len(N) || N
+//	Range proof: Nd || Np || Domain || V || CL || CR || CO || CS || WNLA proof
+//
+// Nd, Np and the domain tag of the range proof header are 4-byte big-endian integers as well. Vector lengths above
+// maxProofVectorLength are rejected with ErrTooLarge.
+
+// ErrTruncated is returned when a proof stream ends before the proof has been fully read.
+var ErrTruncated = errors.New("proof stream truncated")
+
+// ErrTooLarge is returned when a decoded proof declares a vector longer than maxProofVectorLength.
+var ErrTooLarge = errors.New("declared vector length too large")
+
+// maxProofVectorLength bounds every vector length prefix accepted by the decoders. WNLA proofs carry one R and X
+// entry per folding round and fewer than six final scalars, so real proofs stay far below this limit.
+const maxProofVectorLength = 1 << 16
+
+func readFull(r io.Reader, buf []byte) error {
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return ErrTruncated
+		}
+		return err
+	}
+	return nil
+}
+
+func writePoint(w io.Writer, p *bn256.G1) error {
+	if p == nil {
+		return errors.New("point cannot be nil")
+	}
+	_, err := w.Write(p.Marshal())
+	return err
+}
+
+func readPoint(r io.Reader) (*bn256.G1, error) {
+	buf := make([]byte, pointSize)
+	if err := readFull(r, buf); err != nil {
+		return nil, err
+	}
+	p, err := unmarshalPoint(buf)
+	if err != nil {
+		return nil, fmt.Errorf("invalid point: %w", err)
+	}
+	return p, nil
+}
+
+func writeLength(w io.Writer, n int) error {
+	if uint64(n) > 0xffffffff {
+		return fmt.Errorf("vector length %d does not fit the length prefix", n)
+	}
+	_, err := w.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	return err
+}
+
+func readLength(r io.Reader) (int, error) {
+	buf := make([]byte, 4)
+	if err := readFull(r, buf); err != nil {
+		return 0, err
+	}
+	return int(binary.BigEndian.Uint32(buf)), nil
+}
+
+// readVectorLength reads a vector length prefix for elements of elemSize bytes. If r reports how many bytes are
+// left (as bytes.Reader, bytes.Buffer and strings.Reader do), a length that cannot fit is rejected before reading.
+func readVectorLength(r io.Reader, elemSize int) (int, error) {
+	n, err := readLength(r)
+	if err != nil {
+		return 0, err
+	}
+	if n > maxProofVectorLength {
+		return 0, fmt.Errorf("%w: %d elements", ErrTooLarge, n)
+	}
+	if l, ok := r.(interface{ Len() int }); ok && n > l.Len()/elemSize {
+		return 0, fmt.Errorf("%d elements of %d bytes declared, %d bytes left: %w", n, elemSize, l.Len(), ErrTruncated)
+	}
+	return n, nil
+}
+
+func writePoints(w io.Writer, ps []*bn256.G1) error {
+	if err := writeLength(w, len(ps)); err != nil {
+		return err
+	}
+	for i, p := range ps {
+		if err := writePoint(w, p); err != nil {
+			return fmt.Errorf("point %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// readPoints grows the result as points arrive, so a forged length prefix does not cause a large allocation up front.
+func readPoints(r io.Reader) ([]*bn256.G1, error) {
+	n, err := readVectorLength(r, pointSize)
+	if err != nil {
+		return nil, err
+	}
+	var res []*bn256.G1
+	for i := 0; i < n; i++ {
+		p, err := readPoint(r)
+		if err != nil {
+			return nil, fmt.Errorf("point %d: %w", i, err)
+		}
+		res = append(res, p)
+	}
+	return res, nil
+}
+
+func writeScalars(w io.Writer, ss []*big.Int) error {
+	if err := writeLength(w, len(ss)); err != nil {
+		return err
+	}
+	for i, s := range ss {
+		if s == nil {
+			return fmt.Errorf("scalar %d cannot be nil", i)
+		}
+		if _, err := w.Write(scalarTo32Byte(s)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func readScalars(r io.Reader) ([]*big.Int, error) {
+	n, err := readVectorLength(r, scalarSize)
+	if err != nil {
+		return nil, err
+	}
+	var res []*big.Int
+	buf := make([]byte, scalarSize)
+	for i := 0; i < n; i++ {
+		if err := readFull(r, buf); err != nil {
+			return nil, fmt.Errorf("scalar %d: %w", i, err)
+		}
+		res = append(res, new(big.Int).SetBytes(buf))
+	}
+	return res, nil
+}
+
+// WriteWNLAProof streams the WNLA proof to w.
+func WriteWNLAProof(w io.Writer, proof *WeightNormLinearArgumentProof) error {
+	if proof == nil {
+		return errors.New("proof cannot be nil")
+	}
+	if err := writePoints(w, proof.R); err != nil {
+		return fmt.Errorf("failed to write R: %w", err)
+	}
+	if err := writePoints(w, proof.X); err != nil {
+		return fmt.Errorf("failed to write X: %w", err)
+	}
+	if err := writeScalars(w, proof.L); err != nil {
+		return fmt.Errorf("failed to write L: %w", err)
+	}
+	if err := writeScalars(w, proof.N); err != nil {
+		return fmt.Errorf("failed to write N: %w", err)
+	}
+	return nil
+}
+
+// ReadWNLAProof reads a WNLA proof written by WriteWNLAProof. A stream that ends early returns an error wrapping
+// ErrTruncated.
+func ReadWNLAProof(r io.Reader) (*WeightNormLinearArgumentProof, error) {
+	var err error
+	proof := new(WeightNormLinearArgumentProof)
+	if proof.R, err = readPoints(r); err != nil {
+		return nil, fmt.Errorf("failed to read R: %w", err)
+	}
+	if proof.X, err = readPoints(r); err != nil {
+		return nil, fmt.Errorf("failed to read X: %w", err)
+	}
+	if proof.L, err = readScalars(r); err != nil {
+		return nil, fmt.Errorf("failed to read L: %w", err)
+	}
+	if proof.N, err = readScalars(r); err != nil {
+		return nil, fmt.Errorf("failed to read N: %w", err)
+	}
+	return proof, nil
+}
+
+// WriteRangeProof streams the reciprocal range proof to w.
+func WriteRangeProof(w io.Writer, proof *ReciprocalProof) error {
+	if proof == nil || proof.ArithmeticCircuitProof == nil {
+		return errors.New("proof cannot be nil")
+	}
+	if proof.Nd < 0 || proof.Np < 0 {
+		return errors.New("proof header cannot be negative")
+	}
+	if err := writeLength(w, proof.Nd); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+	if err := writeLength(w, proof.Np); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+	if _, err := w.Write(binary.BigEndian.AppendUint32(nil, proof.Domain)); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+	for _, p := range []*bn256.G1{proof.V, proof.CL, proof.CR, proof.CO, proof.CS} {
+		if err := writePoint(w, p); err != nil {
+			return fmt.Errorf("failed to write commitment: %w", err)
+		}
+	}
+	return WriteWNLAProof(w, proof.WNLA)
+}
+
+// ReadRangeProof reads a reciprocal range proof written by WriteRangeProof. A stream that ends early returns an
+// error wrapping ErrTruncated.
+func ReadRangeProof(r io.Reader) (*ReciprocalProof, error) {
+	proof := &ReciprocalProof{ArithmeticCircuitProof: new(ArithmeticCircuitProof)}
+	for _, n := range []*int{&proof.Nd, &proof.Np} {
+		var err error
+		if *n, err = readLength(r); err != nil {
+			return nil, fmt.Errorf("failed to read header: %w", err)
+		}
+	}
+	domain := make([]byte, 4)
+	if err := readFull(r, domain); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	proof.Domain = binary.BigEndian.Uint32(domain)
+	for _, p := range []**bn256.G1{&proof.V, &proof.CL, &proof.CR, &proof.CO, &proof.CS} {
+		var err error
+		if *p, err = readPoint(r); err != nil {
+			return nil, fmt.Errorf("failed to read commitment: %w", err)
+		}
+	}
+
+	wnla, err := ReadWNLAProof(r)
+	if err != nil {
+		return nil, err
+	}
+	proof.WNLA = wnla
+	return proof, nil
+}
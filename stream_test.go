@@ -0,0 +1,100 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+	"testing/iotest"
+)
+
+func TestRangeProofStream(t *testing.T) {
+	x := uint64(0x123456789abcdef0)
+	digits := UInt64Hex(x)
+
+	Nd := 16
+	Np := 16
+
+	wnlaPublic := NewWeightNormLinearPublic(32, 16)
+
+	public := &ReciprocalPublic{
+		G:     wnlaPublic.G,
+		GVec:  wnlaPublic.GVec[:Nd],
+		HVec:  wnlaPublic.HVec[:Nd+1+9],
+		Nd:    Nd,
+		Np:    Np,
+		GVec_: wnlaPublic.GVec[Nd:],
+		HVec_: wnlaPublic.HVec[Nd+1+9:],
+	}
+
+	private := &ReciprocalPrivate{
+		X:      new(big.Int).SetUint64(x),
+		M:      HexMapping(digits),
+		Digits: digits,
+		S:      NewRandScalar(),
+	}
+
+	VCom := public.CommitValue(private.X, private.S)
+	proof, err := ProveRange(public, NewKeccakFS(), private)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteRangeProof(&buf, proof); err != nil {
+		t.Fatalf("WriteRangeProof failed: %v", err)
+	}
+	data := buf.Bytes()
+
+	// Reading one byte at a time exercises partial reads.
+	decoded, err := ReadRangeProof(iotest.OneByteReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("ReadRangeProof failed: %v", err)
+	}
+
+	if decoded.Nd != Nd || decoded.Np != Np {
+		t.Errorf("Header changed across a round trip: Nd=%d, Np=%d", decoded.Nd, decoded.Np)
+	}
+
+	if err := VerifyRange(public, VCom, NewKeccakFS(), decoded); err != nil {
+		t.Fatalf("Decoded proof failed verification: %v", err)
+	}
+
+	for _, n := range []int{0, 1, 63, 64, 5 * 64, 5*64 + 3, len(data) - 1} {
+		if _, err := ReadRangeProof(bytes.NewReader(data[:n])); !errors.Is(err, ErrTruncated) {
+			t.Errorf("Truncated stream of %d bytes: expected ErrTruncated, got %v", n, err)
+		}
+	}
+}
+
+func TestWNLAProofStreamLengthPrefix(t *testing.T) {
+	// Declares 2^32-1 points but carries none.
+	data := []byte{0xff, 0xff, 0xff, 0xff}
+
+	if _, err := ReadWNLAProof(bytes.NewReader(data)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge, got %v", err)
+	}
+
+	// Declares 2^31 scalars after empty R and X.
+	data = []byte{0, 0, 0, 0, 0, 0, 0, 0, 0x80, 0, 0, 0}
+
+	if _, err := ReadWNLAProof(bytes.NewReader(data)); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge, got %v", err)
+	}
+
+	// Within the limit, but longer than the remaining input: rejected up front when the reader knows its length and
+	// on the first missing point otherwise.
+	data = append([]byte{0, 0, 0x10, 0}, make([]byte, 3*pointSize)...)
+
+	if _, err := ReadWNLAProof(bytes.NewReader(data)); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Expected ErrTruncated, got %v", err)
+	}
+
+	if _, err := ReadWNLAProof(iotest.OneByteReader(bytes.NewReader(data))); !errors.Is(err, ErrTruncated) {
+		t.Errorf("Expected ErrTruncated, got %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+)
+
+// SumProof proves that a set of value commitments opens to values summing up to a public total.
+// It is a Schnorr proof of knowledge of r with sum(coms) - total*G = r*HVec[0]: the left side is a commitment to
+// zero exactly when the committed values sum up to total.
+type SumProof struct {
+	R *bn256.G1
+	S *big.Int
+}
+
+// sumExcess returns sum(coms) - total*G.
+func sumExcess(public *ReciprocalPublic, coms []*bn256.G1, total *big.Int) (*bn256.G1, error) {
+	res := new(bn256.G1).ScalarMult(public.G, minus(total))
+	for i, com := range coms {
+		if com == nil {
+			return nil, fmt.Errorf("commitment %d cannot be nil", i)
+		}
+		res.Add(res, com)
+	}
+	return res, nil
+}
+
+func sumChallenge(fs FiatShamirEngine, coms []*bn256.G1, total *big.Int, P, R *bn256.G1) (*big.Int, error) {
+	if err := addPointVector(fs, coms); err != nil {
+		return nil, err
+	}
+	if err := fs.AddNumber(total); err != nil {
+		return nil, err
+	}
+	if err := fs.AddPoint(P); err != nil {
+		return nil, err
+	}
+	if err := fs.AddPoint(R); err != nil {
+		return nil, err
+	}
+	return fs.GetChallenge(), nil
+}
+
+// ProveSum proves that the values committed in coms[i] = CommitValue(values[i], blindings[i]) sum up to total
+// without revealing the individual values. Use empty FiatShamirEngine for call.
+func ProveSum(public *ReciprocalPublic, fs FiatShamirEngine, coms []*bn256.G1, values, blindings []*big.Int, total *big.Int) (*SumProof, error) {
+	if err := public.Validate(); err != nil {
+		return nil, err
+	}
+
+	if fs == nil {
+		return nil, errors.New("Fiat-Shamir engine cannot be nil")
+	}
+
+	if len(coms) == 0 || len(coms) != len(values) || len(coms) != len(blindings) {
+		return nil, errors.New("commitments, values and blindings should have the same non-zero length")
+	}
+
+	if total == nil {
+		return nil, errors.New("total cannot be nil")
+	}
+
+	sum, r := bint(0), bint(0)
+	for i := range coms {
+		if values[i] == nil || blindings[i] == nil {
+			return nil, fmt.Errorf("opening %d cannot be nil", i)
+		}
+		if !VerifyOpening(public, coms[i], values[i], blindings[i]) {
+			return nil, fmt.Errorf("opening %d does not match its commitment", i)
+		}
+		sum = add(sum, values[i])
+		r = add(r, blindings[i])
+	}
+
+	if !NewScalar(sum).Equal(NewScalar(total)) {
+		return nil, errors.New("values do not sum up to total")
+	}
+
+	P, err := sumExcess(public, coms, total)
+	if err != nil {
+		return nil, err
+	}
+
+	R, s, err := proveDLog(public.HVec[0], r, func(R *bn256.G1) (*big.Int, error) {
+		return sumChallenge(fs, coms, total, P, R)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SumProof{R: R, S: s}, nil
+}
+
+// VerifySum verifies that the values committed in coms sum up to total. If err is nil then proof is valid.
+// Use empty FiatShamirEngine for call.
+func VerifySum(public *ReciprocalPublic, fs FiatShamirEngine, coms []*bn256.G1, total *big.Int, proof *SumProof) error {
+	if err := public.Validate(); err != nil {
+		return err
+	}
+
+	if fs == nil {
+		return errors.New("Fiat-Shamir engine cannot be nil")
+	}
+
+	if len(coms) == 0 || total == nil {
+		return errors.New("commitments and total cannot be empty")
+	}
+
+	if proof == nil || proof.R == nil || !isCanonicalScalar(proof.S) {
+		return errors.New("invalid sum proof")
+	}
+
+	P, err := sumExcess(public, coms, total)
+	if err != nil {
+		return err
+	}
+
+	err = verifyDLog(public.HVec[0], P, proof.R, proof.S, func(R *bn256.G1) (*big.Int, error) {
+		return sumChallenge(fs, coms, total, P, R)
+	})
+	if errors.Is(err, errDLogFailed) {
+		return fmt.Errorf("failed to verify sum proof: %w", err)
+	}
+	return err
+}
@@ -0,0 +1,49 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"testing"
+)
+
+func TestSumProof(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	values := []*big.Int{bint(100), bint(250), bint(0), bint(650)}
+	blindings := make([]*big.Int, len(values))
+	coms := make([]*bn256.G1, len(values))
+	for i := range values {
+		blindings[i] = NewRandScalar()
+		coms[i] = public.CommitValue(values[i], blindings[i])
+	}
+
+	total := bint(1000)
+
+	proof, err := ProveSum(public, NewKeccakFS(), coms, values, blindings, total)
+	if err != nil {
+		t.Fatalf("ProveSum failed: %v", err)
+	}
+
+	if err := VerifySum(public, NewKeccakFS(), coms, total, proof); err != nil {
+		t.Fatalf("Valid sum proof rejected: %v", err)
+	}
+
+	if err := VerifySum(public, NewKeccakFS(), coms, bint(1001), proof); err == nil {
+		t.Error("Sum proof accepted for a different total")
+	}
+
+	if err := VerifySum(public, NewKeccakFS(), coms[:3], total, proof); err == nil {
+		t.Error("Sum proof accepted for a subset of commitments")
+	}
+
+	if _, err := ProveSum(public, NewKeccakFS(), coms, values, blindings, bint(999)); err == nil {
+		t.Error("ProveSum should fail for a wrong total")
+	}
+}
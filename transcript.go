@@ -0,0 +1,88 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Operations of a TranscriptEntry.
+const (
+	TranscriptPoint     = "point"
+	TranscriptNumber    = "number"
+	TranscriptDomain    = "domain"
+	TranscriptBytes     = "bytes"
+	TranscriptUint64    = "uint64"
+	TranscriptChallenge = "challenge"
+)
+
+// ErrTranscriptMismatch is returned by ReplayTranscript when a re-derived challenge differs from the recorded one.
+var ErrTranscriptMismatch = errors.New("re-derived challenge does not match the transcript")
+
+// TranscriptEntry is one input absorbed by a transcript or one challenge it derived. Data holds the bytes the
+// transcript absorbs for the input: the point encoding, the 32-byte number, the 8-byte integer, the domain or the
+// raw bytes. For a challenge Data is its 32-byte encoding. Vectors appear as their length followed by the elements;
+// the length is a number under TranscriptV1 and lengthTag followed by an integer under TranscriptV2 (see addLength).
+type TranscriptEntry struct {
+	Op   string `json:"op"`
+	Data []byte `json:"data"`
+}
+
+// TranscriptRecorder collects the entries of a transcript created with WithTranscriptRecorder, for example to keep
+// them in an audit log. Use one recorder per transcript.
+type TranscriptRecorder struct {
+	entries []TranscriptEntry
+}
+
+// Entries returns the recorded entries in the order they were absorbed or derived.
+func (r *TranscriptRecorder) Entries() []TranscriptEntry {
+	return append([]TranscriptEntry{}, r.entries...)
+}
+
+func (r *TranscriptRecorder) add(op string, data []byte) {
+	r.entries = append(r.entries, TranscriptEntry{Op: op, Data: append([]byte{}, data...)})
+}
+
+// ReplayTranscript absorbs the recorded inputs into fs and checks every recorded challenge against the one fs
+// derives at the same position. Pass a fresh transcript of the engine that produced the entries, without
+// WithAppDomain: the application domain is one of the entries. Vectors are replayed element by element, which
+// absorbs the same bytes.
+func ReplayTranscript(fs FiatShamirEngine, entries []TranscriptEntry) error {
+	for i, e := range entries {
+		var err error
+		switch e.Op {
+		case TranscriptPoint:
+			p, perr := unmarshalPoint(e.Data)
+			if perr != nil {
+				return fmt.Errorf("entry %d: %w", i, perr)
+			}
+			err = fs.AddPoint(p)
+		case TranscriptNumber:
+			err = fs.AddNumber(new(big.Int).SetBytes(e.Data))
+		case TranscriptUint64:
+			if len(e.Data) != 8 {
+				return fmt.Errorf("entry %d: integer must be 8 bytes, got %d", i, len(e.Data))
+			}
+			err = addUint64(fs, binary.BigEndian.Uint64(e.Data))
+		case TranscriptDomain:
+			err = fs.AddDomain(string(e.Data))
+		case TranscriptBytes:
+			err = fs.AddBytes(e.Data)
+		case TranscriptChallenge:
+			if fs.GetChallenge().Cmp(new(big.Int).SetBytes(e.Data)) != 0 {
+				return fmt.Errorf("%w: entry %d", ErrTranscriptMismatch, i)
+			}
+		default:
+			return fmt.Errorf("entry %d: unknown operation %q", i, e.Op)
+		}
+		if err != nil {
+			return fmt.Errorf("entry %d: %w", i, err)
+		}
+	}
+	return nil
+}
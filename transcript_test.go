@@ -0,0 +1,86 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestTranscriptRecorder(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	private, err := public.newPrivate(new(big.Int).SetUint64(0xab4f0540ab4f0540), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	VCom := public.CommitValue(private.X, private.S)
+
+	recorder := &TranscriptRecorder{}
+	proof, err := ProveRange(public, NewKeccakFS(WithAppDomain("audit"), WithTranscriptRecorder(recorder)), private)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	// Recording does not change the challenges.
+	if err := VerifyRange(public, VCom, NewKeccakFS(WithAppDomain("audit")), proof); err != nil {
+		t.Fatalf("Recorded proof rejected: %v", err)
+	}
+
+	entries := recorder.Entries()
+	if len(entries) == 0 || entries[0].Op != TranscriptDomain || string(entries[0].Data) != "audit" {
+		t.Fatal("The application domain is not the first entry")
+	}
+
+	// An auditor re-derives the challenges from the logged entries.
+	data, err := json.Marshal(entries)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %v", err)
+	}
+
+	var logged []TranscriptEntry
+	if err := json.Unmarshal(data, &logged); err != nil {
+		t.Fatalf("json.Unmarshal failed: %v", err)
+	}
+
+	if err := ReplayTranscript(NewKeccakFS(), logged); err != nil {
+		t.Fatalf("ReplayTranscript failed: %v", err)
+	}
+
+	// The verifier absorbs the same inputs and derives the same challenges.
+	verifierRecorder := &TranscriptRecorder{}
+	if err := VerifyRange(public, VCom, NewKeccakFS(WithAppDomain("audit"), WithTranscriptRecorder(verifierRecorder)), proof); err != nil {
+		t.Fatalf("VerifyRange failed: %v", err)
+	}
+
+	if len(verifierRecorder.Entries()) != len(entries) {
+		t.Errorf("Prover recorded %d entries, verifier %d", len(entries), len(verifierRecorder.Entries()))
+	}
+
+	for i, e := range entries {
+		if e.Op == TranscriptChallenge {
+			tampered := append([]TranscriptEntry{}, entries...)
+			tampered[i-1] = TranscriptEntry{Op: TranscriptNumber, Data: scalarTo32Byte(bint(7))}
+			if err := ReplayTranscript(NewKeccakFS(), tampered); !errors.Is(err, ErrTranscriptMismatch) {
+				t.Errorf("Expected ErrTranscriptMismatch, got %v", err)
+			}
+			break
+		}
+	}
+
+	if err := ReplayTranscript(NewBlake2bFS(), entries); !errors.Is(err, ErrTranscriptMismatch) {
+		t.Errorf("Expected ErrTranscriptMismatch for another engine, got %v", err)
+	}
+
+	if err := ReplayTranscript(NewKeccakFS(), []TranscriptEntry{{Op: "hash"}}); err == nil {
+		t.Error("Expected an error for an unknown operation")
+	}
+}
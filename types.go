@@ -11,30 +11,49 @@ import (
 
 // ReciprocalPublic dimensions:
 // Nd - count of private proles (size of committed value), Np - count of public poles (number system base).
-// Nm = Nd, No = Np
+// Nm = Nd, No = Np (or the size of DigitSet if set)
 // Nv = 1 + Nd
 // G and HVec[0] will be used for the value commitment: VCom = value*G + blinding*HVec[0]
+// G may be any caller-chosen point, e.g. the value generator of an existing commitment scheme, as long as Validate
+// accepts it. The prover and the verifier must use exactly the same G: it is part of the statement, and a proof
+// made with one G does not verify with another.
 type ReciprocalPublic struct {
 	G      *bn256.G1
 	GVec   []*bn256.G1 // Nm
-	HVec   []*bn256.G1 // Nv+9
+	HVec   []*bn256.G1 // Nv+circuitBlindingSlots
 	Nd, Np int
 
 	// Vectors of points that will be used in WNLA protocol
 	GVec_ []*bn256.G1 // 2^n - Nm
-	HVec_ []*bn256.G1 // 2^n - (Nv+9)
+	HVec_ []*bn256.G1 // 2^n - (Nv+circuitBlindingSlots)
+
+	// Optional derivation labels, set by NewReciprocalPublic
+	Labels *GeneratorLabels
+
+	// Optional legal digit values, ascending and within [0, Np). Nil means every digit 0..Np-1 is legal. A digit
+	// set such as {0..9} with Np = 16 restricts the value to decimal digits stored in hex positions. The digit
+	// multiplicities M are indexed by position in the set.
+	DigitSet []int
 }
 
 type ReciprocalPrivate struct {
 	X      *big.Int // Committed value
 	M      []*big.Int
-	Digits []*big.Int
-	S      *big.Int // Blinding value (secret)
+	Digits []*big.Int // Digits[j] is the digit of Np^j, least significant first as returned by Base.Digits
+	S      *big.Int   // Blinding value (secret)
 }
 
 type ReciprocalProof struct {
 	*ArithmeticCircuitProof
 	V *bn256.G1
+
+	// Header with the dimensions the proof was produced for, zero if unknown. It is not part of the transcript
+	// and only lets the verifier report parameter mismatches clearly.
+	Nd, Np int
+
+	// Domain is the tag of the prover's application and generator domains (see DomainTag), zero if unknown. The
+	// transcript absorbs the tag of the domains, not this copy; it only lets the verifier report ErrDomainMismatch.
+	Domain uint32
 }
 
 type PartitionType int
@@ -53,7 +72,7 @@ type ArithmeticCircuitPublic struct {
 	K                  int // Count of witness vectors v.
 	G                  *bn256.G1
 	GVec               []*bn256.G1 // Nm
-	HVec               []*bn256.G1 // Nv+9
+	HVec               []*bn256.G1 // Nv+circuitBlindingSlots
 
 	Wm [][]*big.Int // Nm * Nw
 	Wl [][]*big.Int // Nl * Nw
@@ -68,7 +87,7 @@ type ArithmeticCircuitPublic struct {
 
 	// Vectors of points that will be used in WNLA protocol
 	GVec_ []*bn256.G1 // 2^n - Nm
-	HVec_ []*bn256.G1 // 2^n - (Nv+9)
+	HVec_ []*bn256.G1 // 2^n - (Nv+circuitBlindingSlots)
 }
 
 type ArithmeticCircuitPrivate struct {
@@ -86,6 +105,10 @@ type ArithmeticCircuitProof struct {
 
 // WeightNormLinearArgumentProof contains the proof of knowledge of vectors L, N for corresponding commitment C (is not
 // included into the proof structure).
+//
+// L and N are sent in full and cannot be shortened. The verifier only learns the final commitment
+// v*G + <L, H'> + <N, G'> as a group element, so recovering an omitted entry x from the known point x*H'[j] would
+// mean solving a discrete logarithm. Every entry is needed, and a compressed proof variant is not possible.
 type WeightNormLinearArgumentProof struct {
 	R, X []*bn256.G1
 	L, N []*big.Int
@@ -93,11 +116,15 @@ type WeightNormLinearArgumentProof struct {
 
 // WeightNormLinearPublic contains the public values to be used in weight norm linear argument proof.
 // The GVec and HVec sizes are recommended to be a powers of 2 and equal to the `n` and `l` private vector sizes.
+// G may be set by the caller; the prover and the verifier must use exactly the same G.
 type WeightNormLinearPublic struct {
 	G          *bn256.G1
 	GVec, HVec []*bn256.G1
 	C          []*big.Int
 	Ro, Mu     *big.Int // mu = ro^2
+
+	// Optional derivation labels, set by NewLabeledWeightNormLinearPublic
+	Labels *GeneratorLabels
 }
 
 func NewWeightNormLinearPublic(lLen int, nLen int) *WeightNormLinearPublic {
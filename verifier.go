@@ -0,0 +1,406 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"runtime"
+	"sync"
+)
+
+// ErrBaseMismatch is returned when a range proof was produced for a different digit count or base than the
+// verifier's public parameters.
+var ErrBaseMismatch = errors.New("proof dimensions do not match public parameters")
+
+// ErrDomainMismatch is returned when the header of a range proof records a different application domain (see
+// WithAppDomain) or generator domain than those of the verifier (see DomainTag).
+var ErrDomainMismatch = errors.New("proof was produced under a different domain")
+
+// VerifyStage names the part of a range proof that failed to verify.
+type VerifyStage int
+
+const (
+	// VerifyStageRange covers the inputs of the proof and the digit-range argument: the reciprocal circuit and its
+	// reduction to the WNLA.
+	VerifyStageRange VerifyStage = iota + 1
+	// VerifyStageWNLA covers the WNLA proof the range argument is reduced to: its round count and its base case.
+	VerifyStageWNLA
+)
+
+func (s VerifyStage) String() string {
+	switch s {
+	case VerifyStageRange:
+		return "digit-range argument"
+	case VerifyStageWNLA:
+		return "WNLA"
+	}
+	return fmt.Sprintf("VerifyStage(%d)", int(s))
+}
+
+// VerifyError describes why a range proof was rejected. It wraps the underlying error, so errors.Is and errors.As
+// see through it.
+type VerifyError struct {
+	Stage VerifyStage
+	// ExpectedRounds is the number of WNLA rounds the public parameters require, ActualRounds the number of X
+	// points the proof carries.
+	ExpectedRounds int
+	ActualRounds   int
+	// CommitmentAbsorbed reports whether the value commitment had been absorbed into the transcript when the
+	// verification failed. If not, the proof was rejected before any challenge was derived from it.
+	CommitmentAbsorbed bool
+	Err                error
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("%s failed (WNLA rounds: expected %d, got %d; value commitment absorbed: %t): %v",
+		e.Stage, e.ExpectedRounds, e.ActualRounds, e.CommitmentAbsorbed, e.Err)
+}
+
+func (e *VerifyError) Unwrap() error {
+	return e.Err
+}
+
+// Verifier verifies range proofs for one set of public parameters. The parameter checks and everything that only
+// depends on the parameters are computed once, on the first verification, and reused for every following proof:
+// the circuit coefficients, the parts of the range circuit that do not depend on the challenges, the concatenated
+// generator vectors of the WNLA and tables of multiples of every generator, which make the multi-scalar
+// multiplications over the generators several times faster. The tables take about 5 KiB per generator. Options of
+// a single call that change the parameters, WithDigitCount and WithValueGenerators, only share the tables; an
+// MSMFunc passed with WithMSM replaces them. A Verifier is safe for concurrent use by multiple goroutines.
+type Verifier struct {
+	public *ReciprocalPublic
+	opts   []VerifyOption
+	optErr error // result of checking the options of NewVerifier
+	tables bool  // whether to precompute the generator tables
+
+	once   sync.Once
+	coeffs *rangeCoefficients
+	pre    *verifierPrecompute
+	err    error // result of the one-time parameter checks
+}
+
+// verifierPrecompute holds the generator-side data of the parameters selected by the options of NewVerifier. It
+// must not be modified once built.
+type verifierPrecompute struct {
+	template *rangeTemplate
+	table    *generatorTable // nil if the Verifier has no tables or an MSMFunc of its own
+}
+
+// NewVerifier creates a Verifier for the public parameters. The parameters must not be modified while the
+// Verifier is in use. The options apply to every verification, before the options of the call; an MSMFunc passed
+// with WithMSM is checked here once, and Verify returns the error of the check.
+func NewVerifier(public *ReciprocalPublic, opts ...VerifyOption) *Verifier {
+	v := newVerifier(public, opts)
+	v.tables = true
+	return v
+}
+
+// newVerifier creates a Verifier without generator tables, for a single verification, where building them costs
+// more than they save.
+func newVerifier(public *ReciprocalPublic, opts []VerifyOption) *Verifier {
+	v := &Verifier{public: public, opts: opts}
+	if msm := newVerifyConfig(opts).msm; msm != nil {
+		v.optErr = checkMSM(msm)
+	}
+	return v
+}
+
+func (v *Verifier) coefficients() (*rangeCoefficients, error) {
+	v.once.Do(func() {
+		if v.err = v.public.Validate(); v.err != nil {
+			return
+		}
+
+		v.coeffs = &rangeCoefficients{
+			negBasePowers: negBasePowers(scalarField{bn256.Order}, v.public.Np, v.public.Nd),
+		}
+
+		cfg := newVerifyConfig(v.opts)
+
+		var view *ReciprocalPublic
+		if view, v.err = v.public.rangeView(cfg); v.err != nil {
+			return
+		}
+
+		v.pre = &verifierPrecompute{
+			template: newRangeTemplate(view.group(), v.coeffs.negBasePowers[:view.Nd]),
+		}
+
+		if v.tables && cfg.msm == nil {
+			points := append([]*bn256.G1{view.G}, view.GVec...)
+			points = append(append(append(points, view.HVec...), view.GVec_...), view.HVec_...)
+			v.pre.table = newGeneratorTable(points)
+		}
+	})
+	return v.coeffs, v.err
+}
+
+// Verify verifies the range proof for the value commitment V. If err is nil then proof is valid. See VerifyRange.
+// Use empty FiatShamirEngine for call: an engine that has already derived challenges is rejected with
+// ErrTranscriptReused. Use VerifyChained to continue a transcript.
+func (v *Verifier) Verify(V *bn256.G1, fs FiatShamirEngine, proof *ReciprocalProof, opts ...VerifyOption) error {
+	if err := checkFreshTranscript(fs); err != nil {
+		return err
+	}
+
+	return v.VerifyChained(V, fs, proof, opts...)
+}
+
+// VerifyChained works like Verify but continues the transcript fs as it is, see Prover.ProveChained.
+func (v *Verifier) VerifyChained(V *bn256.G1, fs FiatShamirEngine, proof *ReciprocalProof, opts ...VerifyOption) error {
+	if v.optErr != nil {
+		return v.optErr
+	}
+
+	coeffs, err := v.coefficients()
+	if err != nil {
+		return err
+	}
+
+	// An MSMFunc of the call has not been checked by NewVerifier.
+	callCfg := newVerifyConfig(opts)
+	if callCfg.msm != nil {
+		if err := checkMSM(callCfg.msm); err != nil {
+			return err
+		}
+	}
+
+	cfg := newVerifyConfig(append(append([]VerifyOption{}, v.opts...), opts...))
+
+	public, err := v.public.rangeParameters(fs, cfg)
+	if err != nil {
+		return err
+	}
+
+	// The template is for the parameters of NewVerifier's options, the call may select others.
+	template := v.pre.template
+	if callCfg.digitCount != 0 || callCfg.valueG != nil || callCfg.valueH != nil {
+		template = newRangeTemplate(public.group(), coeffs.negBasePowers[:public.Nd])
+	}
+
+	msm := cfg.msm
+	if msm == nil && v.pre.table != nil {
+		msm = v.pre.table.msm
+	}
+
+	return verifyReciprocal(public, V, fs, proof, rangeDomainTag(v.public, fs), template.circuit, msm)
+}
+
+// verifyReciprocal verifies the reciprocal argument for the value commitment V over the circuit built for the
+// challenge e. Statements extending the range circuit must absorb their public inputs into fs beforehand. The
+// domain tag must be the one the prover used, see proveReciprocal. Errors are returned as *VerifyError.
+func verifyReciprocal(public *ReciprocalPublic, V *bn256.G1, fs FiatShamirEngine, proof *ReciprocalProof, tag uint32, newCircuit func(e *big.Int) *GroupArithmeticCircuitPublic, msm MSMFunc) error {
+	return verifyGroupReciprocal(public.group(), toPoint(V), fs, proof.group(), tag, newCircuit, msm)
+}
+
+// verifyGroupReciprocal works like verifyReciprocal over an arbitrary group.
+func verifyGroupReciprocal(public *GroupReciprocalPublic, V Point, fs FiatShamirEngine, proof *GroupReciprocalProof, tag uint32, newCircuit func(e *big.Int) *GroupArithmeticCircuitPublic, msm MSMFunc) error {
+	absorbed := false
+	fail := func(err error) error {
+		verr := &VerifyError{
+			Stage:              VerifyStageRange,
+			ExpectedRounds:     wnlaRounds(len(public.HVec)+len(public.HVec_), len(public.GVec)+len(public.GVec_)),
+			CommitmentAbsorbed: absorbed,
+			Err:                err,
+		}
+
+		if proof != nil && proof.GroupArithmeticCircuitProof != nil && proof.WNLA != nil {
+			verr.ActualRounds = len(proof.WNLA.X)
+		}
+
+		if errors.Is(err, ErrProofLengthMismatch) || errors.Is(err, ErrFinalCommitmentMismatch) {
+			verr.Stage = VerifyStageWNLA
+		}
+
+		return verr
+	}
+
+	if fs == nil {
+		return fail(errors.New("Fiat-Shamir engine cannot be nil"))
+	}
+
+	if V == nil {
+		return fail(errors.New("value commitment cannot be nil"))
+	}
+
+	if proof == nil || proof.GroupArithmeticCircuitProof == nil || proof.V == nil {
+		return fail(errors.New("range proof is incomplete"))
+	}
+
+	if (proof.Np != 0 && proof.Np != public.Np) || (proof.Nd != 0 && proof.Nd != public.Nd) {
+		return fail(fmt.Errorf("%w: proof has Nd=%d, Np=%d, parameters have Nd=%d, Np=%d",
+			ErrBaseMismatch, proof.Nd, proof.Np, public.Nd, public.Np))
+	}
+
+	if proof.Domain != 0 && tag != 0 && proof.Domain != tag {
+		return fail(fmt.Errorf("%w: proof has domain tag %08x, transcript has %08x", ErrDomainMismatch, proof.Domain, tag))
+	}
+
+	// The header only reports the mismatch early; the tag of the verifier's own domains is what binds the proof.
+	if err := absorbDomainTag(fs, tag); err != nil {
+		return fail(err)
+	}
+
+	// Absorbed first, as by the prover: the challenges depend on the value commitment.
+	if err := addGroupPoint(fs, V); err != nil {
+		return fail(err)
+	}
+	absorbed = true
+
+	f := public.field()
+
+	e := f.challenge(fs)
+	if err := checkPoleChallenge(f, e, public.digitSet()); err != nil {
+		return fail(err)
+	}
+
+	circuit := newCircuit(e)
+
+	if err := verifyCircuit(circuit, []Point{public.Group.Add(V, proof.V)}, fs, proof.GroupArithmeticCircuitProof, msm); err != nil {
+		return fail(err)
+	}
+
+	return nil
+}
+
+// MSMFunc computes the multi-scalar multiplication sum(scalars[i]*points[i]). Both slices have the same length, the
+// scalars are reduced modulo bn256.Order, and neither the slices nor the points may be modified.
+type MSMFunc func(points []*bn256.G1, scalars []*big.Int) *bn256.G1
+
+// ErrMSMMismatch is returned when an MSMFunc passed with WithMSM disagrees with the built-in multi-scalar
+// multiplication.
+var ErrMSMMismatch = errors.New("multi-scalar multiplication disagrees with the built-in one")
+
+// WithMSM makes range verification compute its multi-scalar multiplications over the generator vectors with f
+// instead of the built-in one, e.g. to hand them to a hardware-accelerated backend. These dominate the verification
+// time. A proof verifies the same with or without the option.
+//
+// f is compared against the built-in implementation on a small fixed input and rejected with ErrMSMMismatch if it
+// disagrees. Pass the option to NewVerifier to run the check once for all verifications; VerifyRange, VerifyBit and
+// VerifyDigitSum, and Verifier.Verify given the option per call, check f on every call. The check cannot prove f
+// correct: f is trusted with the verdict, and one that returns wrong results for other inputs can accept invalid
+// proofs.
+func WithMSM(f MSMFunc) VerifyOption {
+	return func(cfg *proveConfig) {
+		cfg.msm = f
+	}
+}
+
+// errMSMNil is returned when an MSMFunc returns nil during a verification.
+var errMSMNil = errors.New("multi-scalar multiplication returned nil")
+
+// mul returns f(points, scalars), or the built-in result for a nil f. Scalars missing at the end count as zero and
+// surplus scalars are ignored, as by vectorPointScalarMul.
+func (f MSMFunc) mul(points []*bn256.G1, scalars []*big.Int) (*bn256.G1, error) {
+	if f == nil {
+		return vectorPointScalarMul(points, scalars), nil
+	}
+
+	n := min(len(points), len(scalars))
+	res := f(points[:n], scalars[:n])
+	if res == nil {
+		return nil, errMSMNil
+	}
+	return res, nil
+}
+
+// statementMSM returns the MSMFunc of opts for verifiers of fixed statements, like VerifyBit, after checking it. It
+// rejects the RangeOptions, which these statements do not support.
+func statementMSM(opts []VerifyOption) (MSMFunc, error) {
+	cfg := newVerifyConfig(opts)
+	if cfg.digitCount != 0 || cfg.valueG != nil || cfg.valueH != nil {
+		return nil, errors.New("range options are not supported by this statement")
+	}
+
+	if cfg.msm != nil {
+		if err := checkMSM(cfg.msm); err != nil {
+			return nil, err
+		}
+	}
+	return cfg.msm, nil
+}
+
+// checkMSM compares f with the built-in multi-scalar multiplication on a few multiples of the base point, with
+// scalars covering zero, one, the largest field element and a full-width value.
+func checkMSM(f MSMFunc) error {
+	scalars := []*big.Int{
+		bint(0),
+		bint(1),
+		sub(bint(0), bint(1)),
+		new(big.Int).Rsh(bn256.Order, 1),
+	}
+
+	points := make([]*bn256.G1, len(scalars))
+	for i := range points {
+		points[i] = new(bn256.G1).ScalarBaseMult(bint(i + 1))
+	}
+
+	for n := 1; n <= len(points); n++ {
+		res := f(points[:n], scalars[:n])
+		if res == nil || !pointsEqual(res, vectorPointScalarMul(points[:n], scalars[:n])) {
+			return fmt.Errorf("%w: wrong result for %d points", ErrMSMMismatch, n)
+		}
+	}
+
+	return nil
+}
+
+// VerifyRangeEach verifies proofs[i] for the value commitment coms[i] and returns one result per pair, nil for a
+// valid proof, so a caller can accept the valid proofs of a batch and drop the rest. The proofs share one Verifier
+// and are checked in parallel, each with a fresh NewKeccakFS(opts...) transcript. If the slices differ in length,
+// the unpaired entries are reported as errors.
+func VerifyRangeEach(public *ReciprocalPublic, coms []*bn256.G1, proofs []*ReciprocalProof, opts ...FSOption) []error {
+	verifier := NewVerifier(public)
+
+	res := make([]error, max(len(coms), len(proofs)))
+	_ = parallelFor(len(res), runtime.GOMAXPROCS(0), func(i int) error {
+		switch {
+		case i >= len(proofs):
+			res[i] = fmt.Errorf("no proof for commitment %d", i)
+		case i >= len(coms):
+			res[i] = fmt.Errorf("no commitment for proof %d", i)
+		default:
+			res[i] = verifier.Verify(coms[i], NewKeccakFS(opts...), proofs[i])
+		}
+		return nil
+	})
+
+	return res
+}
+
+// ErrNoMatchingCommitment is returned by VerifyRangeAny when the proof verifies for none of the candidates.
+var ErrNoMatchingCommitment = errors.New("proof does not verify for any candidate commitment")
+
+// VerifyRangeAny verifies the proof against every candidate commitment and returns the lowest index it is valid
+// for, or ErrNoMatchingCommitment. The transcript absorbs the value commitment first, so every candidate gets a
+// fresh NewKeccakFS(opts...) transcript; the parameter checks and circuit coefficients of one Verifier and the
+// checks of the proof itself are shared, and the candidates are checked in parallel.
+func VerifyRangeAny(public *ReciprocalPublic, coms []*bn256.G1, proof *ReciprocalProof, opts ...FSOption) (int, error) {
+	verifier := NewVerifier(public)
+	if _, err := verifier.coefficients(); err != nil {
+		return -1, err
+	}
+
+	if proof == nil || proof.ArithmeticCircuitProof == nil || proof.V == nil {
+		return -1, errors.New("range proof is incomplete")
+	}
+
+	valid := make([]bool, len(coms))
+	_ = parallelFor(len(coms), runtime.GOMAXPROCS(0), func(i int) error {
+		valid[i] = coms[i] != nil && verifier.Verify(coms[i], NewKeccakFS(opts...), proof) == nil
+		return nil
+	})
+
+	for i := range valid {
+		if valid[i] {
+			return i, nil
+		}
+	}
+
+	return -1, ErrNoMatchingCommitment
+}
@@ -0,0 +1,481 @@
+// Package bulletproofs
+// Copyright 2024 Distributed Lab. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+package bulletproofs
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
+	"math/big"
+	"testing"
+)
+
+func newVerifierTestProof(tb testing.TB) (*ReciprocalPublic, *ReciprocalProof, *ReciprocalPrivate) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		tb.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	x := uint64(0x123456789abcdef0)
+	digits := UInt64Hex(x)
+
+	private := &ReciprocalPrivate{
+		X:      new(big.Int).SetUint64(x),
+		M:      HexMapping(digits),
+		Digits: digits,
+		S:      NewRandScalar(),
+	}
+
+	proof, err := ProveRange(public, NewKeccakFS(), private)
+	if err != nil {
+		tb.Fatalf("ProveRange failed: %v", err)
+	}
+
+	return public, proof, private
+}
+
+// expectRejected fails the test unless err is a *VerifyError of the given stage that wraps target, so a negative
+// test only passes if the proof is rejected by the check it is aimed at.
+func expectRejected(tb testing.TB, err error, stage VerifyStage, target error) {
+	tb.Helper()
+
+	var verr *VerifyError
+	if !errors.As(err, &verr) {
+		tb.Errorf("Expected a VerifyError, got %v", err)
+		return
+	}
+
+	if verr.Stage != stage || !errors.Is(err, target) {
+		tb.Errorf("Expected rejection by %v with %q, got %v", stage, target, err)
+	}
+}
+
+func TestVerifierReuse(t *testing.T) {
+	public, proof, private := newVerifierTestProof(t)
+	VCom := public.CommitValue(private.X, private.S)
+
+	verifier := NewVerifier(public)
+
+	for i := 0; i < 3; i++ {
+		if err := verifier.Verify(VCom, NewKeccakFS(), proof); err != nil {
+			t.Fatalf("Verification %d failed: %v", i, err)
+		}
+	}
+
+	if err := verifier.Verify(public.CommitValue(bint(1), private.S), NewKeccakFS(), proof); err == nil {
+		t.Error("Verifier accepted a proof for the wrong commitment")
+	}
+}
+
+func TestVerifierPrecompute(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 32, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	x := new(big.Int).SetUint64(0xab4f0540ab4f0540)
+	s := NewRandScalar()
+	com := public.CommitValue(x, s)
+
+	wide, err := public.newPrivate(x, s)
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	narrowPublic, err := public.withDigitCount(16)
+	if err != nil {
+		t.Fatalf("withDigitCount failed: %v", err)
+	}
+
+	narrow, err := narrowPublic.newPrivate(x, s)
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	wideProof, err := ProveRange(public, NewKeccakFS(), wide)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	narrowProof, err := ProveRange(public, NewKeccakFS(), narrow, WithDigitCount(16))
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	verifier := NewVerifier(public)
+	for i := 0; i < 2; i++ {
+		if err := verifier.Verify(com, NewKeccakFS(), wideProof); err != nil {
+			t.Fatalf("Verification %d failed: %v", i, err)
+		}
+
+		// The call selects other parameters than the precomputed ones.
+		if err := verifier.Verify(com, NewKeccakFS(), narrowProof, WithDigitCount(16)); err != nil {
+			t.Fatalf("Verification %d with WithDigitCount failed: %v", i, err)
+		}
+	}
+
+	if verifier.pre == nil || verifier.pre.table == nil {
+		t.Fatal("Verifier has no generator tables")
+	}
+
+	// The circuit of the template equals the one built from scratch.
+	e := NewRandScalar()
+	expected := rangeCircuit(public.group(), e, negBasePowers(scalarField{bn256.Order}, public.Np, public.Nd))
+	actual := verifier.pre.template.circuit(e)
+	for i := range expected.Wl {
+		if fmt.Sprint(expected.Wl[i]) != fmt.Sprint(actual.Wl[i]) {
+			t.Errorf("Row %d of Wl differs", i)
+		}
+	}
+	for i := range expected.Wm {
+		if fmt.Sprint(expected.Wm[i]) != fmt.Sprint(actual.Wm[i]) {
+			t.Errorf("Row %d of Wm differs", i)
+		}
+	}
+
+	narrowVerifier := NewVerifier(public, WithDigitCount(16))
+	if err := narrowVerifier.Verify(com, NewKeccakFS(), narrowProof); err != nil {
+		t.Errorf("Verifier for 16 digits failed: %v", err)
+	}
+
+	if err := narrowVerifier.Verify(com, NewKeccakFS(), wideProof); !errors.Is(err, ErrBaseMismatch) {
+		t.Errorf("Expected ErrBaseMismatch, got %v", err)
+	}
+
+	msmVerifier := NewVerifier(public, WithMSM(vectorPointScalarMul))
+	if err := msmVerifier.Verify(com, NewKeccakFS(), wideProof); err != nil {
+		t.Errorf("Verifier with WithMSM failed: %v", err)
+	}
+
+	if msmVerifier.pre.table != nil {
+		t.Error("Verifier built generator tables despite WithMSM")
+	}
+}
+
+func TestVerifierBaseMismatch(t *testing.T) {
+	public, proof, private := newVerifierTestProof(t)
+	VCom := public.CommitValue(private.X, private.S)
+
+	if proof.Nd != public.Nd || proof.Np != public.Np {
+		t.Fatalf("Proof header Nd=%d, Np=%d does not match the parameters", proof.Nd, proof.Np)
+	}
+
+	decimal := *public
+	decimal.Np = 10
+
+	if err := VerifyRange(&decimal, VCom, NewKeccakFS(), proof); !errors.Is(err, ErrBaseMismatch) {
+		t.Errorf("Expected ErrBaseMismatch, got %v", err)
+	}
+
+	// Without a header the proof still fails, only less specifically.
+	unlabeled := *proof
+	unlabeled.Nd, unlabeled.Np = 0, 0
+
+	expectRejected(t, VerifyRange(&decimal, VCom, NewKeccakFS(), &unlabeled), VerifyStageWNLA, ErrFinalCommitmentMismatch)
+}
+
+func BenchmarkVerifierReuse(b *testing.B) {
+	public, proof, private := newVerifierTestProof(b)
+	VCom := public.CommitValue(private.X, private.S)
+
+	b.Run("Verifier", func(b *testing.B) {
+		verifier := NewVerifier(public)
+		for i := 0; i < b.N; i++ {
+			if err := verifier.Verify(VCom, NewKeccakFS(), proof); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("VerifyRange", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := VerifyRange(public, VCom, NewKeccakFS(), proof); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestVerifierRoundMismatch(t *testing.T) {
+	public, proof, private := newVerifierTestProof(t)
+	VCom := public.CommitValue(private.X, private.S)
+
+	wnla := proof.WNLA
+	rounds := len(wnla.X)
+
+	extraX := append(append([]*bn256.G1{}, wnla.X...), wnla.X[0])
+	extraR := append(append([]*bn256.G1{}, wnla.R...), wnla.R[0])
+
+	for name, forged := range map[string]*WeightNormLinearArgumentProof{
+		"too few":   {X: wnla.X[:rounds-1], R: wnla.R[:rounds-1], L: wnla.L, N: wnla.N},
+		"too many":  {X: extraX, R: extraR, L: wnla.L, N: wnla.N},
+		"unequal":   {X: wnla.X, R: wnla.R[:rounds-1], L: wnla.L, N: wnla.N},
+		"no rounds": {L: wnla.L, N: wnla.N},
+	} {
+		circuitProof := *proof.ArithmeticCircuitProof
+		circuitProof.WNLA = forged
+
+		err := VerifyRange(public, VCom, NewKeccakFS(), &ReciprocalProof{ArithmeticCircuitProof: &circuitProof, V: proof.V})
+		if !errors.Is(err, ErrProofLengthMismatch) {
+			t.Errorf("%s: expected ErrProofLengthMismatch, got %v", name, err)
+		}
+	}
+}
+
+func TestVerifyRangeEach(t *testing.T) {
+	public, proof, private := newVerifierTestProof(t)
+	VCom := public.CommitValue(private.X, private.S)
+
+	other, err := public.newPrivate(bint(7), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	otherProof, err := ProveRange(public, NewKeccakFS(), other)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+	otherCom := public.CommitValue(other.X, other.S)
+
+	coms := []*bn256.G1{VCom, otherCom, VCom, otherCom}
+	proofs := []*ReciprocalProof{proof, otherProof, otherProof, nil}
+
+	results := VerifyRangeEach(public, coms, proofs)
+	if len(results) != len(proofs) {
+		t.Fatalf("Expected %d results, got %d", len(proofs), len(results))
+	}
+
+	for i, valid := range []bool{true, true, false, false} {
+		if (results[i] == nil) != valid {
+			t.Errorf("Proof %d: expected valid=%v, got %v", i, valid, results[i])
+		}
+	}
+
+	results = VerifyRangeEach(public, coms[:1], proofs[:2])
+	if len(results) != 2 || results[0] != nil || results[1] == nil {
+		t.Errorf("Unexpected results for an unpaired proof: %v", results)
+	}
+
+	if results := VerifyRangeEach(public, coms[:1], proofs[:1], WithAppDomain("app")); results[0] == nil {
+		t.Error("Proof verified under another app domain")
+	}
+}
+
+func TestVerifyRangeAny(t *testing.T) {
+	public, proof, private := newVerifierTestProof(t)
+	VCom := public.CommitValue(private.X, private.S)
+
+	candidates := []*bn256.G1{
+		public.CommitValue(bint(1), NewRandScalar()),
+		nil,
+		public.CommitValue(private.X, NewRandScalar()),
+		VCom,
+		public.CommitValue(bint(2), NewRandScalar()),
+	}
+
+	index, err := VerifyRangeAny(public, candidates, proof)
+	if err != nil {
+		t.Fatalf("VerifyRangeAny failed: %v", err)
+	}
+
+	if index != 3 {
+		t.Errorf("Expected index 3, got %d", index)
+	}
+
+	if _, err := VerifyRangeAny(public, append(candidates[:3:3], candidates[4]), proof); !errors.Is(err, ErrNoMatchingCommitment) {
+		t.Errorf("Expected ErrNoMatchingCommitment, got %v", err)
+	}
+
+	if _, err := VerifyRangeAny(public, candidates, proof, WithAppDomain("app")); !errors.Is(err, ErrNoMatchingCommitment) {
+		t.Errorf("Expected ErrNoMatchingCommitment under another app domain, got %v", err)
+	}
+
+	if _, err := VerifyRangeAny(public, nil, proof); !errors.Is(err, ErrNoMatchingCommitment) {
+		t.Errorf("Expected ErrNoMatchingCommitment for no candidates, got %v", err)
+	}
+
+	if _, err := VerifyRangeAny(public, candidates, nil); err == nil {
+		t.Error("Expected an error for a nil proof")
+	}
+}
+
+func TestDomainMismatch(t *testing.T) {
+	public, err := NewReciprocalPublic(DOMAIN_RANGE, 16, 16)
+	if err != nil {
+		t.Fatalf("NewReciprocalPublic failed: %v", err)
+	}
+
+	private, err := public.newPrivate(bint(0xd0e), NewRandScalar())
+	if err != nil {
+		t.Fatalf("newPrivate failed: %v", err)
+	}
+
+	VCom := public.CommitValue(private.X, private.S)
+
+	proof, err := ProveRange(public, NewKeccakFS(WithAppDomain("deployment-a")), private)
+	if err != nil {
+		t.Fatalf("ProveRange failed: %v", err)
+	}
+
+	if tag := DomainTag("deployment-a", DOMAIN_RANGE); proof.Domain != tag {
+		t.Errorf("Expected domain tag %08x, got %08x", tag, proof.Domain)
+	}
+
+	if DomainTag("deployment-a", DOMAIN_RANGE) == DomainTag("deployment-a", DOMAIN_CIRCUIT) {
+		t.Error("The domain tag does not cover the generator domain")
+	}
+
+	if err := VerifyRange(public, VCom, NewKeccakFS(WithAppDomain("deployment-a")), proof); err != nil {
+		t.Fatalf("VerifyRange failed: %v", err)
+	}
+
+	for _, fs := range []FiatShamirEngine{NewKeccakFS(), NewKeccakFS(WithAppDomain("deployment-b"))} {
+		if err := VerifyRange(public, VCom, fs, proof); !errors.Is(err, ErrDomainMismatch) {
+			t.Errorf("Expected ErrDomainMismatch, got %v", err)
+		}
+	}
+
+	// The tag survives the CBOR and the stream encodings.
+	data, err := proof.MarshalCBOR()
+	if err != nil {
+		t.Fatalf("MarshalCBOR failed: %v", err)
+	}
+
+	decoded := new(ReciprocalProof)
+	if err := decoded.UnmarshalCBOR(data); err != nil {
+		t.Fatalf("UnmarshalCBOR failed: %v", err)
+	}
+
+	if err := VerifyRange(public, VCom, NewKeccakFS(), decoded); !errors.Is(err, ErrDomainMismatch) {
+		t.Errorf("Expected ErrDomainMismatch after CBOR round trip, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteRangeProof(&buf, proof); err != nil {
+		t.Fatalf("WriteRangeProof failed: %v", err)
+	}
+
+	if decoded, err = ReadRangeProof(&buf); err != nil {
+		t.Fatalf("ReadRangeProof failed: %v", err)
+	}
+
+	if decoded.Domain != proof.Domain {
+		t.Errorf("Expected domain tag %08x after stream round trip, got %08x", proof.Domain, decoded.Domain)
+	}
+
+	// Without a tag the mismatch is only detected as an invalid proof. The transcript absorbs the tag of the
+	// verifier's domains, so absorbing the same bytes with AddDomain instead of WithAppDomain does not help either.
+	untagged := *proof
+	untagged.Domain = 0
+	expectRejected(t, VerifyRange(public, VCom, NewKeccakFS(), &untagged), VerifyStageWNLA, ErrFinalCommitmentMismatch)
+
+	fs := NewKeccakFS()
+	if err := fs.AddDomain("deployment-a"); err != nil {
+		t.Fatalf("AddDomain failed: %v", err)
+	}
+	expectRejected(t, VerifyRange(public, VCom, fs, &untagged), VerifyStageWNLA, ErrFinalCommitmentMismatch)
+
+	if err := VerifyRange(public, VCom, NewKeccakFS(WithAppDomain("deployment-a")), &untagged); err != nil {
+		t.Errorf("VerifyRange failed without the header tag: %v", err)
+	}
+}
+
+func TestVerifyError(t *testing.T) {
+	public, proof, private := newVerifierTestProof(t)
+	VCom := public.CommitValue(private.X, private.S)
+	rounds := len(proof.WNLA.X)
+
+	var verr *VerifyError
+
+	// A commitment to another value gets through the range argument and fails in the WNLA base case.
+	err := VerifyRange(public, public.CommitValue(bint(1), private.S), NewKeccakFS(), proof)
+	if !errors.As(err, &verr) || !errors.Is(err, ErrFinalCommitmentMismatch) {
+		t.Fatalf("Expected a VerifyError for a final commitment mismatch, got %v", err)
+	}
+
+	if verr.Stage != VerifyStageWNLA || !verr.CommitmentAbsorbed || verr.ExpectedRounds != rounds || verr.ActualRounds != rounds {
+		t.Errorf("Unexpected diagnostics: %+v", *verr)
+	}
+
+	circuitProof := *proof.ArithmeticCircuitProof
+	circuitProof.WNLA = &WeightNormLinearArgumentProof{X: proof.WNLA.X[:rounds-1], R: proof.WNLA.R[:rounds-1], L: proof.WNLA.L, N: proof.WNLA.N}
+
+	err = VerifyRange(public, VCom, NewKeccakFS(), &ReciprocalProof{ArithmeticCircuitProof: &circuitProof, V: proof.V})
+	if !errors.As(err, &verr) || verr.Stage != VerifyStageWNLA || verr.ExpectedRounds != rounds || verr.ActualRounds != rounds-1 {
+		t.Errorf("Unexpected diagnostics for a missing round: %v", err)
+	}
+
+	// Header checks fail before the value commitment is absorbed.
+	mismatched := *proof
+	mismatched.Nd = 8
+	err = VerifyRange(public, VCom, NewKeccakFS(), &mismatched)
+	if !errors.As(err, &verr) || !errors.Is(err, ErrBaseMismatch) || verr.Stage != VerifyStageRange || verr.CommitmentAbsorbed {
+		t.Errorf("Unexpected diagnostics for a base mismatch: %v", err)
+	}
+}
+
+func TestWithMSM(t *testing.T) {
+	public, proof, private := newVerifierTestProof(t)
+	VCom := public.CommitValue(private.X, private.S)
+
+	calls := 0
+	counting := func(points []*bn256.G1, scalars []*big.Int) *bn256.G1 {
+		calls++
+		return vectorPointScalarMul(points, scalars)
+	}
+
+	if err := VerifyRange(public, VCom, NewKeccakFS(), proof, WithMSM(counting)); err != nil {
+		t.Fatalf("Verification with a correct MSM failed: %v", err)
+	}
+
+	// The check on the fixed input makes four calls, the circuit and the final WNLA commitment one each.
+	if calls != 6 {
+		t.Errorf("MSM called %d times, expected 6", calls)
+	}
+
+	if err := VerifyRange(public, new(bn256.G1).Add(VCom, VCom), NewKeccakFS(), proof, WithMSM(counting)); err == nil {
+		t.Error("Invalid proof accepted with a correct MSM")
+	}
+
+	// Drops the last term, which the fixed input catches.
+	wrong := func(points []*bn256.G1, scalars []*big.Int) *bn256.G1 {
+		return vectorPointScalarMul(points[:len(points)-1], scalars)
+	}
+
+	if err := VerifyRange(public, VCom, NewKeccakFS(), proof, WithMSM(wrong)); !errors.Is(err, ErrMSMMismatch) {
+		t.Errorf("Expected ErrMSMMismatch, got %v", err)
+	}
+
+	// NewVerifier checks the function once for all verifications.
+	calls = 0
+	verifier := NewVerifier(public, WithMSM(counting))
+	for i := 0; i < 2; i++ {
+		if err := verifier.Verify(VCom, NewKeccakFS(), proof); err != nil {
+			t.Fatalf("Verify with a correct MSM failed: %v", err)
+		}
+	}
+
+	if calls != 8 {
+		t.Errorf("MSM called %d times for two verifications, expected 8", calls)
+	}
+
+	if err := NewVerifier(public, WithMSM(wrong)).Verify(VCom, NewKeccakFS(), proof); !errors.Is(err, ErrMSMMismatch) {
+		t.Errorf("Expected ErrMSMMismatch from NewVerifier, got %v", err)
+	}
+
+	// A function that passes the check but is wrong on the real input fails the proof instead of accepting it.
+	check := 0
+	late := func(points []*bn256.G1, scalars []*big.Int) *bn256.G1 {
+		if check++; check <= 4 {
+			return vectorPointScalarMul(points, scalars)
+		}
+		return nil
+	}
+
+	if err := VerifyRange(public, VCom, NewKeccakFS(), proof, WithMSM(late)); !errors.Is(err, errMSMNil) {
+		t.Errorf("Expected an error for an MSM returning nil, got %v", err)
+	}
+}
@@ -5,224 +5,949 @@
 package bulletproofs
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"github.com/cloudflare/bn256"
 	"math/big"
 )
 
+// GroupWNLAPublic contains the public values of the weight norm linear argument over an arbitrary Group.
+// WeightNormLinearPublic is the same structure fixed to the BN256 backend.
+type GroupWNLAPublic struct {
+	Group      Group
+	G          Point
+	GVec, HVec []Point
+	C          []*big.Int
+	Ro, Mu     *big.Int // mu = ro^2
+}
+
+// GroupWNLAProof is the weight norm linear argument proof over an arbitrary Group.
+type GroupWNLAProof struct {
+	R, X []Point
+	L, N []*big.Int
+}
+
+// NewGroupWNLAPublic deterministically derives WNLA public parameters over the group using the same labels as
+// NewLabeledWeightNormLinearPublic: "g", "g-i", "h-i" for generators and "c-i", "ro" for scalars.
+func NewGroupWNLAPublic(group Group, domain string, lLen int, nLen int) (*GroupWNLAPublic, error) {
+	if group == nil {
+		return nil, errors.New("group cannot be nil")
+	}
+
+	f := scalarField{group.Order()}
+
+	g, err := group.DeriveGenerator(domain, "g")
+	if err != nil {
+		return nil, err
+	}
+
+	derive := func(prefix string, n int) ([]Point, error) {
+		res := make([]Point, n)
+		for i := range res {
+			if res[i], err = group.DeriveGenerator(domain, fmt.Sprintf("%s-%d", prefix, i)); err != nil {
+				return nil, err
+			}
+		}
+		return res, nil
+	}
+
+	gvec, err := derive("g", nLen)
+	if err != nil {
+		return nil, err
+	}
+
+	hvec, err := derive("h", lLen)
+	if err != nil {
+		return nil, err
+	}
+
+	c := make([]*big.Int, lLen)
+	for i := range c {
+		if c[i], err = deriveGroupScalar(f, domain, fmt.Sprintf("c-%d", i)); err != nil {
+			return nil, err
+		}
+	}
+
+	ro, err := deriveGroupScalar(f, domain, "ro")
+	if err != nil {
+		return nil, err
+	}
+
+	return &GroupWNLAPublic{
+		Group: group,
+		G:     g,
+		GVec:  gvec,
+		HVec:  hvec,
+		C:     c,
+		Ro:    ro,
+		Mu:    f.mul(ro, ro),
+	}, nil
+}
+
+func (p *GroupWNLAPublic) field() scalarField {
+	return scalarField{p.Group.Order()}
+}
+
+// Commit creates a commitment for vectors n, l based on public parameters p.
+// Commit(l, n) = v*G + <l, H> + <n, G>
+// where v = <c, l> + |n^2|_mu
+func (p *GroupWNLAPublic) Commit(l []*big.Int, n []*big.Int) Point {
+	f := p.field()
+	v_ := f.add(f.vectorMul(p.C, l), f.weightVectorMul(n, n, p.Mu))
+	C := p.Group.ScalarMult(p.G, v_)
+	C = p.Group.Add(C, groupVectorPointScalarMul(p.Group, p.HVec, l))
+	C = p.Group.Add(C, groupVectorPointScalarMul(p.Group, p.GVec, n))
+	return C
+}
+
+// Validate checks the invariants the WNLA protocol relies on: all generators are set and distinct, G is a group
+// element other than the identity, C has one weight per HVec entry and Mu = Ro^2.
+func (p *GroupWNLAPublic) Validate() error {
+	if p == nil {
+		return errors.New("WNLA public parameters cannot be nil")
+	}
+
+	if p.Group == nil {
+		return errors.New("group cannot be nil")
+	}
+
+	if p.G == nil {
+		return errors.New("generator G cannot be nil")
+	}
+
+	if err := checkBasePoint(p.Group, p.G); err != nil {
+		return err
+	}
+
+	if len(p.C) != len(p.HVec) {
+		return fmt.Errorf("len(C)=%d does not match len(HVec)=%d", len(p.C), len(p.HVec))
+	}
+
+	for i := range p.GVec {
+		if p.GVec[i] == nil {
+			return fmt.Errorf("GVec[%d] cannot be nil", i)
+		}
+	}
+
+	for i := range p.HVec {
+		if p.HVec[i] == nil {
+			return fmt.Errorf("HVec[%d] cannot be nil", i)
+		}
+	}
+
+	for i := range p.C {
+		if p.C[i] == nil {
+			return fmt.Errorf("C[%d] cannot be nil", i)
+		}
+	}
+
+	if err := p.checkDistinct(); err != nil {
+		return err
+	}
+
+	if p.Ro == nil || p.Mu == nil {
+		return errors.New("Ro and Mu cannot be nil")
+	}
+
+	f := p.field()
+
+	if f.add(p.Ro, nil).Sign() == 0 {
+		return errors.New("Ro cannot be zero")
+	}
+
+	if f.mul(p.Ro, p.Ro).Cmp(f.add(p.Mu, nil)) != 0 {
+		return errors.New("Mu must be equal to Ro^2")
+	}
+
+	return nil
+}
+
+// ProveOption configures proof generation. Options that change the statement, like WithDigitCount, are RangeOptions
+// and can be passed to the prover and the verifier alike.
+type ProveOption func(*proveConfig)
+
+// VerifyOption configures range verification, see WithMSM.
+type VerifyOption func(*proveConfig)
+
+// RangeOption changes the range statement and must be passed to the prover and the verifier alike. It is usable as
+// both a ProveOption and a VerifyOption.
+type RangeOption = func(*proveConfig)
+
+// proveConfig holds the options of both the prover and the verifier. Each side ignores the fields of the other.
+type proveConfig struct {
+	lowMemory  bool
+	digitCount int
+
+	valueG, valueH *bn256.G1
+
+	digitCommitment *DigitCommitment
+
+	msm MSMFunc
+}
+
+func newProveConfig(opts []ProveOption) *proveConfig {
+	cfg := &proveConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+func newVerifyConfig(opts []VerifyOption) *proveConfig {
+	cfg := &proveConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithLowMemory makes the WNLA prover fold its vectors in place instead of allocating new ones every round. The
+// proof is the same as without the option. For starting vectors l and n of lengths lLen and nLen the prover keeps
+// one working copy of l, c and HVec (lLen entries each) and of n and GVec (nLen entries each) for all rounds, where
+// the default prover allocates the folded vectors of every round, about twice as much in total, and leaves the
+// vectors of the previous rounds to the garbage collector.
+func WithLowMemory() ProveOption {
+	return func(cfg *proveConfig) {
+		cfg.lowMemory = true
+	}
+}
+
+// ProveGroupWNLA generates zero knowledge proof of knowledge of two vectors l and n that satisfies the commitment
+// Com (see GroupWNLAPublic.Commit() function). Use empty FiatShamirEngine for call. The scalar multiplications of
+// every backend are variable time, see Group.
+func ProveGroupWNLA(public *GroupWNLAPublic, Com Point, fs FiatShamirEngine, l, n []*big.Int, opts ...ProveOption) (*GroupWNLAProof, error) {
+	if err := public.Validate(); err != nil {
+		return nil, err
+	}
+
+	if fs == nil {
+		return nil, errors.New("Fiat-Shamir engine cannot be nil")
+	}
+
+	if Com == nil {
+		return nil, errors.New("commitment cannot be nil")
+	}
+
+	if len(l) != len(public.HVec) || len(n) != len(public.GVec) {
+		return nil, fmt.Errorf("witness sizes l=%d, n=%d do not match generators HVec=%d, GVec=%d", len(l), len(n), len(public.HVec), len(public.GVec))
+	}
+
+	return proveGroupWNLA(public, Com, fs, l, n, newProveConfig(opts).lowMemory)
+}
+
+// VerifyGroupWNLA verifies the weight norm linear argument proof. If err is nil then proof is valid.
+// Use empty FiatShamirEngine for call. Also, use the same commitment that has been used during proving.
+//
+// Instead of folding the generator vectors every round, the verifier first replays the transcript to collect all
+// round challenges and then checks the final commitment with a single multi-scalar multiplication over the
+// original generators.
+func VerifyGroupWNLA(public *GroupWNLAPublic, proof *GroupWNLAProof, Com Point, fs FiatShamirEngine) error {
+	return verifyGroupWNLA(public, proof, Com, fs, nil)
+}
+
+// verifyGroupWNLA works like VerifyGroupWNLA and computes the final commitment with msm over BN256, see WithMSM.
+func verifyGroupWNLA(public *GroupWNLAPublic, proof *GroupWNLAProof, Com Point, fs FiatShamirEngine, msm MSMFunc) error {
+	expected, opened, err := groupWNLABaseCase(public, proof, Com, fs, msm)
+	if err != nil {
+		return err
+	}
+
+	if !public.Group.Equal(opened, expected) {
+		return fmt.Errorf("failed to verify proof: %w", ErrFinalCommitmentMismatch)
+	}
+
+	return nil
+}
+
+// groupWNLABaseCase runs the verification up to the base case and returns both sides of the final check: Com
+// folded with the round challenges and the commitment to L and N of the proof over the folded parameters. A nil msm
+// uses the built-in multi-scalar multiplication.
+func groupWNLABaseCase(public *GroupWNLAPublic, proof *GroupWNLAProof, Com Point, fs FiatShamirEngine, msm MSMFunc) (expected, opened Point, err error) {
+	if err := public.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if fs == nil {
+		return nil, nil, errors.New("Fiat-Shamir engine cannot be nil")
+	}
+
+	if Com == nil {
+		return nil, nil, errors.New("commitment cannot be nil")
+	}
+
+	if err := proof.checkScalars(public.field()); err != nil {
+		return nil, nil, err
+	}
+
+	if err := proof.checkRounds(len(public.HVec), len(public.GVec)); err != nil {
+		return nil, nil, err
+	}
+
+	ch, err := replayWNLA(public, proof, Com, fs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	opened, err = ch.finalCommitment(public, proof, msm)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ch.Com, opened, nil
+}
+
+// WeightedNormSquared returns the weighted norm |n|^2_mu = sum n[i]^2 * mu^(i+1) mod bn256.Order used by the WNLA
+// commitment. The weights start with mu^1 for the first element, so n = [a, b] gives a^2*mu + b^2*mu^2.
+func WeightedNormSquared(n []*big.Int, mu *big.Int) *big.Int {
+	return weightVectorMul(n, n, mu)
+}
+
 // CommitWNLA creates a commitment for vectors n, l based on public parameters p.
 // Commit(l, n) = v*G + <l, H> + <n, G>
 // where v = <c, l> + |n^2|_mu
+// Every intermediate value is reduced modulo bn256.Order, so any scalars are accepted. The inputs are not checked;
+// use CommitWNLAChecked for parameters or vectors that may be malformed.
 func (p *WeightNormLinearPublic) CommitWNLA(l []*big.Int, n []*big.Int) *bn256.G1 {
-	v_ := add(vectorMul(p.C, l), weightVectorMul(n, n, p.Mu))
+	v_ := add(vectorMul(p.C, l), WeightedNormSquared(n, p.Mu))
 	C := new(bn256.G1).ScalarMult(p.G, v_)
 	C.Add(C, vectorPointScalarMul(p.HVec, l))
 	C.Add(C, vectorPointScalarMul(p.GVec, n))
 	return C
 }
 
+// CommitWNLAChecked works like CommitWNLA but first checks its inputs. The error names the offending input if the
+// parameters are invalid (see Validate), a vector is longer than its generators or an entry is nil.
+func (p *WeightNormLinearPublic) CommitWNLAChecked(l []*big.Int, n []*big.Int) (*bn256.G1, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	if len(l) > len(p.HVec) {
+		return nil, fmt.Errorf("len(l)=%d exceeds len(HVec)=%d", len(l), len(p.HVec))
+	}
+
+	if len(n) > len(p.GVec) {
+		return nil, fmt.Errorf("len(n)=%d exceeds len(GVec)=%d", len(n), len(p.GVec))
+	}
+
+	for i := range l {
+		if l[i] == nil {
+			return nil, fmt.Errorf("l[%d] cannot be nil", i)
+		}
+	}
+
+	for i := range n {
+		if n[i] == nil {
+			return nil, fmt.Errorf("n[%d] cannot be nil", i)
+		}
+	}
+
+	return p.CommitWNLA(l, n), nil
+}
+
+// Validate checks the invariants the WNLA protocol relies on: all generators are set and distinct, C has one weight
+// per HVec entry and Mu = Ro^2.
+func (p *WeightNormLinearPublic) Validate() error {
+	if p == nil {
+		return errors.New("WNLA public parameters cannot be nil")
+	}
+	return p.group().Validate()
+}
+
+// ErrDuplicateGenerator is returned by Validate when G, GVec and HVec do not consist of distinct points. A repeated
+// generator has a known discrete logarithm relative to another one, which breaks the binding of the commitment.
+var ErrDuplicateGenerator = errors.New("generators are not distinct")
+
+// ErrInvalidGenerator is returned by Validate when the base point G is not a usable group element. The identity
+// would drop the weighted norm from the commitment, so the commitment would no longer bind the vectors.
+var ErrInvalidGenerator = errors.New("invalid generator")
+
+// checkBasePoint checks that g decodes as an element of the group and is not the identity. Both backends are
+// curves of prime order, so every point on the curve lies in the group and no further subgroup check is needed.
+func checkBasePoint(group Group, g Point) error {
+	if _, err := group.Unmarshal(g.Marshal()); err != nil {
+		return fmt.Errorf("%w: G is not a %s point: %v", ErrInvalidGenerator, group.Name(), err)
+	}
+	if group.Equal(g, group.Identity()) {
+		return fmt.Errorf("%w: G is the identity", ErrInvalidGenerator)
+	}
+	return nil
+}
+
+// checkDistinct checks that G and the points of GVec and HVec are pairwise distinct by their encodings. The points
+// must not be nil.
+func (p *GroupWNLAPublic) checkDistinct() error {
+	name := func(i int) string {
+		switch {
+		case i == 0:
+			return "G"
+		case i <= len(p.GVec):
+			return fmt.Sprintf("GVec[%d]", i-1)
+		default:
+			return fmt.Sprintf("HVec[%d]", i-1-len(p.GVec))
+		}
+	}
+
+	points := append(append([]Point{p.G}, p.GVec...), p.HVec...)
+	seen := make(map[string]int, len(points))
+	for i := range points {
+		key := string(points[i].Marshal())
+		if prev, ok := seen[key]; ok {
+			return fmt.Errorf("%w: %s equals %s", ErrDuplicateGenerator, name(i), name(prev))
+		}
+		seen[key] = i
+	}
+
+	return nil
+}
+
+// group returns the parameters as GroupWNLAPublic over the BN256 backend. The vectors are not copied.
+func (p *WeightNormLinearPublic) group() *GroupWNLAPublic {
+	var G Point
+	if p.G != nil {
+		G = p.G
+	}
+
+	return &GroupWNLAPublic{
+		Group: BN256,
+		G:     G,
+		GVec:  toPoints(p.GVec),
+		HVec:  toPoints(p.HVec),
+		C:     p.C,
+		Ro:    p.Ro,
+		Mu:    p.Mu,
+	}
+}
+
+func (p *WeightNormLinearArgumentProof) group() *GroupWNLAProof {
+	return &GroupWNLAProof{
+		R: toPoints(p.R),
+		X: toPoints(p.X),
+		L: p.L,
+		N: p.N,
+	}
+}
+
+func (p *GroupWNLAProof) bn256() *WeightNormLinearArgumentProof {
+	return &WeightNormLinearArgumentProof{
+		R: toG1s(p.R),
+		X: toG1s(p.X),
+		L: p.L,
+		N: p.N,
+	}
+}
+
 // VerifyWNLA verifies the weight norm linear argument proof. If err is nil then proof is valid.
 // Use empty FiatShamirEngine for call. Also, use the same commitment that has been used during proving.
+// See VerifyGroupWNLA.
 func VerifyWNLA(public *WeightNormLinearPublic, proof *WeightNormLinearArgumentProof, Com *bn256.G1, fs FiatShamirEngine) error {
-	if len(proof.X) != len(proof.R) {
-		return errors.New("invalid length for R and X vectors: should be equal")
+	return verifyWNLA(public, proof, Com, fs, nil)
+}
+
+// verifyWNLA works like VerifyWNLA and computes the final commitment with msm, see WithMSM.
+func verifyWNLA(public *WeightNormLinearPublic, proof *WeightNormLinearArgumentProof, Com *bn256.G1, fs FiatShamirEngine, msm MSMFunc) error {
+	if err := public.Validate(); err != nil {
+		return err
+	}
+
+	if err := proof.checkScalars(); err != nil {
+		return err
+	}
+
+	if Com == nil {
+		return errors.New("commitment cannot be nil")
+	}
+
+	return verifyGroupWNLA(public.group(), proof.group(), Com, fs, msm)
+}
+
+// ExpectedWNLACommitment runs the verification of VerifyWNLA up to the base case and returns both sides of its
+// final check, for debugging a proof that fails with ErrFinalCommitmentMismatch. expected is the commitment Com
+// folded with the challenges of every round, opened the commitment to the L and N sent by the prover over the
+// folded parameters. The proof is valid exactly when they are equal. The challenges depend on the transcript, so
+// pass the commitment and a FiatShamirEngine in the same state as for VerifyWNLA.
+func ExpectedWNLACommitment(public *WeightNormLinearPublic, proof *WeightNormLinearArgumentProof, Com *bn256.G1, fs FiatShamirEngine) (expected, opened *bn256.G1, err error) {
+	if err := public.Validate(); err != nil {
+		return nil, nil, err
+	}
+
+	if err := proof.checkScalars(); err != nil {
+		return nil, nil, err
+	}
+
+	if Com == nil {
+		return nil, nil, errors.New("commitment cannot be nil")
+	}
+
+	e, o, err := groupWNLABaseCase(public.group(), proof.group(), Com, fs, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return e.(*bn256.G1), o.(*bn256.G1), nil
+}
+
+// ProveWNLA generates zero knowledge proof of knowledge of two vectors l and n that
+// satisfies the commitment C (see WeightNormLinearPublic.Commit() function).
+// Use empty FiatShamirEngine for call. See ProveGroupWNLA.
+func ProveWNLA(public *WeightNormLinearPublic, Com *bn256.G1, fs FiatShamirEngine, l, n []*big.Int, opts ...ProveOption) (*WeightNormLinearArgumentProof, error) {
+	if err := public.Validate(); err != nil {
+		return nil, err
+	}
+
+	if Com == nil {
+		return nil, errors.New("commitment cannot be nil")
+	}
+
+	proof, err := ProveGroupWNLA(public.group(), Com, fs, l, n, opts...)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(proof.X) == 0 {
-		// Base case: verify final commitment matches the reduced parameters
-		commitment := public.CommitWNLA(proof.L, proof.N)
-		if commitment == nil {
-			return errors.New("commitment calculation failed - possible overflow")
+	return proof.bn256(), nil
+}
+
+// ErrNonCanonicalScalar is returned when a proof carries a scalar outside of [0, order). Arithmetic reduces
+// modulo the order, so such a scalar could still verify; rejecting it keeps every proof uniquely encoded.
+var ErrNonCanonicalScalar = errors.New("proof scalar is not in [0, order)")
+
+// ErrProofLengthMismatch is returned when a WNLA proof does not carry exactly one X and one R point for every
+// reduction round the public parameters require.
+var ErrProofLengthMismatch = errors.New("proof round count does not match public parameters")
+
+// ErrFinalCommitmentMismatch is returned when the base case of a WNLA proof does not open the folded commitment.
+var ErrFinalCommitmentMismatch = errors.New("final commitment mismatch")
+
+// checkRounds ensures the proof has one X and one R point per round of the reduction of vectors of lengths lLen
+// and nLen (see WNLARounds). It only looks at the lengths, so it is cheap enough to run before any curve work.
+func (p *GroupWNLAProof) checkRounds(lLen, nLen int) error {
+	if expected := wnlaRounds(lLen, nLen); len(p.X) != expected || len(p.R) != expected {
+		return fmt.Errorf("%w: got %d X and %d R points, expected %d", ErrProofLengthMismatch, len(p.X), len(p.R), expected)
+	}
+	return nil
+}
+
+// checkScalars ensures all scalars of the proof are canonical field elements.
+func (p *WeightNormLinearArgumentProof) checkScalars() error {
+	if p == nil {
+		return errors.New("WNLA proof cannot be nil")
+	}
+	return p.group().checkScalars(scalarField{bn256.Order})
+}
+
+// checkScalars ensures all scalars of the proof are canonical elements of the field f.
+func (p *GroupWNLAProof) checkScalars(f scalarField) error {
+	if p == nil {
+		return errors.New("WNLA proof cannot be nil")
+	}
+
+	for i, s := range p.L {
+		if !f.isCanonical(s) {
+			return fmt.Errorf("%w: L[%d]", ErrNonCanonicalScalar, i)
 		}
+	}
 
-		if !bytes.Equal(commitment.Marshal(), Com.Marshal()) {
-			return fmt.Errorf("failed to verify proof: final commitment mismatch")
+	for i, s := range p.N {
+		if !f.isCanonical(s) {
+			return fmt.Errorf("%w: N[%d]", ErrNonCanonicalScalar, i)
 		}
+	}
+
+	return nil
+}
 
-		return nil
+// foldWNLAPublic runs one round of the verifier's reduction over the public parameters using challenge y:
+// G' = ro*G0 + y*G1, H' = H0 + y*H1, c' = c0 + y*c1, ro' = mu, mu' = mu^2.
+func foldWNLAPublic(public *GroupWNLAPublic, y *big.Int) *GroupWNLAPublic {
+	g, f := public.Group, public.field()
+
+	return &GroupWNLAPublic{
+		Group: g,
+		G:     public.G,
+		GVec:  foldPoints(g, public.GVec, public.Ro, y),
+		HVec:  foldPoints(g, public.HVec, nil, y),
+		C:     foldScalars(f, public.C, nil, y),
+		Ro:    public.Mu,
+		Mu:    f.mul(public.Mu, public.Mu),
 	}
+}
 
-	// Simple Fiat-Shamir transcript matching original implementation
-	if err := fs.AddPoint(Com); err != nil {
+// foldWNLACommitment updates the commitment algebraically for the next round: Com' = Com + X*y + R*(y^2-1).
+func foldWNLACommitment(g Group, Com, X, R Point, y *big.Int) Point {
+	f := scalarField{g.Order()}
+	Com_ := g.Add(Com, g.ScalarMult(X, y))
+	return g.Add(Com_, g.ScalarMult(R, f.sub(f.mul(y, y), big.NewInt(1))))
+}
+
+// wnlaChallenges holds everything the WNLA verifier derives round by round: the challenge Y[k] and weight RoundRo[k]
+// of every round, together with the weights and the commitment reached after the last round. Having the whole
+// sequence at hand lets the verifier compute the fully folded generators directly from the original vectors
+// instead of folding them round by round.
+type wnlaChallenges struct {
+	Y, RoundRo []*big.Int
+	Ro, Mu     *big.Int
+	Com        Point
+}
+
+// absorbWNLARound absorbs the messages of one WNLA reduction round: the current commitment, X and R, and the current
+// lengths of HVec and GVec. Transcripts of TranscriptV2 and later start the round with its index. The index and the
+// lengths are absorbed with addLength.
+func absorbWNLARound(fs FiatShamirEngine, round int, Com, X, R Point, nH, nG int) error {
+	if transcriptVersion(fs) >= TranscriptV2 {
+		if err := addLength(fs, round); err != nil {
+			return fmt.Errorf("failed to add round index to transcript: %w", err)
+		}
+	}
+	if err := addGroupPoint(fs, Com); err != nil {
 		return fmt.Errorf("failed to add commitment to transcript: %w", err)
 	}
-	if err := fs.AddPoint(proof.X[0]); err != nil {
+	if err := addGroupPoint(fs, X); err != nil {
 		return fmt.Errorf("failed to add proof X to transcript: %w", err)
 	}
-	if err := fs.AddPoint(proof.R[0]); err != nil {
+	if err := addGroupPoint(fs, R); err != nil {
 		return fmt.Errorf("failed to add proof R to transcript: %w", err)
 	}
-	if err := fs.AddNumber(bint(len(public.HVec))); err != nil {
+	if err := addLength(fs, nH); err != nil {
 		return fmt.Errorf("failed to add HVec length to transcript: %w", err)
 	}
-	if err := fs.AddNumber(bint(len(public.GVec))); err != nil {
+	if err := addLength(fs, nG); err != nil {
 		return fmt.Errorf("failed to add GVec length to transcript: %w", err)
 	}
+	return nil
+}
+
+// replayWNLA runs the WNLA verifier transcript for all rounds of the proof without touching the generator vectors.
+// Only the commitment is folded, because it is absorbed into the transcript every round.
+func replayWNLA(public *GroupWNLAPublic, proof *GroupWNLAProof, Com Point, fs FiatShamirEngine) (*wnlaChallenges, error) {
+	if len(proof.X) != len(proof.R) {
+		return nil, errors.New("invalid length for R and X vectors: should be equal")
+	}
+
+	g, f := public.Group, public.field()
+
+	res := &wnlaChallenges{
+		Y:       make([]*big.Int, len(proof.X)),
+		RoundRo: make([]*big.Int, len(proof.X)),
+		Ro:      public.Ro,
+		Mu:      public.Mu,
+		Com:     Com,
+	}
+
+	nH, nG := len(public.HVec), len(public.GVec)
+
+	for k := range proof.X {
+		if err := absorbWNLARound(fs, k, res.Com, proof.X[k], proof.R[k], nH, nG); err != nil {
+			return nil, err
+		}
+
+		y := new(big.Int).Mod(fs.GetChallenge(), f.order)
+		if y.Sign() == 0 {
+			return nil, fmt.Errorf("%w: round %d", ErrInvalidChallenge, k)
+		}
 
-	// Challenge using Fiat-Shamir heuristic
-	y := fs.GetChallenge()
-
-	c0, c1 := reduceVector(public.C)
-	G0, G1 := reducePoints(public.GVec)
-	H0, H1 := reducePoints(public.HVec)
-
-	// Both calculates new vector points and new commitment
-	H_ := vectorPointsAdd(H0, vectorPointMulOnScalar(H1, y))
-	G_ := vectorPointsAdd(vectorPointMulOnScalar(G0, public.Ro), vectorPointMulOnScalar(G1, y))
-	c_ := vectorAdd(c0, vectorMulOnScalar(c1, y))
-
-	// CRITICAL FIX: Update commitment algebraically
-	// Com' = Com + X*y + R*(y²-1)
-	Com_ := new(bn256.G1).Set(Com)
-	Com_.Add(Com_, new(bn256.G1).ScalarMult(proof.X[0], y))
-	Com_.Add(Com_, new(bn256.G1).ScalarMult(proof.R[0], sub(mul(y, y), bint(1))))
-
-	// Recursive run
-	return VerifyWNLA(
-		&WeightNormLinearPublic{
-			G:    public.G,
-			GVec: G_,
-			HVec: H_,
-			C:    c_,
-			Ro:   public.Mu,
-			Mu:   mul(public.Mu, public.Mu),
-		},
-		&WeightNormLinearArgumentProof{
-			R: proof.R[1:],
-			X: proof.X[1:],
-			L: proof.L,
-			N: proof.N,
-		},
-		Com_,
-		fs,
-	)
+		res.Y[k] = y
+		res.RoundRo[k] = res.Ro
+		res.Com = foldWNLACommitment(g, res.Com, proof.X[k], proof.R[k], y)
+		res.Ro, res.Mu = res.Mu, f.mul(res.Mu, res.Mu)
+		nH, nG = foldedLen(nH, 1), foldedLen(nG, 1)
+	}
+
+	return res, nil
 }
 
-// ProveWNLA generates zero knowledge proof of knowledge of two vectors l and n that
-// satisfies the commitment C (see WeightNormLinearPublic.Commit() function).
-// Use empty FiatShamirEngine for call.
-func ProveWNLA(public *WeightNormLinearPublic, Com *bn256.G1, fs FiatShamirEngine, l, n []*big.Int) *WeightNormLinearArgumentProof {
-	// Pass original commitment unchanged through recursion
-	return proveWNLARecursive(public, Com, fs, l, n)
+// foldedLen returns the length of a vector of length n after the given number of folding rounds.
+func foldedLen(n, rounds int) int {
+	for i := 0; i < rounds; i++ {
+		n = (n + 1) / 2
+	}
+	return n
 }
 
-// proveWNLARecursive handles the recursive proving logic without domain separation
-func proveWNLARecursive(public *WeightNormLinearPublic, Com *bn256.G1, fs FiatShamirEngine, l, n []*big.Int) *WeightNormLinearArgumentProof {
-	if len(l)+len(n) < 6 {
+// WNLARounds returns the number of reduction rounds of a WNLA proof, and so the number of X and R points it
+// carries, for l and n vectors of vectorLen entries each. Every round halves both vectors, rounding up, until
+// len(l)+len(n) < 6.
+func WNLARounds(vectorLen int) int {
+	return wnlaRounds(vectorLen, vectorLen)
+}
+
+// wnlaRounds returns the number of reduction rounds for l and n vectors of lengths lLen and nLen.
+func wnlaRounds(lLen, nLen int) int {
+	rounds := 0
+	for lLen+nLen >= 6 {
+		lLen, nLen = foldedLen(lLen, 1), foldedLen(nLen, 1)
+		rounds++
+	}
+	return rounds
+}
 
-		// Prover sends l, n to Verifier
-		return &WeightNormLinearArgumentProof{
-			R: make([]*bn256.G1, 0),
-			X: make([]*bn256.G1, 0),
-			L: l,
-			N: n,
+// foldCoefficients returns for every index i < n of an original vector the product of the challenges it gets
+// multiplied by while folding. Element i ends up at position i >> rounds; in round k it sits at an odd position if
+// bit k of i is set and is then multiplied by odd[k], otherwise by even[k] (a nil even means 1).
+//
+// The product only depends on the low len(odd) bits of i, so the 2^rounds distinct products are built once by
+// doubling the table every round and shared between the indices. This costs O(n) work and allocations instead of
+// O(n*rounds). The returned values must not be modified.
+func foldCoefficients(f scalarField, n int, even, odd []*big.Int) []*big.Int {
+	table := make([]*big.Int, 1, 1<<len(odd))
+	table[0] = big.NewInt(1)
+
+	for k := range odd {
+		for j := range table {
+			table = append(table, f.mul(table[j], odd[k]))
+			if even != nil {
+				table[j] = f.mul(table[j], even[k])
+			}
 		}
 	}
 
-	roinv := inv(public.Ro)
+	mask := len(table) - 1
 
-	// Prover calculates new reduced values, vx and vr and sends X, R to verifier
-	c0, c1 := reduceVector(public.C)
-	l0, l1 := reduceVector(l)
-	n0, n1 := reduceVector(n)
-	G0, G1 := reducePoints(public.GVec)
-	H0, H1 := reducePoints(public.HVec)
+	res := make([]*big.Int, n)
+	for i := range res {
+		res[i] = table[i&mask]
+	}
+	return res
+}
 
-	mu2 := mul(public.Mu, public.Mu)
+// foldedC returns the weights vector c reached after the last round. cc are the fold coefficients of the HVec
+// side, foldCoefficients(f, len(C), nil, ch.Y).
+func (ch *wnlaChallenges) foldedC(f scalarField, C, cc []*big.Int) []*big.Int {
+	rounds := len(ch.Y)
 
-	vx := add(
-		mul(weightVectorMul(n0, n1, mu2), mul(bint(2), roinv)),
-		add(vectorMul(c0, l1), vectorMul(c1, l0)),
-	)
+	res := zeroVector(foldedLen(len(C), rounds))
+	for i := range C {
+		res[i>>rounds] = f.add(res[i>>rounds], f.mul(C[i], cc[i]))
+	}
+	return res
+}
 
-	vr := add(weightVectorMul(n1, n1, mu2), vectorMul(c1, l1))
+// finalCommitment computes Commit(L, N) over the parameters reached after the last round as one multi-scalar
+// multiplication over the original generators: v*G + sum(L[i>>k]*hc[i]*H[i]) + sum(N[i>>k]*gc[i]*G[i]), where hc
+// and gc are the accumulated challenge products and v = <c', L> + |N|^2_mu' uses the folded c' and mu'. Over BN256
+// a non-nil msm computes the multiplication instead of the group operations.
+func (ch *wnlaChallenges) finalCommitment(public *GroupWNLAPublic, proof *GroupWNLAProof, msm MSMFunc) (Point, error) {
+	f := public.field()
+	rounds := len(ch.Y)
+	gc := foldCoefficients(f, len(public.GVec), ch.RoundRo, ch.Y)
+	hc := foldCoefficients(f, len(public.HVec), nil, ch.Y)
+	c := ch.foldedC(f, public.C, hc)
+
+	points := make([]Point, 0, 1+len(public.HVec)+len(public.GVec))
+	scalars := make([]*big.Int, 0, cap(points))
+
+	points = append(points, public.G)
+	scalars = append(scalars, f.add(f.vectorMul(c, proof.L), f.weightVectorMul(proof.N, proof.N, ch.Mu)))
+
+	for i := range public.HVec {
+		if j := i >> rounds; j < len(proof.L) {
+			points = append(points, public.HVec[i])
+			scalars = append(scalars, f.mul(proof.L[j], hc[i]))
+		}
+	}
+
+	for i := range public.GVec {
+		if j := i >> rounds; j < len(proof.N) {
+			points = append(points, public.GVec[i])
+			scalars = append(scalars, f.mul(proof.N[j], gc[i]))
+		}
+	}
 
-	X := new(bn256.G1).ScalarMult(public.G, vx)
-	X.Add(X, vectorPointScalarMul(H0, l1))
-	X.Add(X, vectorPointScalarMul(H1, l0))
-	X.Add(X, vectorPointScalarMul(G0, vectorMulOnScalar(n1, public.Ro)))
-	X.Add(X, vectorPointScalarMul(G1, vectorMulOnScalar(n0, roinv)))
+	if _, ok := public.Group.(bn256Group); ok && msm != nil {
+		res, err := msm.mul(toG1s(points), scalars)
+		if err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
 
-	R := new(bn256.G1).ScalarMult(public.G, vr)
-	R.Add(R, vectorPointScalarMul(H1, l1))
-	R.Add(R, vectorPointScalarMul(G1, n1))
+	return groupVectorPointScalarMul(public.Group, points, scalars), nil
+}
 
-	// Simple Fiat-Shamir transcript matching original implementation
-	_ = fs.AddPoint(Com)
-	_ = fs.AddPoint(X)
-	_ = fs.AddPoint(R)
-	_ = fs.AddNumber(bint(len(public.HVec)))
-	_ = fs.AddNumber(bint(len(public.GVec)))
+// foldedPublic computes the public parameters reached after the last round directly from the original ones:
+// every folded generator is the combination of the original generators mapped to it, weighted by the
+// accumulated challenge products. The result equals repeated application of foldWNLAPublic.
+func (ch *wnlaChallenges) foldedPublic(public *GroupWNLAPublic) *GroupWNLAPublic {
+	g, f := public.Group, public.field()
+	rounds := len(ch.Y)
+	gc := foldCoefficients(f, len(public.GVec), ch.RoundRo, ch.Y)
+	hc := foldCoefficients(f, len(public.HVec), nil, ch.Y)
+
+	GVec := make([]Point, foldedLen(len(public.GVec), rounds))
+	for j := range GVec {
+		GVec[j] = g.Identity()
+	}
+	for i := range public.GVec {
+		GVec[i>>rounds] = g.Add(GVec[i>>rounds], g.ScalarMult(public.GVec[i], gc[i]))
+	}
 
-	// Challenge using Fiat-Shamir heuristic
-	y := fs.GetChallenge()
+	HVec := make([]Point, foldedLen(len(public.HVec), rounds))
+	for j := range HVec {
+		HVec[j] = g.Identity()
+	}
+	for i := range public.HVec {
+		HVec[i>>rounds] = g.Add(HVec[i>>rounds], g.ScalarMult(public.HVec[i], hc[i]))
+	}
 
-	// Both calculates new vector points and new commitment
-	H_ := vectorPointsAdd(H0, vectorPointMulOnScalar(H1, y))
-	G_ := vectorPointsAdd(vectorPointMulOnScalar(G0, public.Ro), vectorPointMulOnScalar(G1, y))
-	c_ := vectorAdd(c0, vectorMulOnScalar(c1, y))
+	return &GroupWNLAPublic{
+		Group: g,
+		G:     public.G,
+		GVec:  GVec,
+		HVec:  HVec,
+		C:     ch.foldedC(f, public.C, hc),
+		Ro:    ch.Ro,
+		Mu:    ch.Mu,
+	}
+}
 
-	// Prover calculates new reduced vectors
-	l_ := vectorAdd(l0, vectorMulOnScalar(l1, y))
-	n_ := vectorAdd(vectorMulOnScalar(n0, roinv), vectorMulOnScalar(n1, y))
+// proveGroupWNLA runs the WNLA prover rounds until len(l)+len(n) < 6. With inPlace it works on one copy of the
+// vectors that is folded in place, after a round the first half of every buffer holds the folded vector; otherwise
+// every round allocates the folded vectors. Both produce the same proof.
+func proveGroupWNLA(public *GroupWNLAPublic, Com Point, fs FiatShamirEngine, l, n []*big.Int, inPlace bool) (*GroupWNLAProof, error) {
+	cur := public
+	if inPlace {
+		l = append(make([]*big.Int, 0, len(l)), l...)
+		n = append(make([]*big.Int, 0, len(n)), n...)
+
+		cur = &GroupWNLAPublic{
+			Group: public.Group,
+			G:     public.G,
+			GVec:  append(make([]Point, 0, len(public.GVec)), public.GVec...),
+			HVec:  append(make([]Point, 0, len(public.HVec)), public.HVec...),
+			C:     append(make([]*big.Int, 0, len(public.C)), public.C...),
+			Ro:    public.Ro,
+			Mu:    public.Mu,
+		}
+	}
 
-	public_ := &WeightNormLinearPublic{
-		G:    public.G,
-		GVec: G_,
-		HVec: H_,
-		C:    c_,
-		Ro:   public.Mu,
-		Mu:   mu2,
+	rounds := wnlaRounds(len(l), len(n))
+	proof := &GroupWNLAProof{
+		R: make([]Point, 0, rounds),
+		X: make([]Point, 0, rounds),
 	}
 
-	// Compute fresh commitment with transformed parameters (correct approach)
-	commitment := public_.CommitWNLA(l_, n_)
-	if commitment == nil {
-		// If commitment fails, return an empty proof indicating failure
-		return &WeightNormLinearArgumentProof{
-			R: []*bn256.G1{},
-			X: []*bn256.G1{},
-			L: l_,
-			N: n_,
+	f := public.field()
+
+	for len(l)+len(n) >= 6 {
+		// Prover calculates new reduced values, vx and vr and sends X, R to verifier
+		X, R := wnlaRoundMessages(cur, l, n)
+
+		if err := absorbWNLARound(fs, len(proof.X), Com, X, R, len(cur.HVec), len(cur.GVec)); err != nil {
+			return nil, err
 		}
+
+		// Challenge using Fiat-Shamir heuristic
+		y := new(big.Int).Mod(fs.GetChallenge(), f.order)
+		if y.Sign() == 0 {
+			return nil, fmt.Errorf("%w: round %d", ErrInvalidChallenge, len(proof.X))
+		}
+
+		proof.X = append(proof.X, X)
+		proof.R = append(proof.R, R)
+
+		// Both calculates new vector points and new commitment
+		cur, l, n = foldWNLAProver(cur, l, n, y, inPlace)
+		Com = cur.Commit(l, n)
+	}
+
+	// Prover sends l, n to Verifier
+	proof.L, proof.N = l, n
+	return proof, nil
+}
+
+// wnlaRoundMessages computes the messages X and R of one reduction round. Pairs (2i, 2i+1) of every vector hold the
+// i-th entries of its even and odd halves; they are read by index instead of being split off.
+func wnlaRoundMessages(cur *GroupWNLAPublic, l, n []*big.Int) (X, R Point) {
+	g, f := cur.Group, cur.field()
+
+	roinv := f.inv(cur.Ro)
+	mu2 := f.mul(cur.Mu, cur.Mu)
+
+	vx, vr := big.NewInt(0), big.NewInt(0)
+	X, R = g.Identity(), g.Identity()
+
+	weight := mu2
+	for i := 0; 2*i+1 < len(n); i++ {
+		n0, n1 := n[2*i], n[2*i+1]
+		vx = f.add(vx, f.mul(f.mul(n0, n1), weight))
+		vr = f.add(vr, f.mul(f.mul(n1, n1), weight))
+		weight = f.mul(weight, mu2)
+
+		X = g.Add(X, g.ScalarMult(cur.GVec[2*i], f.mul(n1, cur.Ro)))
+		X = g.Add(X, g.ScalarMult(cur.GVec[2*i+1], f.mul(n0, roinv)))
+		R = g.Add(R, g.ScalarMult(cur.GVec[2*i+1], n1))
 	}
 
-	res := proveWNLARecursive(
-		public_,
-		commitment,
-		fs,
-		l_,
-		n_,
-	)
+	vx = f.mul(vx, f.mul(big.NewInt(2), roinv))
 
-	return &WeightNormLinearArgumentProof{
-		R: append([]*bn256.G1{R}, res.R...),
-		X: append([]*bn256.G1{X}, res.X...),
-		L: res.L,
-		N: res.N,
+	for i := 0; 2*i+1 < len(l); i++ {
+		l0, l1 := l[2*i], l[2*i+1]
+		vx = f.add(vx, f.add(f.mul(cur.C[2*i], l1), f.mul(cur.C[2*i+1], l0)))
+		vr = f.add(vr, f.mul(cur.C[2*i+1], l1))
+
+		X = g.Add(X, g.ScalarMult(cur.HVec[2*i], l1))
+		X = g.Add(X, g.ScalarMult(cur.HVec[2*i+1], l0))
+		R = g.Add(R, g.ScalarMult(cur.HVec[2*i+1], l1))
 	}
+
+	X = g.Add(g.ScalarMult(cur.G, vx), X)
+	R = g.Add(g.ScalarMult(cur.G, vr), R)
+	return X, R
 }
 
-func reduceVector(v []*big.Int) ([]*big.Int, []*big.Int) {
-	res0 := make([]*big.Int, 0, len(v)/2)
-	res1 := make([]*big.Int, 0, len(v)/2)
+// foldWNLAProver folds the public parameters and the prover's vectors with the challenge y: the parameters as
+// foldWNLAPublic does, l' = l0 + y*l1 and n' = ro^-1*n0 + y*n1. With inPlace the buffers of cur, l and n are
+// reused and cur is updated and returned.
+func foldWNLAProver(cur *GroupWNLAPublic, l, n []*big.Int, y *big.Int, inPlace bool) (*GroupWNLAPublic, []*big.Int, []*big.Int) {
+	g, f := cur.Group, cur.field()
+	roinv := f.inv(cur.Ro)
 
-	for i := range v {
-		if i%2 == 0 {
-			res0 = append(res0, v[i])
-		} else {
-			res1 = append(res1, v[i])
+	if !inPlace {
+		return foldWNLAPublic(cur, y), foldScalars(f, l, nil, y), foldScalars(f, n, roinv, y)
+	}
+
+	cur.HVec = foldPointsInPlace(g, cur.HVec, nil, y)
+	cur.GVec = foldPointsInPlace(g, cur.GVec, cur.Ro, y)
+	cur.C = foldScalarsInPlace(f, cur.C, nil, y)
+	cur.Ro, cur.Mu = cur.Mu, f.mul(cur.Mu, cur.Mu)
+
+	return cur, foldScalarsInPlace(f, l, nil, y), foldScalarsInPlace(f, n, roinv, y)
+}
+
+// foldScalarsInPlace computes foldScalars(f, v, a, b) into the first half of v and returns that half.
+func foldScalarsInPlace(f scalarField, v []*big.Int, a, b *big.Int) []*big.Int {
+	half := (len(v) + 1) / 2
+	for i := 0; i < half; i++ {
+		v0 := v[2*i]
+		if a != nil {
+			v0 = f.mul(v0, a)
+		}
+		if 2*i+1 < len(v) {
+			v0 = f.add(v0, f.mul(v[2*i+1], b))
 		}
+		v[i] = f.add(v0, nil)
 	}
+	return v[:half]
+}
 
-	return res0, res1
+// foldPointsInPlace computes foldPoints(g, v, a, b) into the first half of v and returns that half.
+func foldPointsInPlace(g Group, v []Point, a, b *big.Int) []Point {
+	half := (len(v) + 1) / 2
+	for i := 0; i < half; i++ {
+		v0 := v[2*i]
+		if a != nil {
+			v0 = g.ScalarMult(v0, a)
+		}
+		if 2*i+1 < len(v) {
+			v0 = g.Add(v0, g.ScalarMult(v[2*i+1], b))
+		}
+		v[i] = v0
+	}
+	return v[:half]
 }
 
-func reducePoints(v []*bn256.G1) ([]*bn256.G1, []*bn256.G1) {
-	res0 := make([]*bn256.G1, 0, len(v)/2)
-	res1 := make([]*bn256.G1, 0, len(v)/2)
+// reduceVector splits v into the elements at even and at odd positions.
+func reduceVector[T any](v []T) ([]T, []T) {
+	res0 := make([]T, 0, (len(v)+1)/2)
+	res1 := make([]T, 0, len(v)/2)
 
 	for i := range v {
 		if i%2 == 0 {
@@ -234,3 +959,33 @@ func reducePoints(v []*bn256.G1) ([]*bn256.G1, []*bn256.G1) {
 
 	return res0, res1
 }
+
+// foldScalars returns a*v0 + b*v1 for the even and odd halves v0, v1 of v. A nil a means 1.
+func foldScalars(f scalarField, v []*big.Int, a, b *big.Int) []*big.Int {
+	v0, v1 := reduceVector(v)
+	if a != nil {
+		v0 = f.vectorMulOnScalar(v0, a)
+	}
+	return f.vectorAdd(v0, f.vectorMulOnScalar(v1, b))
+}
+
+// foldPoints returns a*v0 + b*v1 for the even and odd halves v0, v1 of v. A nil a means 1.
+func foldPoints(g Group, v []Point, a, b *big.Int) []Point {
+	v0, v1 := reduceVector(v)
+	if a != nil {
+		v0 = groupVectorPointMulOnScalar(g, v0, a)
+	}
+	return groupVectorPointsAdd(g, v0, groupVectorPointMulOnScalar(g, v1, b))
+}
+
+// FoldScalars runs the WNLA reduction of a scalar vector for the challenge y: v' = v0 + y*v1, where v0 and v1 hold
+// the elements of v at even and at odd positions. An odd-length v keeps its last element in v0.
+func FoldScalars(v []*big.Int, y *big.Int) []*big.Int {
+	return foldScalars(scalarField{bn256.Order}, v, nil, y)
+}
+
+// FoldPoints runs the WNLA reduction of a generator vector: G' = a*G0 + b*G1, where G0 and G1 hold the points of v
+// at even and at odd positions. WNLA folds GVec with a = ro, b = y and HVec with a = 1, b = y.
+func FoldPoints(v []*bn256.G1, a, b *big.Int) []*bn256.G1 {
+	return toG1s(foldPoints(BN256, toPoints(v), a, b))
+}
@@ -5,30 +5,805 @@
 package bulletproofs
 
 import (
+	"errors"
+	"fmt"
+	"github.com/cloudflare/bn256"
 	"github.com/davecgh/go-spew/spew"
 	"math/big"
 	"testing"
 )
 
 func TestWNLA(t *testing.T) {
-	// Use smaller dimensions to reduce chance of overflow with large random parameters
+	fullRange := func(t *testing.T) ([]*big.Int, []*big.Int) {
+		l, err := RandScalarVector(64)
+		if err != nil {
+			t.Fatalf("RandScalarVector failed: %v", err)
+		}
+
+		n, err := RandScalarVector(32)
+		if err != nil {
+			t.Fatalf("RandScalarVector failed: %v", err)
+		}
+
+		// The largest field elements are valid witnesses as well.
+		l[0], n[0] = minus(bint(1)), minus(bint(1))
+		return l, n
+	}
+
+	testCases := []struct {
+		name    string
+		lLen    int
+		nLen    int
+		witness func(t *testing.T) ([]*big.Int, []*big.Int)
+	}{
+		{"Small", 4, 2, func(*testing.T) ([]*big.Int, []*big.Int) {
+			return []*big.Int{bint(1), bint(2), bint(3), bint(4)}, []*big.Int{bint(5), bint(6)}
+		}},
+		{"Full range", 64, 32, fullRange},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			public := NewWeightNormLinearPublic(tc.lLen, tc.nLen)
+			spew.Dump(public)
+			l, n := tc.witness(t)
+
+			commitment, err := public.CommitWNLAChecked(l, n)
+			if err != nil {
+				t.Fatalf("CommitWNLAChecked failed: %v", err)
+			}
+
+			proof, err := ProveWNLA(public, commitment, NewKeccakFS(), l, n)
+			if err != nil {
+				t.Fatalf("ProveWNLA failed: %v", err)
+			}
+			t.Log(proof.DebugString())
+
+			if err := VerifyWNLA(public, proof, commitment, NewKeccakFS()); err != nil {
+				t.Fatalf("WNLA verification failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestVectorHelpersDoNotAlias(t *testing.T) {
+	// a[:2] has spare capacity, padding it in place would overwrite a[2].
+	a := []*big.Int{bint(1), bint(2), bint(3)}
+	b := []*big.Int{bint(4), bint(5), bint(6)}
+
+	if got := vectorMul(a[:2], b); got.Cmp(bint(14)) != 0 {
+		t.Errorf("vectorMul: expected 14, got %v", got)
+	}
+
+	if got := weightVectorMul(a[:2], b, bint(2)); got.Cmp(bint(4*2+10*4)) != 0 {
+		t.Errorf("weightVectorMul: expected 48, got %v", got)
+	}
+
+	if got := vectorAdd(a[:2], b); len(got) != 3 || got[2].Cmp(bint(6)) != 0 {
+		t.Errorf("vectorAdd: unexpected result %v", got)
+	}
+
+	if got := vectorSub(a[:1], b); len(got) != 3 || got[1].Cmp(minus(bint(5))) != 0 {
+		t.Errorf("vectorSub: unexpected result %v", got)
+	}
+
+	if a[2].Cmp(bint(3)) != 0 || a[1].Cmp(bint(2)) != 0 {
+		t.Errorf("Argument modified: %v", a)
+	}
+}
+
+func TestCommitWNLAUnreducedScalars(t *testing.T) {
 	public := NewWeightNormLinearPublic(4, 2)
-	spew.Dump(public)
 
-	// Use small values to avoid triggering overflow protection
-	l := []*big.Int{bint(1), bint(2), bint(3), bint(4)}
-	n := []*big.Int{bint(5), bint(6)}
+	// Scalars outside of [0, order) commit to the same value as their reductions.
+	l := []*big.Int{minus(bint(1)), NewRandScalar(), NewRandScalar(), NewRandScalar()}
+	n := []*big.Int{NewRandScalar(), NewRandScalar()}
+
+	unreduced := []*big.Int{big.NewInt(-1), new(big.Int).Add(l[1], bn256.Order), new(big.Int).Add(l[2], new(big.Int).Lsh(bn256.Order, 100)), l[3]}
+
+	expected := public.CommitWNLA(l, n)
+
+	actual, err := public.CommitWNLAChecked(unreduced, n)
+	if err != nil {
+		t.Fatalf("CommitWNLAChecked failed for unreduced scalars: %v", err)
+	}
+
+	if !pointsEqual(expected, actual) {
+		t.Error("Unreduced scalars changed the commitment")
+	}
+}
+
+func TestPointsEqual(t *testing.T) {
+	a := new(bn256.G1).ScalarBaseMult(bint(7))
+	b := new(bn256.G1).Add(new(bn256.G1).ScalarBaseMult(bint(3)), new(bn256.G1).ScalarBaseMult(bint(4)))
+
+	if !pointsEqual(a, b) {
+		t.Error("Equal points compared as different")
+	}
+
+	if pointsEqual(a, new(bn256.G1).ScalarBaseMult(bint(8))) {
+		t.Error("Different points compared as equal")
+	}
+}
+
+func TestWNLAFoldedPublic(t *testing.T) {
+	public := NewWeightNormLinearPublic(32, 16)
+
+	l := make([]*big.Int, 32)
+	for i := range l {
+		l[i] = NewRandScalar()
+	}
+
+	n := make([]*big.Int, 16)
+	for i := range n {
+		n[i] = NewRandScalar()
+	}
+
+	commitment, err := public.CommitWNLAChecked(l, n)
+	if err != nil {
+		t.Fatalf("CommitWNLAChecked failed: %v", err)
+	}
+	proof, err := ProveWNLA(public, commitment, NewKeccakFS(), l, n)
+	if err != nil {
+		t.Fatalf("ProveWNLA failed: %v", err)
+	}
+
+	gp := public.group()
+
+	ch, err := replayWNLA(gp, proof.group(), commitment, NewKeccakFS())
+	if err != nil {
+		t.Fatalf("replayWNLA failed: %v", err)
+	}
+
+	if len(ch.Y) != len(proof.X) {
+		t.Fatalf("Expected %d challenges, got %d", len(proof.X), len(ch.Y))
+	}
+
+	// Reference: fold round by round as the recursive verifier does.
+	expected := gp
+	for _, y := range ch.Y {
+		expected = foldWNLAPublic(expected, y)
+	}
+
+	direct := ch.foldedPublic(gp)
+
+	if len(direct.GVec) != len(expected.GVec) || len(direct.HVec) != len(expected.HVec) || len(direct.C) != len(expected.C) {
+		t.Fatal("Folded vector lengths differ")
+	}
+
+	for i := range expected.GVec {
+		if !BN256.Equal(direct.GVec[i], expected.GVec[i]) {
+			t.Errorf("GVec[%d] differs", i)
+		}
+	}
+
+	for i := range expected.HVec {
+		if !BN256.Equal(direct.HVec[i], expected.HVec[i]) {
+			t.Errorf("HVec[%d] differs", i)
+		}
+	}
+
+	for i := range expected.C {
+		if direct.C[i].Cmp(expected.C[i]) != 0 {
+			t.Errorf("C[%d] differs", i)
+		}
+	}
+
+	if direct.Ro.Cmp(expected.Ro) != 0 || direct.Mu.Cmp(expected.Mu) != 0 {
+		t.Error("Folded weights differ")
+	}
+
+	if !BN256.Equal(direct.Commit(proof.L, proof.N), ch.Com) {
+		t.Error("Final commitment does not match the directly folded parameters")
+	}
+}
+
+// verifyWNLARecursive is the reference verifier that folds the generator vectors round by round.
+func verifyWNLARecursive(public *GroupWNLAPublic, proof *GroupWNLAProof, Com Point, fs FiatShamirEngine) error {
+	if len(proof.X) != len(proof.R) {
+		return errors.New("invalid length for R and X vectors: should be equal")
+	}
+
+	if len(proof.X) == 0 {
+		if !public.Group.Equal(public.Commit(proof.L, proof.N), Com) {
+			return errors.New("final commitment mismatch")
+		}
+		return nil
+	}
+
+	_ = addGroupPoint(fs, Com)
+	_ = addGroupPoint(fs, proof.X[0])
+	_ = addGroupPoint(fs, proof.R[0])
+	_ = fs.AddNumber(bint(len(public.HVec)))
+	_ = fs.AddNumber(bint(len(public.GVec)))
+
+	y := fs.GetChallenge()
+
+	return verifyWNLARecursive(
+		foldWNLAPublic(public, y),
+		&GroupWNLAProof{
+			R: proof.R[1:],
+			X: proof.X[1:],
+			L: proof.L,
+			N: proof.N,
+		},
+		foldWNLACommitment(public.Group, Com, proof.X[0], proof.R[0], y),
+		fs,
+	)
+}
+
+func TestVerifyWNLADifferential(t *testing.T) {
+	for _, dims := range [][2]int{{4, 2}, {8, 8}, {16, 4}, {32, 16}} {
+		public := NewWeightNormLinearPublic(dims[0], dims[1])
+
+		l := make([]*big.Int, dims[0])
+		for i := range l {
+			l[i] = NewRandScalar()
+		}
+
+		n := make([]*big.Int, dims[1])
+		for i := range n {
+			n[i] = NewRandScalar()
+		}
+
+		commitment, err := public.CommitWNLAChecked(l, n)
+		if err != nil {
+			t.Fatalf("CommitWNLAChecked failed: %v", err)
+		}
+		proof, err := ProveWNLA(public, commitment, NewKeccakFS(), l, n)
+		if err != nil {
+			t.Fatalf("ProveWNLA failed: %v", err)
+		}
+
+		tampered := map[string]func() (*WeightNormLinearArgumentProof, *bn256.G1){
+			"valid": func() (*WeightNormLinearArgumentProof, *bn256.G1) {
+				return proof, commitment
+			},
+			"wrong commitment": func() (*WeightNormLinearArgumentProof, *bn256.G1) {
+				return proof, NewRandPoint()
+			},
+			"modified L": func() (*WeightNormLinearArgumentProof, *bn256.G1) {
+				p := *proof
+				p.L = append([]*big.Int{add(proof.L[0], bint(1))}, proof.L[1:]...)
+				return &p, commitment
+			},
+			"modified N": func() (*WeightNormLinearArgumentProof, *bn256.G1) {
+				p := *proof
+				p.N = append([]*big.Int{add(proof.N[0], bint(1))}, proof.N[1:]...)
+				return &p, commitment
+			},
+			"swapped X and R": func() (*WeightNormLinearArgumentProof, *bn256.G1) {
+				p := *proof
+				p.X, p.R = proof.R, proof.X
+				return &p, commitment
+			},
+		}
+
+		for name, f := range tampered {
+			p, com := f()
+			fast := VerifyWNLA(public, p, com, NewKeccakFS())
+			slow := verifyWNLARecursive(public.group(), p.group(), com, NewKeccakFS())
+
+			if (fast == nil) != (slow == nil) {
+				t.Errorf("%v %s: verifiers disagree: fast=%v recursive=%v", dims, name, fast, slow)
+			}
+
+			if name == "valid" && fast != nil {
+				t.Errorf("%v: valid proof rejected: %v", dims, fast)
+			}
+
+			if name != "valid" && len(proof.X) > 0 && fast == nil {
+				t.Errorf("%v %s: tampered proof accepted", dims, name)
+			}
+		}
+	}
+}
+
+func TestWNLALowMemory(t *testing.T) {
+	for _, dims := range [][2]int{{4, 2}, {7, 5}, {16, 4}, {32, 16}, {33, 9}} {
+		public := NewWeightNormLinearPublic(dims[0], dims[1])
+
+		l, err := RandScalarVector(dims[0])
+		if err != nil {
+			t.Fatalf("RandScalarVector failed: %v", err)
+		}
+
+		n, err := RandScalarVector(dims[1])
+		if err != nil {
+			t.Fatalf("RandScalarVector failed: %v", err)
+		}
+
+		commitment, err := public.CommitWNLAChecked(l, n)
+		if err != nil {
+			t.Fatalf("CommitWNLAChecked failed: %v", err)
+		}
+
+		proof, err := ProveWNLA(public, commitment, NewKeccakFS(), l, n)
+		if err != nil {
+			t.Fatalf("ProveWNLA failed: %v", err)
+		}
+
+		low, err := ProveWNLA(public, commitment, NewKeccakFS(), l, n, WithLowMemory())
+		if err != nil {
+			t.Fatalf("ProveWNLA with WithLowMemory failed: %v", err)
+		}
+
+		if proof.DebugString() != low.DebugString() {
+			t.Errorf("%v: proofs differ:\n%s\n%s", dims, proof.DebugString(), low.DebugString())
+		}
 
-	commitment := public.CommitWNLA(l, n)
-	if commitment == nil {
-		t.Fatal("CommitWNLA returned nil - possible overflow protection triggered")
+		if err := VerifyWNLA(public, low, commitment, NewKeccakFS()); err != nil {
+			t.Errorf("%v: WNLA verification failed: %v", dims, err)
+		}
 	}
+}
+
+func TestWNLATranscriptError(t *testing.T) {
+	public := NewWeightNormLinearPublic(16, 8)
+
+	l, err := RandScalarVector(16)
+	if err != nil {
+		t.Fatalf("RandScalarVector failed: %v", err)
+	}
+
+	n, err := RandScalarVector(8)
+	if err != nil {
+		t.Fatalf("RandScalarVector failed: %v", err)
+	}
+
+	commitment, err := public.CommitWNLAChecked(l, n)
+	if err != nil {
+		t.Fatalf("CommitWNLAChecked failed: %v", err)
+	}
+
+	// The proof has three rounds of three points each. The engine rejects the second round, which both provers
+	// must report instead of returning a proof.
+	for _, opts := range [][]ProveOption{nil, {WithLowMemory()}} {
+		fs := &failingFS{FiatShamirEngine: NewKeccakFS(), points: 4}
+		if _, err := ProveWNLA(public, commitment, fs, l, n, opts...); !errors.Is(err, errFailingFS) {
+			t.Errorf("Expected the transcript error, got %v", err)
+		}
+	}
+}
+
+var errFailingFS = errors.New("transcript failure")
+
+// failingFS absorbs the given number of points and fails from then on.
+type failingFS struct {
+	FiatShamirEngine
+	points int
+}
+
+func (f *failingFS) AddPoint(p *bn256.G1) error {
+	if f.points--; f.points < 0 {
+		return errFailingFS
+	}
+	return f.FiatShamirEngine.AddPoint(p)
+}
+
+func BenchmarkProveWNLA(b *testing.B) {
+	public := NewWeightNormLinearPublic(64, 64)
+
+	l, err := RandScalarVector(64)
+	if err != nil {
+		b.Fatalf("RandScalarVector failed: %v", err)
+	}
+
+	n, err := RandScalarVector(64)
+	if err != nil {
+		b.Fatalf("RandScalarVector failed: %v", err)
+	}
+
+	commitment, err := public.CommitWNLAChecked(l, n)
+	if err != nil {
+		b.Fatalf("CommitWNLAChecked failed: %v", err)
+	}
+
+	for _, bm := range []struct {
+		name string
+		opts []ProveOption
+	}{{"default", nil}, {"low memory", []ProveOption{WithLowMemory()}}} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := ProveWNLA(public, commitment, NewKeccakFS(), l, n, bm.opts...); err != nil {
+					b.Fatalf("ProveWNLA failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkVerifyWNLA reports the allocations of the verifier, which grow with the vector length but not with the
+// number of rounds: compare allocs/op divided by the length between the sizes.
+func BenchmarkVerifyWNLA(b *testing.B) {
+	for _, size := range []int{16, 64, 256} {
+		public := NewWeightNormLinearPublic(size, size)
+
+		l, err := RandScalarVector(size)
+		if err != nil {
+			b.Fatalf("RandScalarVector failed: %v", err)
+		}
+
+		n, err := RandScalarVector(size)
+		if err != nil {
+			b.Fatalf("RandScalarVector failed: %v", err)
+		}
 
-	proof := ProveWNLA(public, commitment, NewKeccakFS(), l, n)
-	spew.Dump(proof)
+		commitment, err := public.CommitWNLAChecked(l, n)
+		if err != nil {
+			b.Fatalf("CommitWNLAChecked failed: %v", err)
+		}
+
+		proof, err := ProveWNLA(public, commitment, NewKeccakFS(), l, n)
+		if err != nil {
+			b.Fatalf("ProveWNLA failed: %v", err)
+		}
+
+		b.Run(fmt.Sprintf("%d rounds", WNLARounds(size)), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := VerifyWNLA(public, proof, commitment, NewKeccakFS()); err != nil {
+					b.Fatalf("VerifyWNLA failed: %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyWNLARejectsNonCanonicalScalars(t *testing.T) {
+	public := NewWeightNormLinearPublic(8, 4)
+
+	l := []*big.Int{bint(1), bint(2), bint(3), bint(4), bint(5), bint(6), bint(7), bint(8)}
+	n := []*big.Int{bint(9), bint(10), bint(11), bint(12)}
+
+	commitment, err := public.CommitWNLAChecked(l, n)
+	if err != nil {
+		t.Fatalf("CommitWNLAChecked failed: %v", err)
+	}
+	proof, err := ProveWNLA(public, commitment, NewKeccakFS(), l, n)
+	if err != nil {
+		t.Fatalf("ProveWNLA failed: %v", err)
+	}
 
 	if err := VerifyWNLA(public, proof, commitment, NewKeccakFS()); err != nil {
-		t.Fatalf("WNLA verification failed: %v", err)
+		t.Fatalf("Valid proof rejected: %v", err)
+	}
+
+	// L[0] + order is the same field element but a different encoding.
+	malleated := *proof
+	malleated.L = append([]*big.Int{new(big.Int).Add(proof.L[0], bn256.Order)}, proof.L[1:]...)
+
+	if err := VerifyWNLA(public, &malleated, commitment, NewKeccakFS()); !errors.Is(err, ErrNonCanonicalScalar) {
+		t.Errorf("Expected ErrNonCanonicalScalar for L, got %v", err)
+	}
+
+	malleated = *proof
+	malleated.N = append([]*big.Int{new(big.Int).Neg(proof.N[0])}, proof.N[1:]...)
+
+	if err := VerifyWNLA(public, &malleated, commitment, NewKeccakFS()); !errors.Is(err, ErrNonCanonicalScalar) {
+		t.Errorf("Expected ErrNonCanonicalScalar for negative N, got %v", err)
 	}
 }
 
+func TestFold(t *testing.T) {
+	// [1, 2, 3] folds to [1 + 10*2, 3]
+	v := FoldScalars([]*big.Int{bint(1), bint(2), bint(3)}, bint(10))
+	if len(v) != 2 || v[0].Cmp(bint(21)) != 0 || v[1].Cmp(bint(3)) != 0 {
+		t.Errorf("Unexpected folded scalars: %v", v)
+	}
+
+	// [1G, 2G, 3G, 4G] folds to [5*1G + 7*2G, 5*3G + 7*4G] = [19G, 43G]
+	points := []*bn256.G1{
+		new(bn256.G1).ScalarBaseMult(bint(1)),
+		new(bn256.G1).ScalarBaseMult(bint(2)),
+		new(bn256.G1).ScalarBaseMult(bint(3)),
+		new(bn256.G1).ScalarBaseMult(bint(4)),
+	}
+
+	folded := FoldPoints(points, bint(5), bint(7))
+	if len(folded) != 2 ||
+		!pointsEqual(folded[0], new(bn256.G1).ScalarBaseMult(bint(19))) ||
+		!pointsEqual(folded[1], new(bn256.G1).ScalarBaseMult(bint(43))) {
+		t.Error("Unexpected folded points")
+	}
+
+	// One verifier round folds the public parameters with the exported helpers.
+	public := NewWeightNormLinearPublic(8, 4)
+	y := bint(3)
+	folded_ := foldWNLAPublic(public.group(), y)
+
+	for i, p := range FoldPoints(public.GVec, public.Ro, y) {
+		if !BN256.Equal(p, folded_.GVec[i]) {
+			t.Errorf("GVec[%d] differs from the WNLA folding", i)
+		}
+	}
+
+	for i, p := range FoldPoints(public.HVec, bint(1), y) {
+		if !BN256.Equal(p, folded_.HVec[i]) {
+			t.Errorf("HVec[%d] differs from the WNLA folding", i)
+		}
+	}
+
+	for i, c := range FoldScalars(public.C, y) {
+		if c.Cmp(folded_.C[i]) != 0 {
+			t.Errorf("C[%d] differs from the WNLA folding", i)
+		}
+	}
+}
+
+func TestWeightedNormSquared(t *testing.T) {
+	// 1^2*2 + 2^2*2^2 + 3^2*2^3 = 2 + 16 + 72
+	if got := WeightedNormSquared([]*big.Int{bint(1), bint(2), bint(3)}, bint(2)); got.Cmp(bint(90)) != 0 {
+		t.Errorf("Expected 90, got %v", got)
+	}
+
+	if got := WeightedNormSquared(nil, bint(2)); got.Sign() != 0 {
+		t.Errorf("Expected 0 for an empty vector, got %v", got)
+	}
+
+	// Reduced modulo the group order: (-1)^2 * (-1) = -1
+	if got := WeightedNormSquared([]*big.Int{minus(bint(1))}, minus(bint(1))); got.Cmp(minus(bint(1))) != 0 {
+		t.Errorf("Expected order-1, got %v", got)
+	}
+}
+
+func TestWNLARounds(t *testing.T) {
+	for _, dims := range [][2]int{{1, 1}, {2, 2}, {3, 3}, {4, 4}, {5, 5}, {8, 8}, {16, 16}, {32, 32}, {4, 2}, {32, 16}, {7, 1}} {
+		public := NewWeightNormLinearPublic(dims[0], dims[1])
+
+		l, err := RandScalarVector(dims[0])
+		if err != nil {
+			t.Fatalf("RandScalarVector failed: %v", err)
+		}
+
+		n, err := RandScalarVector(dims[1])
+		if err != nil {
+			t.Fatalf("RandScalarVector failed: %v", err)
+		}
+
+		commitment, err := public.CommitWNLAChecked(l, n)
+		if err != nil {
+			t.Fatalf("CommitWNLAChecked failed: %v", err)
+		}
+
+		proof, err := ProveWNLA(public, commitment, NewKeccakFS(), l, n)
+		if err != nil {
+			t.Fatalf("ProveWNLA failed: %v", err)
+		}
+
+		if got := wnlaRounds(dims[0], dims[1]); got != len(proof.X) || got != len(proof.R) {
+			t.Errorf("l=%d, n=%d: expected %d rounds, proof has %d", dims[0], dims[1], got, len(proof.X))
+		}
+
+		if dims[0] == dims[1] && WNLARounds(dims[0]) != len(proof.X) {
+			t.Errorf("WNLARounds(%d)=%d, proof has %d rounds", dims[0], WNLARounds(dims[0]), len(proof.X))
+		}
+	}
+}
+
+func TestWNLARoundMismatch(t *testing.T) {
+	public := NewWeightNormLinearPublic(16, 16)
+
+	l, n := oneVector(16), oneVector(16)
+
+	commitment, err := public.CommitWNLAChecked(l, n)
+	if err != nil {
+		t.Fatalf("CommitWNLAChecked failed: %v", err)
+	}
+
+	proof, err := ProveWNLA(public, commitment, NewKeccakFS(), l, n)
+	if err != nil {
+		t.Fatalf("ProveWNLA failed: %v", err)
+	}
+
+	rounds := len(proof.X)
+
+	tooFew := &WeightNormLinearArgumentProof{X: proof.X[:rounds-1], R: proof.R[:rounds-1], L: proof.L, N: proof.N}
+	if err := VerifyWNLA(public, tooFew, commitment, NewKeccakFS()); !errors.Is(err, ErrProofLengthMismatch) {
+		t.Errorf("Expected ErrProofLengthMismatch for too few rounds, got %v", err)
+	}
+
+	tooMany := &WeightNormLinearArgumentProof{
+		X: append(append([]*bn256.G1{}, proof.X...), proof.X[0]),
+		R: append(append([]*bn256.G1{}, proof.R...), proof.R[0]),
+		L: proof.L,
+		N: proof.N,
+	}
+	if err := VerifyWNLA(public, tooMany, commitment, NewKeccakFS()); !errors.Is(err, ErrProofLengthMismatch) {
+		t.Errorf("Expected ErrProofLengthMismatch for too many rounds, got %v", err)
+	}
+}
+
+func TestWNLAPublicValidate(t *testing.T) {
+	l := []*big.Int{bint(4), bint(5), bint(10), bint(1)}
+	n := []*big.Int{bint(2), bint(1)}
+
+	valid := NewWeightNormLinearPublic(4, 2)
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("Valid parameters rejected: %v", err)
+	}
+
+	commitment, err := valid.CommitWNLAChecked(l, n)
+	if err != nil {
+		t.Fatalf("CommitWNLAChecked failed: %v", err)
+	}
+
+	shortC := *valid
+	shortC.C = valid.C[:3]
+
+	nilPoint := *valid
+	nilPoint.HVec = append([]*bn256.G1{nil}, valid.HVec[1:]...)
+
+	wrongMu := *valid
+	wrongMu.Mu = add(valid.Mu, bint(1))
+
+	for name, public := range map[string]*WeightNormLinearPublic{
+		"nil":       nil,
+		"short C":   &shortC,
+		"nil point": &nilPoint,
+		"wrong mu":  &wrongMu,
+	} {
+		if err := public.Validate(); err == nil {
+			t.Errorf("%s: expected Validate to fail", name)
+		}
+
+		if _, err := ProveWNLA(public, commitment, NewKeccakFS(), l, n); err == nil {
+			t.Errorf("%s: expected ProveWNLA to fail", name)
+		}
+
+		if err := VerifyWNLA(public, &WeightNormLinearArgumentProof{}, commitment, NewKeccakFS()); err == nil {
+			t.Errorf("%s: expected VerifyWNLA to fail", name)
+		}
+	}
+
+	if _, err := ProveWNLA(valid, commitment, NewKeccakFS(), l[:3], n); err == nil {
+		t.Error("Expected ProveWNLA to reject a witness that does not match the generators")
+	}
+}
+
+func TestWNLADuplicateGenerator(t *testing.T) {
+	valid := NewWeightNormLinearPublic(4, 2)
+
+	withGVec := func(gvec ...*bn256.G1) *WeightNormLinearPublic {
+		public := *valid
+		public.GVec = gvec
+		return &public
+	}
+
+	withHVec := func(hvec ...*bn256.G1) *WeightNormLinearPublic {
+		public := *valid
+		public.HVec = hvec
+		return &public
+	}
+
+	for name, public := range map[string]*WeightNormLinearPublic{
+		"repeated in GVec":   withGVec(valid.GVec[0], valid.GVec[0]),
+		"repeated in HVec":   withHVec(valid.HVec[0], valid.HVec[1], valid.HVec[2], valid.HVec[1]),
+		"G in GVec":          withGVec(valid.GVec[0], valid.G),
+		"G in HVec":          withHVec(valid.HVec[0], valid.HVec[1], valid.HVec[2], valid.G),
+		"GVec point in HVec": withHVec(valid.HVec[0], valid.GVec[1], valid.HVec[2], valid.HVec[3]),
+		"equal as points": withGVec(valid.GVec[0],
+			new(bn256.G1).Add(valid.GVec[0], new(bn256.G1).ScalarBaseMult(bint(0)))),
+	} {
+		if err := public.Validate(); !errors.Is(err, ErrDuplicateGenerator) {
+			t.Errorf("%s: expected ErrDuplicateGenerator, got %v", name, err)
+		}
+	}
+}
+
+func TestWNLATranscriptV2(t *testing.T) {
+	public := NewWeightNormLinearPublic(32, 16)
+
+	l, err := RandScalarVector(32)
+	if err != nil {
+		t.Fatalf("RandScalarVector failed: %v", err)
+	}
+
+	n, err := RandScalarVector(16)
+	if err != nil {
+		t.Fatalf("RandScalarVector failed: %v", err)
+	}
+
+	commitment, err := public.CommitWNLAChecked(l, n)
+	if err != nil {
+		t.Fatalf("CommitWNLAChecked failed: %v", err)
+	}
+
+	v2 := func(opts ...FSOption) FiatShamirEngine {
+		return NewKeccakFS(append(opts, WithTranscriptVersion(TranscriptV2))...)
+	}
+
+	for name, opts := range map[string][]ProveOption{"recursive": nil, "low memory": {WithLowMemory()}} {
+		recorder := &TranscriptRecorder{}
+		proof, err := ProveWNLA(public, commitment, v2(WithTranscriptRecorder(recorder)), l, n, opts...)
+		if err != nil {
+			t.Fatalf("%s: ProveWNLA failed: %v", name, err)
+		}
+
+		if err := VerifyWNLA(public, proof, commitment, v2()); err != nil {
+			t.Errorf("%s: VerifyWNLA failed: %v", name, err)
+		}
+
+		if err := VerifyWNLA(public, proof, commitment, NewKeccakFS()); !errors.Is(err, ErrFinalCommitmentMismatch) {
+			t.Errorf("%s: expected ErrFinalCommitmentMismatch for a TranscriptV1 verifier, got %v", name, err)
+		}
+
+		// Every round starts with its index as a tagged length.
+		entries := recorder.Entries()
+		round, start := 0, true
+		for i, e := range entries {
+			if start {
+				if e.Op != TranscriptBytes || len(e.Data) != 1 || e.Data[0] != lengthTag || i+1 == len(entries) ||
+					entries[i+1].Op != TranscriptUint64 || new(big.Int).SetBytes(entries[i+1].Data).Cmp(bint(round)) != 0 {
+					t.Fatalf("%s: round %d does not start with its index", name, round)
+				}
+				round++
+			}
+			start = e.Op == TranscriptChallenge
+		}
+
+		if round != len(proof.X) {
+			t.Errorf("%s: found %d round indices, expected %d", name, round, len(proof.X))
+		}
+	}
+
+	proof, err := ProveWNLA(public, commitment, NewKeccakFS(), l, n)
+	if err != nil {
+		t.Fatalf("ProveWNLA failed: %v", err)
+	}
+
+	if err := VerifyWNLA(public, proof, commitment, v2()); err == nil {
+		t.Error("TranscriptV1 proof accepted by a TranscriptV2 verifier")
+	}
+}
+
+func TestExpectedWNLACommitment(t *testing.T) {
+	public := NewWeightNormLinearPublic(16, 8)
+
+	l, err := RandScalarVector(16)
+	if err != nil {
+		t.Fatalf("RandScalarVector failed: %v", err)
+	}
+
+	n, err := RandScalarVector(8)
+	if err != nil {
+		t.Fatalf("RandScalarVector failed: %v", err)
+	}
+
+	commitment, err := public.CommitWNLAChecked(l, n)
+	if err != nil {
+		t.Fatalf("CommitWNLAChecked failed: %v", err)
+	}
+
+	proof, err := ProveWNLA(public, commitment, NewKeccakFS(), l, n)
+	if err != nil {
+		t.Fatalf("ProveWNLA failed: %v", err)
+	}
+
+	expected, opened, err := ExpectedWNLACommitment(public, proof, commitment, NewKeccakFS())
+	if err != nil {
+		t.Fatalf("ExpectedWNLACommitment failed: %v", err)
+	}
+
+	if !pointsEqual(expected, opened) {
+		t.Error("Valid proof has different base-case commitments")
+	}
+
+	// A tampered opening changes only the opened side.
+	tampered := *proof
+	tampered.L = append([]*big.Int{add(proof.L[0], bint(1))}, proof.L[1:]...)
+
+	tamperedExpected, tamperedOpened, err := ExpectedWNLACommitment(public, &tampered, commitment, NewKeccakFS())
+	if err != nil {
+		t.Fatalf("ExpectedWNLACommitment failed: %v", err)
+	}
+
+	if !pointsEqual(tamperedExpected, expected) || pointsEqual(tamperedOpened, expected) {
+		t.Error("Tampered opening should only change the opened commitment")
+	}
+
+	if _, _, err := ExpectedWNLACommitment(public, proof, nil, NewKeccakFS()); err == nil {
+		t.Error("Expected an error for a nil commitment")
+	}
+}